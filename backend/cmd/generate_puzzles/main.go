@@ -17,6 +17,7 @@ func main() {
 	countPerDifficulty := flag.Int("count", 10, "Number of puzzles to generate per difficulty level")
 	difficultyFlag := flag.Int("difficulty", 0, "Generate puzzles for a specific difficulty (1-5, 0 for all)")
 	cleanFlag := flag.Bool("clean", false, "Clean existing puzzles before generating new ones")
+	unsolvableFlag := flag.Int("unsolvable", 0, "Number of puzzles without a solution to keep for the impossible variant")
 	flag.Parse()
 
 	// Create a simple configuration
@@ -124,6 +125,15 @@ func main() {
 		}
 	}
 
+	// Find puzzles without a solution for the impossible variant
+	if *unsolvableFlag > 0 {
+		fmt.Printf("Finding %d puzzles without a solution...\n", *unsolvableFlag)
+		err = puzzleService.PreGenerateUnsolvablePuzzles(*unsolvableFlag)
+		if err != nil {
+			log.Fatalf("Failed to generate unsolvable puzzles: %v", err)
+		}
+	}
+
 	// Print statistics
 	elapsed := time.Since(startTime)
 	fmt.Printf("Generation completed in %s\n", elapsed)
@@ -138,6 +148,14 @@ func main() {
 		fmt.Printf("Difficulty %d: %d puzzles\n", difficulty, count)
 	}
 
+	// Count puzzles without a solution
+	unsolvableCount, err := puzzleRepo.CountUnsolvablePuzzles()
+	if err != nil {
+		log.Printf("Failed to count unsolvable puzzles: %v", err)
+	} else {
+		fmt.Printf("Unsolvable: %d puzzles\n", unsolvableCount)
+	}
+
 	// Count total puzzles
 	totalCount, err := puzzleRepo.CountPuzzles()
 	if err != nil {
@@ -7,17 +7,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis/v8"
+	"github.com/hectoclash/internal/bot"
+	"github.com/hectoclash/internal/challenge"
 	"github.com/hectoclash/internal/config"
 	"github.com/hectoclash/internal/game"
 	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/lobby"
 	"github.com/hectoclash/internal/matchmaking"
 	"github.com/hectoclash/internal/middleware"
 	"github.com/hectoclash/internal/practice"
 	"github.com/hectoclash/internal/puzzle"
+	"github.com/hectoclash/internal/rating"
 	"github.com/hectoclash/internal/repository"
 	"github.com/hectoclash/internal/routes"
 	"github.com/hectoclash/internal/services"
+	"github.com/hectoclash/internal/tournament"
 	"github.com/hectoclash/internal/websocket"
+	"github.com/hectoclash/internal/zen"
 )
 
 func main() {
@@ -43,12 +49,21 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
-	// Initialize Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.Redis.URL,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	// Initialize the matchmaking queue store; only the Redis store needs a Redis server
+	var queueStore matchmaking.QueueStore
+	switch cfg.Matchmaking.Store {
+	case "redis":
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.URL,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		queueStore = matchmaking.NewRedisStore(redisClient)
+	case "memory":
+		queueStore = matchmaking.NewMemoryStore()
+	default:
+		log.Fatalf("Unknown matchmaking store %q", cfg.Matchmaking.Store)
+	}
 
 	// Initialize WebSocket hub
 	// We'll set the matchmaking service after it's initialized
@@ -59,42 +74,112 @@ func main() {
 	userRepo := repository.NewUserRepository(db.DB)
 	gameRepo := repository.NewGameRepository(db.DB)
 	puzzleRepo := repository.NewPuzzleRepository(db.DB)
+	tournamentRepo := repository.NewTournamentRepository(db.DB)
+	lobbyRepo := repository.NewLobbyRepository(db.DB)
+	botTokenRepo := repository.NewBotTokenRepository(db.DB)
+	rushRepo := repository.NewRushRepository(db.DB)
+	zenRepo := repository.NewZenRepository(db.DB)
+	friendRepo := repository.NewFriendRepository(db.DB)
+	matchmakingStatsRepo := repository.NewMatchmakingStatsRepository(db.DB)
+	puzzleExposureRepo := repository.NewPuzzleExposureRepository(db.DB)
 	// Initialize solution metrics repository for future use
 	_ = repository.NewSolutionMetricsRepository(db.DB)
 
 	// Initialize services
 	authService := services.NewAuthService(userRepo, cfg)
+	botAccountService := services.NewBotAccountService(userRepo, botTokenRepo)
 	puzzleService := puzzle.NewService(puzzleRepo, userRepo, db.DB)
+	puzzleService.SetExposureRepository(puzzleExposureRepo)
 
 	// Initialize event service
-	eventService := game.NewEventService(wsHub)
+	eventService := game.NewEventService(wsHub, gameRepo)
 
 	// Initialize game service
 	gameService := game.NewService(gameRepo, userRepo, puzzleService, eventService)
+	gameService.SetDisconnectPolicy(cfg.Game.ReconnectGracePeriod, cfg.Game.ForfeitPenalty)
+
+	// Initialize rating service, which rates head-to-head games as they complete
+	ratingService := rating.NewService(userRepo, gameRepo, gameService)
 
 	// Initialize practice service
-	practiceService := practice.NewService(gameRepo, userRepo, puzzleService, eventService)
+	practiceService := practice.NewService(gameRepo, userRepo, puzzleService, eventService, rushRepo)
+
+	// Initialize zen service
+	zenService := zen.NewService(userRepo, puzzleRepo, zenRepo, puzzleService)
 
 	// Initialize matchmaking service
-	matchmakingService := matchmaking.NewService(redisClient, userRepo, gameService, wsHub)
+	matchmakingService := matchmaking.NewService(queueStore, userRepo, gameService, wsHub)
+	matchmakingService.SetReadyCheckPolicy(cfg.Matchmaking.ReadyCheckTimeout, cfg.Matchmaking.DeclineCooldown)
+	matchmakingService.SetFriendRepository(friendRepo)
+	matchmakingService.SetTelemetry(matchmakingStatsRepo)
+
+	// Keep apart players who blocked each other or met recently, and optionally those who have seen every puzzle of their rating
+	constraints := []matchmaking.Constraint{
+		matchmaking.NewBlockedUsersConstraint(friendRepo),
+		matchmaking.NewRecentOpponentsConstraint(gameRepo, cfg.Matchmaking.RecentOpponents, cfg.Matchmaking.RecentOpponentWait),
+	}
+	if cfg.Matchmaking.PreferUnseenPuzzles {
+		constraints = append(constraints, matchmaking.NewUnseenPuzzlesConstraint(gameRepo, puzzleRepo, cfg.Matchmaking.UnseenPuzzleWait))
+	}
+	matchmakingService.SetConstraints(constraints...)
 	go matchmakingService.Start()
 
+	// Initialize bot service, which offers bots to players waiting in the queue
+	if cfg.Bots.Enabled {
+		profiles := bot.DefaultProfiles()
+		if cfg.Bots.ProfilesFile != "" {
+			profiles, err = bot.LoadProfiles(cfg.Bots.ProfilesFile)
+			if err != nil {
+				log.Fatalf("Failed to load bot profiles: %v", err)
+			}
+		}
+
+		botService, err := bot.NewService(userRepo, gameService, profiles)
+		if err != nil {
+			log.Fatalf("Failed to initialize bot service: %v", err)
+		}
+		matchmakingService.SetBotService(botService, cfg.Bots.OfferAfter)
+	}
+
+	// Initialize tournament service
+	tournamentService := tournament.NewService(tournamentRepo, userRepo, gameService, wsHub)
+	go tournamentService.Start()
+
+	// Initialize lobby service
+	lobbyService := lobby.NewService(lobbyRepo, gameService, wsHub)
+
+	// Initialize challenge service, which lets players challenge each other and external bots directly
+	challengeService := challenge.NewService(userRepo, friendRepo, gameService, wsHub, cfg.Game.ChallengeTimeout)
+	challengeService.SetBotChallengeTimeout(cfg.BotAPI.ChallengeTimeout)
+
 	// Set the matchmaking service in the WebSocket hub
 	wsHub.SetMatchmakingService(matchmakingService)
 
 	// Initialize middlewares
-	authMiddleware := middleware.NewAuthMiddleware(authService)
+	botLimiter := middleware.NewRateLimiter(cfg.BotAPI.RateLimit, cfg.BotAPI.RateBurst)
+	authMiddleware := middleware.NewAuthMiddleware(authService, botAccountService, botLimiter)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, cfg)
 	gameHandler := handlers.NewGameHandler(gameService)
 	puzzleHandler := handlers.NewPuzzleHandler(puzzleService, puzzleRepo, userRepo)
 	wsHandler := websocket.NewHandler(wsHub)
+	wsHandler.SetGameAccess(gameService)
+	wsHandler.SetLobbyAccess(lobbyService)
 	matchmakingHandler := handlers.NewMatchmakingHandler(matchmakingService)
+	tournamentHandler := handlers.NewTournamentHandler(tournamentService)
+	lobbyHandler := handlers.NewLobbyHandler(lobbyService)
+	ratingHandler := handlers.NewRatingHandler(ratingService)
+	botHandler := handlers.NewBotHandler(botAccountService, challengeService)
+	rushHandler := handlers.NewRushHandler(rushRepo)
+	challengeHandler := handlers.NewChallengeHandler(challengeService)
 
 	// Initialize practice handler
 	practiceHandler := websocket.NewPracticeHandler(wsHub, practiceService)
 
+	// Initialize zen handler
+	_ = websocket.NewZenHandler(wsHub, zenService)
+
 	// Start a goroutine to clean up inactive practice sessions
 	go func() {
 		ticker := time.NewTicker(10 * time.Minute)
@@ -109,6 +194,13 @@ func main() {
 	routes.SetupGameRoutes(router, gameHandler, authMiddleware)
 	routes.SetupPuzzleRoutes(router, puzzleHandler, authMiddleware)
 	routes.SetupMatchmakingRoutes(router, matchmakingHandler, authMiddleware)
+	routes.SetupTournamentRoutes(router, tournamentHandler, authMiddleware)
+	routes.SetupLobbyRoutes(router, lobbyHandler, authMiddleware)
+	routes.SetupUserRoutes(router, ratingHandler, rushHandler, authMiddleware)
+	routes.SetupLeaderboardRoutes(router, ratingHandler, rushHandler, authMiddleware)
+	routes.SetupBotRoutes(router, botHandler, authMiddleware)
+	routes.SetupChallengeRoutes(router, challengeHandler, authMiddleware)
+	routes.SetupAdminRoutes(router, matchmakingHandler, authMiddleware)
 	routes.RegisterWebSocketRoutes(router, wsHandler, authMiddleware)
 
 	// Health check route
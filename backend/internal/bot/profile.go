@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// Bounds on how long a bot takes to solve a puzzle
+const (
+	minSolveTime = 3 * time.Second
+	maxSolveTime = 10 * time.Minute
+)
+
+// Most wrong attempts a bot submits before its correct one
+const maxWrongAttempts = 3
+
+// Profile describes how strong a bot plays.
+// Solve times follow a log-normal distribution around the median, which matches how
+// human solve times are skewed towards long tails.
+type Profile struct {
+	Name      string                `json:"name"`
+	Rating    int                   `json:"rating"`
+	SolveTime SolveTimeDistribution `json:"solve_time"`
+	ErrorRate float64               `json:"error_rate"` // Chance of each wrong attempt before solving
+}
+
+// SolveTimeDistribution describes the distribution of a bot's solve times
+type SolveTimeDistribution struct {
+	Median float64 `json:"median"` // Median solve time in seconds for a difficulty 1 puzzle
+	Spread float64 `json:"spread"` // Standard deviation of the logarithm of the solve time
+}
+
+// DefaultProfiles returns the built-in bot profiles, calibrated to rating bands
+func DefaultProfiles() []Profile {
+	return []Profile{
+		{Name: "Novice", Rating: 800, SolveTime: SolveTimeDistribution{Median: 150, Spread: 0.5}, ErrorRate: 0.5},
+		{Name: "Casual", Rating: 1000, SolveTime: SolveTimeDistribution{Median: 100, Spread: 0.45}, ErrorRate: 0.4},
+		{Name: "Club", Rating: 1200, SolveTime: SolveTimeDistribution{Median: 70, Spread: 0.4}, ErrorRate: 0.3},
+		{Name: "Strong", Rating: 1500, SolveTime: SolveTimeDistribution{Median: 45, Spread: 0.35}, ErrorRate: 0.2},
+		{Name: "Expert", Rating: 1800, SolveTime: SolveTimeDistribution{Median: 28, Spread: 0.3}, ErrorRate: 0.12},
+		{Name: "Master", Rating: 2100, SolveTime: SolveTimeDistribution{Median: 16, Spread: 0.25}, ErrorRate: 0.06},
+	}
+}
+
+// LoadProfiles reads bot profiles from a JSON file holding a list of profiles
+func LoadProfiles(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("failed to parse bot profiles: %w", err)
+	}
+
+	if err := validateProfiles(profiles); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// Helper function to check that profiles are usable
+func validateProfiles(profiles []Profile) error {
+	if len(profiles) == 0 {
+		return errors.New("no bot profiles")
+	}
+
+	names := make(map[string]bool)
+	for _, p := range profiles {
+		if p.Name == "" {
+			return errors.New("bot profile has no name")
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate bot profile %s", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Rating <= 0 {
+			return fmt.Errorf("bot profile %s has an invalid rating", p.Name)
+		}
+		if p.SolveTime.Median <= 0 || p.SolveTime.Spread < 0 {
+			return fmt.Errorf("bot profile %s has an invalid solve time", p.Name)
+		}
+		if p.ErrorRate < 0 || p.ErrorRate >= 1 {
+			return fmt.Errorf("bot profile %s has an invalid error rate", p.Name)
+		}
+	}
+
+	return nil
+}
+
+// sampleSolveTime draws how long the bot takes to solve a puzzle of a difficulty
+func (p Profile) sampleSolveTime(rng *rand.Rand, difficulty int) time.Duration {
+	seconds := p.SolveTime.Median * math.Exp(rng.NormFloat64()*p.SolveTime.Spread)
+
+	// Harder puzzles take longer
+	if difficulty > 1 {
+		seconds *= 1 + 0.3*float64(difficulty-1)
+	}
+
+	solveTime := time.Duration(seconds * float64(time.Second))
+	if solveTime < minSolveTime {
+		return minSolveTime
+	}
+	if solveTime > maxSolveTime {
+		return maxSolveTime
+	}
+	return solveTime
+}
+
+// sampleWrongAttempts draws how many wrong solutions the bot submits before its correct one
+func (p Profile) sampleWrongAttempts(rng *rand.Rand) int {
+	attempts := 0
+	for attempts < maxWrongAttempts && rng.Float64() < p.ErrorRate {
+		attempts++
+	}
+	return attempts
+}
+
+// Helper function to sort profiles from weakest to strongest
+func sortProfiles(profiles []Profile) {
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Rating < profiles[j].Rating
+	})
+}
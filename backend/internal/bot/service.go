@@ -0,0 +1,297 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	mathrand "math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/puzzle"
+	"github.com/hectoclash/internal/repository"
+)
+
+const (
+	// Bot accounts are named after their profile, behind models.BuiltInBotPrefix
+	botEmailDomain = "bots.hectoclash"
+
+	// How long a bot waits for its game to start
+	startTimeout = 30 * time.Second
+
+	// How often a bot checks whether its game is still going while it "thinks"
+	pollInterval = 2 * time.Second
+
+	// Bots pick among the simplest solutions, like a person would find first
+	simplestSolutions = 10
+)
+
+// Bot is a server-side player account together with how strong it plays
+type Bot struct {
+	User    *models.User
+	Profile Profile
+}
+
+// Service provides bot opponents that play through the normal game flow
+type Service struct {
+	userRepo    *repository.UserRepository
+	gameService *game.Service
+	solver      *puzzle.Solver
+	bots        []Bot // Sorted from weakest to strongest
+	rng         *mathrand.Rand
+	mu          sync.Mutex
+}
+
+// NewService creates a new bot service, creating the bot accounts of the profiles if they do not exist yet
+func NewService(userRepo *repository.UserRepository, gameService *game.Service, profiles []Profile) (*Service, error) {
+	if err := validateProfiles(profiles); err != nil {
+		return nil, err
+	}
+
+	profiles = append([]Profile(nil), profiles...)
+	sortProfiles(profiles)
+
+	service := &Service{
+		userRepo:    userRepo,
+		gameService: gameService,
+		solver:      puzzle.NewSolver(),
+		rng:         mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, profile := range profiles {
+		user, err := service.ensureBotUser(profile)
+		if errors.Is(err, errBotNameTaken) {
+			// Another account got the name before it was reserved; leave it alone
+			log.Printf("Skipping bot %s: %v", profile.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to set up bot %s: %w", profile.Name, err)
+		}
+		service.bots = append(service.bots, Bot{User: user, Profile: profile})
+	}
+	if len(service.bots) == 0 {
+		return nil, errors.New("no bot could be set up")
+	}
+
+	log.Printf("Bot service ready with %d bots", len(service.bots))
+
+	return service, nil
+}
+
+// Opponent returns the bot whose rating is closest to a player's
+func (s *Service) Opponent(rating int) Bot {
+	best := s.bots[0]
+	for _, b := range s.bots[1:] {
+		if abs(b.Profile.Rating-rating) < abs(best.Profile.Rating-rating) {
+			best = b
+		}
+	}
+	return best
+}
+
+// Find finds a bot by the ID of its account
+func (s *Service) Find(userID string) (Bot, error) {
+	for _, b := range s.bots {
+		if b.User.ID == userID {
+			return b, nil
+		}
+	}
+	return Bot{}, errors.New("bot not found")
+}
+
+// Play makes a bot play a game it has joined. It returns once the bot has finished or the game has ended.
+func (s *Service) Play(gameID string, b Bot) {
+	// Wait for the game to start
+	g, err := s.waitForStart(gameID)
+	if err != nil {
+		log.Printf("Bot %s could not play game %s: %v", b.User.Username, gameID, err)
+		return
+	}
+
+	startedAt := time.Now()
+	if g.StartedAt != nil {
+		startedAt = *g.StartedAt
+	}
+
+	// Find a solution like a player would
+	solutions := s.solver.Solve(g.PuzzleSequence)
+	if len(solutions) == 0 && g.Variant == models.GameVariantImpossible {
+		// The variant lets the bot claim there is no solution, once it has looked long enough
+		s.mu.Lock()
+		solveTime := b.Profile.sampleSolveTime(s.rng, g.Difficulty)
+		s.mu.Unlock()
+
+		if !s.waitUntil(gameID, startedAt.Add(solveTime)) {
+			return
+		}
+		if err := s.gameService.SubmitSolution(gameID, b.User.ID, puzzle.ImpossibleClaim); err != nil {
+			log.Printf("Bot %s could not claim game %s is impossible: %v", b.User.Username, gameID, err)
+		}
+		return
+	}
+	if len(solutions) == 0 {
+		// Nobody can solve it, so the bot concedes rather than keep the game waiting
+		if err := s.gameService.ForfeitPlayer(gameID, b.User.ID); err != nil {
+			log.Printf("Bot %s could not forfeit game %s: %v", b.User.Username, gameID, err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	solution := solutions[s.rng.Intn(min(len(solutions), simplestSolutions))]
+	solveTime := b.Profile.sampleSolveTime(s.rng, g.Difficulty)
+	wrongAttempts := b.Profile.sampleWrongAttempts(s.rng)
+	s.mu.Unlock()
+
+	// Wrong attempts are spread over the time before the solution
+	wrong := wrongSolution(g.PuzzleSequence, solutions)
+	if wrong == "" {
+		wrongAttempts = 0
+	}
+
+	for i := 1; i <= wrongAttempts; i++ {
+		at := startedAt.Add(solveTime * time.Duration(i) / time.Duration(wrongAttempts+1))
+		if !s.waitUntil(gameID, at) {
+			return
+		}
+		if err := s.gameService.SubmitSolution(gameID, b.User.ID, wrong); err != nil {
+			return
+		}
+	}
+
+	// Submit the solution
+	if !s.waitUntil(gameID, startedAt.Add(solveTime)) {
+		return
+	}
+	if err := s.gameService.SubmitSolution(gameID, b.User.ID, solution); err != nil {
+		log.Printf("Bot %s could not submit its solution to game %s: %v", b.User.Username, gameID, err)
+	}
+}
+
+// errBotNameTaken is returned when a bot's username belongs to an account that is not a built-in bot
+var errBotNameTaken = errors.New("username belongs to another account")
+
+// ensureBotUser finds or creates the account of a bot and keeps its ratings at the profile's rating
+func (s *Service) ensureBotUser(profile Profile) (*models.User, error) {
+	username := models.BuiltInBotPrefix + strings.ToLower(profile.Name)
+
+	user, err := s.userRepo.FindByUsername(username)
+	if err != nil {
+		// Bots never log in, so their password is random
+		password, err := randomPassword()
+		if err != nil {
+			return nil, err
+		}
+
+		user = &models.User{
+			Username: username,
+			Email:    username + "@" + botEmailDomain,
+			Password: password,
+			Rating:   profile.Rating,
+			IsBot:    true,
+		}
+		if err := s.userRepo.Create(user); err != nil {
+			return nil, err
+		}
+	}
+
+	// People and external bots who took the name before it was reserved keep their account
+	if !user.IsBot || user.BotOwnerID != nil {
+		return nil, fmt.Errorf("%w: %s", errBotNameTaken, username)
+	}
+
+	// Bots are matched on the rating of their profile in every mode
+	for _, mode := range models.RatingModes {
+		rating, err := s.userRepo.GetUserRating(user.ID, mode)
+		if err != nil {
+			return nil, err
+		}
+		if rating.Rating == profile.Rating {
+			continue
+		}
+
+		rating.Rating = profile.Rating
+		if err := s.userRepo.SaveUserRating(rating); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// waitForStart waits until a game is active
+func (s *Service) waitForStart(gameID string) (*models.Game, error) {
+	deadline := time.Now().Add(startTimeout)
+	for {
+		g, err := s.gameService.GetGame(gameID)
+		if err != nil {
+			return nil, err
+		}
+
+		switch g.Status {
+		case models.GameStatusActive:
+			return g, nil
+		case models.GameStatusWaiting:
+		default:
+			return nil, errors.New("game ended before it started")
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.New("game did not start")
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// waitUntil sleeps until a point in time, reporting false if the game ends first
+func (s *Service) waitUntil(gameID string, at time.Time) bool {
+	for {
+		wait := time.Until(at)
+		if wait <= 0 {
+			return true
+		}
+		if wait > pollInterval {
+			wait = pollInterval
+		}
+		time.Sleep(wait)
+
+		g, err := s.gameService.GetGame(gameID)
+		if err != nil || g.Status != models.GameStatusActive {
+			return false
+		}
+	}
+}
+
+// Helper function to get a plausible but wrong solution: the digits added up
+func wrongSolution(sequence string, solutions []string) string {
+	wrong := strings.Join(strings.Split(sequence, ""), "+")
+	for _, solution := range solutions {
+		if solution == wrong {
+			return ""
+		}
+	}
+	return wrong
+}
+
+// Helper function to generate a random password for a bot account
+func randomPassword() (string, error) {
+	bytes := make([]byte, 24)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// Helper function to get the absolute value of a number
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
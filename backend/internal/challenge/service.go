@@ -0,0 +1,556 @@
+package challenge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+	"github.com/hectoclash/internal/websocket"
+)
+
+const (
+	maxTimeLimit         = 3600 // in seconds
+	maxPendingChallenges = 5    // Open challenges a player may have sent at once
+	defaultChallengeType = "duel"
+)
+
+// Game types a player can be challenged to
+var challengeGameTypes = map[string]bool{
+	"duel":  true,
+	"blitz": true,
+}
+
+// Settings are the terms of a challenge
+type Settings struct {
+	GameType  string `json:"game_type"`
+	Variant   string `json:"variant"`
+	TimeLimit int    `json:"time_limit"` // in seconds, 0 for no limit
+	Ranked    bool   `json:"ranked"`
+}
+
+// Challenge is an open invitation from one player to another to play a game
+type Challenge struct {
+	ID           string `json:"id"`
+	ChallengerID string `json:"challenger_id"`
+	ChallengedID string `json:"challenged_id"`
+	Settings
+	CounterOf string    `json:"counter_of,omitempty"` // The challenge this one answers with other settings
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+
+	challenger *models.User
+	challenged *models.User // An external bot answers through the bot API
+	timer      *time.Timer
+}
+
+// Service lets players challenge each other and external bots directly. Open challenges are kept
+// in memory and lapse if they are not answered in time.
+type Service struct {
+	userRepo    *repository.UserRepository
+	friendRepo  *repository.FriendRepository
+	gameService *game.Service
+	hub         *websocket.Hub
+	timeout     time.Duration
+	botTimeout  time.Duration         // How long a challenge to an external bot stays open
+	challenges  map[string]*Challenge // Open challenges by ID
+	mu          sync.Mutex
+}
+
+// NewService creates a new challenge service
+func NewService(userRepo *repository.UserRepository, friendRepo *repository.FriendRepository, gameService *game.Service, hub *websocket.Hub, timeout time.Duration) *Service {
+	service := &Service{
+		userRepo:    userRepo,
+		friendRepo:  friendRepo,
+		gameService: gameService,
+		hub:         hub,
+		timeout:     timeout,
+		botTimeout:  timeout,
+		challenges:  make(map[string]*Challenge),
+	}
+
+	// Register challenge message handlers
+	if hub != nil {
+		hub.RegisterMessageHandler(websocket.MessageTypeChallengeOffer, service.handleOffer)
+		hub.RegisterMessageHandler(websocket.MessageTypeChallengeAccept, service.handleAccept)
+		hub.RegisterMessageHandler(websocket.MessageTypeChallengeDecline, service.handleDecline)
+		hub.RegisterMessageHandler(websocket.MessageTypeChallengeCounter, service.handleCounter)
+	}
+
+	return service
+}
+
+// SetBotChallengeTimeout sets how long a challenge to an external bot stays open
+func (s *Service) SetBotChallengeTimeout(timeout time.Duration) {
+	s.botTimeout = timeout
+}
+
+// Create challenges a player to a game with the given settings
+func (s *Service) Create(challengerID, challengedID string, settings Settings) (*Challenge, error) {
+	settings, err := normalizeSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	challenger, challenged, err := s.checkOpponent(challengerID, challengedID, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	challenge, err := s.openLocked(challenger, challenged, settings, "")
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s challenged user %s to a %s game", challengerID, challengedID, settings.GameType)
+
+	s.notify(challenge, websocket.MessageTypeChallengeOffer, "", "")
+
+	return challenge, nil
+}
+
+// Accept accepts a challenge sent to a player and starts the game
+func (s *Service) Accept(userID, challengeID string) (*models.Game, error) {
+	s.mu.Lock()
+	challenge, err := s.findLocked(challengeID)
+	if err == nil && challenge.ChallengedID != userID {
+		err = errors.New("challenge is not for this user")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// Close the challenge before creating the game so it cannot be accepted twice
+	s.closeLocked(challenge)
+	s.mu.Unlock()
+
+	g, err := s.gameService.CreateChallengeGame(challenge.ChallengerID, challenge.ChallengedID,
+		challenge.GameType, challenge.Variant, challenge.TimeLimit, !challenge.Ranked)
+	if err != nil {
+		s.notify(challenge, websocket.MessageTypeChallengeCancelled, "", "failed")
+		return nil, fmt.Errorf("failed to create game: %w", err)
+	}
+
+	log.Printf("User %s accepted challenge %s, starting game %s", userID, challenge.ID, g.ID)
+
+	s.notify(challenge, websocket.MessageTypeChallengeStart, g.ID, "")
+
+	return g, nil
+}
+
+// Decline declines a challenge sent to a player, or withdraws it when sent by the player. The
+// challenged player may give a reason, which is passed on instead of "declined".
+func (s *Service) Decline(userID, challengeID, reason string) (*Challenge, error) {
+	s.mu.Lock()
+	challenge, err := s.findLocked(challengeID)
+	if err == nil && challenge.ChallengedID != userID && challenge.ChallengerID != userID {
+		err = errors.New("challenge is not for this user")
+	}
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.closeLocked(challenge)
+	s.mu.Unlock()
+
+	if challenge.ChallengerID == userID {
+		reason = "withdrawn"
+	} else if reason == "" {
+		reason = "declined"
+	}
+	s.notify(challenge, websocket.MessageTypeChallengeCancelled, "", reason)
+
+	return challenge, nil
+}
+
+// Counter answers a challenge sent to a player with a challenge of their own in the other
+// direction, with other settings. The counter-challenge replaces the original.
+func (s *Service) Counter(userID, challengeID string, settings Settings) (*Challenge, error) {
+	s.mu.Lock()
+	original, err := s.findLocked(challengeID)
+	if err == nil && original.ChallengedID != userID {
+		err = errors.New("challenge is not for this user")
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, err = normalizeSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+	if settings == original.Settings {
+		return nil, errors.New("counter-challenge must change the settings")
+	}
+
+	// The players were checked with the original, but the new settings may be ranked
+	if err := checkRanked(settings, original.challenged, original.challenger); err != nil {
+		return nil, err
+	}
+	if original.challenger.IsExternalBot() && !s.hub.IsBotOnline(original.challenger.ID) {
+		return nil, errors.New("bot is offline")
+	}
+
+	s.mu.Lock()
+	// The original may have been withdrawn or have lapsed in the meantime
+	if s.challenges[original.ID] != original {
+		s.mu.Unlock()
+		return nil, errors.New("challenge not found")
+	}
+	counter, err := s.openLocked(original.challenged, original.challenger, settings, original.ID)
+	if err == nil {
+		s.closeLocked(original)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("User %s countered challenge %s with challenge %s", userID, original.ID, counter.ID)
+
+	s.notify(original, websocket.MessageTypeChallengeCancelled, "", "countered")
+	s.notify(counter, websocket.MessageTypeChallengeOffer, "", "")
+
+	return counter, nil
+}
+
+// Incoming gets the open challenges sent to a player, oldest first
+func (s *Service) Incoming(userID string) []Challenge {
+	return s.list(func(challenge *Challenge) bool {
+		return challenge.ChallengedID == userID
+	})
+}
+
+// Outgoing gets the open challenges a player has sent, oldest first
+func (s *Service) Outgoing(userID string) []Challenge {
+	return s.list(func(challenge *Challenge) bool {
+		return challenge.ChallengerID == userID
+	})
+}
+
+// list copies the open challenges that match a filter, oldest first
+func (s *Service) list(match func(*Challenge) bool) []Challenge {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenges := make([]Challenge, 0)
+	for _, challenge := range s.challenges {
+		if match(challenge) {
+			challenges = append(challenges, *challenge)
+		}
+	}
+	sort.Slice(challenges, func(i, j int) bool {
+		return challenges[i].CreatedAt.Before(challenges[j].CreatedAt)
+	})
+	return challenges
+}
+
+// checkOpponent checks that a player may challenge another with the given settings, and returns
+// both players
+func (s *Service) checkOpponent(challengerID, challengedID string, settings Settings) (*models.User, *models.User, error) {
+	if challengerID == challengedID {
+		return nil, nil, errors.New("cannot challenge yourself")
+	}
+
+	challenger, err := s.userRepo.FindByID(challengerID)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	challenged, err := s.userRepo.FindByID(challengedID)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	// Built-in bots only play through matchmaking; external bots answer through the bot API
+	if challenged.IsBot && !challenged.IsExternalBot() {
+		return nil, nil, errors.New("bots cannot be challenged directly")
+	}
+	if challenged.IsExternalBot() && !s.hub.IsBotOnline(challenged.ID) {
+		return nil, nil, errors.New("bot is offline")
+	}
+
+	if err := checkRanked(settings, challenger, challenged); err != nil {
+		return nil, nil, err
+	}
+
+	// Blocks work both ways, without telling the challenger which way
+	for _, pair := range [][2]string{{challengerID, challengedID}, {challengedID, challengerID}} {
+		blocked, err := s.friendRepo.IsBlocked(pair[0], pair[1])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to check blocks: %w", err)
+		}
+		if blocked {
+			return nil, nil, errors.New("user cannot be challenged")
+		}
+	}
+
+	return challenger, challenged, nil
+}
+
+// openLocked opens a challenge to a player and starts its timer. A counter-challenge may be opened
+// while the challenge it replaces is still open. The caller must hold mu.
+func (s *Service) openLocked(challenger, challenged *models.User, settings Settings, counterOf string) (*Challenge, error) {
+	challengerID, challengedID := challenger.ID, challenged.ID
+	sent := 0
+	for _, open := range s.challenges {
+		if open.ID == counterOf {
+			continue
+		}
+		if open.ChallengerID == challengerID {
+			sent++
+		}
+		if (open.ChallengerID == challengerID && open.ChallengedID == challengedID) ||
+			(open.ChallengerID == challengedID && open.ChallengedID == challengerID) {
+			return nil, errors.New("a challenge between these players is already open")
+		}
+	}
+	if sent >= maxPendingChallenges {
+		return nil, fmt.Errorf("cannot have more than %d open challenges", maxPendingChallenges)
+	}
+
+	timeout := s.timeout
+	if challenged.IsExternalBot() {
+		timeout = s.botTimeout
+	}
+
+	now := time.Now()
+	challenge := &Challenge{
+		ID:           uuid.NewString(),
+		ChallengerID: challengerID,
+		ChallengedID: challengedID,
+		Settings:     settings,
+		CounterOf:    counterOf,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(timeout),
+		challenger:   challenger,
+		challenged:   challenged,
+	}
+
+	// Withdraw the challenge if it is not answered in time
+	challenge.timer = time.AfterFunc(timeout, func() {
+		s.expire(challenge)
+	})
+	s.challenges[challenge.ID] = challenge
+
+	return challenge, nil
+}
+
+// findLocked finds an open challenge. The caller must hold mu.
+func (s *Service) findLocked(challengeID string) (*Challenge, error) {
+	challenge, exists := s.challenges[challengeID]
+	if !exists {
+		return nil, errors.New("challenge not found")
+	}
+	return challenge, nil
+}
+
+// closeLocked stops a challenge's timer and forgets it. The caller must hold mu.
+func (s *Service) closeLocked(challenge *Challenge) {
+	challenge.timer.Stop()
+	delete(s.challenges, challenge.ID)
+}
+
+// expire withdraws a challenge that was not answered in time
+func (s *Service) expire(challenge *Challenge) {
+	s.mu.Lock()
+	if s.challenges[challenge.ID] != challenge {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.challenges, challenge.ID)
+	s.mu.Unlock()
+
+	s.notify(challenge, websocket.MessageTypeChallengeCancelled, "", "expired")
+}
+
+// handleOffer handles a client challenging another player
+func (s *Service) handleOffer(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ChallengePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		s.hub.SendError(c, 400, "Invalid challenge payload")
+		return
+	}
+
+	if _, err := s.Create(c.UserID, payload.UserID, payloadSettings(payload)); err != nil {
+		s.hub.SendError(c, 400, err.Error())
+	}
+}
+
+// handleAccept handles a client accepting a challenge
+func (s *Service) handleAccept(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ChallengePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		s.hub.SendError(c, 400, "Invalid challenge payload")
+		return
+	}
+
+	if _, err := s.Accept(c.UserID, payload.ChallengeID); err != nil {
+		s.hub.SendError(c, 400, err.Error())
+	}
+}
+
+// handleDecline handles a client declining or withdrawing a challenge
+func (s *Service) handleDecline(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ChallengePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		s.hub.SendError(c, 400, "Invalid challenge payload")
+		return
+	}
+
+	if _, err := s.Decline(c.UserID, payload.ChallengeID, ""); err != nil {
+		s.hub.SendError(c, 400, err.Error())
+	}
+}
+
+// handleCounter handles a client answering a challenge with other settings
+func (s *Service) handleCounter(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ChallengePayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		s.hub.SendError(c, 400, "Invalid challenge payload")
+		return
+	}
+
+	if _, err := s.Counter(c.UserID, payload.ChallengeID, payloadSettings(payload)); err != nil {
+		s.hub.SendError(c, 400, err.Error())
+	}
+}
+
+// notify sends a challenge message to both players, if they are connected. A challenged external
+// bot hears of offers and cancellations on its event stream, and of the game when it starts.
+func (s *Service) notify(challenge *Challenge, messageType websocket.MessageType, gameID, reason string) {
+	if s.hub == nil {
+		return
+	}
+
+	challenger := s.playerPayload(challenge.ChallengerID)
+	challenged := s.playerPayload(challenge.ChallengedID)
+	payload := websocket.ChallengePayload{
+		ChallengeID: challenge.ID,
+		Challenger:  challenger,
+		Challenged:  challenged,
+		GameType:    challenge.GameType,
+		Variant:     challenge.Variant,
+		TimeLimit:   challenge.TimeLimit,
+		Ranked:      challenge.Ranked,
+		CounterOf:   challenge.CounterOf,
+		ExpiresAt:   challenge.ExpiresAt.UnixNano() / int64(time.Millisecond),
+		GameID:      gameID,
+		Reason:      reason,
+	}
+
+	userIDs := []string{challenge.ChallengerID, challenge.ChallengedID}
+	if challenge.challenged.IsExternalBot() {
+		userIDs = userIDs[:1]
+		s.notifyBot(challenge, messageType, challenger, challenged, reason)
+	}
+
+	for _, userID := range userIDs {
+		client := s.hub.GetClientByUserID(userID)
+		if client == nil {
+			continue
+		}
+		if err := s.hub.SendChallenge(client, messageType, payload); err != nil {
+			log.Printf("Error sending %s to user %s: %v", messageType, userID, err)
+		}
+	}
+}
+
+// notifyBot sends an offer or cancellation of a challenge to the event stream of the challenged bot
+func (s *Service) notifyBot(challenge *Challenge, messageType websocket.MessageType, challenger, bot *websocket.PlayerPayload, reason string) {
+	switch messageType {
+	case websocket.MessageTypeChallengeOffer:
+		messageType = websocket.MessageTypeBotChallenge
+	case websocket.MessageTypeChallengeCancelled:
+		messageType = websocket.MessageTypeBotChallengeCanceled
+	default:
+		return
+	}
+	if challenger == nil || bot == nil {
+		return
+	}
+
+	payload := websocket.BotChallengePayload{
+		ChallengeID: challenge.ID,
+		Challenger:  *challenger,
+		Bot:         *bot,
+		GameType:    challenge.GameType,
+		Variant:     challenge.Variant,
+		TimeLimit:   challenge.TimeLimit,
+		Ranked:      challenge.Ranked,
+		ExpiresAt:   challenge.ExpiresAt.UnixNano() / int64(time.Millisecond),
+		Reason:      reason,
+	}
+	if err := s.hub.SendBotChallenge(messageType, payload); err != nil {
+		log.Printf("Error sending %s to bot %s: %v", messageType, challenge.ChallengedID, err)
+	}
+}
+
+// playerPayload describes a player for WebSocket messages, or nil if they cannot be found
+func (s *Service) playerPayload(userID string) *websocket.PlayerPayload {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		log.Printf("Error getting user %s: %v", userID, err)
+		return nil
+	}
+	return &websocket.PlayerPayload{
+		UserID:   user.ID,
+		Username: user.Username,
+		IsBot:    user.IsBot,
+	}
+}
+
+// Helper function to validate the settings of a challenge, filling in the defaults
+func normalizeSettings(settings Settings) (Settings, error) {
+	if settings.GameType == "" {
+		settings.GameType = defaultChallengeType
+	}
+	if !challengeGameTypes[settings.GameType] {
+		return settings, errors.New("invalid game type")
+	}
+
+	if settings.Variant == "" {
+		settings.Variant = models.GameVariantClassic
+	}
+	if !models.IsValidGameVariant(settings.Variant) {
+		return settings, errors.New("unknown variant")
+	}
+
+	if settings.TimeLimit < 0 || settings.TimeLimit > maxTimeLimit {
+		return settings, fmt.Errorf("time limit must be between 0 and %d seconds", maxTimeLimit)
+	}
+
+	return settings, nil
+}
+
+// Helper function to check that both players may play a ranked challenge. Bots only play ranked
+// games against people when an operator has allowed it, whichever side of the challenge they are.
+func checkRanked(settings Settings, challenger, challenged *models.User) error {
+	if !settings.Ranked {
+		return nil
+	}
+	if !challenger.PlaysRanked() || !challenged.PlaysRanked() {
+		return errors.New("bots without ranked play can only play casual challenges")
+	}
+	return nil
+}
+
+// Helper function to read the settings of a challenge message
+func payloadSettings(payload websocket.ChallengePayload) Settings {
+	return Settings{
+		GameType:  payload.GameType,
+		Variant:   payload.Variant,
+		TimeLimit: payload.TimeLimit,
+		Ranked:    payload.Ranked,
+	}
+}
@@ -0,0 +1,198 @@
+package challenge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestNormalizeSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		want     Settings
+		wantErr  bool
+	}{
+		{
+			name:     "defaults",
+			settings: Settings{},
+			want:     Settings{GameType: "duel", Variant: models.GameVariantClassic},
+		},
+		{
+			name:     "kept",
+			settings: Settings{GameType: "blitz", Variant: models.GameVariantImpossible, TimeLimit: 120, Ranked: true},
+			want:     Settings{GameType: "blitz", Variant: models.GameVariantImpossible, TimeLimit: 120, Ranked: true},
+		},
+		{
+			name:     "unknown game type",
+			settings: Settings{GameType: "tournament"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown variant",
+			settings: Settings{Variant: "reverse"},
+			wantErr:  true,
+		},
+		{
+			name:     "time limit too long",
+			settings: Settings{TimeLimit: maxTimeLimit + 1},
+			wantErr:  true,
+		},
+		{
+			name:     "negative time limit",
+			settings: Settings{TimeLimit: -1},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeSettings(tt.settings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("normalizeSettings() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenChallenges(t *testing.T) {
+	s := NewService(nil, nil, nil, nil, time.Minute)
+	settings := Settings{GameType: "duel", Variant: models.GameVariantClassic}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.openLocked(player("alice"), player("bob"), settings, ""); err != nil {
+		t.Fatalf("openLocked() error = %v", err)
+	}
+
+	// Only one challenge may be open between two players, in either direction
+	if _, err := s.openLocked(player("alice"), player("bob"), settings, ""); err == nil {
+		t.Error("openLocked() opened a second challenge to the same player")
+	}
+	if _, err := s.openLocked(player("bob"), player("alice"), settings, ""); err == nil {
+		t.Error("openLocked() opened a challenge back to the challenger")
+	}
+
+	// A player may only have a few challenges open at once
+	for i := 1; i < maxPendingChallenges; i++ {
+		if _, err := s.openLocked(player("alice"), player(string(rune('c'+i))), settings, ""); err != nil {
+			t.Fatalf("openLocked() error = %v", err)
+		}
+	}
+	if _, err := s.openLocked(player("alice"), player("zed"), settings, ""); err == nil {
+		t.Error("openLocked() opened more challenges than allowed")
+	}
+}
+
+func TestCounterReplacesChallenge(t *testing.T) {
+	s := NewService(nil, nil, nil, nil, time.Minute)
+	settings := Settings{GameType: "duel", Variant: models.GameVariantClassic}
+
+	s.mu.Lock()
+	original, err := s.openLocked(player("alice"), player("bob"), settings, "")
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatalf("openLocked() error = %v", err)
+	}
+
+	if _, err := s.Counter("alice", original.ID, Settings{GameType: "blitz"}); err == nil {
+		t.Error("Counter() let the challenger counter their own challenge")
+	}
+	if _, err := s.Counter("bob", original.ID, settings); err == nil {
+		t.Error("Counter() accepted a counter-challenge with the same settings")
+	}
+
+	counter, err := s.Counter("bob", original.ID, Settings{GameType: "blitz", TimeLimit: 60})
+	if err != nil {
+		t.Fatalf("Counter() error = %v", err)
+	}
+	if counter.ChallengerID != "bob" || counter.ChallengedID != "alice" || counter.CounterOf != original.ID {
+		t.Errorf("Counter() = %+v, want a challenge from bob to alice countering %s", counter, original.ID)
+	}
+
+	if incoming := s.Incoming("bob"); len(incoming) != 0 {
+		t.Errorf("Incoming(bob) = %d challenges, want the original to be replaced", len(incoming))
+	}
+	if incoming := s.Incoming("alice"); len(incoming) != 1 || incoming[0].ID != counter.ID {
+		t.Errorf("Incoming(alice) = %+v, want the counter-challenge", incoming)
+	}
+	if outgoing := s.Outgoing("bob"); len(outgoing) != 1 || outgoing[0].ID != counter.ID {
+		t.Errorf("Outgoing(bob) = %+v, want the counter-challenge", outgoing)
+	}
+}
+
+func TestChallengeExpires(t *testing.T) {
+	s := NewService(nil, nil, nil, nil, 20*time.Millisecond)
+
+	s.mu.Lock()
+	_, err := s.openLocked(player("alice"), player("bob"), Settings{GameType: "duel", Variant: models.GameVariantClassic}, "")
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatalf("openLocked() error = %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(s.Outgoing("alice")) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("challenge did not expire")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCheckRanked(t *testing.T) {
+	ownerID := "owner"
+	human := &models.User{ID: "human"}
+	bot := &models.User{ID: "bot", IsBot: true, BotOwnerID: &ownerID}
+	rankedBot := &models.User{ID: "ranked-bot", IsBot: true, BotOwnerID: &ownerID, BotRanked: true}
+
+	tests := []struct {
+		name       string
+		ranked     bool
+		challenger *models.User
+		challenged *models.User
+		wantErr    bool
+	}{
+		{"casual against a bot", false, human, bot, false},
+		{"ranked between people", true, human, human, false},
+		{"ranked to a bot", true, human, bot, true},
+		{"ranked from a bot", true, bot, human, true},
+		{"ranked from a ranked bot", true, rankedBot, human, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRanked(Settings{Ranked: tt.ranked}, tt.challenger, tt.challenged)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkRanked() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCounterKeepsRankedRule(t *testing.T) {
+	ownerID := "owner"
+	s := NewService(nil, nil, nil, nil, time.Minute)
+
+	s.mu.Lock()
+	original, err := s.openLocked(&models.User{ID: "bot", IsBot: true, BotOwnerID: &ownerID}, player("alice"),
+		Settings{GameType: "duel", Variant: models.GameVariantClassic}, "")
+	s.mu.Unlock()
+	if err != nil {
+		t.Fatalf("openLocked() error = %v", err)
+	}
+
+	if _, err := s.Counter("alice", original.ID, Settings{GameType: "duel", Ranked: true}); err == nil {
+		t.Error("Counter() turned a challenge from a bot without ranked play into a ranked one")
+	}
+}
+
+// Helper function to make a player who is a person
+func player(id string) *models.User {
+	return &models.User{ID: id}
+}
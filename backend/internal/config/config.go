@@ -13,11 +13,15 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	CORS     CORSConfig
-	Redis    RedisConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	CORS        CORSConfig
+	Redis       RedisConfig
+	Matchmaking MatchmakingConfig
+	Game        GameConfig
+	Bots        BotConfig
+	BotAPI      BotAPIConfig
 }
 
 // ServerConfig holds all server related configuration
@@ -57,6 +61,38 @@ type RedisConfig struct {
 	DB       int
 }
 
+// MatchmakingConfig holds all matchmaking related configuration
+type MatchmakingConfig struct {
+	Store               string        // Where the queues are kept: "redis", shared by every replica, or "memory" for a single node
+	ReadyCheckTimeout   time.Duration // How long matched players have to accept their match
+	DeclineCooldown     time.Duration // How long players who decline or miss a match stay out of the queue
+	RecentOpponents     int           // Players are not paired with anyone they met in this many of their last games
+	RecentOpponentWait  time.Duration // How long a player waits before recent opponents are allowed again
+	PreferUnseenPuzzles bool          // Prefer pairs who have puzzles of their rating left that neither has played
+	UnseenPuzzleWait    time.Duration // How long a player waits before a pair without unseen puzzles is allowed
+}
+
+// GameConfig holds all game related configuration
+type GameConfig struct {
+	ReconnectGracePeriod time.Duration // How long a disconnected player has to come back
+	ForfeitPenalty       int           // Rating lost when a player forfeits by not coming back
+	ChallengeTimeout     time.Duration // How long a challenge to another player stays open
+}
+
+// BotConfig holds all configuration of the server's bot opponents
+type BotConfig struct {
+	Enabled      bool          // Offer bots to players waiting in the matchmaking queue
+	OfferAfter   time.Duration // How long a player waits before being offered a bot
+	ProfilesFile string        // Optional JSON file with bot profiles, replacing the built-in ones
+}
+
+// BotAPIConfig holds all configuration of the API for external bots
+type BotAPIConfig struct {
+	RateLimit        int           // Requests a bot may make per minute
+	RateBurst        int           // Requests a bot may make at once
+	ChallengeTimeout time.Duration // How long a challenge to a bot stays open
+}
+
 // Load loads the configuration from environment variables
 func Load() *Config {
 	// Load .env file if it exists
@@ -92,6 +128,30 @@ func Load() *Config {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
+		Matchmaking: MatchmakingConfig{
+			Store:               getEnv("MATCHMAKING_STORE", "redis"),
+			ReadyCheckTimeout:   time.Duration(getEnvAsInt("READY_CHECK_TIMEOUT", 15)) * time.Second,
+			DeclineCooldown:     time.Duration(getEnvAsInt("READY_CHECK_COOLDOWN", 30)) * time.Second,
+			RecentOpponents:     getEnvAsInt("MATCHMAKING_RECENT_OPPONENTS", 3),
+			RecentOpponentWait:  time.Duration(getEnvAsInt("MATCHMAKING_RECENT_OPPONENT_WAIT", 30)) * time.Second,
+			PreferUnseenPuzzles: getEnvAsBool("MATCHMAKING_PREFER_UNSEEN_PUZZLES", false),
+			UnseenPuzzleWait:    time.Duration(getEnvAsInt("MATCHMAKING_UNSEEN_PUZZLE_WAIT", 15)) * time.Second,
+		},
+		Game: GameConfig{
+			ReconnectGracePeriod: time.Duration(getEnvAsInt("RECONNECT_GRACE_PERIOD", 30)) * time.Second,
+			ForfeitPenalty:       getEnvAsInt("FORFEIT_RATING_PENALTY", 15),
+			ChallengeTimeout:     time.Duration(getEnvAsInt("CHALLENGE_TIMEOUT", 120)) * time.Second,
+		},
+		Bots: BotConfig{
+			Enabled:      getEnvAsBool("BOTS_ENABLED", true),
+			OfferAfter:   time.Duration(getEnvAsInt("BOT_OFFER_AFTER", 20)) * time.Second,
+			ProfilesFile: getEnv("BOT_PROFILES_FILE", ""),
+		},
+		BotAPI: BotAPIConfig{
+			RateLimit:        getEnvAsInt("BOT_API_RATE_LIMIT", 60),
+			RateBurst:        getEnvAsInt("BOT_API_RATE_BURST", 10),
+			ChallengeTimeout: time.Duration(getEnvAsInt("BOT_CHALLENGE_TIMEOUT", 60)) * time.Second,
+		},
 	}
 
 	// Build the database URL
@@ -123,3 +183,12 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// Helper function to get an environment variable as a boolean
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,283 @@
+package game
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// Defaults used until a policy is configured
+const (
+	defaultReconnectGracePeriod = 30 * time.Second
+	defaultForfeitPenalty       = 15
+)
+
+// DisconnectService forfeits players who drop out of an active game and do not come back in time
+type DisconnectService struct {
+	gameService    *Service
+	eventService   *EventService
+	gracePeriod    time.Duration
+	forfeitPenalty int
+	timers         map[string]*time.Timer // Grace timers by game ID and user ID
+	mutex          sync.Mutex
+}
+
+// NewDisconnectService creates a new disconnect service
+func NewDisconnectService(gameService *Service, eventService *EventService) *DisconnectService {
+	service := &DisconnectService{
+		gameService:    gameService,
+		eventService:   eventService,
+		gracePeriod:    defaultReconnectGracePeriod,
+		forfeitPenalty: defaultForfeitPenalty,
+		timers:         make(map[string]*time.Timer),
+	}
+
+	// Listen for players leaving and rejoining game rooms
+	if eventService != nil && eventService.hub != nil {
+		eventService.hub.SetPresenceListener(service)
+	}
+
+	return service
+}
+
+// SetPolicy sets the reconnect grace period and the rating penalty for forfeiting
+func (s *DisconnectService) SetPolicy(gracePeriod time.Duration, forfeitPenalty int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.gracePeriod = gracePeriod
+	s.forfeitPenalty = forfeitPenalty
+}
+
+// PlayerDisconnected starts the grace period of a player who dropped out of a game room.
+// It is called from the hub's run loop, so the game itself is only looked at once the period ends.
+func (s *DisconnectService) PlayerDisconnected(gameID, userID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := timerKey(gameID, userID)
+	if _, exists := s.timers[key]; exists {
+		return
+	}
+
+	s.timers[key] = time.AfterFunc(s.gracePeriod, func() {
+		s.mutex.Lock()
+		delete(s.timers, key)
+		s.mutex.Unlock()
+
+		if err := s.Forfeit(gameID, userID); err != nil {
+			log.Printf("Error forfeiting player %s in game %s: %v", userID, gameID, err)
+		}
+	})
+}
+
+// PlayerReconnected cancels the grace period of a player who came back
+func (s *DisconnectService) PlayerReconnected(gameID, userID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := timerKey(gameID, userID)
+	if timer, exists := s.timers[key]; exists {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+}
+
+// Forfeit forfeits a player who did not come back to an active game.
+// The game is abandoned when nobody is left, a head-to-head game goes to the last player standing,
+// and a game with sides to the last side standing.
+func (s *DisconnectService) Forfeit(gameID, userID string) error {
+	// Whatever happens next, the player is no longer waited for
+	if s.eventService != nil && s.eventService.hub != nil {
+		defer s.eventService.hub.ForgetDisconnected(gameID, userID)
+	}
+
+	s.mutex.Lock()
+	penalty := s.forfeitPenalty
+	s.mutex.Unlock()
+
+	var (
+		game      *models.Game
+		forfeited bool
+		completed bool
+	)
+
+	// Forfeit under the game's lock, like solution submissions
+	err := s.gameService.gameRepo.Transaction(func(games *repository.GameRepository, users *repository.UserRepository) error {
+		// Find and lock game by ID
+		var err error
+		game, err = games.FindByIDForUpdate(gameID)
+		if err != nil {
+			return err
+		}
+
+		// Only active games can be forfeited
+		if game.Status != models.GameStatusActive {
+			return nil
+		}
+
+		// Find the player
+		var player *models.Player
+		for i := range game.Players {
+			if game.Players[i].UserID == userID {
+				player = &game.Players[i]
+				break
+			}
+		}
+
+		// Players who already finished keep their result
+		if player == nil || player.Forfeited || player.FinishedAt != nil {
+			return nil
+		}
+
+		// Leaving a game that is not rated costs no rating
+		if !game.IsRated() {
+			penalty = 0
+		}
+
+		// Mark the player as forfeited
+		now := time.Now()
+		ratingChange := -penalty
+		player.Forfeited = true
+		player.FinishedAt = &now
+		player.RatingChange = &ratingChange
+
+		err = games.UpdatePlayer(player)
+		if err != nil {
+			return err
+		}
+
+		// Apply the rating penalty
+		err = applyPenalty(users, userID, game, penalty)
+		if err != nil {
+			return err
+		}
+		forfeited = true
+
+		// End the game if the forfeit leaves nobody to wait for
+		status, winnerID, over := settlement(game, s.isDisconnected)
+		if !over {
+			return nil
+		}
+
+		endGame(game, status, winnerID)
+		completed, err = games.CompleteGame(game)
+		return err
+	})
+	if err != nil || !forfeited {
+		return err
+	}
+
+	// Let the duel end without the player
+	s.gameService.duelService.ForfeitPlayer(gameID, userID)
+
+	// Notify clients
+	if s.eventService != nil && s.eventService.hub != nil {
+		err = s.eventService.hub.BroadcastPlayerForfeited(gameID, userID, websocket.ForfeitPayload{
+			Reason:       "disconnected",
+			RatingChange: -penalty,
+		})
+		if err != nil {
+			log.Printf("Error broadcasting player forfeited: %v", err)
+		}
+	}
+
+	if completed {
+		s.gameService.announceGameEnd(game)
+	}
+
+	return nil
+}
+
+// isDisconnected checks if a player of a game dropped out and has not come back yet
+func (s *DisconnectService) isDisconnected(gameID, userID string) bool {
+	return s.eventService != nil && s.eventService.hub != nil && s.eventService.hub.IsDisconnected(gameID, userID)
+}
+
+// settlement decides how a game ends once forfeits leave nobody to wait for. Players who are
+// disconnected count as gone, so a game nobody is connected to anymore is abandoned rather than
+// awarded. It reports false while players are still playing.
+func settlement(game *models.Game, disconnected func(gameID, userID string) bool) (models.GameStatus, string, bool) {
+	remaining := make([]models.Player, 0, len(game.Players))
+	present := make([]models.Player, 0, len(game.Players))
+	for _, p := range game.Players {
+		if p.Forfeited {
+			continue
+		}
+		remaining = append(remaining, p)
+		if !disconnected(game.ID, p.UserID) {
+			present = append(present, p)
+		}
+	}
+
+	switch {
+	case len(present) == 0:
+		// Nobody is left
+		return models.GameStatusAbandoned, "", true
+
+	case len(present) == 1 && isHeadToHead(game.GameType):
+		// The last player standing wins
+		return models.GameStatusCompleted, present[0].UserID, true
+
+	case present[0].Team != 0 && len(sidePlayers(present, present[0].Team)) == len(present):
+		// The last side standing wins, led by its best player if any of them solved the puzzle
+		if winnerID := bestCorrectPlayer(present); winnerID != "" {
+			return models.GameStatusCompleted, winnerID, true
+		}
+		return models.GameStatusCompleted, present[0].UserID, true
+	}
+
+	// Everyone still in the game may already be done
+	if !allPlayersFinished(remaining) {
+		return "", "", false
+	}
+
+	return models.GameStatusCompleted, gameWinner(remaining), true
+}
+
+// Helper function to deduct the forfeit penalty from a user's rating in the game's mode. The rating
+// service does not rate players who forfeited, so the penalty is their only change for the game.
+func applyPenalty(users *repository.UserRepository, userID string, game *models.Game, penalty int) error {
+	userRating, err := users.LockUserRating(userID, models.RatingModeForGameType(game.GameType))
+	if err != nil {
+		return err
+	}
+
+	oldRating := userRating.Rating
+	userRating.Rating -= penalty
+	if userRating.Rating < 0 {
+		userRating.Rating = 0
+	}
+
+	err = users.SaveUserRating(userRating)
+	if err != nil {
+		return err
+	}
+
+	// Record the change
+	if userRating.Rating != oldRating {
+		err = users.AddRatingHistory(userID, &game.ID, userRating.Mode, models.RatingSourceForfeit, oldRating, userRating.Rating)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update user stats
+	stats, err := users.LockUserStats(userID)
+	if err != nil {
+		return err
+	}
+
+	stats.GamesPlayed++
+
+	return users.UpdateUserStats(stats)
+}
+
+// Helper function to build the key of a grace timer
+func timerKey(gameID, userID string) string {
+	return gameID + "/" + userID
+}
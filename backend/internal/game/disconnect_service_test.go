@@ -0,0 +1,98 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestSettlement(t *testing.T) {
+	finished := time.Now()
+	correct, score := true, 10
+
+	tests := []struct {
+		name         string
+		gameType     string
+		players      []models.Player
+		disconnected []string
+		wantStatus   models.GameStatus
+		wantWinner   string
+		wantOver     bool
+	}{
+		{
+			name:       "last player standing wins a duel",
+			gameType:   "duel",
+			players:    []models.Player{{UserID: "a", Forfeited: true}, {UserID: "b"}},
+			wantStatus: models.GameStatusCompleted,
+			wantWinner: "b",
+			wantOver:   true,
+		},
+		{
+			name:         "duel is abandoned when the other player is disconnected too",
+			gameType:     "duel",
+			players:      []models.Player{{UserID: "a", Forfeited: true}, {UserID: "b"}},
+			disconnected: []string{"b"},
+			wantStatus:   models.GameStatusAbandoned,
+			wantOver:     true,
+		},
+		{
+			name:     "practice goes on while its player is playing",
+			gameType: "practice",
+			players:  []models.Player{{UserID: "a", Forfeited: true}, {UserID: "b"}},
+		},
+		{
+			name:     "game goes to the best finished player",
+			gameType: "practice",
+			players: []models.Player{
+				{UserID: "a", Forfeited: true},
+				{UserID: "b", FinishedAt: &finished, IsCorrect: &correct, Score: &score},
+			},
+			wantStatus: models.GameStatusCompleted,
+			wantWinner: "b",
+			wantOver:   true,
+		},
+		{
+			name:     "last side standing wins a team game",
+			gameType: "team",
+			players: []models.Player{
+				{UserID: "a", Team: 1, Forfeited: true},
+				{UserID: "b", Team: 1, Forfeited: true},
+				{UserID: "c", Team: 2},
+				{UserID: "d", Team: 2, FinishedAt: &finished, IsCorrect: &correct, Score: &score},
+			},
+			wantStatus: models.GameStatusCompleted,
+			wantWinner: "d",
+			wantOver:   true,
+		},
+		{
+			name:     "team game goes on while both sides are playing",
+			gameType: "team",
+			players: []models.Player{
+				{UserID: "a", Team: 1, Forfeited: true},
+				{UserID: "b", Team: 1},
+				{UserID: "c", Team: 2},
+				{UserID: "d", Team: 2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			game := &models.Game{ID: "game", GameType: tt.gameType, Players: tt.players}
+			disconnected := func(gameID, userID string) bool {
+				for _, id := range tt.disconnected {
+					if id == userID {
+						return true
+					}
+				}
+				return false
+			}
+
+			status, winner, over := settlement(game, disconnected)
+			if status != tt.wantStatus || winner != tt.wantWinner || over != tt.wantOver {
+				t.Errorf("settlement() = %q, %q, %v, want %q, %q, %v",
+					status, winner, over, tt.wantStatus, tt.wantWinner, tt.wantOver)
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ type DuelRoom struct {
 type DuelPlayer struct {
 	UserID       string
 	Username     string
+	IsBot        bool
 	Progress     float64
 	IsCorrect    bool
 	SolutionTime float64
@@ -73,6 +74,7 @@ func (s *DuelService) CreateDuelRoom(game *models.Game) (*DuelRoom, error) {
 		room.Players[player.UserID] = &DuelPlayer{
 			UserID:   player.UserID,
 			Username: player.User.Username,
+			IsBot:    player.User.IsBot,
 			Progress: 0,
 		}
 	}
@@ -91,9 +93,9 @@ func (s *DuelService) CreateDuelRoom(game *models.Game) (*DuelRoom, error) {
 // GetDuelRoom gets a duel room by game ID
 func (s *DuelService) GetDuelRoom(gameID string) (*DuelRoom, error) {
 	s.mutex.RLock()
-	defer s.mutex.RUnlock()
-
 	room, exists := s.rooms[gameID]
+	s.mutex.RUnlock()
+
 	if !exists {
 		// Try to load the room from the database
 		game, err := s.gameRepo.FindByID(gameID)
@@ -101,11 +103,15 @@ func (s *DuelService) GetDuelRoom(gameID string) (*DuelRoom, error) {
 			return nil, err
 		}
 
-		// Create room from game
-		s.mutex.RUnlock()
+		// Create room from game, or use the one a concurrent call just created
 		room, err = s.CreateDuelRoom(game)
 		if err != nil {
-			return nil, err
+			s.mutex.RLock()
+			room, exists = s.rooms[gameID]
+			s.mutex.RUnlock()
+			if !exists {
+				return nil, err
+			}
 		}
 		return room, nil
 	}
@@ -140,6 +146,7 @@ func (s *DuelService) JoinDuelRoom(gameID, userID string) error {
 	room.Players[userID] = &DuelPlayer{
 		UserID:   userID,
 		Username: user.Username,
+		IsBot:    user.IsBot,
 		Progress: 0,
 	}
 
@@ -151,6 +158,7 @@ func (s *DuelService) JoinDuelRoom(gameID, userID string) error {
 			players = append(players, websocket.PlayerPayload{
 				UserID:   id,
 				Username: player.Username,
+				IsBot:    player.IsBot,
 				Progress: player.Progress,
 			})
 		}
@@ -214,6 +222,8 @@ func (s *DuelService) StartDuel(gameID string) error {
 
 	// Notify clients that the duel has started
 	if s.eventService != nil {
+		s.eventService.RecordGameStarted(gameID)
+
 		// Convert start time to milliseconds
 		startTime := now.UnixNano() / int64(time.Millisecond)
 
@@ -226,6 +236,9 @@ func (s *DuelService) StartDuel(gameID string) error {
 		if err != nil {
 			log.Printf("Error broadcasting game start: %v", err)
 		}
+
+		// Deliver the puzzle to external bots playing the duel
+		s.eventService.NotifyBotGameStart(game)
 	}
 
 	return nil
@@ -295,93 +308,48 @@ func (s *DuelService) SubmitSolution(gameID, userID, solution string, isCorrect
 		}
 	}
 
-	// Check if all players have submitted solutions
-	allSubmitted := true
-	for _, p := range room.Players {
-		if p.Progress < 1.0 {
-			allSubmitted = false
-			break
-		}
-	}
-
-	// If all players have submitted solutions, end the duel
-	if allSubmitted {
-		// Find the winner
-		var winnerID string
-		var bestScore int
-		for id, p := range room.Players {
-			if p.IsCorrect && p.Score > bestScore {
-				bestScore = p.Score
-				winnerID = id
-			}
-		}
-
-		// Update game in database
-		game, err := s.gameRepo.FindByID(gameID)
-		if err != nil {
-			return err
-		}
-
-		game.Status = models.GameStatusCompleted
-		now := time.Now()
-		game.CompletedAt = &now
-
-		// Calculate game duration
-		if game.StartedAt != nil {
-			duration := now.Sub(*game.StartedAt).Seconds()
-			game.Duration = &duration
-		}
+	// The game service completes the game once every player is done
+	return nil
+}
 
-		// Set winner
-		if winnerID != "" {
-			game.WinnerID = &winnerID
-		}
+// EndDuelRoom marks a duel room as completed once its game has ended
+func (s *DuelService) EndDuelRoom(gameID string) {
+	s.mutex.RLock()
+	room, exists := s.rooms[gameID]
+	s.mutex.RUnlock()
+	if !exists {
+		return
+	}
 
-		err = s.gameRepo.Update(game)
-		if err != nil {
-			return err
-		}
+	room.Mutex.Lock()
+	room.Status = models.GameStatusCompleted
+	room.Mutex.Unlock()
+
+	// Remove room after a delay
+	go func() {
+		time.Sleep(5 * time.Minute)
+		s.mutex.Lock()
+		delete(s.rooms, gameID)
+		s.mutex.Unlock()
+	}()
+}
 
-		// Update room status
-		room.Status = models.GameStatusCompleted
-
-		// Notify clients that the duel has ended
-		if s.eventService != nil && winnerID != "" {
-			// Convert players to player payloads
-			players := make([]websocket.PlayerPayload, 0, len(room.Players))
-			for id, p := range room.Players {
-				isCorrect := p.IsCorrect
-				score := p.Score
-				players = append(players, websocket.PlayerPayload{
-					UserID:    id,
-					Username:  p.Username,
-					Progress:  1.0,
-					IsCorrect: &isCorrect,
-					Score:     &score,
-				})
-			}
+// ForfeitPlayer marks a player who forfeited as done so the duel can end without them
+func (s *DuelService) ForfeitPlayer(gameID, userID string) {
+	s.mutex.RLock()
+	room, exists := s.rooms[gameID]
+	s.mutex.RUnlock()
+	if !exists {
+		return
+	}
 
-			// Broadcast game end
-			err = s.eventService.hub.BroadcastGameEnd(
-				gameID,
-				winnerID,
-				players,
-			)
-			if err != nil {
-				log.Printf("Error broadcasting game end: %v", err)
-			}
-		}
+	room.Mutex.Lock()
+	defer room.Mutex.Unlock()
 
-		// Remove room after a delay
-		go func() {
-			time.Sleep(5 * time.Minute)
-			s.mutex.Lock()
-			delete(s.rooms, gameID)
-			s.mutex.Unlock()
-		}()
+	if player, ok := room.Players[userID]; ok {
+		player.IsCorrect = false
+		player.Progress = 1.0
 	}
-
-	return nil
 }
 
 // GetDuelStatus gets the status of a duel
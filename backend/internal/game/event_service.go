@@ -3,22 +3,41 @@ package game
 import (
 	"encoding/json"
 	"log"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
 	"github.com/hectoclash/internal/websocket"
 )
 
+// GameCompletedHandler is invoked after a game has been marked as completed
+type GameCompletedHandler func(game *models.Game)
+
 // EventService handles game events and WebSocket communication
 type EventService struct {
-	hub *websocket.Hub
+	hub               *websocket.Hub
+	gameRepo          *repository.GameRepository
+	completedHandlers []GameCompletedHandler
+	origins           map[string]time.Time // Creation time of games with recent events, used for event offsets
+	mu                sync.RWMutex
 }
 
 // NewEventService creates a new game event service
-func NewEventService(hub *websocket.Hub) *EventService {
-	return &EventService{
-		hub: hub,
+func NewEventService(hub *websocket.Hub, gameRepo *repository.GameRepository) *EventService {
+	service := &EventService{
+		hub:      hub,
+		gameRepo: gameRepo,
+		origins:  make(map[string]time.Time),
+	}
+
+	// Record progress reported by clients while relaying it to the game room
+	if hub != nil {
+		hub.RegisterMessageHandler(websocket.MessageTypePlayerProgress, service.handlePlayerProgress)
 	}
+
+	return service
 }
 
 // NotifyGameCreated notifies clients that a game has been created
@@ -29,6 +48,7 @@ func (s *EventService) NotifyGameCreated(game *models.Game) error {
 		players[i] = websocket.PlayerPayload{
 			UserID:   player.UserID,
 			Username: player.User.Username,
+			IsBot:    player.User.IsBot,
 			Progress: 0,
 		}
 	}
@@ -51,6 +71,7 @@ func (s *EventService) NotifyPlayerJoined(game *models.Game, player *models.Play
 		players[i] = websocket.PlayerPayload{
 			UserID:   p.UserID,
 			Username: p.User.Username,
+			IsBot:    p.User.IsBot,
 			Progress: 0,
 		}
 	}
@@ -70,6 +91,9 @@ func (s *EventService) NotifyGameStarted(game *models.Game) error {
 	// Convert start time to milliseconds
 	startTime := game.StartedAt.UnixNano() / int64(time.Millisecond)
 
+	// Deliver the puzzle to external bots playing the game
+	s.NotifyBotGameStart(game)
+
 	// Broadcast game start
 	return s.hub.BroadcastGameStart(
 		game.ID,
@@ -78,6 +102,45 @@ func (s *EventService) NotifyGameStarted(game *models.Game) error {
 	)
 }
 
+// NotifyBotGameStart delivers the puzzle of a started game to the event streams of the external bots playing it
+func (s *EventService) NotifyBotGameStart(game *models.Game) {
+	if game.StartedAt == nil {
+		return
+	}
+
+	for _, player := range game.Players {
+		if !player.User.IsExternalBot() {
+			continue
+		}
+
+		// Everyone else in the game is an opponent
+		opponents := make([]websocket.PlayerPayload, 0, len(game.Players)-1)
+		for _, p := range game.Players {
+			if p.UserID != player.UserID {
+				opponents = append(opponents, websocket.PlayerPayload{
+					UserID:   p.UserID,
+					Username: p.User.Username,
+					IsBot:    p.User.IsBot,
+				})
+			}
+		}
+
+		err := s.hub.StartBotGame(player.UserID, websocket.BotGameStartPayload{
+			GameID:     game.ID,
+			GameType:   game.GameType,
+			Variant:    game.Variant,
+			Puzzle:     game.PuzzleSequence,
+			Difficulty: game.Difficulty,
+			TimeLimit:  game.TimeLimit,
+			StartedAt:  game.StartedAt.UnixNano() / int64(time.Millisecond),
+			Opponents:  opponents,
+		})
+		if err != nil {
+			log.Printf("Error delivering game %s to bot %s: %v", game.ID, player.UserID, err)
+		}
+	}
+}
+
 // NotifyPlayerProgress notifies clients about a player's progress
 func (s *EventService) NotifyPlayerProgress(gameID, userID string, progress float64) error {
 	// Create player progress payload
@@ -167,6 +230,7 @@ func (s *EventService) NotifyGameEnded(game *models.Game) error {
 		players[i] = websocket.PlayerPayload{
 			UserID:    player.UserID,
 			Username:  player.User.Username,
+			IsBot:     player.User.IsBot,
 			Progress:  1.0, // Game is over, so progress is 100%
 			IsCorrect: isCorrect,
 			Score:     score,
@@ -180,3 +244,156 @@ func (s *EventService) NotifyGameEnded(game *models.Game) error {
 		players,
 	)
 }
+
+// OnGameCompleted registers a handler that is called whenever a game completes
+func (s *EventService) OnGameCompleted(handler GameCompletedHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.completedHandlers = append(s.completedHandlers, handler)
+}
+
+// NotifyGameCompleted calls every registered game completion handler
+func (s *EventService) NotifyGameCompleted(game *models.Game) {
+	s.mu.RLock()
+	handlers := make([]GameCompletedHandler, len(s.completedHandlers))
+	copy(handlers, s.completedHandlers)
+	s.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(game)
+	}
+}
+
+// RecordPlayerJoined records that a player joined a game
+func (s *EventService) RecordPlayerJoined(gameID, userID string) {
+	s.recordEvent(&models.GameEvent{
+		GameID: gameID,
+		UserID: &userID,
+		Type:   models.GameEventPlayerJoined,
+	})
+}
+
+// RecordGameStarted records that a game started
+func (s *EventService) RecordGameStarted(gameID string) {
+	s.recordEvent(&models.GameEvent{
+		GameID: gameID,
+		Type:   models.GameEventGameStarted,
+	})
+}
+
+// RecordAttempt records a solution attempt together with its validation outcome
+func (s *EventService) RecordAttempt(gameID, userID, solution string, isCorrect bool, score, attempt int) {
+	s.recordEvent(&models.GameEvent{
+		GameID:    gameID,
+		UserID:    &userID,
+		Type:      models.GameEventAttempt,
+		Solution:  &solution,
+		IsCorrect: &isCorrect,
+		Score:     &score,
+		Attempt:   &attempt,
+	})
+}
+
+// RecordProgress records a player's progress
+func (s *EventService) RecordProgress(gameID, userID string, progress float64) {
+	s.recordEvent(&models.GameEvent{
+		GameID:   gameID,
+		UserID:   &userID,
+		Type:     models.GameEventProgress,
+		Progress: &progress,
+	})
+}
+
+// RecordGameEnded records that a game ended, with the winner if there is one
+func (s *EventService) RecordGameEnded(gameID string, winnerID *string) {
+	event := &models.GameEvent{
+		GameID: gameID,
+		Type:   models.GameEventGameEnded,
+	}
+	if winnerID != nil && *winnerID != "" {
+		event.UserID = winnerID
+	}
+	s.recordEvent(event)
+
+	// No more events are expected for this game
+	s.mu.Lock()
+	delete(s.origins, gameID)
+	s.mu.Unlock()
+}
+
+// recordEvent stamps an event with its offset and appends it to the game's event log
+func (s *EventService) recordEvent(event *models.GameEvent) {
+	if s.gameRepo == nil {
+		return
+	}
+
+	now := time.Now()
+	origin, err := s.gameOrigin(event.GameID)
+	if err != nil {
+		log.Printf("Error recording %s event for game %s: %v", event.Type, event.GameID, err)
+		return
+	}
+
+	event.OccurredAt = now
+	event.OffsetMs = now.Sub(origin).Milliseconds()
+	if event.OffsetMs < 0 {
+		event.OffsetMs = 0
+	}
+
+	if err := s.gameRepo.AppendEvent(event); err != nil {
+		log.Printf("Error recording %s event for game %s: %v", event.Type, event.GameID, err)
+	}
+}
+
+// Helper function to get the creation time of a game, which is the origin of its timeline
+func (s *EventService) gameOrigin(gameID string) (time.Time, error) {
+	s.mu.RLock()
+	origin, ok := s.origins[gameID]
+	s.mu.RUnlock()
+	if ok {
+		return origin, nil
+	}
+
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.mu.Lock()
+	s.origins[game.ID] = game.CreatedAt
+	s.mu.Unlock()
+
+	return game.CreatedAt, nil
+}
+
+// handlePlayerProgress relays a player's progress to the game room and records it. Progress from
+// anyone who is not a player of the game is dropped.
+func (s *EventService) handlePlayerProgress(c *websocket.Client, msg *websocket.Message) {
+	if msg.GameID == "" {
+		return
+	}
+
+	// Parse the payload
+	var payload websocket.PlayerProgressPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		log.Printf("Error parsing player progress payload: %v", err)
+		return
+	}
+	progress := math.Max(0, math.Min(1, payload.Progress))
+
+	// Check membership off the hub's goroutine, then relay and record the progress
+	go func(gameID, userID string) {
+		if s.gameRepo == nil {
+			return
+		}
+		if _, err := s.gameRepo.FindPlayerByGameAndUser(gameID, userID); err != nil {
+			return
+		}
+
+		if err := s.NotifyPlayerProgress(gameID, userID, progress); err != nil {
+			log.Printf("Error relaying progress of %s in game %s: %v", userID, gameID, err)
+		}
+		s.RecordProgress(gameID, userID, progress)
+	}(msg.GameID, c.UserID)
+}
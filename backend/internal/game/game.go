@@ -4,6 +4,8 @@ import (
 	"errors"
 	"log"
 	"math"
+	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/hectoclash/internal/models"
@@ -18,6 +20,8 @@ type Service struct {
 	puzzleService *puzzle.Service
 	eventService  *EventService
 	duelService   *DuelService
+	rematchService *RematchService
+	disconnectService *DisconnectService
 }
 
 // NewService creates a new game service
@@ -32,22 +36,34 @@ func NewService(gameRepo *repository.GameRepository, userRepo *repository.UserRe
 	// Initialize duel service
 	service.duelService = NewDuelService(gameRepo, userRepo, eventService)
 
+	// Initialize rematch service
+	service.rematchService = NewRematchService(service, eventService)
+
+	// Initialize disconnect service
+	service.disconnectService = NewDisconnectService(service, eventService)
+
 	return service
 }
 
 // CreateGame creates a new game
 func (s *Service) CreateGame(creatorID string, gameType string) (*models.Game, error) {
-	// Get user for ELO rating
-	user, err := s.userRepo.FindByID(creatorID)
+	return s.CreateMatchedGame(creatorID, gameType, models.GameVariantClassic, false)
+}
+
+// CreateMatchedGame creates a new game with the settings of a matchmaking queue. Casual games are not rated.
+func (s *Service) CreateMatchedGame(creatorID, gameType, variant string, casual bool) (*models.Game, error) {
+	// Get the creator's rating in the game's mode
+	userRating, err := s.userRepo.GetUserRating(creatorID, models.RatingModeForGameType(gameType))
 	if err != nil {
 		return nil, err
 	}
 
-	// Get a puzzle suitable for the user's ELO rating
-	puzzleObj, err := s.puzzleService.GetPuzzleForUser(user.Rating)
+	// Get a puzzle suitable for the creator's rating, that they have not seen yet
+	puzzleObj, err := s.puzzleService.GetPuzzleForUser(creatorID, userRating.Rating)
 	if err != nil {
 		return nil, err
 	}
+	puzzleObj = s.puzzleForVariant(variant, puzzleObj)
 
 	// Create a new game
 	game := &models.Game{
@@ -55,6 +71,8 @@ func (s *Service) CreateGame(creatorID string, gameType string) (*models.Game, e
 		Status:         models.GameStatusWaiting,
 		GameType:       gameType,
 		Difficulty:     int(puzzleObj.Difficulty),
+		Variant:        variant,
+		Casual:         casual,
 	}
 
 	// Save the game
@@ -75,6 +93,11 @@ func (s *Service) CreateGame(creatorID string, gameType string) (*models.Game, e
 		return nil, err
 	}
 
+	// Record the join in the game's event log
+	if s.eventService != nil {
+		s.eventService.RecordPlayerJoined(game.ID, creatorID)
+	}
+
 	// Reload the game with player information
 	game, err = s.gameRepo.FindByID(game.ID)
 	if err != nil {
@@ -86,8 +109,8 @@ func (s *Service) CreateGame(creatorID string, gameType string) (*models.Game, e
 		go s.eventService.NotifyGameCreated(game)
 	}
 
-	// Create duel room if game type is duel
-	if game.GameType == "duel" {
+	// Create duel room if game type is played head-to-head
+	if isHeadToHead(game.GameType) {
 		_, err = s.duelService.CreateDuelRoom(game)
 		if err != nil {
 			log.Printf("Error creating duel room: %v", err)
@@ -110,6 +133,11 @@ func (s *Service) JoinGame(gameID, userID string) error {
 		return errors.New("game is not in waiting status")
 	}
 
+	// Private games are only joined through their lobby
+	if game.IsPrivate {
+		return errors.New("game is private")
+	}
+
 	// Check if user is already in the game
 	for _, player := range game.Players {
 		if player.UserID == userID {
@@ -129,6 +157,14 @@ func (s *Service) JoinGame(gameID, userID string) error {
 		return err
 	}
 
+	// Record the join in the game's event log
+	if s.eventService != nil {
+		s.eventService.RecordPlayerJoined(gameID, userID)
+	}
+
+	// The player has now seen the game's puzzle
+	s.puzzleService.MarkSeen([]string{userID}, game.PuzzleSequence)
+
 	// Reload the player with user information
 	player, err = s.gameRepo.FindPlayerByGameAndUser(gameID, userID)
 	if err != nil {
@@ -146,8 +182,8 @@ func (s *Service) JoinGame(gameID, userID string) error {
 		go s.eventService.NotifyPlayerJoined(game, player)
 	}
 
-	// Join duel room if game type is duel
-	if game.GameType == "duel" {
+	// Join duel room if game type is played head-to-head
+	if isHeadToHead(game.GameType) {
 		err = s.duelService.JoinDuelRoom(gameID, userID)
 		if err != nil {
 			log.Printf("Error joining duel room: %v", err)
@@ -155,7 +191,7 @@ func (s *Service) JoinGame(gameID, userID string) error {
 	}
 
 	// If game has enough players (2 for duel), change status to active
-	if len(game.Players) + 1 >= 2 && game.GameType == "duel" {
+	if len(game.Players) + 1 >= 2 && isHeadToHead(game.GameType) {
 		// Start the duel using the duel service
 		err = s.duelService.StartDuel(gameID)
 		if err != nil {
@@ -174,6 +210,8 @@ func (s *Service) JoinGame(gameID, userID string) error {
 
 			// Notify clients that the game has started
 			if s.eventService != nil {
+				s.eventService.RecordGameStarted(gameID)
+
 				// Reload the game with updated information
 				game, err = s.gameRepo.FindByID(gameID)
 				if err != nil {
@@ -188,219 +226,857 @@ func (s *Service) JoinGame(gameID, userID string) error {
 	return nil
 }
 
-// SubmitSolution submits a solution for a game
+// SubmitSolution submits a solution for a game.
+// The submission is a single transaction that locks the game's row, so concurrent submissions
+// see each other's results and only one of them can complete the game.
 func (s *Service) SubmitSolution(gameID, userID, solution string) error {
-	// Find game by ID
-	game, err := s.gameRepo.FindByID(gameID)
+	var (
+		game             *models.Game
+		player           *models.Player
+		validationResult *puzzle.ValidationResult
+		progress         float64
+		completed        bool
+	)
+
+	err := s.gameRepo.Transaction(func(games *repository.GameRepository, users *repository.UserRepository) error {
+		// Find and lock game by ID
+		var err error
+		game, err = games.FindByIDForUpdate(gameID)
+		if err != nil {
+			return err
+		}
+
+		// Check if game is active
+		if game.Status != models.GameStatusActive {
+			return errors.New("game is not active")
+		}
+
+		// Find player
+		player = nil
+		for i := range game.Players {
+			if game.Players[i].UserID == userID {
+				player = &game.Players[i]
+				break
+			}
+		}
+		if player == nil {
+			return errors.New("player not found")
+		}
+
+		// Players who solved the puzzle or forfeited are done
+		if player.FinishedAt != nil {
+			return errors.New("player has already finished")
+		}
+
+		// Get the user's rating in the game's mode for the puzzle rating change
+		userRating, err := users.LockUserRating(userID, models.RatingModeForGameType(game.GameType))
+		if err != nil {
+			return err
+		}
+
+		// Validate solution against the game's puzzle. In the impossible variant, players can
+		// claim that the puzzle has no solution instead.
+		claim := game.Variant == models.GameVariantImpossible && solution == puzzle.ImpossibleClaim
+		if claim {
+			validationResult = s.puzzleService.CheckImpossibleClaim(game.PuzzleSequence, models.DifficultyLevel(game.Difficulty), userRating.Rating)
+		} else {
+			validationResult = s.puzzleService.CheckSolution(game.PuzzleSequence, models.DifficultyLevel(game.Difficulty), solution, userRating.Rating)
+		}
+
+		// Calculate solution time
+		now := time.Now()
+		var solveTime float64
+		if game.StartedAt != nil {
+			solveTime = now.Sub(*game.StartedAt).Seconds()
+		}
+
+		// Update player's solution
+		player.SolutionSubmitted = &solution
+		player.SolutionTime = &solveTime
+		isCorrect := validationResult.IsCorrect
+		player.IsCorrect = &isCorrect
+		player.Attempts++
+
+		// Calculate progress (0-100%)
+		if isCorrect {
+			progress = 1.0 // 100%
+		} else {
+			// Calculate progress based on attempts (more attempts = more progress)
+			progress = math.Min(0.8, float64(player.Attempts)*0.1) // Max 80% for incorrect solutions
+		}
+
+		// If solution is correct, mark player as finished. A wrong claim also finishes the player,
+		// with the penalty from the validation result.
+		if isCorrect || claim {
+			player.FinishedAt = &now
+
+			// Use the score from the validation result
+			score := validationResult.Score
+			player.Score = &score
+
+			// Head-to-head games and games with sides are rated against the opponents once the
+			// game completes, other games against the puzzle. Casual games are not rated.
+			if !isHeadToHead(game.GameType) && !game.HasSides() && !game.Casual && validationResult.RatingChange != 0 {
+				ratingChange := validationResult.RatingChange
+				player.RatingChange = &ratingChange
+
+				// Update user's rating
+				oldRating := userRating.Rating
+				userRating.Rating += ratingChange
+				err = users.SaveUserRating(userRating)
+				if err != nil {
+					return err
+				}
+
+				// Record the change
+				err = users.AddRatingHistory(userID, &game.ID, userRating.Mode, models.RatingSource(game.GameType), oldRating, userRating.Rating)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Update player in database
+		err = games.UpdatePlayer(player)
+		if err != nil {
+			return err
+		}
+
+		// The game is over once every player has finished. The player list was read under
+		// the game's lock and already includes this submission.
+		if player.FinishedAt == nil || !allPlayersFinished(game.Players) {
+			return nil
+		}
+
+		// Determine winner
+		endGame(game, models.GameStatusCompleted, gameWinner(game.Players))
+
+		completed, err = games.CompleteGame(game)
+		if err != nil || !completed {
+			return err
+		}
+
+		// Count the game once for every player, and a win only for the winner or their side
+		return recordGameStats(users, game, now)
+	})
 	if err != nil {
 		return err
 	}
 
-	// Check if game is active
-	if game.Status != models.GameStatusActive {
-		return errors.New("game is not active")
+	isCorrect := validationResult.IsCorrect
+
+	// Record the attempt and progress in the game's event log
+	if s.eventService != nil {
+		attemptScore := 0
+		if isCorrect {
+			attemptScore = validationResult.Score
+		}
+		s.eventService.RecordAttempt(gameID, userID, solution, isCorrect, attemptScore, player.Attempts)
+		s.eventService.RecordProgress(gameID, userID, progress)
+	}
+
+	// Update the duel room if game type is played head-to-head
+	if isHeadToHead(game.GameType) {
+		err = s.duelService.UpdatePlayerProgress(gameID, userID, progress)
+		if err != nil {
+			log.Printf("Error updating player progress in duel: %v", err)
+		}
+
+		if isCorrect {
+			err = s.duelService.SubmitSolution(gameID, userID, solution, isCorrect, *player.Score, *player.SolutionTime)
+			if err != nil {
+				log.Printf("Error submitting solution to duel: %v", err)
+			}
+		}
+	} else if s.eventService != nil {
+		// Notify clients about the player's progress and solution
+		go s.eventService.NotifyPlayerProgress(gameID, userID, progress)
+		if isCorrect {
+			go s.eventService.NotifySolutionSubmitted(gameID, userID, solution, isCorrect, *player.Score)
+		}
+	}
+
+	// Notify clients that the game has ended
+	if completed {
+		s.announceGameEnd(game)
+	}
+
+	return nil
+}
+
+// SetDisconnectPolicy sets how long disconnected players have to come back and what forfeiting costs them
+func (s *Service) SetDisconnectPolicy(gracePeriod time.Duration, forfeitPenalty int) {
+	s.disconnectService.SetPolicy(gracePeriod, forfeitPenalty)
+}
+
+// ForfeitPlayer makes a player give up an active game
+func (s *Service) ForfeitPlayer(gameID, userID string) error {
+	return s.disconnectService.Forfeit(gameID, userID)
+}
+
+// OnGameCompleted registers a handler that is called whenever a game completes
+func (s *Service) OnGameCompleted(handler GameCompletedHandler) {
+	if s.eventService != nil {
+		s.eventService.OnGameCompleted(handler)
 	}
+}
+
+// GetGame gets a game by ID
+func (s *Service) GetGame(gameID string) (*models.Game, error) {
+	return s.gameRepo.FindByID(gameID)
+}
 
-	// Find player
-	player, err := s.gameRepo.FindPlayerByGameAndUser(gameID, userID)
+// GetActiveGames gets all active games
+func (s *Service) GetActiveGames() ([]models.Game, error) {
+	return s.gameRepo.FindActiveGames()
+}
+
+// GetLiveGames gets the active head-to-head games that can be spectated
+func (s *Service) GetLiveGames() ([]models.LiveGameResponse, error) {
+	games, err := s.gameRepo.FindActiveGames()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	liveGames := make([]models.LiveGameResponse, 0, len(games))
+	for _, game := range games {
+		if !isHeadToHead(game.GameType) {
+			continue
+		}
+
+		liveGame := models.LiveGameResponse{
+			GameResponse: game.ToResponse(),
+		}
+
+		// Solutions stay hidden until the game ends
+		for i := range liveGame.Players {
+			liveGame.Players[i].SolutionSubmitted = nil
+		}
+
+		if s.eventService != nil && s.eventService.hub != nil {
+			liveGame.Spectators = s.eventService.hub.SpectatorCount(game.ID)
+		}
+
+		liveGames = append(liveGames, liveGame)
+	}
+
+	return liveGames, nil
+}
+
+// GetGamesByUser gets all games for a user
+func (s *Service) GetGamesByUser(userID string, limit, offset int) ([]models.Game, error) {
+	return s.gameRepo.FindGamesByUserID(userID, limit, offset)
+}
+
+// CountGamesByUser counts all games for a user
+func (s *Service) CountGamesByUser(userID string) (int64, error) {
+	return s.gameRepo.CountGamesByUserID(userID)
+}
+
+// GetRecentGames gets recent games with pagination
+func (s *Service) GetRecentGames(limit, offset int) ([]models.Game, error) {
+	return s.gameRepo.FindRecentGames(limit, offset)
+}
+
+// CountGames counts all games
+func (s *Service) CountGames() (int64, error) {
+	return s.gameRepo.CountGames()
+}
+
+// GetReplay gets the replay timeline of a finished game
+func (s *Service) GetReplay(gameID string) (*models.GameReplay, error) {
+	// Find game by ID
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate solution
-	validationResult, err := s.puzzleService.ValidateSolution(game.ID, solution, userID)
+	// Only finished games can be replayed
+	if game.Status != models.GameStatusCompleted && game.Status != models.GameStatusAbandoned {
+		return nil, errors.New("game has not finished")
+	}
+
+	// Get the game's event log
+	events, err := s.gameRepo.GetEvents(gameID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Calculate solution time
-	var solveTime float64
+	replay := &models.GameReplay{
+		GameID:         game.ID,
+		GameType:       game.GameType,
+		PuzzleSequence: game.PuzzleSequence,
+		Status:         game.Status,
+		WinnerID:       game.WinnerID,
+		Players:        make([]models.PlayerResponse, len(game.Players)),
+		Events:         events,
+	}
+
+	for i, player := range game.Players {
+		replay.Players[i] = player.ToResponse()
+	}
+
+	// Place the start and end of the game on the timeline
 	if game.StartedAt != nil {
-		solveTime = time.Since(*game.StartedAt).Seconds()
+		startOffset := game.StartedAt.Sub(game.CreatedAt).Milliseconds()
+		replay.StartOffsetMs = &startOffset
+	}
+	if game.CompletedAt != nil {
+		replay.DurationMs = game.CompletedAt.Sub(game.CreatedAt).Milliseconds()
+	}
+	if len(events) > 0 && events[len(events)-1].OffsetMs > replay.DurationMs {
+		replay.DurationMs = events[len(events)-1].OffsetMs
 	}
 
-	// Update player's solution
-	player.SolutionSubmitted = &solution
-	player.SolutionTime = &solveTime
-	isCorrect := validationResult.IsCorrect
-	player.IsCorrect = &isCorrect
-	player.Attempts++
+	return replay, nil
+}
 
-	// Calculate progress (0-100%)
-	progress := float64(0)
-	if isCorrect {
-		progress = 1.0 // 100%
-	} else {
-		// Calculate progress based on attempts (more attempts = more progress)
-		progress = math.Min(0.8, float64(player.Attempts) * 0.1) // Max 80% for incorrect solutions
+// IsPlayer checks if a user is a player of a game
+func (s *Service) IsPlayer(gameID, userID string) (bool, error) {
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return false, err
 	}
+	return hasPlayer(game, userID), nil
+}
 
-	// Update progress in duel room if game type is duel
-	if game.GameType == "duel" {
-		err = s.duelService.UpdatePlayerProgress(gameID, userID, progress)
+// CanSpectate checks if a user may watch a game. Private games are only open to their players.
+func (s *Service) CanSpectate(gameID, userID string) (bool, error) {
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return false, err
+	}
+	return !game.IsPrivate || hasPlayer(game, userID), nil
+}
+
+// CreateRematch creates a new game between the players of a finished game
+func (s *Service) CreateRematch(previousGameID string) (*models.Game, error) {
+	// Find previous game by ID
+	previous, err := s.gameRepo.FindByID(previousGameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if previous game is completed
+	if previous.Status != models.GameStatusCompleted {
+		return nil, errors.New("game has not finished")
+	}
+
+	// Add the same players, on the same sides
+	userIDs := make([]string, len(previous.Players))
+	teams := make(map[string]int)
+	for i, p := range previous.Players {
+		userIDs[i] = p.UserID
+		if p.Team != 0 {
+			teams[p.UserID] = p.Team
+		}
+	}
+
+	// Get a fresh puzzle that is fair to every player. They have all played the previous one.
+	puzzleObj, selection, err := s.puzzleForPlayers(userIDs, previous.GameType, previous.Variant)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link the rematch to the chain it continues
+	seriesID := previous.ID
+	if previous.SeriesID != nil {
+		seriesID = *previous.SeriesID
+	}
+
+	// Create a new game
+	game := &models.Game{
+		PuzzleSequence: puzzleObj.Sequence,
+		Status:         models.GameStatusWaiting,
+		GameType:       previous.GameType,
+		Difficulty:     int(puzzleObj.Difficulty),
+		Variant:        previous.Variant,
+		TimeLimit:      previous.TimeLimit,
+		IsPrivate:      previous.IsPrivate,
+		Casual:         previous.Casual,
+		RematchOfID:    &previous.ID,
+		SeriesID:       &seriesID,
+		PuzzleSelection: selection,
+	}
+
+	return s.startGameWithTeams(game, userIDs, teams)
+}
+
+// CreatePrivateGame creates and starts a private game for the members of a lobby
+func (s *Service) CreatePrivateGame(userIDs []string, difficulty, timeLimit int, variant string) (*models.Game, error) {
+	if len(userIDs) < 2 {
+		return nil, errors.New("not enough players")
+	}
+
+	// Get a puzzle of the requested difficulty, or one fair to every player, that none of them has seen
+	var (
+		puzzleObj *models.Puzzle
+		selection models.PuzzleSelection
+		err       error
+	)
+	if difficulty > 0 {
+		puzzleObj, err = s.puzzleService.GetSimilarPuzzle(models.DifficultyLevel(difficulty), "", userIDs)
 		if err != nil {
-			log.Printf("Error updating player progress in duel: %v", err)
+			return nil, err
 		}
+		puzzleObj = s.puzzleForVariant(variant, puzzleObj)
 	} else {
-		// Notify clients about the player's progress
-		if s.eventService != nil {
-			go s.eventService.NotifyPlayerProgress(gameID, userID, progress)
+		puzzleObj, selection, err = s.puzzleForPlayers(userIDs, "private", variant)
+		if err != nil {
+			return nil, err
 		}
 	}
 
-	// If solution is correct, mark player as finished
-	if isCorrect {
-		now := time.Now()
-		player.FinishedAt = &now
+	// Create a new game
+	game := &models.Game{
+		PuzzleSequence: puzzleObj.Sequence,
+		Status:         models.GameStatusWaiting,
+		GameType:       "private",
+		Difficulty:     int(puzzleObj.Difficulty),
+		Variant:        variant,
+		TimeLimit:      timeLimit,
+		IsPrivate:      true,
+		PuzzleSelection: selection,
+	}
 
-		// Use the score and rating change from the validation result
-		score := validationResult.Score
-		player.Score = &score
-		ratingChange := validationResult.RatingChange
-		player.RatingChange = &ratingChange
+	return s.startGameWithPlayers(game, userIDs)
+}
 
-		// Update solution in duel room if game type is duel
-		if game.GameType == "duel" {
-			err = s.duelService.SubmitSolution(gameID, userID, solution, isCorrect, score, *player.SolutionTime)
-			if err != nil {
-				log.Printf("Error submitting solution to duel: %v", err)
-			}
-		} else {
-			// Notify clients that a solution has been submitted
-			if s.eventService != nil {
-				go s.eventService.NotifySolutionSubmitted(gameID, userID, solution, isCorrect, score)
-			}
+// CreateTeamGame creates and starts a matched game between two sides of players. The puzzle suits
+// the average rating of every player. Casual games are not rated.
+func (s *Service) CreateTeamGame(sides [2][]string, gameType, variant string, casual bool) (*models.Game, error) {
+	if len(sides[0]) == 0 || len(sides[1]) == 0 {
+		return nil, errors.New("not enough players")
+	}
+
+	var userIDs []string
+	teams := make(map[string]int)
+	for i, side := range sides {
+		for _, userID := range side {
+			userIDs = append(userIDs, userID)
+			teams[userID] = i + 1
 		}
+	}
+
+	// Get a puzzle that is fair to every player
+	puzzleObj, selection, err := s.puzzleForPlayers(userIDs, gameType, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new game
+	game := &models.Game{
+		PuzzleSequence: puzzleObj.Sequence,
+		Status:         models.GameStatusWaiting,
+		GameType:       gameType,
+		Difficulty:     int(puzzleObj.Difficulty),
+		Variant:        variant,
+		Casual:         casual,
+		PuzzleSelection: selection,
+	}
+
+	return s.startGameWithTeams(game, userIDs, teams)
+}
+
+// CreateMatchedDuel creates and starts a game between two players matched in a queue, with the
+// queue's settings. The puzzle is fair to both players. Casual games are not rated.
+func (s *Service) CreateMatchedDuel(userID, opponentID, gameType, variant string, casual bool) (*models.Game, error) {
+	return s.createHeadToHeadGame([]string{userID, opponentID}, gameType, variant, 0, casual)
+}
+
+// CreateChallengeGame creates and starts a game between a player and the opponent who accepted
+// their challenge, with the challenge's settings. The puzzle is fair to both players.
+// Casual games are not rated.
+func (s *Service) CreateChallengeGame(challengerID, opponentID, gameType, variant string, timeLimit int, casual bool) (*models.Game, error) {
+	return s.createHeadToHeadGame([]string{challengerID, opponentID}, gameType, variant, timeLimit, casual)
+}
+
+// createHeadToHeadGame creates and starts a game between two players, with a puzzle fair to both
+func (s *Service) createHeadToHeadGame(userIDs []string, gameType, variant string, timeLimit int, casual bool) (*models.Game, error) {
+
+	// Get a puzzle that is fair to every player
+	puzzleObj, selection, err := s.puzzleForPlayers(userIDs, gameType, variant)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new game
+	game := &models.Game{
+		PuzzleSequence: puzzleObj.Sequence,
+		Status:         models.GameStatusWaiting,
+		GameType:       gameType,
+		Difficulty:     int(puzzleObj.Difficulty),
+		Variant:        variant,
+		TimeLimit:      timeLimit,
+		Casual:         casual,
+		PuzzleSelection: selection,
+	}
+
+	return s.startGameWithPlayers(game, userIDs)
+}
+
+// Share of impossible variant games that get a puzzle without a solution
+const unsolvableChance = 0.25
+
+// puzzleForVariant swaps a game's puzzle for one without a solution some of the time, when the
+// variant lets players claim that there is none. Otherwise the puzzle is kept.
+func (s *Service) puzzleForVariant(variant string, puzzleObj *models.Puzzle) *models.Puzzle {
+	if variant != models.GameVariantImpossible || rand.Float64() >= unsolvableChance {
+		return puzzleObj
+	}
+
+	unsolvable, err := s.puzzleService.GetUnsolvablePuzzle()
+	if err != nil {
+		log.Printf("Error getting unsolvable puzzle: %v", err)
+		return puzzleObj
+	}
+	return unsolvable
+}
+
+// startGameWithPlayers saves a game with a fixed set of players and starts it straight away
+func (s *Service) startGameWithPlayers(game *models.Game, userIDs []string) (*models.Game, error) {
+	return s.startGameWithTeams(game, userIDs, nil)
+}
 
-		// Update user's rating
-		user, err := s.userRepo.FindByID(userID)
+// startGameWithTeams saves a game with a fixed set of players, on the sides given by user ID, and
+// starts it straight away. Games with sides are started whatever their type.
+func (s *Service) startGameWithTeams(game *models.Game, userIDs []string, teams map[string]int) (*models.Game, error) {
+	// Save the game
+	err := s.gameRepo.Create(game)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the players
+	for _, userID := range userIDs {
+		player := &models.Player{
+			GameID: game.ID,
+			UserID: userID,
+			Team:   teams[userID],
+		}
+
+		err = s.gameRepo.AddPlayerToGame(player)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		user.Rating += ratingChange
-		err = s.userRepo.Update(user)
+		if s.eventService != nil {
+			s.eventService.RecordPlayerJoined(game.ID, userID)
+		}
+	}
+
+	// Every player has now seen the game's puzzle
+	s.puzzleService.MarkSeen(userIDs, game.PuzzleSequence)
+
+	// Reload the game with player information
+	game, err = s.gameRepo.FindByID(game.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify clients that a game has been created
+	if s.eventService != nil {
+		go s.eventService.NotifyGameCreated(game)
+	}
+
+	// All players are present, so start the duel straight away
+	if isHeadToHead(game.GameType) || len(teams) > 0 {
+		_, err = s.duelService.CreateDuelRoom(game)
 		if err != nil {
-			return err
+			log.Printf("Error creating duel room: %v", err)
 		}
 
-		// Update user stats
-		stats, err := s.userRepo.GetUserStats(userID)
+		err = s.duelService.StartDuel(game.ID)
 		if err != nil {
-			return err
+			log.Printf("Error starting duel: %v", err)
 		}
+	}
 
-		stats.GamesPlayed++
-		stats.GamesWon++
-		stats.Rating = user.Rating
+	// Enforce the time limit, if any
+	s.scheduleTimeLimit(game)
 
-		// Update streak
-		stats.UpdateStreak(now)
+	return s.gameRepo.FindByID(game.ID)
+}
 
-		// Update average solve time
-		if stats.AvgSolveTime == 0 {
-			stats.AvgSolveTime = solveTime
-		} else {
-			stats.AvgSolveTime = (stats.AvgSolveTime*float64(stats.GamesPlayed-1) + solveTime) / float64(stats.GamesPlayed)
+// scheduleTimeLimit ends a game once its time limit has passed
+func (s *Service) scheduleTimeLimit(game *models.Game) {
+	if game.TimeLimit <= 0 {
+		return
+	}
+
+	gameID := game.ID
+	time.AfterFunc(time.Duration(game.TimeLimit)*time.Second, func() {
+		if err := s.ExpireGame(gameID); err != nil {
+			log.Printf("Error ending game %s after its time limit: %v", gameID, err)
 		}
+	})
+}
 
-		err = s.userRepo.UpdateUserStats(stats)
-		if err != nil {
-			return err
+// ExpireGame completes a game that ran out of time, awarding it to the best correct solution so far.
+// A game that has already ended is left as it is.
+func (s *Service) ExpireGame(gameID string) error {
+	// Find game by ID
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return err
+	}
+
+	// Nothing to do if the game finished in time
+	if game.Status != models.GameStatusActive {
+		return nil
+	}
+
+	return s.finishGame(game, models.GameStatusCompleted, gameWinner(game.Players))
+}
+
+// Helper function to find the player with the best correct score, or "" if nobody solved the puzzle.
+// Equal scores go to whoever solved the puzzle first, so the winner does not depend on the order of the players.
+func bestCorrectPlayer(players []models.Player) string {
+	var best *models.Player
+	for i := range players {
+		p := &players[i]
+		if p.IsCorrect == nil || !*p.IsCorrect || p.Score == nil || *p.Score <= 0 {
+			continue
+		}
+
+		if best == nil || *p.Score > *best.Score || (*p.Score == *best.Score && finishedBefore(p, best)) {
+			best = p
 		}
+	}
 
-		// Puzzle stats are already updated by the validation service
+	if best == nil {
+		return ""
+	}
+	return best.UserID
+}
 
-		// Check if all players have finished
-		allFinished := true
-		for _, p := range game.Players {
-			if p.FinishedAt == nil {
-				allFinished = false
-				break
-			}
+// Helper function to decide the winner of a game from the solutions of its players. A game with sides
+// goes to the side with the higher total correct score, or on equal totals to the side of the best
+// correct player, and its winner is the best player of that side. Other games go to the best correct player.
+func gameWinner(players []models.Player) string {
+	var totals [3]int
+	hasSides := false
+	for _, p := range players {
+		if p.Team == 0 || p.Team >= len(totals) {
+			continue
 		}
+		hasSides = true
+		if p.IsCorrect != nil && *p.IsCorrect && p.Score != nil && *p.Score > 0 {
+			totals[p.Team] += *p.Score
+		}
+	}
+	if !hasSides {
+		return bestCorrectPlayer(players)
+	}
 
-		// If all players have finished, mark game as completed
-		if allFinished {
-			game.Status = models.GameStatusCompleted
-			game.CompletedAt = &now
+	var side int
+	switch {
+	case totals[1] > totals[2]:
+		side = 1
+	case totals[2] > totals[1]:
+		side = 2
+	default:
+		side = teamOf(players, bestCorrectPlayer(players))
+	}
+	if side == 0 {
+		return ""
+	}
+	return bestCorrectPlayer(sidePlayers(players, side))
+}
 
-			// Calculate game duration
-			if game.StartedAt != nil {
-				duration := now.Sub(*game.StartedAt).Seconds()
-				game.Duration = &duration
-			}
+// Helper function to get the side of a player, or 0 if they are not a player or there are no sides
+func teamOf(players []models.Player, userID string) int {
+	for _, p := range players {
+		if p.UserID == userID {
+			return p.Team
+		}
+	}
+	return 0
+}
+
+// Helper function to get the players of one side
+func sidePlayers(players []models.Player, team int) []models.Player {
+	side := make([]models.Player, 0, len(players))
+	for _, p := range players {
+		if p.Team == team {
+			side = append(side, p)
+		}
+	}
+	return side
+}
+
+// Helper function to check if a player finished before another
+func finishedBefore(p, other *models.Player) bool {
+	if p.FinishedAt == nil || other.FinishedAt == nil {
+		return false
+	}
+	return p.FinishedAt.Before(*other.FinishedAt)
+}
+
+// recordGameStats counts a completed game in the stats of its players. Only the winner, or every
+// player of the winning side, gets a win and their solve time. Players who forfeited were counted
+// when they forfeited.
+func recordGameStats(users *repository.UserRepository, game *models.Game, now time.Time) error {
+	// Lock the stats in the same order in every game, so that games sharing players cannot deadlock
+	players := append([]models.Player(nil), game.Players...)
+	sort.Slice(players, func(i, j int) bool { return players[i].UserID < players[j].UserID })
+
+	for _, player := range players {
+		if player.Forfeited {
+			continue
+		}
+
+		stats, err := users.LockUserStats(player.UserID)
+		if err != nil {
+			return err
+		}
+
+		stats.GamesPlayed++
+		stats.UpdateStreak(now)
 
-			// Determine winner
-			var winnerID string
-			var bestScore int
-			for _, p := range game.Players {
-				if p.Score != nil && *p.Score > bestScore {
-					bestScore = *p.Score
-					winnerID = p.UserID
+		won := game.WinnerID != nil && *game.WinnerID == player.UserID
+		if game.WinningTeam != 0 && player.Team == game.WinningTeam {
+			won = true
+		}
+		if won {
+			stats.GamesWon++
+
+			// Update the average solve time of won games, from the players who solved the puzzle
+			if player.SolutionTime != nil && player.IsCorrect != nil && *player.IsCorrect {
+				if stats.AvgSolveTime == 0 {
+					stats.AvgSolveTime = *player.SolutionTime
+				} else {
+					stats.AvgSolveTime = (stats.AvgSolveTime*float64(stats.GamesWon-1) + *player.SolutionTime) / float64(stats.GamesWon)
 				}
 			}
-			game.WinnerID = &winnerID
+		}
 
-			// Update game in database
-			err = s.gameRepo.Update(game)
-			if err != nil {
-				return err
-			}
+		err = users.UpdateUserStats(stats)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-			// Notify clients that the game has ended
-			if s.eventService != nil {
-				// Reload the game with updated information
-				game, err = s.gameRepo.FindByID(gameID)
-				if err != nil {
-					return err
-				}
+// Helper function to check if a user is a player of a game
+func hasPlayer(game *models.Game, userID string) bool {
+	for _, player := range game.Players {
+		if player.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
 
-				go s.eventService.NotifyGameEnded(game)
-			}
+// Helper function to check if every player of a game has finished
+func allPlayersFinished(players []models.Player) bool {
+	for _, p := range players {
+		if p.FinishedAt == nil {
+			return false
 		}
 	}
+	return true
+}
 
-	// Update player in database
-	err = s.gameRepo.UpdatePlayer(player)
+// finishGame ends a game outside the solution flow, closes its duel room and notifies listeners.
+// A game that has already ended is left as it is.
+func (s *Service) finishGame(game *models.Game, status models.GameStatus, winnerID string) error {
+	endGame(game, status, winnerID)
+
+	// Update game in database
+	completed, err := s.gameRepo.CompleteGame(game)
 	if err != nil {
 		return err
 	}
+	if !completed {
+		return nil
+	}
+
+	s.announceGameEnd(game)
 
 	return nil
 }
 
-// GetGame gets a game by ID
-func (s *Service) GetGame(gameID string) (*models.Game, error) {
-	return s.gameRepo.FindByID(gameID)
-}
+// Helper function to set the final status, duration and winner of a game
+func endGame(game *models.Game, status models.GameStatus, winnerID string) {
+	now := time.Now()
+	game.Status = status
+	game.CompletedAt = &now
 
-// GetActiveGames gets all active games
-func (s *Service) GetActiveGames() ([]models.Game, error) {
-	return s.gameRepo.FindActiveGames()
-}
+	// Calculate game duration
+	if game.StartedAt != nil {
+		duration := now.Sub(*game.StartedAt).Seconds()
+		game.Duration = &duration
+	}
 
-// GetGamesByUser gets all games for a user
-func (s *Service) GetGamesByUser(userID string, limit, offset int) ([]models.Game, error) {
-	return s.gameRepo.FindGamesByUserID(userID, limit, offset)
+	if winnerID != "" {
+		game.WinnerID = &winnerID
+		game.WinningTeam = teamOf(game.Players, winnerID)
+	}
 }
 
-// CountGamesByUser counts all games for a user
-func (s *Service) CountGamesByUser(userID string) (int64, error) {
-	return s.gameRepo.CountGamesByUserID(userID)
-}
+// announceGameEnd closes the duel room of a game that has just ended and notifies listeners
+func (s *Service) announceGameEnd(game *models.Game) {
+	// Close the duel room
+	s.duelService.EndDuelRoom(game.ID)
 
-// GetRecentGames gets recent games with pagination
-func (s *Service) GetRecentGames(limit, offset int) ([]models.Game, error) {
-	return s.gameRepo.FindRecentGames(limit, offset)
+	// Notify clients that the game has ended
+	if s.eventService != nil {
+		s.eventService.RecordGameEnded(game.ID, game.WinnerID)
+		go s.eventService.NotifyGameEnded(game)
+		go s.eventService.NotifyGameCompleted(game)
+	}
 }
 
-// CountGames counts all games
-func (s *Service) CountGames() (int64, error) {
-	return s.gameRepo.CountGames()
+// GetSeries gets the head-to-head series of rematches a game belongs to
+func (s *Service) GetSeries(gameID string) (*models.GameSeriesResponse, error) {
+	// Find game by ID
+	game, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first game of a chain identifies the series
+	seriesID := game.ID
+	if game.SeriesID != nil {
+		seriesID = *game.SeriesID
+	}
+
+	games, err := s.gameRepo.FindGamesBySeriesID(seriesID)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &models.GameSeriesResponse{
+		SeriesID: seriesID,
+		Games:    make([]models.GameResponse, len(games)),
+		Wins:     make(map[string]int),
+	}
+
+	for i, g := range games {
+		series.Games[i] = g.ToResponse()
+
+		// Tally finished games
+		if g.Status != models.GameStatusCompleted {
+			continue
+		}
+		if g.WinnerID != nil && *g.WinnerID != "" {
+			series.Wins[*g.WinnerID]++
+		} else {
+			series.Draws++
+		}
+	}
+
+	return series, nil
 }
 
 // GetDuelStatus gets the status of a duel
 func (s *Service) GetDuelStatus(gameID string) (*models.GameResponse, error) {
 	return s.duelService.GetDuelStatus(gameID)
 }
+
+// isHeadToHead reports whether a game type is played as a duel between players
+func isHeadToHead(gameType string) bool {
+	return gameType == "duel" || gameType == "blitz" || gameType == "tournament" || gameType == "private"
+}
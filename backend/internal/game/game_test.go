@@ -0,0 +1,67 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestGameWinner(t *testing.T) {
+	start := time.Now()
+	solved := func(userID string, team, score int, after time.Duration) models.Player {
+		correct := true
+		finished := start.Add(after)
+		return models.Player{UserID: userID, Team: team, IsCorrect: &correct, Score: &score, FinishedAt: &finished}
+	}
+	failed := func(userID string, team int) models.Player {
+		correct := false
+		return models.Player{UserID: userID, Team: team, IsCorrect: &correct, FinishedAt: &start}
+	}
+
+	tests := []struct {
+		name    string
+		players []models.Player
+		want    string
+	}{
+		{
+			name:    "best correct player wins a game without sides",
+			players: []models.Player{solved("a", 0, 50, time.Second), solved("b", 0, 80, 2*time.Second)},
+			want:    "b",
+		},
+		{
+			name:    "nobody wins a game nobody solved",
+			players: []models.Player{failed("a", 0), failed("b", 0)},
+			want:    "",
+		},
+		{
+			name: "side with the higher total wins, led by its best player",
+			players: []models.Player{
+				solved("a", 1, 60, time.Second), solved("b", 1, 50, time.Second),
+				solved("c", 2, 90, time.Second), failed("d", 2),
+			},
+			want: "a",
+		},
+		{
+			name: "equal totals go to the side of the best player",
+			players: []models.Player{
+				solved("a", 1, 40, time.Second), solved("b", 1, 40, time.Second),
+				solved("c", 2, 80, time.Second), failed("d", 2),
+			},
+			want: "c",
+		},
+		{
+			name:    "nobody wins a team game nobody solved",
+			players: []models.Player{failed("a", 1), failed("b", 2)},
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gameWinner(tt.players); got != tt.want {
+				t.Errorf("gameWinner() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -61,11 +61,11 @@ type PracticeSession struct {
 func (s *PracticeService) CreatePracticeSession(config PracticeSessionConfig) (*PracticeSession, error) {
 	// Get user's current ELO if not specified
 	if config.StartELO <= 0 {
-		user, err := s.userRepo.FindByID(config.UserID)
+		practiceRating, err := s.userRepo.GetUserRating(config.UserID, models.RatingModePractice)
 		if err != nil {
 			return nil, err
 		}
-		config.StartELO = user.Rating
+		config.StartELO = practiceRating.Rating
 	}
 
 	// Create a new practice session
@@ -93,8 +93,8 @@ func (s *PracticeService) CreatePracticeSession(config PracticeSessionConfig) (*
 
 // generateNextPuzzle generates the next puzzle for a practice session
 func (s *PracticeService) generateNextPuzzle(session *PracticeSession) error {
-	// Get a puzzle suitable for the current ELO
-	puzzle, err := s.puzzleService.GetPuzzleForUser(session.CurrentELO)
+	// Get a puzzle suitable for the current ELO, that the user has not seen yet
+	puzzle, err := s.puzzleService.GetPuzzleForUser(session.UserID, session.CurrentELO)
 	if err != nil {
 		return err
 	}
@@ -218,18 +218,25 @@ func (s *PracticeService) SubmitSolution(session *PracticeSession, solution stri
 		session.CurrentELO += ratingChange
 		session.LastUpdatedAt = now
 
-		// Update user's rating if this is a ranked practice session
-		user, err := s.userRepo.FindByID(session.UserID)
+		// Update user's practice rating
+		practiceRating, err := s.userRepo.GetUserRating(session.UserID, models.RatingModePractice)
 		if err != nil {
 			return nil, err
 		}
 
-		user.Rating += ratingChange
-		err = s.userRepo.Update(user)
+		oldRating := practiceRating.Rating
+		practiceRating.Rating += ratingChange
+		err = s.userRepo.SaveUserRating(practiceRating)
 		if err != nil {
 			return nil, err
 		}
 
+		// Record the change
+		err = s.userRepo.AddRatingHistory(session.UserID, &game.ID, models.RatingModePractice, models.RatingSourcePractice, oldRating, practiceRating.Rating)
+		if err != nil {
+			log.Printf("Error recording rating history: %v", err)
+		}
+
 		// Update user stats
 		stats, err := s.userRepo.GetUserStats(session.UserID)
 		if err != nil {
@@ -238,7 +245,6 @@ func (s *PracticeService) SubmitSolution(session *PracticeSession, solution stri
 
 		stats.GamesPlayed++
 		stats.GamesWon++
-		stats.Rating = user.Rating
 
 		// Update streak
 		stats.UpdateStreak(now)
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"log"
+	"math/rand"
+
+	"github.com/hectoclash/internal/models"
+)
+
+// Limits of the search for a puzzle that is fair to every player of a game
+const (
+	fairPuzzleCandidates = 200 // Stored puzzles of the target rating that are considered
+	fairPuzzleGenerated  = 3   // New puzzles generated when every candidate has been attempted
+)
+
+// puzzleForPlayers gets a puzzle of a variant that is fair to the players of a game type. The
+// puzzle suits their mean rating, none of them has attempted or been served it, and its ELO range
+// covers their ratings as closely as possible. The returned selection tells how it was picked.
+func (s *Service) puzzleForPlayers(userIDs []string, gameType, variant string) (*models.Puzzle, models.PuzzleSelection, error) {
+	var selection models.PuzzleSelection
+
+	ratings := make([]int, len(userIDs))
+	for i, userID := range userIDs {
+		userRating, err := s.userRepo.GetUserRating(userID, models.RatingModeForGameType(gameType))
+		if err != nil {
+			return nil, selection, err
+		}
+		ratings[i] = userRating.Rating
+	}
+	selection.TargetRating, selection.RatingSpread = ratingSummary(ratings)
+
+	puzzleObj, err := s.unseenPuzzle(userIDs, ratings, &selection)
+	if err != nil {
+		return nil, selection, err
+	}
+
+	if variantPuzzle := s.puzzleForVariant(variant, puzzleObj); variantPuzzle != puzzleObj {
+		selection.Reason = models.PuzzleSelectionUnsolvable
+		selection.Misfit = 0
+		puzzleObj = variantPuzzle
+	}
+	return puzzleObj, selection, nil
+}
+
+// unseenPuzzle gets the best balanced stored puzzle of the target rating that none of the players
+// has attempted or been served. When they have attempted every candidate a new puzzle is generated, and when
+// that fails too a puzzle of the target rating is reused.
+func (s *Service) unseenPuzzle(userIDs []string, ratings []int, selection *models.PuzzleSelection) (*models.Puzzle, error) {
+	candidates, err := s.puzzleService.GetPuzzlesByELORange(selection.TargetRating, fairPuzzleCandidates, 0)
+	if err != nil {
+		return nil, err
+	}
+	selection.Candidates = len(candidates)
+
+	sequences := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		sequences[i] = candidate.Sequence
+	}
+	attempted, err := s.attemptedBy(userIDs, sequences)
+	if err != nil {
+		return nil, err
+	}
+
+	var unseen []models.Puzzle
+	for _, candidate := range candidates {
+		if !attempted[candidate.Sequence] {
+			unseen = append(unseen, candidate)
+		}
+	}
+	selection.Excluded = len(candidates) - len(unseen)
+
+	if puzzleObj := pickBalancedPuzzle(unseen, ratings); puzzleObj != nil {
+		selection.Reason = models.PuzzleSelectionUnseen
+		selection.Misfit = ratingMisfit(*puzzleObj, ratings)
+		return puzzleObj, nil
+	}
+
+	// Generating may hand back a stored puzzle, so check that it is new to the players as well
+	for i := 0; i < fairPuzzleGenerated; i++ {
+		puzzleObj, err := s.puzzleService.GeneratePuzzle()
+		if err != nil {
+			log.Printf("Error generating puzzle: %v", err)
+			break
+		}
+		attempted, err := s.attemptedBy(userIDs, []string{puzzleObj.Sequence})
+		if err != nil {
+			return nil, err
+		}
+		if !attempted[puzzleObj.Sequence] {
+			selection.Reason = models.PuzzleSelectionGenerated
+			selection.Misfit = ratingMisfit(*puzzleObj, ratings)
+			return puzzleObj, nil
+		}
+	}
+
+	puzzleObj, err := s.puzzleService.GetPuzzleForELO(selection.TargetRating)
+	if err != nil {
+		return nil, err
+	}
+	selection.Reason = models.PuzzleSelectionRepeat
+	selection.Misfit = ratingMisfit(*puzzleObj, ratings)
+	return puzzleObj, nil
+}
+
+// attemptedBy finds which of some puzzle sequences any of the players has played in a game or
+// been served, since games played before exposures were tracked have no exposure
+func (s *Service) attemptedBy(userIDs, sequences []string) (map[string]bool, error) {
+	attempted, err := s.gameRepo.FindAttemptedSequences(userIDs, sequences)
+	if err != nil {
+		return nil, err
+	}
+	seen, err := s.puzzleService.SeenBy(userIDs, sequences)
+	if err != nil {
+		return nil, err
+	}
+
+	for sequence := range seen {
+		attempted[sequence] = true
+	}
+	return attempted, nil
+}
+
+// pickBalancedPuzzle picks one of the puzzles whose ELO range fits the ratings best, at random so
+// that players of similar ratings do not keep getting the same puzzle. Returns nil without puzzles.
+func pickBalancedPuzzle(puzzles []models.Puzzle, ratings []int) *models.Puzzle {
+	var best []int
+	bestMisfit := -1
+	for i, puzzleObj := range puzzles {
+		misfit := ratingMisfit(puzzleObj, ratings)
+		switch {
+		case bestMisfit < 0 || misfit < bestMisfit:
+			best, bestMisfit = []int{i}, misfit
+		case misfit == bestMisfit:
+			best = append(best, i)
+		}
+	}
+
+	if len(best) == 0 {
+		return nil
+	}
+	return &puzzles[best[rand.Intn(len(best))]]
+}
+
+// ratingMisfit adds up how many rating points each rating falls outside a puzzle's ELO range, so
+// a puzzle whose range covers every player scores 0
+func ratingMisfit(puzzleObj models.Puzzle, ratings []int) int {
+	misfit := 0
+	for _, rating := range ratings {
+		if rating < puzzleObj.MinELO {
+			misfit += puzzleObj.MinELO - rating
+		} else if rating > puzzleObj.MaxELO {
+			misfit += rating - puzzleObj.MaxELO
+		}
+	}
+	return misfit
+}
+
+// Helper function to get the mean of ratings and the gap between the highest and lowest
+func ratingSummary(ratings []int) (mean, spread int) {
+	if len(ratings) == 0 {
+		return 0, 0
+	}
+
+	total, lowest, highest := 0, ratings[0], ratings[0]
+	for _, rating := range ratings {
+		total += rating
+		lowest, highest = min(lowest, rating), max(highest, rating)
+	}
+	return total / len(ratings), highest - lowest
+}
@@ -0,0 +1,52 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestRatingMisfit(t *testing.T) {
+	puzzleObj := models.Puzzle{MinELO: 1200, MaxELO: 1400}
+	tests := []struct {
+		ratings []int
+		want    int
+	}{
+		{[]int{1250, 1350}, 0},
+		{[]int{1200, 1400}, 0},
+		{[]int{1100, 1300}, 100},
+		{[]int{1150, 1500}, 150},
+	}
+	for _, tt := range tests {
+		if got := ratingMisfit(puzzleObj, tt.ratings); got != tt.want {
+			t.Errorf("ratingMisfit(%v) = %d, want %d", tt.ratings, got, tt.want)
+		}
+	}
+}
+
+func TestPickBalancedPuzzle(t *testing.T) {
+	puzzles := []models.Puzzle{
+		{Sequence: "111111", MinELO: 1300, MaxELO: 1600}, // Too hard for the weaker player
+		{Sequence: "222222", MinELO: 1100, MaxELO: 1500}, // Covers both players
+		{Sequence: "333333", MinELO: 900, MaxELO: 1250},  // Too easy for the stronger player
+	}
+	ratings := []int{1150, 1450}
+
+	for i := 0; i < 20; i++ {
+		got := pickBalancedPuzzle(puzzles, ratings)
+		if got == nil || got.Sequence != "222222" {
+			t.Fatalf("pickBalancedPuzzle() = %v, want the puzzle covering both ratings", got)
+		}
+	}
+
+	if got := pickBalancedPuzzle(nil, ratings); got != nil {
+		t.Errorf("pickBalancedPuzzle() without puzzles = %v, want nil", got)
+	}
+}
+
+func TestRatingSummary(t *testing.T) {
+	mean, spread := ratingSummary([]int{1100, 1400, 1250})
+	if mean != 1250 || spread != 300 {
+		t.Errorf("ratingSummary() = mean %d, spread %d, want mean 1250, spread 300", mean, spread)
+	}
+}
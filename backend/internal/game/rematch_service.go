@@ -0,0 +1,268 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// How long a rematch offer stays open
+const rematchOfferTimeout = 30 * time.Second
+
+// RematchOffer represents an open offer to play a finished game again
+type RematchOffer struct {
+	GameID     string
+	FromUserID string
+	ToUserID   string
+	ExpiresAt  time.Time
+	timer      *time.Timer
+}
+
+// RematchService handles rematch offers between the players of a finished duel
+type RematchService struct {
+	gameService  *Service
+	eventService *EventService
+	offers       map[string]*RematchOffer // Open offers by game ID
+	mutex        sync.Mutex
+}
+
+// NewRematchService creates a new rematch service
+func NewRematchService(gameService *Service, eventService *EventService) *RematchService {
+	service := &RematchService{
+		gameService:  gameService,
+		eventService: eventService,
+		offers:       make(map[string]*RematchOffer),
+	}
+
+	// Register rematch message handlers
+	if eventService != nil && eventService.hub != nil {
+		eventService.hub.RegisterMessageHandler(websocket.MessageTypeRematchOffer, service.handleOffer)
+		eventService.hub.RegisterMessageHandler(websocket.MessageTypeRematchAccept, service.handleAccept)
+		eventService.hub.RegisterMessageHandler(websocket.MessageTypeRematchDecline, service.handleDecline)
+	}
+
+	return service
+}
+
+// Offer opens a rematch offer from a player to their opponent
+func (s *RematchService) Offer(gameID, userID string) (*RematchOffer, error) {
+	// Find game by ID
+	game, err := s.gameService.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only finished duels can be rematched
+	if game.GameType != "duel" {
+		return nil, errors.New("game cannot be rematched")
+	}
+	if game.Status != models.GameStatusCompleted {
+		return nil, errors.New("game has not finished")
+	}
+
+	// Find the opponent
+	opponentID, err := rematchOpponent(game, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if a rematch has already been played
+	if _, err := s.gameService.gameRepo.FindRematchOf(gameID); err == nil {
+		return nil, errors.New("rematch has already been created")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// Check if an offer is already open
+	if offer, exists := s.offers[gameID]; exists {
+		if offer.FromUserID == userID {
+			return nil, errors.New("rematch already offered")
+		}
+		return nil, errors.New("opponent has already offered a rematch")
+	}
+
+	offer := &RematchOffer{
+		GameID:     gameID,
+		FromUserID: userID,
+		ToUserID:   opponentID,
+		ExpiresAt:  time.Now().Add(rematchOfferTimeout),
+	}
+
+	// Expire the offer if it is not answered in time
+	offer.timer = time.AfterFunc(rematchOfferTimeout, func() {
+		s.expire(offer)
+	})
+
+	s.offers[gameID] = offer
+
+	return offer, nil
+}
+
+// Accept accepts an open rematch offer and creates the rematch game
+func (s *RematchService) Accept(gameID, userID string) (*models.Game, error) {
+	s.mutex.Lock()
+	offer, exists := s.offers[gameID]
+	if !exists {
+		s.mutex.Unlock()
+		return nil, errors.New("no rematch offer found")
+	}
+	if offer.ToUserID != userID {
+		s.mutex.Unlock()
+		return nil, errors.New("rematch offer is not for this user")
+	}
+
+	// Close the offer before creating the game so it cannot be accepted twice
+	offer.timer.Stop()
+	delete(s.offers, gameID)
+	s.mutex.Unlock()
+
+	return s.gameService.CreateRematch(gameID)
+}
+
+// Decline declines an open rematch offer, or withdraws it when sent by the offering player
+func (s *RematchService) Decline(gameID, userID string) (*RematchOffer, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	offer, exists := s.offers[gameID]
+	if !exists {
+		return nil, errors.New("no rematch offer found")
+	}
+	if offer.ToUserID != userID && offer.FromUserID != userID {
+		return nil, errors.New("rematch offer is not for this user")
+	}
+
+	offer.timer.Stop()
+	delete(s.offers, gameID)
+
+	return offer, nil
+}
+
+// expire removes an offer that was not answered in time
+func (s *RematchService) expire(offer *RematchOffer) {
+	s.mutex.Lock()
+	current, exists := s.offers[offer.GameID]
+	if !exists || current != offer {
+		s.mutex.Unlock()
+		return
+	}
+	delete(s.offers, offer.GameID)
+	s.mutex.Unlock()
+
+	s.broadcast(websocket.MessageTypeRematchCancelled, offer.GameID, offer.FromUserID, websocket.RematchPayload{
+		GameID:     offer.GameID,
+		FromUserID: offer.FromUserID,
+		Reason:     "expired",
+	})
+}
+
+// handleOffer handles a rematch offer from a client
+func (s *RematchService) handleOffer(c *websocket.Client, msg *websocket.Message) {
+	// An offer for a game the opponent already offered a rematch for counts as accepting it
+	s.mutex.Lock()
+	offer, exists := s.offers[msg.GameID]
+	s.mutex.Unlock()
+	if exists && offer.ToUserID == c.UserID {
+		s.handleAccept(c, msg)
+		return
+	}
+
+	offer, err := s.Offer(msg.GameID, c.UserID)
+	if err != nil {
+		s.eventService.hub.SendError(c, 400, err.Error())
+		return
+	}
+
+	s.broadcast(websocket.MessageTypeRematchOffer, offer.GameID, offer.FromUserID, websocket.RematchPayload{
+		GameID:     offer.GameID,
+		FromUserID: offer.FromUserID,
+		ExpiresAt:  offer.ExpiresAt.UnixNano() / int64(time.Millisecond),
+	})
+}
+
+// handleAccept handles a client accepting a rematch offer
+func (s *RematchService) handleAccept(c *websocket.Client, msg *websocket.Message) {
+	game, err := s.Accept(msg.GameID, c.UserID)
+	if err != nil {
+		s.eventService.hub.SendError(c, 400, err.Error())
+		return
+	}
+
+	s.broadcast(websocket.MessageTypeRematchStart, msg.GameID, c.UserID, websocket.RematchPayload{
+		GameID:    msg.GameID,
+		NewGameID: game.ID,
+	})
+}
+
+// handleDecline handles a client declining or withdrawing a rematch offer
+func (s *RematchService) handleDecline(c *websocket.Client, msg *websocket.Message) {
+	offer, err := s.Decline(msg.GameID, c.UserID)
+	if err != nil {
+		s.eventService.hub.SendError(c, 400, err.Error())
+		return
+	}
+
+	reason := "declined"
+	if offer.FromUserID == c.UserID {
+		reason = "withdrawn"
+	}
+
+	s.broadcast(websocket.MessageTypeRematchCancelled, offer.GameID, c.UserID, websocket.RematchPayload{
+		GameID:     offer.GameID,
+		FromUserID: offer.FromUserID,
+		Reason:     reason,
+	})
+}
+
+// broadcast sends a rematch message to everyone in the finished game's room
+func (s *RematchService) broadcast(messageType websocket.MessageType, gameID, userID string, payload websocket.RematchPayload) {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling rematch payload: %v", err)
+		return
+	}
+
+	// Create message
+	msg := &websocket.Message{
+		Type:      messageType,
+		GameID:    gameID,
+		UserID:    userID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Convert message to bytes
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Error marshaling rematch message: %v", err)
+		return
+	}
+
+	// Broadcast message
+	if err := s.eventService.hub.BroadcastToGame(gameID, msgBytes); err != nil {
+		log.Printf("Error broadcasting rematch message: %v", err)
+	}
+}
+
+// Helper function to find the opponent of a player in a two-player game
+func rematchOpponent(game *models.Game, userID string) (string, error) {
+	if len(game.Players) != 2 {
+		return "", errors.New("rematches need exactly two players")
+	}
+
+	switch userID {
+	case game.Players[0].UserID:
+		return game.Players[1].UserID, nil
+	case game.Players[1].UserID:
+		return game.Players[0].UserID, nil
+	}
+
+	return "", errors.New("user is not a player in this game")
+}
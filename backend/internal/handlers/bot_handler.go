@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/challenge"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/services"
+)
+
+// BotHandler handles requests of the bot API, both from the people running bots and from the bots
+type BotHandler struct {
+	botAccountService *services.BotAccountService
+	challengeService  *challenge.Service
+}
+
+// NewBotHandler creates a new bot handler
+func NewBotHandler(botAccountService *services.BotAccountService, challengeService *challenge.Service) *BotHandler {
+	return &BotHandler{
+		botAccountService: botAccountService,
+		challengeService:  challengeService,
+	}
+}
+
+// CreateBot creates a bot account run by the current user
+func (h *BotHandler) CreateBot(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input services.CreateBotInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Create bot
+	b, err := h.botAccountService.CreateBot(userID.(string), input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    b.ToResponse(),
+	})
+}
+
+// GetBots gets the bots the current user runs
+func (h *BotHandler) GetBots(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Get bots
+	bots, err := h.botAccountService.GetBots(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get bots",
+		})
+		return
+	}
+
+	responses := make([]models.UserResponse, len(bots))
+	for i := range bots {
+		responses[i] = bots[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    responses,
+	})
+}
+
+// CreateToken creates an API token for one of the current user's bots
+func (h *BotHandler) CreateToken(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input struct {
+		Name string `json:"name" binding:"required,max=50"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Create token
+	token, err := h.botAccountService.CreateToken(userID.(string), c.Param("id"), input.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    token,
+	})
+}
+
+// GetTokens gets the API tokens of one of the current user's bots
+func (h *BotHandler) GetTokens(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Get tokens
+	tokens, err := h.botAccountService.GetTokens(userID.(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tokens,
+	})
+}
+
+// RevokeToken revokes an API token of one of the current user's bots
+func (h *BotHandler) RevokeToken(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Revoke token
+	err := h.botAccountService.RevokeToken(userID.(string), c.Param("id"), c.Param("tokenId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Token revoked",
+	})
+}
+
+// ChallengeBot challenges an external bot to a game
+func (h *BotHandler) ChallengeBot(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input challenge.Settings
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Create challenge
+	ch, err := h.challengeService.Create(userID.(string), c.Param("id"), input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    ch,
+	})
+}
+
+// GetAccount gets the account of the current bot
+func (h *BotHandler) GetAccount(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Get bot
+	b, err := h.botAccountService.GetBot(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    b.ToResponse(),
+	})
+}
+
+// GetChallenges gets the open challenges to the current bot
+func (h *BotHandler) GetChallenges(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.challengeService.Incoming(userID.(string)),
+	})
+}
+
+// AcceptChallenge accepts a challenge to the current bot and starts the game
+func (h *BotHandler) AcceptChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Accept challenge
+	game, err := h.challengeService.Accept(userID.(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    game.ToResponse(),
+	})
+}
+
+// DeclineChallenge declines a challenge to the current bot
+func (h *BotHandler) DeclineChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse the reason from request, which is optional
+	var input struct {
+		Reason string `json:"reason" binding:"max=100"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input",
+			})
+			return
+		}
+	}
+
+	// Decline challenge
+	if _, err := h.challengeService.Decline(userID.(string), c.Param("id"), input.Reason); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Challenge declined",
+	})
+}
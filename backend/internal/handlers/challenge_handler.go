@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/challenge"
+)
+
+// ChallengeHandler handles requests for direct challenges between players
+type ChallengeHandler struct {
+	challengeService *challenge.Service
+}
+
+// NewChallengeHandler creates a new challenge handler
+func NewChallengeHandler(challengeService *challenge.Service) *ChallengeHandler {
+	return &ChallengeHandler{
+		challengeService: challengeService,
+	}
+}
+
+// GetChallenges lists the open challenges a player has received and sent
+func (h *ChallengeHandler) GetChallenges(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"incoming": h.challengeService.Incoming(userID.(string)),
+			"outgoing": h.challengeService.Outgoing(userID.(string)),
+		},
+	})
+}
+
+// CreateChallenge challenges another player to a game
+func (h *ChallengeHandler) CreateChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input struct {
+		UserID string `json:"user_id" binding:"required"`
+		challenge.Settings
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	ch, err := h.challengeService.Create(userID.(string), input.UserID, input.Settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    ch,
+	})
+}
+
+// AcceptChallenge accepts a challenge and starts its game
+func (h *ChallengeHandler) AcceptChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	g, err := h.challengeService.Accept(userID.(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    g.ToResponse(),
+	})
+}
+
+// DeclineChallenge declines a challenge, or withdraws it when sent by the player
+func (h *ChallengeHandler) DeclineChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	if _, err := h.challengeService.Decline(userID.(string), c.Param("id"), ""); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Challenge cancelled",
+	})
+}
+
+// CounterChallenge answers a challenge with a challenge back with other settings
+func (h *ChallengeHandler) CounterChallenge(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var settings challenge.Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	ch, err := h.challengeService.Counter(userID.(string), c.Param("id"), settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    ch,
+	})
+}
@@ -105,6 +105,24 @@ func (h *GameHandler) GetActiveGames(c *gin.Context) {
 	})
 }
 
+// GetLiveGames gets the games that are currently being played and can be spectated
+func (h *GameHandler) GetLiveGames(c *gin.Context) {
+	// Get live games
+	games, err := h.gameService.GetLiveGames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get live games",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    games,
+	})
+}
+
 // JoinGame adds a player to a game
 func (h *GameHandler) JoinGame(c *gin.Context) {
 	// Get user ID from context
@@ -239,4 +257,64 @@ func (h *GameHandler) GetDuelStatus(c *gin.Context) {
 		"success": true,
 		"data":    game,
 	})
-}
\ No newline at end of file
+}
+
+// GetGameReplay gets the replay timeline of a finished game
+func (h *GameHandler) GetGameReplay(c *gin.Context) {
+	// Get game ID from URL
+	gameID := c.Param("id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Game ID is required",
+		})
+		return
+	}
+
+	// Get replay
+	replay, err := h.gameService.GetReplay(gameID)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "game not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    replay,
+	})
+}
+
+// GetGameSeries gets the head-to-head series of rematches a game belongs to
+func (h *GameHandler) GetGameSeries(c *gin.Context) {
+	// Get game ID from URL
+	gameID := c.Param("id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Game ID is required",
+		})
+		return
+	}
+
+	// Get series
+	series, err := h.gameService.GetSeries(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Game not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    series,
+	})
+}
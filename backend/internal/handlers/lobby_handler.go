@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/lobby"
+	"github.com/hectoclash/internal/models"
+)
+
+// LobbyHandler handles private lobby requests
+type LobbyHandler struct {
+	lobbyService *lobby.Service
+}
+
+// NewLobbyHandler creates a new lobby handler
+func NewLobbyHandler(lobbyService *lobby.Service) *LobbyHandler {
+	return &LobbyHandler{
+		lobbyService: lobbyService,
+	}
+}
+
+// CreateLobby creates a new private lobby
+func (h *LobbyHandler) CreateLobby(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse lobby settings from request, an empty body uses the defaults
+	var settings models.LobbySettings
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&settings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input",
+			})
+			return
+		}
+	}
+
+	// Create lobby
+	l, err := h.lobbyService.CreateLobby(userID.(string), settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
+
+// GetLobby gets a lobby by its invite code
+func (h *LobbyHandler) GetLobby(c *gin.Context) {
+	// Get lobby
+	l, err := h.lobbyService.GetLobby(c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Lobby not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
+
+// JoinLobby adds the current user to a lobby
+func (h *LobbyHandler) JoinLobby(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse password from request, lobbies without a password need no body
+	var input struct {
+		Password string `json:"password"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input",
+			})
+			return
+		}
+	}
+
+	// Join lobby
+	l, err := h.lobbyService.JoinLobby(c.Param("code"), userID.(string), input.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
+
+// LeaveLobby removes the current user from a lobby
+func (h *LobbyHandler) LeaveLobby(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Leave lobby
+	_, err := h.lobbyService.LeaveLobby(c.Param("code"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Left lobby",
+	})
+}
+
+// UpdateSettings changes the settings of a lobby
+func (h *LobbyHandler) UpdateSettings(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse lobby settings from request
+	var settings models.LobbySettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Update settings
+	l, err := h.lobbyService.UpdateSettings(c.Param("code"), userID.(string), settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
+
+// KickPlayer removes a member from a lobby
+func (h *LobbyHandler) KickPlayer(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse target user from request
+	var input struct {
+		UserID string `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Kick player
+	l, err := h.lobbyService.KickPlayer(c.Param("code"), userID.(string), input.UserID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
+
+// StartLobby starts the lobby's game
+func (h *LobbyHandler) StartLobby(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Start lobby
+	l, err := h.lobbyService.StartLobby(c.Param("code"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    l.ToResponse(),
+	})
+}
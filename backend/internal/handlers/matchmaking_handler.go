@@ -5,9 +5,12 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/hectoclash/internal/matchmaking"
+	"github.com/hectoclash/internal/repository"
 )
 
 // MatchmakingHandler handles matchmaking-related requests
@@ -43,6 +46,7 @@ func (h *MatchmakingHandler) JoinQueue(c *gin.Context) {
 	var input struct {
 		GameType string `json:"game_type" binding:"required"`
 		Ranked   bool   `json:"ranked"`
+		Variant  string `json:"variant"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		log.Printf("Error binding JSON: %v", err)
@@ -57,7 +61,7 @@ func (h *MatchmakingHandler) JoinQueue(c *gin.Context) {
 	log.Printf("Parsed input: %+v", input)
 
 	// Join queue
-	err := h.matchmakingService.JoinQueue(userID.(string), input.GameType, input.Ranked)
+	err := h.matchmakingService.JoinQueue(userID.(string), input.GameType, input.Ranked, input.Variant)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -113,7 +117,7 @@ func (h *MatchmakingHandler) GetQueueStatus(c *gin.Context) {
 	}
 
 	// Get queue status
-	inQueue, waitTime, gameID, err := h.matchmakingService.GetQueueStatus(userID.(string))
+	status, err := h.matchmakingService.GetQueueStatus(userID.(string))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -125,9 +129,16 @@ func (h *MatchmakingHandler) GetQueueStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"in_queue":  inQueue,
-			"wait_time": waitTime.Seconds(),
-			"game_id":   gameID,
+			"in_queue":       status.Status == "queued" || status.Status == "ready_check" || status.Status == "matched",
+			"status":         status.Status,
+			"wait_time":      status.WaitTime,
+			"time_in_queue":  status.TimeInQueue,
+			"estimated_wait": status.EstimatedWait,
+			"queue":          status.Queue,
+			"queues":         status.Queues,
+			"game_id":        status.GameID,
+			"ready_check_id": status.ReadyCheckID,
+			"cooldown":       status.Cooldown,
 		},
 	})
 }
@@ -172,3 +183,279 @@ func (h *MatchmakingHandler) CreateCustomGame(c *gin.Context) {
 		"data":    game,
 	})
 }
+
+// AcceptBotOffer starts a game against the bot offered to a player waiting in the queue
+func (h *MatchmakingHandler) AcceptBotOffer(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Accept the offer
+	game, err := h.matchmakingService.AcceptBotOffer(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    game.ToResponse(),
+	})
+}
+
+// AcceptReadyCheck accepts the match a player was offered
+func (h *MatchmakingHandler) AcceptReadyCheck(c *gin.Context) {
+	h.answerReadyCheck(c, h.matchmakingService.AcceptReadyCheck, "Match accepted")
+}
+
+// DeclineReadyCheck declines the match a player was offered
+func (h *MatchmakingHandler) DeclineReadyCheck(c *gin.Context) {
+	h.answerReadyCheck(c, h.matchmakingService.DeclineReadyCheck, "Match declined")
+}
+
+// answerReadyCheck answers a player's ready check, optionally naming it in the request body
+func (h *MatchmakingHandler) answerReadyCheck(c *gin.Context, answer func(userID, readyCheckID string) error, message string) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input struct {
+		ReadyCheckID string `json:"ready_check_id"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input",
+			})
+			return
+		}
+	}
+
+	if err := answer(userID.(string), input.ReadyCheckID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// GetParty gets the party of a player
+func (h *MatchmakingHandler) GetParty(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	party := h.matchmakingService.GetParty(userID.(string))
+	if party == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Not in a party",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    party,
+	})
+}
+
+// InviteToParty invites a friend into a player's party
+func (h *MatchmakingHandler) InviteToParty(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse request
+	var input struct {
+		FriendID string `json:"friend_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	party, err := h.matchmakingService.InviteToParty(userID.(string), input.FriendID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    party,
+	})
+}
+
+// AcceptPartyInvite joins the party a player was invited into
+func (h *MatchmakingHandler) AcceptPartyInvite(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	party, err := h.matchmakingService.AcceptPartyInvite(userID.(string), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    party,
+	})
+}
+
+// LeaveParty takes a player out of their party
+func (h *MatchmakingHandler) LeaveParty(c *gin.Context) {
+	h.changeParty(c, h.matchmakingService.LeaveParty, "Left party")
+}
+
+// DisbandParty breaks up the party a player leads
+func (h *MatchmakingHandler) DisbandParty(c *gin.Context) {
+	h.changeParty(c, h.matchmakingService.DisbandParty, "Party disbanded")
+}
+
+// changeParty applies a change to a player's party
+func (h *MatchmakingHandler) changeParty(c *gin.Context, change func(userID string) error, message string) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	if err := change(userID.(string)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+	})
+}
+
+// GetStats summarizes the quality of matchmaking by rating band and by hour, over the last day by default
+func (h *MatchmakingHandler) GetStats(c *gin.Context) {
+	// Get optional time range
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid from time",
+		})
+		return
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid to time",
+		})
+		return
+	}
+
+	filter := repository.MatchmakingStatsFilter{
+		Until:    time.Now(),
+		GameType: c.Query("game_type"),
+		Variant:  c.Query("variant"),
+	}
+	if to != nil {
+		filter.Until = *to
+	}
+	filter.Since = filter.Until.Add(-24 * time.Hour)
+	if from != nil {
+		filter.Since = *from
+	}
+
+	// Get optional ranked filter and rating band width
+	if value := c.Query("ranked"); value != "" {
+		ranked, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid ranked filter",
+			})
+			return
+		}
+		filter.Ranked = &ranked
+	}
+	bandWidth, err := strconv.Atoi(c.DefaultQuery("band", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid band width",
+		})
+		return
+	}
+
+	stats, err := h.matchmakingService.GetStats(filter, bandWidth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
@@ -69,8 +69,8 @@ func (h *PuzzleHandler) GetPuzzleForUser(c *gin.Context) {
 		return
 	}
 
-	// Get user's ELO rating
-	user, err := h.userRepo.FindByID(userID.(string))
+	// Get user's practice rating
+	practiceRating, err := h.userRepo.GetUserRating(userID.(string), models.RatingModePractice)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -79,8 +79,8 @@ func (h *PuzzleHandler) GetPuzzleForUser(c *gin.Context) {
 		return
 	}
 
-	// Get a puzzle suitable for the user's ELO rating
-	puzzle, err := h.puzzleService.GetPuzzleForUser(user.Rating)
+	// Get a puzzle suitable for the user's practice rating, that they have not seen yet
+	puzzle, err := h.puzzleService.GetPuzzleForUser(userID.(string), practiceRating.Rating)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/rating"
+)
+
+// RatingHandler handles rating-related requests
+type RatingHandler struct {
+	ratingService *rating.Service
+}
+
+// NewRatingHandler creates a new rating handler
+func NewRatingHandler(ratingService *rating.Service) *RatingHandler {
+	return &RatingHandler{
+		ratingService: ratingService,
+	}
+}
+
+// GetRatings gets a user's rating in every game mode
+func (h *RatingHandler) GetRatings(c *gin.Context) {
+	// Get ratings
+	ratings, err := h.ratingService.GetRatings(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    ratings,
+	})
+}
+
+// GetRatingHistory gets a user's rating series in a game mode grouped into time buckets
+func (h *RatingHandler) GetRatingHistory(c *gin.Context) {
+	// Get rating mode
+	mode, ok := parseModeParam(c)
+	if !ok {
+		return
+	}
+
+	// Get optional time range
+	from, err := parseTimeParam(c, "from")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid from time",
+		})
+		return
+	}
+	to, err := parseTimeParam(c, "to")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid to time",
+		})
+		return
+	}
+
+	// Get rating history
+	buckets, err := h.ratingService.GetHistory(c.Param("id"), mode, c.Query("bucket"), from, to)
+	if err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == "user not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    buckets,
+	})
+}
+
+// GetRatingStats gets a user's current, peak and lowest rating in a game mode
+func (h *RatingHandler) GetRatingStats(c *gin.Context) {
+	// Get rating mode
+	mode, ok := parseModeParam(c)
+	if !ok {
+		return
+	}
+
+	// Get rating stats
+	stats, err := h.ratingService.GetStats(c.Param("id"), mode)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "User not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// GetLeaderboard gets the top rated players of a game mode
+func (h *RatingHandler) GetLeaderboard(c *gin.Context) {
+	// Get rating mode
+	mode, ok := parseModeParam(c)
+	if !ok {
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := getPaginationParams(c)
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Get leaderboard
+	entries, err := h.ratingService.GetLeaderboard(mode, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get leaderboard",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
+
+// Helper function to parse the rating mode query parameter, defaulting to duel.
+// It writes the error response itself when the mode is unknown.
+func parseModeParam(c *gin.Context) (models.RatingMode, bool) {
+	mode := c.DefaultQuery("mode", string(models.RatingModeDuel))
+	if !models.IsValidRatingMode(mode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Mode must be duel, blitz, practice or team",
+		})
+		return "", false
+	}
+	return models.RatingMode(mode), true
+}
+
+// Helper function to parse an optional RFC 3339 time query parameter
+func parseTimeParam(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
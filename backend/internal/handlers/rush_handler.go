@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+)
+
+// RushHandler handles requests for Puzzle Rush results. Runs themselves are played over WebSocket.
+type RushHandler struct {
+	rushRepo *repository.RushRepository
+}
+
+// NewRushHandler creates a new rush handler
+func NewRushHandler(rushRepo *repository.RushRepository) *RushHandler {
+	return &RushHandler{
+		rushRepo: rushRepo,
+	}
+}
+
+// GetPersonalBests gets a user's best run of each length
+func (h *RushHandler) GetPersonalBests(c *gin.Context) {
+	userID := c.Param("id")
+
+	bests := make(map[string]*models.RushRun, len(models.RushDurations))
+	for _, duration := range models.RushDurations {
+		run, err := h.rushRepo.FindBestByUser(userID, duration)
+		if err != nil {
+			if err.Error() == "rush run not found" {
+				bests[strconv.Itoa(duration)] = nil
+				continue
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to get personal bests",
+			})
+			return
+		}
+		bests[strconv.Itoa(duration)] = run
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    bests,
+	})
+}
+
+// GetRuns gets a user's runs, newest first
+func (h *RushHandler) GetRuns(c *gin.Context) {
+	// Get pagination parameters
+	limit, offset := getPaginationParams(c)
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Get runs
+	runs, err := h.rushRepo.FindByUser(c.Param("id"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get runs",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    runs,
+	})
+}
+
+// GetLeaderboard gets the players with the best runs of a length
+func (h *RushHandler) GetLeaderboard(c *gin.Context) {
+	// Get run length
+	duration, err := strconv.Atoi(c.DefaultQuery("duration", "3"))
+	if err != nil || !models.IsValidRushDuration(duration) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Duration must be 3 or 5",
+		})
+		return
+	}
+
+	// Get pagination parameters
+	limit, offset := getPaginationParams(c)
+	if limit > 100 {
+		limit = 100
+	}
+
+	// Get leaderboard
+	entries, err := h.rushRepo.GetLeaderboard(duration, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get leaderboard",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entries,
+	})
+}
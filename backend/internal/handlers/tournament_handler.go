@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/tournament"
+)
+
+// TournamentHandler handles tournament-related requests
+type TournamentHandler struct {
+	tournamentService *tournament.Service
+}
+
+// NewTournamentHandler creates a new tournament handler
+func NewTournamentHandler(tournamentService *tournament.Service) *TournamentHandler {
+	return &TournamentHandler{
+		tournamentService: tournamentService,
+	}
+}
+
+// CreateTournament creates a new tournament
+func (h *TournamentHandler) CreateTournament(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Parse tournament settings from request
+	var input struct {
+		Name           string     `json:"name" binding:"required"`
+		Format         string     `json:"format" binding:"required"`
+		MaxPlayers     int        `json:"max_players"`
+		TotalRounds    int        `json:"total_rounds"`
+		RoundTimeLimit int        `json:"round_time_limit"`
+		StartsAt       *time.Time `json:"starts_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Invalid input",
+		})
+		return
+	}
+
+	// Create tournament
+	t, err := h.tournamentService.CreateTournament(
+		userID.(string),
+		input.Name,
+		models.TournamentFormat(input.Format),
+		input.MaxPlayers,
+		input.TotalRounds,
+		input.RoundTimeLimit,
+		input.StartsAt,
+	)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// GetTournaments lists tournaments
+func (h *TournamentHandler) GetTournaments(c *gin.Context) {
+	// Get pagination parameters
+	limit, offset := getPaginationParams(c)
+
+	// Get optional status filter
+	status := models.TournamentStatus(c.Query("status"))
+
+	// Get tournaments
+	tournaments, err := h.tournamentService.ListTournaments(status, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get tournaments",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    tournaments,
+	})
+}
+
+// GetTournament gets a tournament by ID
+func (h *TournamentHandler) GetTournament(c *gin.Context) {
+	// Get tournament ID from URL
+	tournamentID := c.Param("id")
+	if tournamentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tournament ID is required",
+		})
+		return
+	}
+
+	// Get tournament
+	t, err := h.tournamentService.GetTournament(tournamentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Tournament not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// Register registers the current user for a tournament
+func (h *TournamentHandler) Register(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Register for tournament
+	participant, err := h.tournamentService.Register(c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    participant,
+	})
+}
+
+// Unregister removes the current user from a tournament
+func (h *TournamentHandler) Unregister(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Unregister from tournament
+	err := h.tournamentService.Unregister(c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Unregistered from tournament",
+	})
+}
+
+// StartTournament starts a tournament
+func (h *TournamentHandler) StartTournament(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Not authenticated",
+		})
+		return
+	}
+
+	// Start tournament
+	t, err := h.tournamentService.StartTournament(c.Param("id"), userID.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    t,
+	})
+}
+
+// GetStandings gets the standings of a tournament
+func (h *TournamentHandler) GetStandings(c *gin.Context) {
+	// Get standings
+	standings, err := h.tournamentService.GetStandings(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get standings",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    standings,
+	})
+}
+
+// GetRounds gets the rounds and pairings of a tournament
+func (h *TournamentHandler) GetRounds(c *gin.Context) {
+	// Get rounds
+	rounds, err := h.tournamentService.GetRounds(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to get rounds",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rounds,
+	})
+}
@@ -0,0 +1,428 @@
+package lobby
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+	"github.com/hectoclash/internal/websocket"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Invite codes avoid characters that are easy to confuse, such as 0/O and 1/I
+	codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	codeLength   = 6
+
+	minPlayers     = 2
+	maxPlayers     = 8
+	maxTimeLimit   = 3600 // in seconds
+	maxCodeRetries = 10
+)
+
+// Service provides private lobby functionality
+type Service struct {
+	lobbyRepo   *repository.LobbyRepository
+	gameService *game.Service
+	hub         *websocket.Hub
+}
+
+// NewService creates a new lobby service
+func NewService(lobbyRepo *repository.LobbyRepository, gameService *game.Service, hub *websocket.Hub) *Service {
+	return &Service{
+		lobbyRepo:   lobbyRepo,
+		gameService: gameService,
+		hub:         hub,
+	}
+}
+
+// CreateLobby creates a private lobby hosted by the given user
+func (s *Service) CreateLobby(hostID string, settings models.LobbySettings) (*models.Lobby, error) {
+	lobby := &models.Lobby{
+		HostID:     hostID,
+		Status:     models.LobbyStatusOpen,
+		MaxPlayers: minPlayers,
+		Variant:    models.GameVariantClassic,
+	}
+
+	// Apply settings
+	if err := applySettings(lobby, settings); err != nil {
+		return nil, err
+	}
+
+	// Generate an unused invite code
+	code, err := s.generateCode()
+	if err != nil {
+		return nil, err
+	}
+	lobby.Code = code
+
+	// Save the lobby
+	if err := s.lobbyRepo.Create(lobby); err != nil {
+		return nil, err
+	}
+
+	// Add host as the first member
+	if err := s.lobbyRepo.AddMember(&models.LobbyMember{LobbyID: lobby.ID, UserID: hostID}); err != nil {
+		return nil, err
+	}
+
+	return s.lobbyRepo.FindByCode(lobby.Code)
+}
+
+// GetLobby gets a lobby by its invite code
+func (s *Service) GetLobby(code string) (*models.Lobby, error) {
+	return s.lobbyRepo.FindByCode(normalizeCode(code))
+}
+
+// IsLobbyMember checks if a user is a member of a lobby
+func (s *Service) IsLobbyMember(code, userID string) (bool, error) {
+	lobby, err := s.lobbyRepo.FindByCode(normalizeCode(code))
+	if err != nil {
+		return false, err
+	}
+	return isMember(lobby, userID), nil
+}
+
+// JoinLobby adds a user to a lobby. The join holds the lobby's row lock, so concurrent joins
+// cannot overfill it.
+func (s *Service) JoinLobby(code, userID, password string) (*models.Lobby, error) {
+	var (
+		lobby  *models.Lobby
+		joined bool
+	)
+
+	err := s.lobbyRepo.Transaction(func(lobbies *repository.LobbyRepository) error {
+		// Find and lock lobby by code
+		var err error
+		lobby, err = lobbies.FindByCodeForUpdate(normalizeCode(code))
+		if err != nil {
+			return err
+		}
+
+		// Check if lobby is open
+		if lobby.Status != models.LobbyStatusOpen {
+			return errors.New("lobby is not open")
+		}
+
+		// Joining twice is harmless
+		if isMember(lobby, userID) {
+			return nil
+		}
+
+		// Users the host removed stay out
+		kicked, err := lobbies.IsKicked(lobby.ID, userID)
+		if err != nil {
+			return err
+		}
+		if kicked {
+			return errors.New("you were removed from this lobby")
+		}
+
+		// Check password
+		if lobby.Password != "" {
+			if err := bcrypt.CompareHashAndPassword([]byte(lobby.Password), []byte(password)); err != nil {
+				return errors.New("incorrect lobby password")
+			}
+		}
+
+		// Check if lobby is full
+		if len(lobby.Members) >= lobby.MaxPlayers {
+			return errors.New("lobby is full")
+		}
+
+		joined = true
+		return lobbies.AddMember(&models.LobbyMember{LobbyID: lobby.ID, UserID: userID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !joined {
+		return lobby, nil
+	}
+
+	return s.reloadAndBroadcast(lobby.Code)
+}
+
+// LeaveLobby removes a user from a lobby, handing the lobby to the next member when the host leaves
+func (s *Service) LeaveLobby(code, userID string) (*models.Lobby, error) {
+	lobby, err := s.lobbyRepo.FindByCode(normalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.lobbyRepo.RemoveMember(lobby.ID, userID); err != nil {
+		return nil, err
+	}
+
+	// Pick a new host, or close the lobby when nobody is left
+	if lobby.HostID == userID && lobby.Status == models.LobbyStatusOpen {
+		var nextHost string
+		for _, member := range lobby.Members {
+			if member.UserID != userID {
+				nextHost = member.UserID
+				break
+			}
+		}
+
+		if nextHost == "" {
+			lobby.Status = models.LobbyStatusClosed
+		} else {
+			lobby.HostID = nextHost
+		}
+
+		if err := s.lobbyRepo.Update(lobby); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.reloadAndBroadcast(lobby.Code)
+}
+
+// UpdateSettings changes the settings of a lobby
+func (s *Service) UpdateSettings(code, userID string, settings models.LobbySettings) (*models.Lobby, error) {
+	lobby, err := s.hostLobby(code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply settings
+	if err := applySettings(lobby, settings); err != nil {
+		return nil, err
+	}
+
+	// Members already in the lobby keep their seat
+	if lobby.MaxPlayers < len(lobby.Members) {
+		return nil, errors.New("lobby already has more members than that")
+	}
+
+	if err := s.lobbyRepo.Update(lobby); err != nil {
+		return nil, err
+	}
+
+	return s.reloadAndBroadcast(lobby.Code)
+}
+
+// KickPlayer removes a member from a lobby. They are not let back in, and stop receiving the
+// lobby's updates.
+func (s *Service) KickPlayer(code, userID, targetID string) (*models.Lobby, error) {
+	lobby, err := s.hostLobby(code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if targetID == userID {
+		return nil, errors.New("host cannot kick themselves")
+	}
+
+	// Record the kick under the lobby's lock, so a join cannot slip in between
+	err = s.lobbyRepo.Transaction(func(lobbies *repository.LobbyRepository) error {
+		if _, err := lobbies.FindByCodeForUpdate(lobby.Code); err != nil {
+			return err
+		}
+		if err := lobbies.RemoveMember(lobby.ID, targetID); err != nil {
+			return err
+		}
+		return lobbies.AddKick(lobby.ID, targetID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Tell the lobby who was removed, then stop sending them its updates
+	if s.hub != nil {
+		if err := s.hub.BroadcastLobbyKicked(lobby.Code, targetID); err != nil {
+			log.Printf("Error broadcasting lobby kick: %v", err)
+		}
+		s.hub.RemoveFromLobby(lobby.Code, targetID)
+	}
+
+	return s.reloadAndBroadcast(lobby.Code)
+}
+
+// StartLobby creates the lobby's private game with the current members
+func (s *Service) StartLobby(code, userID string) (*models.Lobby, error) {
+	lobby, err := s.hostLobby(code, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lobby.Members) < minPlayers {
+		return nil, errors.New("not enough players")
+	}
+
+	// Create the game
+	userIDs := make([]string, len(lobby.Members))
+	for i, member := range lobby.Members {
+		userIDs[i] = member.UserID
+	}
+
+	g, err := s.gameService.CreatePrivateGame(userIDs, lobby.Difficulty, lobby.TimeLimit, lobby.Variant)
+	if err != nil {
+		return nil, err
+	}
+
+	lobby.Status = models.LobbyStatusStarted
+	lobby.GameID = &g.ID
+	if err := s.lobbyRepo.Update(lobby); err != nil {
+		return nil, err
+	}
+
+	return s.reloadAndBroadcast(lobby.Code)
+}
+
+// hostLobby finds an open lobby and checks that the user is its host
+func (s *Service) hostLobby(code, userID string) (*models.Lobby, error) {
+	lobby, err := s.lobbyRepo.FindByCode(normalizeCode(code))
+	if err != nil {
+		return nil, err
+	}
+
+	if lobby.HostID != userID {
+		return nil, errors.New("only the host can do that")
+	}
+
+	if lobby.Status != models.LobbyStatusOpen {
+		return nil, errors.New("lobby is not open")
+	}
+
+	return lobby, nil
+}
+
+// reloadAndBroadcast reloads a lobby and pushes its state to everyone in it
+func (s *Service) reloadAndBroadcast(code string) (*models.Lobby, error) {
+	lobby, err := s.lobbyRepo.FindByCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.hub != nil {
+		if err := s.hub.BroadcastLobbyState(toStatePayload(lobby)); err != nil {
+			// Nobody is connected to the lobby
+			log.Printf("Lobby state not delivered: %v", err)
+		}
+	}
+
+	return lobby, nil
+}
+
+// generateCode generates an invite code that is not in use yet
+func (s *Service) generateCode() (string, error) {
+	max := big.NewInt(int64(len(codeAlphabet)))
+
+	for attempt := 0; attempt < maxCodeRetries; attempt++ {
+		var sb strings.Builder
+		for i := 0; i < codeLength; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(codeAlphabet[n.Int64()])
+		}
+
+		code := sb.String()
+		exists, err := s.lobbyRepo.CodeExists(code)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return code, nil
+		}
+	}
+
+	return "", errors.New("failed to generate invite code")
+}
+
+// Helper function to validate settings and apply them to a lobby
+func applySettings(lobby *models.Lobby, settings models.LobbySettings) error {
+	if settings.MaxPlayers != 0 {
+		if settings.MaxPlayers < minPlayers || settings.MaxPlayers > maxPlayers {
+			return errors.New("max players must be between 2 and 8")
+		}
+		lobby.MaxPlayers = settings.MaxPlayers
+	}
+
+	if settings.TimeLimit != nil {
+		if *settings.TimeLimit < 0 || *settings.TimeLimit > maxTimeLimit {
+			return errors.New("time limit must be between 0 and 3600 seconds")
+		}
+		lobby.TimeLimit = *settings.TimeLimit
+	}
+
+	if settings.Difficulty != nil {
+		if *settings.Difficulty < 0 || *settings.Difficulty > int(models.DifficultyChampion) {
+			return errors.New("difficulty must be between 0 and 5")
+		}
+		lobby.Difficulty = *settings.Difficulty
+	}
+
+	if settings.Variant != "" {
+		if !models.IsValidGameVariant(settings.Variant) {
+			return errors.New("unknown variant")
+		}
+		lobby.Variant = settings.Variant
+	}
+
+	if settings.Password != nil {
+		if *settings.Password == "" {
+			lobby.Password = ""
+		} else {
+			hash, err := bcrypt.GenerateFromPassword([]byte(*settings.Password), bcrypt.DefaultCost)
+			if err != nil {
+				return err
+			}
+			lobby.Password = string(hash)
+		}
+	}
+
+	return nil
+}
+
+// Helper function to check if a user is a member of a lobby
+func isMember(lobby *models.Lobby, userID string) bool {
+	for _, member := range lobby.Members {
+		if member.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to normalize an invite code typed by a user
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Helper function to convert a lobby to its WebSocket payload
+func toStatePayload(lobby *models.Lobby) websocket.LobbyStatePayload {
+	payload := websocket.LobbyStatePayload{
+		Code:        lobby.Code,
+		HostID:      lobby.HostID,
+		Status:      string(lobby.Status),
+		HasPassword: lobby.Password != "",
+		MaxPlayers:  lobby.MaxPlayers,
+		TimeLimit:   lobby.TimeLimit,
+		Difficulty:  lobby.Difficulty,
+		Variant:     lobby.Variant,
+		Members:     make([]websocket.PlayerPayload, len(lobby.Members)),
+	}
+
+	if lobby.GameID != nil {
+		payload.GameID = *lobby.GameID
+	}
+
+	for i, member := range lobby.Members {
+		payload.Members[i] = websocket.PlayerPayload{
+			UserID:   member.UserID,
+			Username: member.User.Username,
+			IsBot:    member.User.IsBot,
+		}
+	}
+
+	return payload
+}
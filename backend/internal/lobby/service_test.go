@@ -0,0 +1,121 @@
+package lobby
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hectoclash/internal/config"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+)
+
+func TestApplySettingsKeepsOmittedSettings(t *testing.T) {
+	lobby := &models.Lobby{MaxPlayers: 4, TimeLimit: 300, Difficulty: 3, Variant: models.GameVariantImpossible}
+
+	timeLimit := 600
+	if err := applySettings(lobby, models.LobbySettings{TimeLimit: &timeLimit}); err != nil {
+		t.Fatalf("applySettings() error = %v", err)
+	}
+	want := models.Lobby{MaxPlayers: 4, TimeLimit: 600, Difficulty: 3, Variant: models.GameVariantImpossible}
+	if lobby.MaxPlayers != want.MaxPlayers || lobby.TimeLimit != want.TimeLimit ||
+		lobby.Difficulty != want.Difficulty || lobby.Variant != want.Variant {
+		t.Errorf("applySettings() = %+v, want %+v", *lobby, want)
+	}
+
+	// Zero is a setting of its own, not a missing one
+	zero := 0
+	if err := applySettings(lobby, models.LobbySettings{TimeLimit: &zero, Difficulty: &zero}); err != nil {
+		t.Fatalf("applySettings() error = %v", err)
+	}
+	if lobby.TimeLimit != 0 || lobby.Difficulty != 0 {
+		t.Errorf("applySettings() kept time limit %d and difficulty %d, want both cleared", lobby.TimeLimit, lobby.Difficulty)
+	}
+
+	tooHard := int(models.DifficultyChampion) + 1
+	if err := applySettings(lobby, models.LobbySettings{Difficulty: &tooHard}); err == nil {
+		t.Error("applySettings() accepted a difficulty above the highest")
+	}
+}
+
+// TestJoinLobbyConcurrentlyAndAfterKick needs a real Postgres database, since it relies on its
+// row locks. Set TEST_DATABASE_URL to run it.
+func TestJoinLobbyConcurrentlyAndAfterKick(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	database, err := repository.NewDatabase(&config.Config{
+		Database: config.DatabaseConfig{URL: url},
+	})
+	if err != nil {
+		t.Fatalf("connecting to database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	db := database.DB
+
+	suffix := time.Now().UnixNano()
+	userIDs := make([]string, 6)
+	for i := range userIDs {
+		user := &models.User{
+			Username: fmt.Sprintf("lobby_test_%d_%d", suffix, i),
+			Email:    fmt.Sprintf("lobby_test_%d_%d@example.com", suffix, i),
+			Password: "password",
+		}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		userIDs[i] = user.ID
+	}
+
+	service := NewService(repository.NewLobbyRepository(db), nil, nil)
+	lobby, err := service.CreateLobby(userIDs[0], models.LobbySettings{MaxPlayers: 3})
+	if err != nil {
+		t.Fatalf("creating lobby: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM lobby_kicks WHERE lobby_id = ?", lobby.ID)
+		db.Exec("DELETE FROM lobby_members WHERE lobby_id = ?", lobby.ID)
+		db.Exec("DELETE FROM lobbies WHERE id = ?", lobby.ID)
+		for _, userID := range userIDs {
+			db.Exec("DELETE FROM users WHERE id = ?", userID)
+		}
+	})
+
+	// Five players race for the two free seats
+	var wg sync.WaitGroup
+	errs := make([]error, len(userIDs)-1)
+	for i, userID := range userIDs[1:] {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			_, errs[i] = service.JoinLobby(lobby.Code, userID, "")
+		}(i, userID)
+	}
+	wg.Wait()
+
+	var joined []string
+	for i, err := range errs {
+		if err == nil {
+			joined = append(joined, userIDs[i+1])
+		}
+	}
+	current, err := service.GetLobby(lobby.Code)
+	if err != nil {
+		t.Fatalf("loading lobby: %v", err)
+	}
+	if len(joined) != 2 || len(current.Members) != 3 {
+		t.Fatalf("%d joins succeeded and the lobby has %d members, want 2 and 3", len(joined), len(current.Members))
+	}
+
+	// A kicked player cannot come back, even with a free seat
+	if _, err := service.KickPlayer(lobby.Code, userIDs[0], joined[0]); err != nil {
+		t.Fatalf("kicking player: %v", err)
+	}
+	if _, err := service.JoinLobby(lobby.Code, joined[0], ""); err == nil {
+		t.Error("kicked player joined the lobby again")
+	}
+}
@@ -0,0 +1,164 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/bot"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// Suffix of the Redis key of the bot offered to a waiting player
+const botOfferSuffix = ":bot_offer"
+
+// BotOffer is a bot offered to a player in the queue
+type BotOffer struct {
+	BotID    string `json:"bot_id"`
+	GameType string `json:"game_type"`
+	Variant  string `json:"variant"`
+}
+
+// SetBotService lets players who wait too long in the queue play a bot instead
+func (s *Service) SetBotService(botService *bot.Service, offerAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.botService = botService
+	s.botOfferAfter = offerAfter
+}
+
+// AcceptBotOffer takes a player out of the queue and starts an unrated game against the bot offered to them
+func (s *Service) AcceptBotOffer(userID string) (*models.Game, error) {
+	ctx := context.Background()
+
+	s.mu.Lock()
+	botService := s.botService
+	s.mu.Unlock()
+
+	if botService == nil {
+		return nil, errors.New("bots are disabled")
+	}
+
+	// Claim the offer so it can only be accepted once
+	offer, err := s.store.TakeBotOffer(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim bot offer: %w", err)
+	}
+	if offer == nil {
+		return nil, errors.New("no bot offer")
+	}
+
+	// Leave the queue; the offer only stands while the player is still waiting
+	if err := s.leaveQueue(userID, models.MatchmakingSampleBot); err != nil {
+		return nil, err
+	}
+
+	b, err := botService.Find(offer.BotID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the game with the bot as opponent, in the variant the player queued for
+	if offer.Variant == "" {
+		offer.Variant = models.GameVariantClassic
+	}
+	game, err := s.gameService.CreateMatchedGame(userID, offer.GameType, offer.Variant, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create game: %w", err)
+	}
+	err = s.gameService.JoinGame(game.ID, b.User.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add bot to game: %w", err)
+	}
+	if err := s.store.SetGame(ctx, userID, game.ID); err != nil {
+		log.Printf("Failed to store game of user %s: %v", userID, err)
+	}
+
+	// Let the bot play
+	go botService.Play(game.ID, b)
+
+	// Notify the player like any other match
+	if s.websocketHub != nil {
+		if client := s.websocketHub.GetClientByUserID(userID); client != nil {
+			opponent := websocket.PlayerPayload{
+				UserID:   b.User.ID,
+				Username: b.User.Username,
+				IsBot:    true,
+			}
+			if err := s.websocketHub.SendMatchFound(client, game.ID, offer.GameType, opponent, false); err != nil {
+				log.Printf("Failed to send match found notification: %v", err)
+			}
+		}
+	}
+
+	log.Printf("Created game %s for user %s against bot %s", game.ID, userID, b.User.Username)
+
+	return s.gameService.GetGame(game.ID)
+}
+
+// offerBots offers a bot to every player who has waited long enough without being matched
+func (s *Service) offerBots(ctx context.Context) {
+	s.mu.Lock()
+	botService, offerAfter := s.botService, s.botOfferAfter
+	s.mu.Unlock()
+
+	if botService == nil {
+		return
+	}
+
+	// Players who joined before this have waited long enough
+	joinedBefore := time.Now().Add(-offerAfter)
+	for _, queue := range Queues() {
+		s.offerBotsInQueue(ctx, botService, queue, joinedBefore)
+	}
+}
+
+// offerBotsInQueue offers a bot to every player of a queue who joined before a time
+func (s *Service) offerBotsInQueue(ctx context.Context, botService *bot.Service, queue QueueID, joinedBefore time.Time) {
+	entries, err := s.store.Waiting(ctx, queue, joinedBefore, time.Now())
+	if err != nil {
+		log.Printf("Failed to get players of queue %s waiting for a bot: %v", queue, err)
+		return
+	}
+
+	for _, entry := range entries {
+		// Bots only stand in for the opponent of a single player
+		if entry.Size() > 1 {
+			continue
+		}
+		userID, expiresAt := entry.UserID, entry.Timeout
+
+		// Each player is offered a bot once per queue entry
+		b := botService.Opponent(entry.Rating)
+		offer := BotOffer{BotID: b.User.ID, GameType: queue.GameType, Variant: queue.Variant}
+		offered, err := s.store.OfferBot(ctx, userID, offer, expiresAt)
+		if err != nil || !offered {
+			continue
+		}
+
+		if s.websocketHub == nil {
+			continue
+		}
+		client := s.websocketHub.GetClientByUserID(userID)
+		if client == nil {
+			continue
+		}
+
+		err = s.websocketHub.SendBotOffer(client, websocket.BotOfferPayload{
+			Bot: websocket.PlayerPayload{
+				UserID:   b.User.ID,
+				Username: b.User.Username,
+				IsBot:    true,
+			},
+			GameType:  queue.GameType,
+			ExpiresAt: expiresAt.UnixNano() / int64(time.Millisecond),
+		})
+		if err != nil {
+			log.Printf("Failed to send bot offer to user %s: %v", userID, err)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hectoclash/internal/repository"
+)
+
+// Constraint is a rule about which queued players may be paired, whether as opponents or, in a
+// team match, on the same side. Constraints are worked out once per tick and queue: Prepare loads
+// what the rule needs about the queue's players in bulk, and the PairCheck it returns judges each
+// candidate pair without going back to the database.
+type Constraint interface {
+	// Name identifies the constraint in logs
+	Name() string
+
+	// Prepare loads what the constraint needs to judge pairs of the entries
+	Prepare(ctx context.Context, entries []QueueEntry) (PairCheck, error)
+}
+
+// PairCheck checks if two queue entries may be paired at a time
+type PairCheck func(a, b QueueEntry, now time.Time) bool
+
+// SetConstraints sets the constraints every pair of queued players has to satisfy
+func (s *Service) SetConstraints(constraints ...Constraint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.constraints = constraints
+}
+
+// pairingRules works out which entries of a queue the constraints keep apart. Every entry waiting
+// now is checked against every other entry close enough in rating to ever be paired with it.
+// There are no rules, so every pair is allowed, when there are no constraints.
+func (s *Service) pairingRules(ctx context.Context, queue QueueID, now time.Time) (*PairingRules, error) {
+	s.mu.Lock()
+	constraints := s.constraints
+	s.mu.Unlock()
+
+	if len(constraints) == 0 {
+		return nil, nil
+	}
+
+	entries, err := s.store.Waiting(ctx, queue, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get players of queue: %w", err)
+	}
+
+	rules := NewPairingRules()
+	for _, entry := range entries {
+		rules.Checked[entry.UserID] = true
+	}
+	if len(entries) < 2 {
+		return rules, nil
+	}
+
+	checks := make([]PairCheck, len(constraints))
+	for i, constraint := range constraints {
+		checks[i], err = constraint.Prepare(ctx, entries)
+		if err != nil {
+			return nil, fmt.Errorf("failed to prepare constraint %s: %w", constraint.Name(), err)
+		}
+	}
+
+	// Walk the entries in rating order, stopping once ratings are too far apart to ever be paired
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Rating < entries[j].Rating
+	})
+	maxGap := queue.Window().Max
+	for i, a := range entries {
+		for _, b := range entries[i+1:] {
+			if b.Rating-a.Rating > maxGap {
+				break
+			}
+			for _, check := range checks {
+				if !check(a, b, now) {
+					rules.Exclude(a.UserID, b.UserID)
+					break
+				}
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+// blockedUsers never pairs players when either has blocked the other
+type blockedUsers struct {
+	friendRepo *repository.FriendRepository
+}
+
+// NewBlockedUsersConstraint creates a constraint that keeps players apart from anyone they
+// blocked or were blocked by
+func NewBlockedUsersConstraint(friendRepo *repository.FriendRepository) Constraint {
+	return &blockedUsers{friendRepo: friendRepo}
+}
+
+// Name identifies the constraint in logs
+func (c *blockedUsers) Name() string {
+	return "blocked_users"
+}
+
+// Prepare loads the blocks between the players of the entries
+func (c *blockedUsers) Prepare(ctx context.Context, entries []QueueEntry) (PairCheck, error) {
+	blocks, err := c.friendRepo.FindBlocksAmong(entryUserIDs(entries))
+	if err != nil {
+		return nil, err
+	}
+
+	blocked := make(map[string]map[string]bool)
+	for _, block := range blocks {
+		addToSet(blocked, block.UserID, block.FriendID)
+		addToSet(blocked, block.FriendID, block.UserID)
+	}
+
+	return func(a, b QueueEntry, now time.Time) bool {
+		return !anyAcross(a, b, blocked)
+	}, nil
+}
+
+// recentOpponents avoids pairing players who met in one of their last few games, until one of
+// them has waited long enough that any game is better than none
+type recentOpponents struct {
+	gameRepo *repository.GameRepository
+	games    int
+	waitFor  time.Duration
+}
+
+// NewRecentOpponentsConstraint creates a constraint that keeps players apart from anyone they
+// played in their last few games, until either has waited for a while
+func NewRecentOpponentsConstraint(gameRepo *repository.GameRepository, games int, waitFor time.Duration) Constraint {
+	return &recentOpponents{gameRepo: gameRepo, games: games, waitFor: waitFor}
+}
+
+// Name identifies the constraint in logs
+func (c *recentOpponents) Name() string {
+	return "recent_opponents"
+}
+
+// Prepare loads who the players of the entries played recently
+func (c *recentOpponents) Prepare(ctx context.Context, entries []QueueEntry) (PairCheck, error) {
+	opponents, err := c.gameRepo.FindRecentOpponents(entryUserIDs(entries), c.games)
+	if err != nil {
+		return nil, err
+	}
+
+	recent := make(map[string]map[string]bool)
+	for userID, ids := range opponents {
+		for _, opponentID := range ids {
+			addToSet(recent, userID, opponentID)
+		}
+	}
+
+	return func(a, b QueueEntry, now time.Time) bool {
+		return longerWait(a, b, now) >= c.waitFor || !anyAcross(a, b, recent)
+	}, nil
+}
+
+// unseenPuzzles prefers pairs for whom puzzles of their rating are left that neither has played,
+// until one of them has waited long enough that a repeated puzzle is better than no game
+type unseenPuzzles struct {
+	gameRepo   *repository.GameRepository
+	puzzleRepo *repository.PuzzleRepository
+	waitFor    time.Duration
+}
+
+// Puzzles of a rating band checked for ones a pair has not played in their last games
+const (
+	unseenPuzzleBand    = 100
+	unseenPuzzlePoolMax = 200
+	unseenPuzzleHistory = 100
+)
+
+// NewUnseenPuzzlesConstraint creates a constraint that prefers pairs who have not played every
+// puzzle of their average rating in their last few games, until either has waited for a while
+func NewUnseenPuzzlesConstraint(gameRepo *repository.GameRepository, puzzleRepo *repository.PuzzleRepository, waitFor time.Duration) Constraint {
+	return &unseenPuzzles{gameRepo: gameRepo, puzzleRepo: puzzleRepo, waitFor: waitFor}
+}
+
+// Name identifies the constraint in logs
+func (c *unseenPuzzles) Name() string {
+	return "unseen_puzzles"
+}
+
+// Prepare loads the puzzles the players of the entries played recently, and the puzzles of every
+// rating band a pair of them could play in
+func (c *unseenPuzzles) Prepare(ctx context.Context, entries []QueueEntry) (PairCheck, error) {
+	sequences, err := c.gameRepo.FindPlayedSequences(entryUserIDs(entries), unseenPuzzleHistory)
+	if err != nil {
+		return nil, err
+	}
+
+	played := make(map[string]map[string]bool)
+	for userID, seqs := range sequences {
+		for _, sequence := range seqs {
+			addToSet(played, userID, sequence)
+		}
+	}
+
+	// A pair plays a puzzle of its average rating, which lies between the lowest and highest rating
+	minRating, maxRating := entries[0].Rating, entries[0].Rating
+	for _, entry := range entries {
+		minRating, maxRating = min(minRating, entry.Rating), max(maxRating, entry.Rating)
+	}
+	pools := make(map[int][]string)
+	for band := ratingBand(minRating); band <= ratingBand(maxRating); band += unseenPuzzleBand {
+		puzzles, err := c.puzzleRepo.GetPuzzlesByELORange(band, unseenPuzzlePoolMax, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, puzzle := range puzzles {
+			pools[band] = append(pools[band], puzzle.Sequence)
+		}
+	}
+
+	return func(a, b QueueEntry, now time.Time) bool {
+		if longerWait(a, b, now) >= c.waitFor {
+			return true
+		}
+
+		// Without stored puzzles of the band, the pair gets a freshly generated one
+		pool := pools[ratingBand((a.Rating+b.Rating)/2)]
+		if len(pool) == 0 {
+			return true
+		}
+
+		players := append(append([]string{}, a.UserIDs()...), b.UserIDs()...)
+		for _, sequence := range pool {
+			seen := false
+			for _, userID := range players {
+				if played[userID][sequence] {
+					seen = true
+					break
+				}
+			}
+			if !seen {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Helper function to list every player of queue entries
+func entryUserIDs(entries []QueueEntry) []string {
+	var userIDs []string
+	for _, entry := range entries {
+		userIDs = append(userIDs, entry.UserIDs()...)
+	}
+	return userIDs
+}
+
+// Helper function to add a value to a set of values by key
+func addToSet(sets map[string]map[string]bool, key, value string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]bool)
+	}
+	sets[key][value] = true
+}
+
+// Helper function to check if any player of one entry is related to any player of another
+func anyAcross(a, b QueueEntry, related map[string]map[string]bool) bool {
+	for _, userID := range a.UserIDs() {
+		for _, otherID := range b.UserIDs() {
+			if related[userID][otherID] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Helper function to get how long the longer waiting entry of a pair has waited
+func longerWait(a, b QueueEntry, now time.Time) time.Duration {
+	joinedAt := a.JoinedAt
+	if b.JoinedAt.Before(joinedAt) {
+		joinedAt = b.JoinedAt
+	}
+	return now.Sub(joinedAt)
+}
+
+// Helper function to round a rating to the middle of its band of puzzles
+func ratingBand(rating int) int {
+	return rating/unseenPuzzleBand*unseenPuzzleBand + unseenPuzzleBand/2
+}
@@ -0,0 +1,149 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// pairConstraint keeps apart pairs of players, until either has waited for a while if waitFor is set
+type pairConstraint struct {
+	apart   [][2]string
+	waitFor time.Duration
+}
+
+func (c pairConstraint) Name() string {
+	return "test"
+}
+
+func (c pairConstraint) Prepare(ctx context.Context, entries []QueueEntry) (PairCheck, error) {
+	return func(a, b QueueEntry, now time.Time) bool {
+		if c.waitFor > 0 && longerWait(a, b, now) >= c.waitFor {
+			return true
+		}
+		for _, pair := range c.apart {
+			if (pair[0] == a.UserID && pair[1] == b.UserID) || (pair[0] == b.UserID && pair[1] == a.UserID) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func TestConstraintsKeepPlayersApart(t *testing.T) {
+	now := time.Now()
+	team := QueueID{GameType: "team", Ranked: true, Variant: "classic"}
+
+	tests := []struct {
+		name        string
+		queue       QueueID
+		entries     []QueueEntry
+		constraints []Constraint
+		want        []string // Sides of each match
+	}{
+		{
+			"no constraints",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 0), testEntry("b", 1210, rankedDuel, now, 0), testEntry("c", 1220, rankedDuel, now, 0)},
+			nil,
+			[]string{"[[a] [b]]"},
+		},
+		{
+			"blocked pair is skipped for the next player in range",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 0), testEntry("b", 1210, rankedDuel, now, 0), testEntry("c", 1220, rankedDuel, now, 0)},
+			[]Constraint{pairConstraint{apart: [][2]string{{"a", "b"}}}},
+			[]string{"[[a] [c]]"},
+		},
+		{
+			"every constraint has to allow a pair",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 0), testEntry("b", 1210, rankedDuel, now, 0), testEntry("c", 1220, rankedDuel, now, 0)},
+			[]Constraint{pairConstraint{apart: [][2]string{{"a", "b"}}}, pairConstraint{apart: [][2]string{{"a", "c"}}}},
+			[]string{"[[b] [c]]"},
+		},
+		{
+			"avoided pair waits",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 10*time.Second), testEntry("b", 1210, rankedDuel, now, 0)},
+			[]Constraint{pairConstraint{apart: [][2]string{{"a", "b"}}, waitFor: 30 * time.Second}},
+			nil,
+		},
+		{
+			"avoided pair plays after waiting long enough",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 40*time.Second), testEntry("b", 1210, rankedDuel, now, 0)},
+			[]Constraint{pairConstraint{apart: [][2]string{{"a", "b"}}, waitFor: 30 * time.Second}},
+			[]string{"[[a] [b]]"},
+		},
+		{
+			"excluded partner is not teamed up",
+			team,
+			[]QueueEntry{testParty("p1", "p2", 1200, team, now, 0), testEntry("a", 1210, team, now, 0), testEntry("b", 1220, team, now, 0), testEntry("c", 1230, team, now, 0)},
+			[]Constraint{pairConstraint{apart: [][2]string{{"a", "b"}}}},
+			[]string{"[[p1 p2] [a c]]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			s := NewService(store, nil, nil, nil)
+			s.SetConstraints(tt.constraints...)
+
+			for _, entry := range tt.entries {
+				if _, err := store.Join(ctx, entry); err != nil {
+					t.Fatalf("Join() error = %v", err)
+				}
+			}
+
+			rules, err := s.pairingRules(ctx, tt.queue, now)
+			if err != nil {
+				t.Fatalf("pairingRules() error = %v", err)
+			}
+			matches, err := store.Match(ctx, tt.queue, tt.queue.Window(), now, rules)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+
+			var got []string
+			for _, m := range matches {
+				got = append(got, fmt.Sprint(m.Sides()))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPairingRulesHoldBackUncheckedEntries(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+
+	for _, userID := range []string{"a", "b", "late"} {
+		if _, err := store.Join(ctx, testEntry(userID, 1200, rankedDuel, now, 0)); err != nil {
+			t.Fatalf("Join() error = %v", err)
+		}
+	}
+
+	// Someone who joined after the rules were worked out waits for the next tick
+	rules := NewPairingRules()
+	rules.Checked["a"] = true
+	rules.Checked["b"] = true
+	rules.Exclude("a", "b")
+
+	matches, err := store.Match(ctx, rankedDuel, rankedWindow, now, rules)
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+	if len(matches) != 0 {
+		t.Errorf("Match() = %d matches, want none", len(matches))
+	}
+	if size, _ := store.Size(ctx, rankedDuel); size != 3 {
+		t.Errorf("Size() = %d, want 3", size)
+	}
+}
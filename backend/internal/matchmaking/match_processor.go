@@ -2,10 +2,8 @@ package matchmaking
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
 	"log"
-	"math"
+	"strings"
 	"time"
 
 	"github.com/hectoclash/internal/websocket"
@@ -57,202 +55,178 @@ func (p *MatchProcessor) Stop() {
 	close(p.stopCh)
 }
 
-// ProcessMatches processes the matchmaking queue and creates matches
+// ProcessMatches processes the matchmaking queue and creates matches.
+// The store picks pairs and takes them out of the queue atomically, so ticks on several replicas
+// never match a player twice. The lock only keeps replicas from doing the same work at once.
 func (p *MatchProcessor) ProcessMatches() {
 	ctx := context.Background()
 
 	// Acquire lock
-	lockSuccess, err := p.service.redisClient.SetNX(ctx, queueLockKey, "1", lockTimeout).Result()
-	if err != nil || !lockSuccess {
+	token, locked, err := p.service.store.Lock(ctx, processorLock, lockTimeout)
+	if err != nil || !locked {
 		return
 	}
-	defer p.service.redisClient.Del(ctx, queueLockKey)
+	defer p.service.unlock(ctx, processorLock, token)
 
-	// Get queue length
-	queueLength, err := p.service.redisClient.ZCard(ctx, queueKey).Result()
-	if err != nil {
-		log.Printf("Failed to get queue length: %v", err)
-		return
-	}
-
-	// If queue is empty or has only one player, return
-	if queueLength < 2 {
-		return
-	}
+	// Offer bots to players who have waited too long, even when nobody else is queued
+	p.service.offerBots(ctx)
 
-	// Get all players in queue
-	players, err := p.service.redisClient.ZRangeWithScores(ctx, queueKey, 0, -1).Result()
-	if err != nil {
-		log.Printf("Failed to get players from queue: %v", err)
-		return
-	}
-
-	// Process each player
-	for i, player := range players {
-		userID := player.Member.(string)
-		rating := int(player.Score)
-
-		// Skip if player is already matched
-		userKey := fmt.Sprintf(userQueueKey, userID)
-		exists, err := p.service.redisClient.Exists(ctx, userKey).Result()
-		if err != nil || exists == 0 {
-			continue
-		}
-
-		// Get player's queue data
-		userDataJSON, err := p.service.redisClient.Get(ctx, userKey).Result()
+	// Take matched pairs out of each queue, keeping apart the players the constraints rule out
+	for _, queue := range Queues() {
+		now := time.Now()
+		rules, err := p.service.pairingRules(ctx, queue, now)
 		if err != nil {
-			log.Printf("Failed to get queue data for user %s: %v", userID, err)
+			log.Printf("Failed to apply matchmaking constraints to queue %s: %v", queue, err)
 			continue
 		}
 
-		// Parse user data
-		var userData struct {
-			GameType string `json:"game_type"`
-			Ranked   bool   `json:"ranked"`
+		// The size of the queue before matching is kept with the matches made
+		size, err := p.service.store.Size(ctx, queue)
+		if err != nil {
+			log.Printf("Failed to get size of queue %s: %v", queue, err)
 		}
 
-		err = json.Unmarshal([]byte(userDataJSON), &userData)
+		matches, err := p.service.store.Match(ctx, queue, queue.Window(), now, rules)
 		if err != nil {
-			log.Printf("Failed to parse queue data for user %s: %v", userID, err)
+			log.Printf("Failed to match players of queue %s: %v", queue, err)
 			continue
 		}
 
-		gameType := userData.GameType
-		ranked := userData.Ranked
+		p.service.recordWaits(ctx, queue, matches, now)
+		p.service.recordSamples(matchSamples(matches, size, now))
 
-		// Get player's join time
-		joinTimeScore, err := p.service.redisClient.ZScore(ctx, queueTimeoutKey, userID).Result()
-		if err != nil {
-			log.Printf("Failed to get join time for user %s: %v", userID, err)
-			continue
+		// Both players have to accept before the game is created
+		for _, m := range matches {
+			p.service.startReadyCheck(m)
 		}
+	}
+}
 
-		joinTime := time.Unix(int64(joinTimeScore), 0).Add(-matchmakingTimeout)
-		waitTime := time.Since(joinTime)
+// createMatch creates the game of a matched pair once both players have accepted, and notifies them
+func (p *MatchProcessor) createMatch(ctx context.Context, m Match) {
+	if m.IsTeamMatch() {
+		p.createTeamMatch(ctx, m)
+		return
+	}
 
-		// Calculate ELO range based on wait time
-		eloRange := initialEloRange + int(math.Floor(waitTime.Seconds()/eloRangeIncrementInterval.Seconds()))*eloRangeIncrement
-		if eloRange > maxEloRange {
-			eloRange = maxEloRange
-		}
+	userID, matchedUserID := m.Player.UserID, m.Opponent.UserID
+	gameType, ranked := m.Player.GameType, m.Player.Ranked
 
-		// Find a match
-		matched := false
-		var matchedUserID string
+	// Create game, with a puzzle fair to both players
+	game, err := p.service.gameService.CreateMatchedDuel(userID, matchedUserID, gameType, m.Player.Variant, !ranked)
+	if err != nil {
+		log.Printf("Failed to create game: %v", err)
+		p.service.requeue(ctx, m.Player, m.Opponent)
+		return
+	}
 
-		for j, otherPlayer := range players {
-			if i == j {
-				continue
-			}
+	// Store game ID for both players
+	for _, id := range []string{userID, matchedUserID} {
+		if err := p.service.store.SetGame(ctx, id, game.ID); err != nil {
+			log.Printf("Failed to store game of user %s: %v", id, err)
+		}
+	}
 
-			otherUserID := otherPlayer.Member.(string)
-			otherRating := int(otherPlayer.Score)
+	// Get clients for both players
+	if p.service.websocketHub != nil {
+		// Get user data for WebSocket notifications
+		user1, err := p.service.userRepo.FindByID(userID)
+		if err != nil {
+			log.Printf("Failed to get user data for WebSocket notification: %v", err)
+			return
+		}
 
-			// Skip if other player is already matched
-			otherUserKey := fmt.Sprintf(userQueueKey, otherUserID)
-			exists, err := p.service.redisClient.Exists(ctx, otherUserKey).Result()
-			if err != nil || exists == 0 {
-				continue
-			}
+		user2, err := p.service.userRepo.FindByID(matchedUserID)
+		if err != nil {
+			log.Printf("Failed to get user data for WebSocket notification: %v", err)
+			return
+		}
 
-			// Get other player's game type
-			otherGameType, err := p.service.redisClient.Get(ctx, otherUserKey).Result()
-			if err != nil {
-				log.Printf("Failed to get game type for user %s: %v", otherUserID, err)
-				continue
-			}
+		// Find clients for both players
+		client1 := p.service.websocketHub.GetClientByUserID(userID)
+		client2 := p.service.websocketHub.GetClientByUserID(matchedUserID)
 
-			// Skip if game types don't match
-			if gameType != otherGameType {
-				continue
-			}
+		// Create player payloads
+		player1Payload := websocket.PlayerPayload{
+			UserID:   userID,
+			Username: user1.Username,
+			IsBot:    user1.IsBot,
+			Progress: 0,
+		}
 
-			// Check if ratings are within range
-			if math.Abs(float64(rating-otherRating)) <= float64(eloRange) {
-				matched = true
-				matchedUserID = otherUserID
-				break
-			}
+		player2Payload := websocket.PlayerPayload{
+			UserID:   matchedUserID,
+			Username: user2.Username,
+			IsBot:    user2.IsBot,
+			Progress: 0,
 		}
 
-		// If a match is found, create a game
-		if matched {
-			// Create game
-			game, err := p.service.gameService.CreateGame(userID, gameType)
+		// Send match found notifications
+		if client1 != nil {
+			err := p.service.websocketHub.SendMatchFound(client1, game.ID, gameType, player2Payload, ranked)
 			if err != nil {
-				log.Printf("Failed to create game: %v", err)
-				continue
+				log.Printf("Failed to send match found notification to player 1: %v", err)
 			}
+		}
 
-			// Add matched player to game
-			err = p.service.gameService.JoinGame(game.ID, matchedUserID)
+		if client2 != nil {
+			err := p.service.websocketHub.SendMatchFound(client2, game.ID, gameType, player1Payload, ranked)
 			if err != nil {
-				log.Printf("Failed to add player to game: %v", err)
-				continue
+				log.Printf("Failed to send match found notification to player 2: %v", err)
 			}
+		}
+	}
 
-			// Store game ID for both players
-			userGameKey1 := fmt.Sprintf(userGameKey, userID)
-			userGameKey2 := fmt.Sprintf(userGameKey, matchedUserID)
-			p.service.redisClient.Set(ctx, userGameKey1, game.ID, time.Hour)
-			p.service.redisClient.Set(ctx, userGameKey2, game.ID, time.Hour)
+	log.Printf("Created game %s for users %s and %s", game.ID, userID, matchedUserID)
+}
 
-			// Get user data for WebSocket notifications
-			user1, err := p.service.userRepo.FindByID(userID)
-			if err != nil {
-				log.Printf("Failed to get user data for WebSocket notification: %v", err)
-			}
+// createTeamMatch creates the game of a team match once every player has accepted, and notifies them
+func (p *MatchProcessor) createTeamMatch(ctx context.Context, m Match) {
+	sides := m.Sides()
+	gameType, ranked := m.Player.GameType, m.Player.Ranked
 
-			user2, err := p.service.userRepo.FindByID(matchedUserID)
-			if err != nil {
-				log.Printf("Failed to get user data for WebSocket notification: %v", err)
-			}
+	// Create game
+	game, err := p.service.gameService.CreateTeamGame(sides, gameType, m.Player.Variant, !ranked)
+	if err != nil {
+		log.Printf("Failed to create team game: %v", err)
+		p.service.requeue(ctx, m.Entries()...)
+		return
+	}
 
-			// Get clients for both players
-			if p.service.websocketHub != nil {
-				// Find clients for both players
-				client1 := p.service.websocketHub.GetClientByUserID(userID)
-				client2 := p.service.websocketHub.GetClientByUserID(matchedUserID)
-
-				// Create player payloads
-				player1Payload := websocket.PlayerPayload{
-					UserID:   userID,
-					Username: user1.Username,
-					Progress: 0,
-				}
+	// Store game ID for every player
+	for _, id := range m.UserIDs() {
+		if err := p.service.store.SetGame(ctx, id, game.ID); err != nil {
+			log.Printf("Failed to store game of user %s: %v", id, err)
+		}
+	}
 
-				player2Payload := websocket.PlayerPayload{
-					UserID:   matchedUserID,
-					Username: user2.Username,
-					Progress: 0,
+	// Tell each player who is on their side and who they play against
+	if p.service.websocketHub != nil {
+		players := p.service.playersByID(m.UserIDs())
+		for i, side := range sides {
+			for _, userID := range side {
+				client := p.service.websocketHub.GetClientByUserID(userID)
+				if client == nil {
+					continue
 				}
 
-				// Send match found notifications
-				if client1 != nil {
-					err := p.service.websocketHub.SendMatchFound(client1, game.ID, gameType, player2Payload, ranked)
-					if err != nil {
-						log.Printf("Failed to send match found notification to player 1: %v", err)
-					}
+				payload := websocket.MatchFoundPayload{
+					GameID:   game.ID,
+					GameType: gameType,
+					IsRanked: ranked,
+					Team:     i + 1,
+				}
+				payload.Teammates, payload.Opponents = describeSides(sides, i, userID, players)
+				if len(payload.Opponents) > 0 {
+					payload.Opponent = payload.Opponents[0]
 				}
 
-				if client2 != nil {
-					err := p.service.websocketHub.SendMatchFound(client2, game.ID, gameType, player1Payload, ranked)
-					if err != nil {
-						log.Printf("Failed to send match found notification to player 2: %v", err)
-					}
+				if err := p.service.websocketHub.SendTeamMatchFound(client, payload); err != nil {
+					log.Printf("Failed to send match found notification to user %s: %v", userID, err)
 				}
 			}
-
-			// Remove both players from queue
-			p.service.redisClient.ZRem(ctx, queueKey, userID)
-			p.service.redisClient.ZRem(ctx, queueKey, matchedUserID)
-			p.service.redisClient.ZRem(ctx, queueTimeoutKey, userID)
-			p.service.redisClient.ZRem(ctx, queueTimeoutKey, matchedUserID)
-			p.service.redisClient.Del(ctx, userKey)
-			p.service.redisClient.Del(ctx, fmt.Sprintf(userQueueKey, matchedUserID))
-
-			log.Printf("Created game %s for users %s and %s", game.ID, userID, matchedUserID)
 		}
 	}
+
+	log.Printf("Created team game %s for users %s against %s", game.ID, strings.Join(sides[0], ", "), strings.Join(sides[1], ", "))
 }
@@ -0,0 +1,208 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hectoclash/internal/config"
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/puzzle"
+	"github.com/hectoclash/internal/repository"
+)
+
+// Prefix that keeps the benchmark away from the real queues when both share a Redis
+const benchmarkPrefix = "matchmaking:bench:"
+
+// Queue the benchmark fills
+var benchmarkQueue = QueueID{GameType: "duel", Ranked: true, Variant: "classic"}
+
+// BenchmarkMatchPlayers measures one matching tick over a full queue. It needs a Redis server,
+// found through REDIS_URL and REDIS_PASSWORD like the server's, and is skipped without one.
+func BenchmarkMatchPlayers(b *testing.B) {
+	for _, size := range []int{1000, 5000, 10000} {
+		b.Run(fmt.Sprintf("players=%d", size), func(b *testing.B) {
+			client := testRedis(b)
+			defer client.Close()
+
+			ctx := context.Background()
+			store := &RedisStore{client: client, prefix: benchmarkPrefix}
+			defer clearBenchmarkQueue(ctx, b, store, size)
+
+			now := time.Now()
+			matched := 0
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				fillBenchmarkQueue(ctx, b, store, size, now, int64(i))
+				b.StartTimer()
+
+				matches, err := store.Match(ctx, benchmarkQueue, rankedWindow, now, nil)
+				if err != nil {
+					b.Fatalf("Failed to match players: %v", err)
+				}
+				matched += 2 * len(matches)
+			}
+
+			b.ReportMetric(float64(matched)/float64(b.N), "matched/tick")
+		})
+	}
+}
+
+// testRedis connects to the Redis server for a test or benchmark, skipping it if there is none
+func testRedis(tb testing.TB) *redis.Client {
+	addr := os.Getenv("REDIS_URL")
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		tb.Skipf("Redis is not available at %s: %v", addr, err)
+	}
+
+	return client
+}
+
+// fillBenchmarkQueue replaces the benchmark queue with players spread around a rating of 1200,
+// who joined at different times over the last minute
+func fillBenchmarkQueue(ctx context.Context, b *testing.B, store *RedisStore, size int, now time.Time, seed int64) {
+	clearBenchmarkQueue(ctx, b, store, size)
+
+	if err := joinScript.Load(ctx, store.client).Err(); err != nil {
+		b.Fatalf("Failed to load join script: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	keys := store.keys(benchmarkQueue)
+
+	pipe := store.client.Pipeline()
+	for i := 0; i < size; i++ {
+		expires := now.Add(time.Duration(1+rng.Intn(int(matchmakingTimeout.Seconds()))) * time.Second)
+		entry := QueueEntry{
+			UserID:   strconv.Itoa(i),
+			Rating:   1200 + int(rng.NormFloat64()*300),
+			JoinedAt: expires.Add(-matchmakingTimeout),
+			GameType: benchmarkQueue.GameType,
+			Ranked:   benchmarkQueue.Ranked,
+			Variant:  benchmarkQueue.Variant,
+			Timeout:  expires,
+		}
+
+		data, err := encodeQueueEntry(entry)
+		if err != nil {
+			b.Fatalf("Failed to encode queue entry: %v", err)
+		}
+
+		joinScript.EvalSha(ctx, pipe,
+			[]string{keys.queue, keys.timeout, store.userKey(entry.UserID)},
+			entry.UserID, entry.Rating, expires.Unix(), string(data), int64(matchmakingTimeout.Seconds()),
+		)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		b.Fatalf("Failed to fill queue: %v", err)
+	}
+}
+
+// clearBenchmarkQueue removes everything the benchmark stored
+func clearBenchmarkQueue(ctx context.Context, b *testing.B, store *RedisStore, size int) {
+	keys := store.keys(benchmarkQueue)
+
+	pipe := store.client.Pipeline()
+	pipe.Del(ctx, keys.queue, keys.timeout)
+	for i := 0; i < size; i++ {
+		pipe.Del(ctx, store.userKey(strconv.Itoa(i)))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		b.Fatalf("Failed to clear queue: %v", err)
+	}
+}
+
+// TestCreateMatchStartsSoloTeamMatch pairs two solo players in a team queue and checks that their
+// game starts with a side each. It needs a real Postgres database, found through TEST_DATABASE_URL,
+// and is skipped without one.
+func TestCreateMatchStartsSoloTeamMatch(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	database, err := repository.NewDatabase(&config.Config{
+		Database: config.DatabaseConfig{URL: url},
+	})
+	if err != nil {
+		t.Fatalf("connecting to database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	db := database.DB
+	userRepo := repository.NewUserRepository(db)
+	puzzleService := puzzle.NewService(repository.NewPuzzleRepository(db), userRepo, db)
+	gameService := game.NewService(repository.NewGameRepository(db), userRepo, puzzleService, nil)
+	store := NewMemoryStore()
+	service := NewService(store, userRepo, gameService, nil)
+
+	suffix := time.Now().UnixNano()
+	userIDs := make([]string, 2)
+	for i := range userIDs {
+		user := &models.User{
+			Username: fmt.Sprintf("team_match_test_%d_%d", suffix, i),
+			Email:    fmt.Sprintf("team_match_test_%d_%d@example.com", suffix, i),
+			Password: "password",
+		}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("creating user: %v", err)
+		}
+		userIDs[i] = user.ID
+	}
+	t.Cleanup(func() {
+		for _, userID := range userIDs {
+			db.Exec("DELETE FROM game_events WHERE game_id IN (SELECT game_id FROM players WHERE user_id = ?)", userID)
+			db.Exec("DELETE FROM games WHERE id IN (SELECT game_id FROM players WHERE user_id = ?)", userID)
+			db.Exec("DELETE FROM players WHERE user_id = ?", userID)
+			db.Exec("DELETE FROM user_ratings WHERE user_id = ?", userID)
+			db.Exec("DELETE FROM users WHERE id = ?", userID)
+		}
+	})
+
+	ctx := context.Background()
+	now := time.Now()
+	team := QueueID{GameType: "team", Ranked: false, Variant: "classic"}
+	m := Match{
+		Player:   testEntry(userIDs[0], 1200, team, now, 0),
+		Opponent: testEntry(userIDs[1], 1200, team, now, 0),
+	}
+
+	service.matchProcessor.createMatch(ctx, m)
+
+	gameID, err := store.Game(ctx, userIDs[0])
+	if err != nil || gameID == "" {
+		t.Fatalf("no game stored for the match: %q, %v", gameID, err)
+	}
+	g, err := gameService.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("loading game: %v", err)
+	}
+
+	if g.Status != models.GameStatusActive {
+		t.Errorf("game status = %s, want %s", g.Status, models.GameStatusActive)
+	}
+	teams := make(map[string]int)
+	for _, p := range g.Players {
+		teams[p.UserID] = p.Team
+	}
+	if teams[userIDs[0]] == 0 || teams[userIDs[1]] == 0 || teams[userIDs[0]] == teams[userIDs[1]] {
+		t.Errorf("player sides = %v, want one side each", teams)
+	}
+}
@@ -2,14 +2,13 @@ package matchmaking
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"sync"
 	"time"
 
-	"github.com/go-redis/redis/v8"
+	"github.com/hectoclash/internal/bot"
 	"github.com/hectoclash/internal/game"
 	"github.com/hectoclash/internal/models"
 	"github.com/hectoclash/internal/repository"
@@ -17,12 +16,9 @@ import (
 )
 
 const (
-	// Queue keys
-	queueKey           = "matchmaking:queue"
-	queueTimeoutKey    = "matchmaking:queue:timeout"
-	queueLockKey       = "matchmaking:queue:lock:%s" // Now includes user ID
-	userQueueKey       = "matchmaking:user:%s"
-	userGameKey        = "matchmaking:user:%s:game"
+	// Lock names
+	processorLock      = "processor" // Held by the replica running a matching tick
+	userLock           = "user:%s"   // Held while a user joins or leaves the queue
 	matchmakingTimeout = 60 * time.Second
 	lockTimeout        = 5 * time.Second
 
@@ -35,37 +31,85 @@ const (
 
 // Service handles matchmaking functionality
 type Service struct {
-	redisClient    *redis.Client
-	userRepo       *repository.UserRepository
-	gameService    *game.Service
-	matchProcessor *MatchProcessor
-	websocketHub   *websocket.Hub
-	mu             sync.Mutex
-	isRunning      bool
-	stopCh         chan struct{}
+	store             QueueStore
+	userRepo          *repository.UserRepository
+	friendRepo        *repository.FriendRepository
+	statsRepo         *repository.MatchmakingStatsRepository
+	gameService       *game.Service
+	matchProcessor    *MatchProcessor
+	websocketHub      *websocket.Hub
+	botService        *bot.Service
+	botOfferAfter     time.Duration
+	constraints       []Constraint // Rules every pair has to satisfy
+	readyCheckTimeout time.Duration
+	declineCooldown   time.Duration
+	readyChecks       map[string]*ReadyCheck // Open ready checks by user ID
+	readyMu           sync.Mutex
+	parties           map[string]*Party // Parties by ID
+	userParties       map[string]*Party // Parties by member user ID
+	partyMu           sync.Mutex
+	mu                sync.Mutex
+	isRunning         bool
+	stopCh            chan struct{}
 }
 
-// QueueEntry represents a player in the matchmaking queue
+// QueueEntry represents a player in the matchmaking queue, or a party queued by its leader
 type QueueEntry struct {
-	UserID    string    `json:"user_id"`
-	Rating    int       `json:"rating"`
-	JoinedAt  time.Time `json:"joined_at"`
-	GameType  string    `json:"game_type"`
-	Timeout   time.Time `json:"timeout"`
+	UserID   string    `json:"user_id"` // The player, or the party's leader
+	Rating   int       `json:"rating"`  // The player's rating, or the average rating of the party
+	JoinedAt time.Time `json:"joined_at"`
+	GameType string    `json:"game_type"`
+	Ranked   bool      `json:"ranked"`
+	Variant  string    `json:"variant"`
+	Timeout  time.Time `json:"timeout"`
+	PartyID  string    `json:"party_id,omitempty"`
+	Members  []string  `json:"members,omitempty"` // Every member of a party, leader first
+}
+
+// Queue gets the queue of an entry
+func (e QueueEntry) Queue() QueueID {
+	return QueueID{GameType: e.GameType, Ranked: e.Ranked, Variant: e.Variant}
+}
+
+// Size gets the number of players queued by an entry
+func (e QueueEntry) Size() int {
+	if len(e.Members) > 0 {
+		return len(e.Members)
+	}
+	return 1
+}
+
+// UserIDs lists the players queued by an entry
+func (e QueueEntry) UserIDs() []string {
+	if len(e.Members) > 0 {
+		return e.Members
+	}
+	return []string{e.UserID}
 }
 
 // NewService creates a new matchmaking service
-func NewService(redisClient *redis.Client, userRepo *repository.UserRepository, gameService *game.Service, websocketHub *websocket.Hub) *Service {
+func NewService(store QueueStore, userRepo *repository.UserRepository, gameService *game.Service, websocketHub *websocket.Hub) *Service {
 	service := &Service{
-		redisClient:  redisClient,
-		userRepo:     userRepo,
-		gameService:  gameService,
-		websocketHub: websocketHub,
-		stopCh:       make(chan struct{}),
+		store:             store,
+		userRepo:          userRepo,
+		gameService:       gameService,
+		websocketHub:      websocketHub,
+		readyCheckTimeout: defaultReadyCheckTimeout,
+		declineCooldown:   defaultDeclineCooldown,
+		readyChecks:       make(map[string]*ReadyCheck),
+		parties:           make(map[string]*Party),
+		userParties:       make(map[string]*Party),
+		stopCh:            make(chan struct{}),
 	}
 
 	service.matchProcessor = NewMatchProcessor(service)
 
+	// Register ready check message handlers
+	if websocketHub != nil {
+		websocketHub.RegisterMessageHandler(websocket.MessageTypeReadyAccept, service.handleReadyAccept)
+		websocketHub.RegisterMessageHandler(websocket.MessageTypeReadyDecline, service.handleReadyDecline)
+	}
+
 	return service
 }
 
@@ -100,221 +144,301 @@ func (s *Service) Stop() {
 	log.Println("Matchmaking service stopped")
 }
 
-// JoinQueue adds a player to the matchmaking queue
-func (s *Service) JoinQueue(userID, gameType string, ranked bool) error {
+// JoinQueue adds a player to the matchmaking queue of a game type, ranked flag and variant
+func (s *Service) JoinQueue(userID, gameType string, ranked bool, variant string) error {
 	ctx := context.Background()
 
-	// Check if user is already in queue
-	userKey := fmt.Sprintf(userQueueKey, userID)
-	exists, err := s.redisClient.Exists(ctx, userKey).Result()
-	if err != nil {
-		return fmt.Errorf("failed to check if user is in queue: %w", err)
+	// Queue for classic games unless told otherwise
+	if variant == "" {
+		variant = models.GameVariantClassic
 	}
-
-	if exists > 0 {
-		return errors.New("user is already in matchmaking queue")
+	queue := QueueID{GameType: gameType, Ranked: ranked, Variant: variant}
+	if !queue.IsValid() {
+		return errors.New("invalid queue")
 	}
 
-	// Get user's rating
-	user, err := s.userRepo.FindByID(userID)
-	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+	// A party is queued by its leader, once every member has joined
+	members := []string{userID}
+	party := s.GetParty(userID)
+	if party != nil {
+		if party.LeaderID != userID {
+			return errors.New("only the party leader can queue the party")
+		}
+		if len(party.Members) < maxPartySize {
+			return errors.New("party is not full; leave the party to queue alone")
+		}
+		if !queue.AllowsParties() {
+			return errors.New("parties can only join team queues and casual queues")
+		}
+		members = party.Members
 	}
 
-	// Calculate timeout
-	timeout := time.Now().Add(matchmakingTimeout)
+	// Every member is rated in the mode of the game type; a party queues with their average rating
+	totalRating := 0
+	for _, memberID := range members {
+		rating, err := s.checkCanQueue(ctx, memberID, gameType, ranked)
+		if err != nil && memberID != userID {
+			return fmt.Errorf("party member %s cannot queue: %w", memberID, err)
+		}
+		if err != nil {
+			return err
+		}
+		totalRating += rating
+	}
+	rating := totalRating / len(members)
 
 	// Acquire lock with user-specific key
-	userLockKey := fmt.Sprintf(queueLockKey, userID)
-	lockSuccess, err := s.redisClient.SetNX(ctx, userLockKey, "1", lockTimeout).Result()
+	lockName := fmt.Sprintf(userLock, userID)
+	token, locked, err := s.store.Lock(ctx, lockName, lockTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
-	if !lockSuccess {
+	if !locked {
 		// If we can't acquire the lock, the user might already be in the process of joining
 		// Let's check if they're already in the queue
-		exists, err := s.redisClient.Exists(ctx, userKey).Result()
-		if err == nil && exists > 0 {
+		existing, err := s.store.Entry(ctx, userID)
+		if err == nil && existing != nil {
 			// User is already in queue, return success
 			return nil
 		}
 		return errors.New("failed to acquire lock for queue operation")
 	}
 
-	defer s.redisClient.Del(ctx, userLockKey)
+	defer s.unlock(ctx, lockName, token)
 
 	// Add to queue
-	err = s.redisClient.ZAdd(ctx, queueKey, &redis.Z{
-		Score:  float64(user.Rating),
-		Member: userID,
-	}).Err()
+	now := time.Now()
+	entry := QueueEntry{
+		UserID:   userID,
+		Rating:   rating,
+		JoinedAt: now,
+		GameType: gameType,
+		Ranked:   ranked,
+		Variant:  variant,
+		Timeout:  now.Add(matchmakingTimeout),
+	}
+	if party != nil {
+		entry.PartyID = party.ID
+		entry.Members = party.Members
+	}
+	added, err := s.store.Join(ctx, entry)
 	if err != nil {
 		return fmt.Errorf("failed to add user to queue: %w", err)
 	}
 
-	// Store user queue data
-	userData := map[string]any{
-		"game_type": gameType,
-		"ranked":    ranked,
+	if !added {
+		return errors.New("user is already in matchmaking queue")
 	}
 
-	// Convert to JSON
-	userDataJSON, err := json.Marshal(userData)
-	if err != nil {
-		// Cleanup if we can't marshal the data
-		s.redisClient.ZRem(ctx, queueKey, userID)
-		return fmt.Errorf("failed to marshal user data: %w", err)
+	if party != nil {
+		log.Printf("Party %s of user %s joined matchmaking queue %s with average rating %d", party.ID, userID, queue, rating)
+		s.broadcastParty(party)
+	} else {
+		log.Printf("User %s joined matchmaking queue %s with %s rating %d", userID, queue, models.RatingModeForGameType(gameType), rating)
 	}
 
-	// Store user queue data
-	err = s.redisClient.Set(ctx, userKey, string(userDataJSON), matchmakingTimeout).Err()
-	if err != nil {
-		// Cleanup if we can't store the data
-		s.redisClient.ZRem(ctx, queueKey, userID)
-		return fmt.Errorf("failed to store user queue data: %w", err)
+	// Trigger match processing
+	go s.matchProcessor.ProcessMatches()
+
+	return nil
+}
+
+// checkCanQueue checks that a player may join a queue, returning their rating in the mode of its game type
+func (s *Service) checkCanQueue(ctx context.Context, userID, gameType string, ranked bool) (int, error) {
+	// Check if user is already in queue, or has a match waiting to be accepted
+	if err := s.checkIdle(userID); err != nil {
+		return 0, err
 	}
 
-	// Store timeout
-	err = s.redisClient.ZAdd(ctx, queueTimeoutKey, &redis.Z{
-		Score:  float64(timeout.Unix()),
-		Member: userID,
-	}).Err()
+	// Players who declined or missed a match sit out for a while
+	cooldownUntil, err := s.store.Cooldown(ctx, userID)
 	if err != nil {
-		// Cleanup if we can't store the timeout
-		s.redisClient.ZRem(ctx, queueKey, userID)
-		s.redisClient.Del(ctx, userKey)
-		return fmt.Errorf("failed to store queue timeout: %w", err)
+		return 0, fmt.Errorf("failed to check matchmaking cooldown: %w", err)
+	}
+	if !cooldownUntil.IsZero() {
+		return 0, cooldownError(cooldownUntil)
 	}
 
-	log.Printf("User %s joined matchmaking queue with rating %d", userID, user.Rating)
+	// Bots stay out of ranked queues with people unless an operator allowed them in
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if ranked && !user.PlaysRanked() {
+		return 0, errors.New("bots may not join ranked queues")
+	}
 
-	// Trigger match processing
-	go s.matchProcessor.ProcessMatches()
+	// Get user's rating in the mode of the game type
+	userRating, err := s.userRepo.GetUserRating(userID, models.RatingModeForGameType(gameType))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user rating: %w", err)
+	}
 
-	return nil
+	return userRating.Rating, nil
 }
 
 // LeaveQueue removes a player from the matchmaking queue
 func (s *Service) LeaveQueue(userID string) error {
+	return s.leaveQueue(userID, models.MatchmakingSampleLeft)
+}
+
+// leaveQueue takes a player out of the queue, recording why they left
+func (s *Service) leaveQueue(userID, reason string) error {
 	ctx := context.Background()
 
-	// Check if user is in queue
-	userKey := fmt.Sprintf(userQueueKey, userID)
-	exists, err := s.redisClient.Exists(ctx, userKey).Result()
+	// Leaving while a match waits to be accepted declines it
+	if s.readyCheck(userID) != nil {
+		return s.DeclineReadyCheck(userID, "")
+	}
+
+	// Check if user is in queue, on their own or with their party
+	existing, err := s.queueEntry(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("failed to check if user is in queue: %w", err)
 	}
 
-	if exists == 0 {
+	if existing == nil {
 		return errors.New("user is not in matchmaking queue")
 	}
 
+	// Any member takes their party out of the queue, whose entry belongs to the leader
+	queuedID := existing.UserID
+
 	// Acquire lock with user-specific key
-	userLockKey := fmt.Sprintf(queueLockKey, userID)
-	lockSuccess, err := s.redisClient.SetNX(ctx, userLockKey, "1", lockTimeout).Result()
+	lockName := fmt.Sprintf(userLock, queuedID)
+	token, locked, err := s.store.Lock(ctx, lockName, lockTimeout)
 	if err != nil {
 		return fmt.Errorf("failed to acquire lock: %w", err)
 	}
 
-	if !lockSuccess {
+	if !locked {
 		// If we can't acquire the lock, the user might already be in the process of leaving
 		// Let's check if they're still in the queue
-		exists, err := s.redisClient.Exists(ctx, userKey).Result()
-		if err == nil && exists == 0 {
+		existing, err := s.store.Entry(ctx, queuedID)
+		if err == nil && existing == nil {
 			// User is already gone from queue, return success
 			return nil
 		}
 		return errors.New("failed to acquire lock for queue operation")
 	}
 
-	defer s.redisClient.Del(ctx, userLockKey)
+	defer s.unlock(ctx, lockName, token)
 
-	// Remove from queue
-	err = s.redisClient.ZRem(ctx, queueKey, userID).Err()
+	// Remove from queue, along with any bot offer made while waiting
+	removed, err := s.store.Leave(ctx, queuedID)
 	if err != nil {
 		return fmt.Errorf("failed to remove user from queue: %w", err)
 	}
 
-	// Remove timeout
-	err = s.redisClient.ZRem(ctx, queueTimeoutKey, userID).Err()
-	if err != nil {
-		return fmt.Errorf("failed to remove queue timeout: %w", err)
-	}
-
-	// Remove user queue data
-	err = s.redisClient.Del(ctx, userKey).Err()
-	if err != nil {
-		return fmt.Errorf("failed to remove user queue data: %w", err)
+	if !removed {
+		return errors.New("user is not in matchmaking queue")
 	}
 
 	log.Printf("User %s left matchmaking queue", userID)
+	s.recordEntries(reason, []QueueEntry{*existing}, time.Now())
+
+	if party := s.GetParty(userID); party != nil && party.ID == existing.PartyID {
+		s.broadcastParty(party)
+	}
 
 	return nil
 }
 
-// GetQueueStatus gets the status of a player in the matchmaking queue
-func (s *Service) GetQueueStatus(userID string) (bool, time.Duration, string, error) {
+// GetQueueStatus gets the status of a player in the matchmaking queue, along with the size and
+// estimated wait of every queue
+func (s *Service) GetQueueStatus(userID string) (*websocket.MatchmakingStatusPayload, error) {
 	ctx := context.Background()
 
-	// Check if user is in queue
-	userKey := fmt.Sprintf(userQueueKey, userID)
-	exists, err := s.redisClient.Exists(ctx, userKey).Result()
+	status := &websocket.MatchmakingStatusPayload{
+		Status: "idle",
+	}
+
+	// Get the statistics of every queue
+	stats, err := s.GetQueueStats()
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range stats {
+		status.Queues = append(status.Queues, queueStatsPayload(st))
+	}
+
+	// Check if user is on cooldown
+	cooldownUntil, err := s.store.Cooldown(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check matchmaking cooldown: %w", err)
+	}
+	if !cooldownUntil.IsZero() {
+		status.Cooldown = time.Until(cooldownUntil).Seconds()
+	}
+
+	// Check if user has a match waiting to be accepted
+	if check := s.readyCheck(userID); check != nil {
+		status.Status = "ready_check"
+		status.ReadyCheckID = check.ID
+		return status, nil
+	}
+
+	// Check if user is in queue, on their own or with their party
+	entry, err := s.queueEntry(ctx, userID)
 	if err != nil {
 		// Log the error but don't fail the request
 		log.Printf("Error checking if user %s is in queue: %v", userID, err)
-		return false, 0, "", nil
+		return status, nil
 	}
-
-	if exists == 0 {
-		return false, 0, "", nil
+	if entry == nil {
+		return status, nil
 	}
 
 	// Check if user is in a game
-	gameKey := fmt.Sprintf(userGameKey, userID)
-	gameID, err := s.redisClient.Get(ctx, gameKey).Result()
-	if err != nil && err != redis.Nil {
-		return false, 0, "", fmt.Errorf("failed to check if user is in a game: %w", err)
+	gameID, err := s.store.Game(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if user is in a game: %w", err)
 	}
 
 	// If user is in a game, return the game ID
-	if err == nil && gameID != "" {
-		return true, 0, gameID, nil
+	if gameID != "" {
+		status.Status = "matched"
+		status.GameID = gameID
+		return status, nil
 	}
 
-	// Check if user is in the queue
-	_, err = s.redisClient.ZRank(ctx, queueKey, userID).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return false, 0, "", nil
+	queue := entry.Queue()
+	inQueue := time.Since(entry.JoinedAt)
+
+	status.Status = "queued"
+	status.WaitTime = time.Until(entry.Timeout).Seconds()
+	status.TimeInQueue = inQueue.Seconds()
+
+	// Describe the user's own queue
+	for _, st := range stats {
+		if st.Queue != queue {
+			continue
 		}
-		return false, 0, "", fmt.Errorf("failed to get user's position in queue: %w", err)
-	}
 
-	// Get user's join time
-	score, err := s.redisClient.ZScore(ctx, queueTimeoutKey, userID).Result()
-	if err != nil {
-		if err == redis.Nil {
-			return false, 0, "", nil
+		payload := queueStatsPayload(st)
+		status.Queue = &payload
+		status.QueueSize = st.Size
+		if remaining := st.EstimatedWait - inQueue; remaining > 0 {
+			status.EstimatedWait = remaining.Seconds()
 		}
-		return false, 0, "", fmt.Errorf("failed to get user's join time: %w", err)
 	}
 
-	timeout := time.Unix(int64(score), 0)
-	waitTime := time.Until(timeout)
-
-	return true, waitTime, "", nil
+	return status, nil
 }
 
-// GetQueueLength gets the number of players in the matchmaking queue
+// GetQueueLength gets the number of players in every matchmaking queue
 func (s *Service) GetQueueLength() (int64, error) {
-	ctx := context.Background()
-
-	count, err := s.redisClient.ZCard(ctx, queueKey).Result()
+	stats, err := s.GetQueueStats()
 	if err != nil {
 		return 0, fmt.Errorf("failed to get queue length: %w", err)
 	}
 
+	var count int64
+	for _, st := range stats {
+		count += int64(st.Size)
+	}
+
 	return count, nil
 }
 
@@ -327,60 +451,44 @@ func (s *Service) cleanupExpiredEntries() {
 		select {
 		case <-ticker.C:
 			ctx := context.Background()
-			now := time.Now().Unix()
-
-			// Get expired entries
-			expiredEntries, err := s.redisClient.ZRangeByScore(ctx, queueTimeoutKey, &redis.ZRangeBy{
-				Min: "0",
-				Max: fmt.Sprintf("%d", now),
-			}).Result()
 
-			if err != nil {
-				log.Printf("Failed to get expired entries: %v", err)
-				continue
+			for _, queue := range Queues() {
+				// Remove expired entries
+				expiredEntries, err := s.store.Expire(ctx, queue, time.Now())
+				if err != nil {
+					log.Printf("Failed to remove expired entries from queue %s: %v", queue, err)
+					continue
+				}
+
+				for _, entry := range expiredEntries {
+					log.Printf("Removed expired entry for user %s from matchmaking queue %s", entry.UserID, queue)
+				}
+				s.recordEntries(models.MatchmakingSampleTimeout, expiredEntries, time.Now())
 			}
 
-			if len(expiredEntries) == 0 {
-				continue
-			}
-
-			// Acquire lock
-			lockSuccess, err := s.redisClient.SetNX(ctx, queueLockKey, "1", lockTimeout).Result()
-			if err != nil {
-				log.Printf("Error acquiring lock for cleanup: %v", err)
-				continue
-			}
-
-			if !lockSuccess {
-				// This is normal - another instance might have the lock
-				// No need to log this as an error
-				continue
-			}
-
-			// Remove expired entries
-			for _, userID := range expiredEntries {
-				// Remove from queue
-				s.redisClient.ZRem(ctx, queueKey, userID)
-
-				// Remove timeout
-				s.redisClient.ZRem(ctx, queueTimeoutKey, userID)
-
-				// Remove user queue data
-				userKey := fmt.Sprintf(userQueueKey, userID)
-				s.redisClient.Del(ctx, userKey)
-
-				log.Printf("Removed expired entry for user %s from matchmaking queue", userID)
-			}
-
-			// Release lock
-			s.redisClient.Del(ctx, queueLockKey)
-
 		case <-s.stopCh:
 			return
 		}
 	}
 }
 
+// requeue puts matched players back into the queue when their game could not be created.
+// They keep their place: their wait and the widening of their ELO window carry on as before.
+func (s *Service) requeue(ctx context.Context, entries ...QueueEntry) {
+	for _, entry := range entries {
+		if _, err := s.store.Join(ctx, entry); err != nil {
+			log.Printf("Failed to put user %s back into matchmaking queue: %v", entry.UserID, err)
+		}
+	}
+}
+
+// unlock releases a lock taken from the store, logging if it fails
+func (s *Service) unlock(ctx context.Context, name, token string) {
+	if err := s.store.Unlock(ctx, name, token); err != nil {
+		log.Printf("Failed to release lock %s: %v", name, err)
+	}
+}
+
 // CreateCustomGame creates a custom game with the specified players
 func (s *Service) CreateCustomGame(creatorID string, opponentIDs []string, gameType string) (*models.Game, error) {
 	// Create the game
@@ -0,0 +1,273 @@
+package matchmaking
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore keeps the matchmaking queues in memory. It suits single-node deployments and
+// tests; replicas each get their own queues, so they would never match each other's players.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]QueueEntry     // Queue entries by user
+	games     map[string]expiring       // Games of matched players by user
+	botOffers map[string]memoryBotOffer // Bot offers by user
+	locks     map[string]expiring       // Lock tokens by name
+	cooldowns map[string]time.Time      // End of each player's cooldown by user
+	waits     map[QueueID]time.Duration // Average waits by queue
+}
+
+// expiring is a value that is only valid until a time
+type expiring struct {
+	value     string
+	expiresAt time.Time
+}
+
+// memoryBotOffer is a bot offer that is only valid until a time
+type memoryBotOffer struct {
+	offer     BotOffer
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates a queue store backed by memory
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]QueueEntry),
+		games:     make(map[string]expiring),
+		botOffers: make(map[string]memoryBotOffer),
+		locks:     make(map[string]expiring),
+		cooldowns: make(map[string]time.Time),
+		waits:     make(map[QueueID]time.Duration),
+	}
+}
+
+// Join adds a player to the queue of their entry
+func (s *MemoryStore) Join(ctx context.Context, entry QueueEntry) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.entries[entry.UserID]; ok && time.Now().Before(existing.Timeout) {
+		return false, nil
+	}
+
+	s.entries[entry.UserID] = entry
+	return true, nil
+}
+
+// Leave takes a player out of their queue
+func (s *MemoryStore) Leave(ctx context.Context, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[userID]
+	s.remove(userID)
+	return ok && time.Now().Before(entry.Timeout), nil
+}
+
+// Entry gets the queue entry of a player
+func (s *MemoryStore) Entry(ctx context.Context, userID string) (*QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[userID]
+	if !ok || !time.Now().Before(entry.Timeout) {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Match pairs up the players of a queue and takes the pairs out of it. Players are paired the
+// same way as by the Redis store's match script.
+func (s *MemoryStore) Match(ctx context.Context, queue QueueID, window EloWindow, now time.Time, rules *PairingRules) ([]Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Collect the players whose entries are still valid
+	players := make([]QueueEntry, 0)
+	for _, entry := range s.entries {
+		if entry.Queue() == queue && now.Before(entry.Timeout) && rules.Considers(entry.UserID) {
+			players = append(players, entry)
+		}
+	}
+
+	matches := pairEntries(players, window, now, rules)
+	for _, m := range matches {
+		for _, entry := range m.Entries() {
+			s.remove(entry.UserID)
+		}
+	}
+
+	return matches, nil
+}
+
+// Expire takes every player whose entry has timed out out of a queue
+func (s *MemoryStore) Expire(ctx context.Context, queue QueueID, now time.Time) ([]QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var expired []QueueEntry
+	for userID, entry := range s.entries {
+		if entry.Queue() == queue && !now.Before(entry.Timeout) {
+			s.remove(userID)
+			expired = append(expired, entry)
+		}
+	}
+	return expired, nil
+}
+
+// Waiting gets the players of a queue who joined before a time and have not timed out
+func (s *MemoryStore) Waiting(ctx context.Context, queue QueueID, joinedBefore, now time.Time) ([]QueueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entries []QueueEntry
+	for _, entry := range s.entries {
+		if entry.Queue() == queue && now.Before(entry.Timeout) && !entry.JoinedAt.After(joinedBefore) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Size gets the number of players in a queue
+func (s *MemoryStore) Size(ctx context.Context, queue QueueID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	size := 0
+	now := time.Now()
+	for _, entry := range s.entries {
+		if entry.Queue() == queue && now.Before(entry.Timeout) {
+			size++
+		}
+	}
+	return size, nil
+}
+
+// RecordWaits adds the waits of matched players to the average wait of their queue
+func (s *MemoryStore) RecordWaits(ctx context.Context, queue QueueID, waits []time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	average, hasAverage := s.waits[queue]
+	for _, wait := range waits {
+		average, hasAverage = smoothWait(average, hasAverage, wait), true
+	}
+	if hasAverage {
+		s.waits[queue] = average
+	}
+	return nil
+}
+
+// AverageWait gets the average wait of a queue's recently matched players
+func (s *MemoryStore) AverageWait(ctx context.Context, queue QueueID) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.waits[queue], nil
+}
+
+// SetGame records the game a matched player was put in
+func (s *MemoryStore) SetGame(ctx context.Context, userID, gameID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.games[userID] = expiring{value: gameID, expiresAt: time.Now().Add(userGameTTL)}
+	return nil
+}
+
+// Game gets the game a player was last matched into
+func (s *MemoryStore) Game(ctx context.Context, userID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	game, ok := s.games[userID]
+	if !ok || !time.Now().Before(game.expiresAt) {
+		delete(s.games, userID)
+		return "", nil
+	}
+	return game.value, nil
+}
+
+// SetCooldown keeps a player out of the queues until a time
+func (s *MemoryStore) SetCooldown(ctx context.Context, userID string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cooldowns[userID] = until
+	return nil
+}
+
+// Cooldown gets when a player may queue again
+func (s *MemoryStore) Cooldown(ctx context.Context, userID string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	until, ok := s.cooldowns[userID]
+	if !ok || !time.Now().Before(until) {
+		delete(s.cooldowns, userID)
+		return time.Time{}, nil
+	}
+	return until, nil
+}
+
+// OfferBot stores a bot offered to a queued player until it expires
+func (s *MemoryStore) OfferBot(ctx context.Context, userID string, offer BotOffer, expiresAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.botOffers[userID]; ok && time.Now().Before(existing.expiresAt) {
+		return false, nil
+	}
+
+	s.botOffers[userID] = memoryBotOffer{offer: offer, expiresAt: expiresAt}
+	return true, nil
+}
+
+// TakeBotOffer removes and returns the bot offered to a player
+func (s *MemoryStore) TakeBotOffer(ctx context.Context, userID string) (*BotOffer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	offer, ok := s.botOffers[userID]
+	delete(s.botOffers, userID)
+	if !ok || !time.Now().Before(offer.expiresAt) {
+		return nil, nil
+	}
+	return &offer.offer, nil
+}
+
+// Lock takes a named lock for a while
+func (s *MemoryStore) Lock(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if lock, ok := s.locks[name]; ok && now.Before(lock.expiresAt) {
+		return "", false, nil
+	}
+
+	token := uuid.NewString()
+	s.locks[name] = expiring{value: token, expiresAt: now.Add(ttl)}
+	return token, true, nil
+}
+
+// Unlock releases a lock, unless it has expired and been taken since
+func (s *MemoryStore) Unlock(ctx context.Context, name, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lock, ok := s.locks[name]; ok && lock.value == token {
+		delete(s.locks, name)
+	}
+	return nil
+}
+
+// remove takes a player out of their queue along with their bot offer. The caller must hold the mutex.
+func (s *MemoryStore) remove(userID string) {
+	delete(s.entries, userID)
+	delete(s.botOffers, userID)
+}
@@ -0,0 +1,404 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Queues the tests put players in
+var (
+	rankedDuel = QueueID{GameType: "duel", Ranked: true, Variant: "classic"}
+	casualDuel = QueueID{GameType: "duel", Ranked: false, Variant: "classic"}
+)
+
+// testEntry creates the queue entry of a player who joined a while before now
+func testEntry(userID string, rating int, queue QueueID, now time.Time, waited time.Duration) QueueEntry {
+	joinedAt := now.Add(-waited)
+	return QueueEntry{
+		UserID:   userID,
+		Rating:   rating,
+		JoinedAt: joinedAt,
+		GameType: queue.GameType,
+		Ranked:   queue.Ranked,
+		Variant:  queue.Variant,
+		Timeout:  joinedAt.Add(matchmakingTimeout),
+	}
+}
+
+func TestEloRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		window EloWindow
+		waited time.Duration
+		want   int
+	}{
+		{"ranked, just joined", rankedWindow, 0, 100},
+		{"ranked, within the first interval", rankedWindow, 4 * time.Second, 100},
+		{"ranked, after one interval", rankedWindow, 5 * time.Second, 150},
+		{"ranked, after three intervals", rankedWindow, 17 * time.Second, 250},
+		{"ranked, capped", rankedWindow, 55 * time.Second, 500},
+		{"casual, just joined", casualWindow, 0, 200},
+		{"casual, after one interval", casualWindow, 5 * time.Second, 300},
+		{"casual, capped", casualWindow, 55 * time.Second, 1000},
+		{"negative wait", rankedWindow, -time.Second, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := eloRange(tt.window, tt.waited); got != tt.want {
+				t.Errorf("eloRange() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreMatchWidensEloWindow(t *testing.T) {
+	tests := []struct {
+		name          string
+		queue         QueueID
+		gap           int
+		waited        time.Duration // How long the lower rated player has waited
+		opponentWaits time.Duration // How long the higher rated player has waited
+		wantMatch     bool
+		wantRange     int
+	}{
+		{"close ratings match at once", rankedDuel, 80, 0, 0, true, 100},
+		{"distant ratings wait", rankedDuel, 180, 0, 0, false, 0},
+		{"distant ratings match after waiting", rankedDuel, 180, 10 * time.Second, 0, true, 200},
+		{"the longer wait of the pair counts", rankedDuel, 180, 0, 10 * time.Second, true, 200},
+		{"ranked window stops widening", rankedDuel, 550, 59 * time.Second, 59 * time.Second, false, 0},
+		{"casual window starts wider", casualDuel, 180, 0, 0, true, 200},
+		{"casual window widens further", casualDuel, 550, 20 * time.Second, 0, true, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			now := time.Now()
+
+			player := testEntry("player", 1200, tt.queue, now, tt.waited)
+			opponent := testEntry("opponent", 1200+tt.gap, tt.queue, now, tt.opponentWaits)
+			for _, entry := range []QueueEntry{player, opponent} {
+				if _, err := store.Join(ctx, entry); err != nil {
+					t.Fatalf("Join() error = %v", err)
+				}
+			}
+
+			matches, err := store.Match(ctx, tt.queue, tt.queue.Window(), now, nil)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+
+			if !tt.wantMatch {
+				if len(matches) != 0 {
+					t.Fatalf("Match() = %d matches, want none", len(matches))
+				}
+				if size, _ := store.Size(ctx, tt.queue); size != 2 {
+					t.Errorf("Size() = %d, want 2", size)
+				}
+				return
+			}
+
+			if len(matches) != 1 {
+				t.Fatalf("Match() = %d matches, want 1", len(matches))
+			}
+			m := matches[0]
+			if m.Player.UserID != "player" || m.Opponent.UserID != "opponent" {
+				t.Errorf("Match() paired %s with %s, want player with opponent", m.Player.UserID, m.Opponent.UserID)
+			}
+			if m.EloRange != tt.wantRange {
+				t.Errorf("Match() ELO range = %d, want %d", m.EloRange, tt.wantRange)
+			}
+			if size, _ := store.Size(ctx, tt.queue); size != 0 {
+				t.Errorf("Size() = %d after match, want 0", size)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreMatchPairsClosestRatings(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	now := time.Now()
+
+	for i, rating := range []int{1000, 1050, 1500, 1560, 2400} {
+		if _, err := store.Join(ctx, testEntry(fmt.Sprintf("user%d", i), rating, rankedDuel, now, 0)); err != nil {
+			t.Fatalf("Join() error = %v", err)
+		}
+	}
+
+	// Players of other queues are never matched into this one
+	if _, err := store.Join(ctx, testEntry("casual", 1010, casualDuel, now, 0)); err != nil {
+		t.Fatalf("Join() error = %v", err)
+	}
+
+	matches, err := store.Match(ctx, rankedDuel, rankedWindow, now, nil)
+	if err != nil {
+		t.Fatalf("Match() error = %v", err)
+	}
+
+	want := [][2]string{{"user0", "user1"}, {"user2", "user3"}}
+	if len(matches) != len(want) {
+		t.Fatalf("Match() = %d matches, want %d", len(matches), len(want))
+	}
+	for i, m := range matches {
+		if m.Player.UserID != want[i][0] || m.Opponent.UserID != want[i][1] {
+			t.Errorf("match %d paired %s with %s, want %s with %s", i, m.Player.UserID, m.Opponent.UserID, want[i][0], want[i][1])
+		}
+	}
+
+	if entry, _ := store.Entry(ctx, "user4"); entry == nil {
+		t.Error("Entry() = nil for the unmatched player, want their entry")
+	}
+	if entry, _ := store.Entry(ctx, "casual"); entry == nil {
+		t.Error("Entry() = nil for the casual player, want their entry")
+	}
+}
+
+// testParty creates the queue entry of a party queued by its leader
+func testParty(leaderID, partnerID string, rating int, queue QueueID, now time.Time, waited time.Duration) QueueEntry {
+	entry := testEntry(leaderID, rating, queue, now, waited)
+	entry.PartyID = "party-" + leaderID
+	entry.Members = []string{leaderID, partnerID}
+	return entry
+}
+
+func TestMemoryStoreMatchParties(t *testing.T) {
+	now := time.Now()
+	team := QueueID{GameType: "team", Ranked: true, Variant: "classic"}
+
+	tests := []struct {
+		name      string
+		entries   []QueueEntry
+		wantSides [][2][]string // Sides of each match, in order
+		wantLeft  []string      // Entries left in the queue
+	}{
+		{
+			"party against party",
+			[]QueueEntry{testParty("a1", "a2", 1200, team, now, 0), testParty("b1", "b2", 1250, team, now, 0)},
+			[][2][]string{{{"a1", "a2"}, {"b1", "b2"}}},
+			nil,
+		},
+		{
+			"party against two solo players",
+			[]QueueEntry{testEntry("solo1", 1180, team, now, 0), testParty("a1", "a2", 1200, team, now, 0), testEntry("solo2", 1260, team, now, 0)},
+			[][2][]string{{{"a1", "a2"}, {"solo1", "solo2"}}},
+			nil,
+		},
+		{
+			"party never plays a single solo player",
+			[]QueueEntry{testParty("a1", "a2", 1200, team, now, 0), testEntry("solo1", 1220, team, now, 0)},
+			nil,
+			[]string{"a1", "solo1"},
+		},
+		{
+			"solo players pair up before a party can use them",
+			[]QueueEntry{testEntry("solo1", 1200, team, now, 0), testEntry("solo2", 1210, team, now, 0), testParty("a1", "a2", 1220, team, now, 0)},
+			[][2][]string{{{"solo1"}, {"solo2"}}},
+			[]string{"a1"},
+		},
+		{
+			"partner out of reach",
+			[]QueueEntry{testParty("a1", "a2", 1200, team, now, 0), testEntry("solo1", 1250, team, now, 0), testEntry("solo2", 1450, team, now, 0)},
+			nil,
+			[]string{"a1", "solo1", "solo2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			for _, entry := range tt.entries {
+				if _, err := store.Join(ctx, entry); err != nil {
+					t.Fatalf("Join() error = %v", err)
+				}
+			}
+
+			matches, err := store.Match(ctx, team, team.Window(), now, nil)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+
+			if len(matches) != len(tt.wantSides) {
+				t.Fatalf("Match() = %d matches, want %d", len(matches), len(tt.wantSides))
+			}
+			for i, m := range matches {
+				if got := fmt.Sprint(m.Sides()); got != fmt.Sprint(tt.wantSides[i]) {
+					t.Errorf("match %d sides = %s, want %s", i, got, fmt.Sprint(tt.wantSides[i]))
+				}
+			}
+
+			if size, _ := store.Size(ctx, team); size != len(tt.wantLeft) {
+				t.Errorf("Size() = %d, want %d", size, len(tt.wantLeft))
+			}
+			for _, userID := range tt.wantLeft {
+				if entry, _ := store.Entry(ctx, userID); entry == nil {
+					t.Errorf("Entry(%s) = nil, want their entry", userID)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreTimeouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		waited      time.Duration
+		wantQueued  bool
+		wantExpired bool
+	}{
+		{"just joined", 0, true, false},
+		{"about to time out", matchmakingTimeout - time.Second, true, false},
+		{"timed out", matchmakingTimeout, false, true},
+		{"long gone", 2 * matchmakingTimeout, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Now()
+
+			// An expired entry is neither queued nor matched
+			store := NewMemoryStore()
+			store.entries["player"] = testEntry("player", 1200, rankedDuel, now, tt.waited)
+			store.entries["opponent"] = testEntry("opponent", 1200, rankedDuel, now, 0)
+
+			entry, err := store.Entry(ctx, "player")
+			if err != nil {
+				t.Fatalf("Entry() error = %v", err)
+			}
+			if (entry != nil) != tt.wantQueued {
+				t.Errorf("Entry() queued = %v, want %v", entry != nil, tt.wantQueued)
+			}
+
+			matches, err := store.Match(ctx, rankedDuel, rankedWindow, now, nil)
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if (len(matches) == 1) != tt.wantQueued {
+				t.Errorf("Match() = %d matches, want a match %v", len(matches), tt.wantQueued)
+			}
+
+			// Expire only takes out players who timed out
+			store = NewMemoryStore()
+			store.entries["player"] = testEntry("player", 1200, rankedDuel, now, tt.waited)
+			if _, err := store.OfferBot(ctx, "player", BotOffer{BotID: "bot"}, now.Add(time.Minute)); err != nil {
+				t.Fatalf("OfferBot() error = %v", err)
+			}
+
+			expired, err := store.Expire(ctx, rankedDuel, now)
+			if err != nil {
+				t.Fatalf("Expire() error = %v", err)
+			}
+			if (len(expired) == 1) != tt.wantExpired {
+				t.Errorf("Expire() = %v, want expired %v", expired, tt.wantExpired)
+			}
+
+			offer, err := store.TakeBotOffer(ctx, "player")
+			if err != nil {
+				t.Fatalf("TakeBotOffer() error = %v", err)
+			}
+			if (offer == nil) != tt.wantExpired {
+				t.Errorf("TakeBotOffer() = %v, want the offer withdrawn %v", offer, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreConcurrentJoinLeave(t *testing.T) {
+	tests := []struct {
+		name    string
+		users   int
+		joiners int // Goroutines joining each user at once
+		leave   bool
+	}{
+		{"one join per user", 50, 1, false},
+		{"racing joins of the same user", 20, 10, false},
+		{"joins then leaves", 50, 1, true},
+		{"racing joins then leaves", 20, 10, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			now := time.Now()
+
+			var mu sync.Mutex
+			joined := make(map[string]int)
+			left := make(map[string]int)
+
+			var wg sync.WaitGroup
+			for u := 0; u < tt.users; u++ {
+				userID := fmt.Sprintf("user%d", u)
+				for j := 0; j < tt.joiners; j++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+
+						added, err := store.Join(ctx, testEntry(userID, 1200, rankedDuel, now, 0))
+						if err != nil {
+							t.Errorf("Join() error = %v", err)
+							return
+						}
+
+						mu.Lock()
+						if added {
+							joined[userID]++
+						}
+						mu.Unlock()
+					}()
+				}
+			}
+			wg.Wait()
+
+			if tt.leave {
+				for u := 0; u < tt.users; u++ {
+					userID := fmt.Sprintf("user%d", u)
+					for j := 0; j < tt.joiners; j++ {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+
+							removed, err := store.Leave(ctx, userID)
+							if err != nil {
+								t.Errorf("Leave() error = %v", err)
+								return
+							}
+
+							mu.Lock()
+							if removed {
+								left[userID]++
+							}
+							mu.Unlock()
+						}()
+					}
+				}
+				wg.Wait()
+			}
+
+			for u := 0; u < tt.users; u++ {
+				userID := fmt.Sprintf("user%d", u)
+				if joined[userID] != 1 {
+					t.Errorf("user %s joined %d times, want once", userID, joined[userID])
+				}
+				if tt.leave && left[userID] != 1 {
+					t.Errorf("user %s left %d times, want once", userID, left[userID])
+				}
+			}
+
+			wantSize := tt.users
+			if tt.leave {
+				wantSize = 0
+			}
+			if size, _ := store.Size(ctx, rankedDuel); size != wantSize {
+				t.Errorf("Size() = %d, want %d", size, wantSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,436 @@
+package matchmaking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// Party settings
+const (
+	maxPartySize       = 2
+	partyInviteTimeout = 60 * time.Second
+)
+
+// Party is a group of friends who queue together. The leader queues the party, which is matched
+// as a unit against parties or solo players of the same total size.
+type Party struct {
+	ID        string               `json:"id"`
+	LeaderID  string               `json:"leader_id"`
+	Members   []string             `json:"members"` // Leader first
+	Invites   map[string]time.Time `json:"invites"` // When each pending invite lapses, by friend
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+// SetFriendRepository lets players invite their friends into a party
+func (s *Service) SetFriendRepository(friendRepo *repository.FriendRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.friendRepo = friendRepo
+}
+
+// InviteToParty invites a friend into a player's party, creating the party if the player has none
+func (s *Service) InviteToParty(userID, friendID string) (*Party, error) {
+	s.mu.Lock()
+	friendRepo := s.friendRepo
+	s.mu.Unlock()
+
+	if friendRepo == nil {
+		return nil, errors.New("parties are disabled")
+	}
+	if userID == friendID {
+		return nil, errors.New("cannot invite yourself")
+	}
+
+	isFriend, err := friendRepo.IsFriend(userID, friendID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check friendship: %w", err)
+	}
+	if !isFriend {
+		return nil, errors.New("only friends can be invited")
+	}
+
+	// A player who queued alone has to leave the queue first
+	if err := s.checkIdle(userID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(partyInviteTimeout)
+
+	s.partyMu.Lock()
+	party := s.userParties[userID]
+	if party == nil {
+		party = &Party{
+			ID:        uuid.NewString(),
+			LeaderID:  userID,
+			Members:   []string{userID},
+			Invites:   make(map[string]time.Time),
+			CreatedAt: now,
+		}
+		s.parties[party.ID] = party
+		s.userParties[userID] = party
+	}
+
+	if party.LeaderID != userID {
+		s.partyMu.Unlock()
+		return nil, errors.New("only the party leader can invite players")
+	}
+	if len(party.Members) >= maxPartySize {
+		s.partyMu.Unlock()
+		return nil, errors.New("party is full")
+	}
+	if s.userParties[friendID] != nil {
+		s.partyMu.Unlock()
+		return nil, errors.New("friend is already in a party")
+	}
+
+	party.Invites[friendID] = expiresAt
+	time.AfterFunc(partyInviteTimeout, func() {
+		s.expirePartyInvite(party.ID, friendID, expiresAt)
+	})
+	snapshot := party.snapshot()
+	s.partyMu.Unlock()
+
+	log.Printf("User %s invited user %s into party %s", userID, friendID, snapshot.ID)
+
+	s.broadcastParty(snapshot)
+
+	// Tell the friend who invited them
+	payload := websocket.PartyPayload{
+		PartyID:   snapshot.ID,
+		LeaderID:  snapshot.LeaderID,
+		ExpiresAt: expiresAt.UnixNano() / int64(time.Millisecond),
+	}
+	if inviter := s.playerPayloads([]string{userID}); len(inviter) == 1 {
+		payload.InvitedBy = &inviter[0]
+	}
+	s.sendParty(friendID, websocket.MessageTypePartyInvite, payload)
+
+	return snapshot, nil
+}
+
+// AcceptPartyInvite joins the party a player was invited into
+func (s *Service) AcceptPartyInvite(userID, partyID string) (*Party, error) {
+	// A player who queued alone has to leave the queue first
+	if err := s.checkIdle(userID); err != nil {
+		return nil, err
+	}
+
+	s.partyMu.Lock()
+	party, exists := s.parties[partyID]
+	if !exists {
+		s.partyMu.Unlock()
+		return nil, errors.New("party not found")
+	}
+
+	expiresAt, invited := party.Invites[userID]
+	if !invited || !time.Now().Before(expiresAt) {
+		s.partyMu.Unlock()
+		return nil, errors.New("no party invite found")
+	}
+	if s.userParties[userID] != nil {
+		s.partyMu.Unlock()
+		return nil, errors.New("user is already in a party")
+	}
+	if len(party.Members) >= maxPartySize {
+		s.partyMu.Unlock()
+		return nil, errors.New("party is full")
+	}
+
+	party.Members = append(party.Members, userID)
+	delete(party.Invites, userID)
+	s.userParties[userID] = party
+
+	// Other invites lapse once the party is full
+	if len(party.Members) >= maxPartySize {
+		party.Invites = make(map[string]time.Time)
+	}
+	snapshot := party.snapshot()
+	s.partyMu.Unlock()
+
+	log.Printf("User %s joined party %s", userID, partyID)
+
+	s.broadcastParty(snapshot)
+
+	return snapshot, nil
+}
+
+// LeaveParty takes a player out of their party. A party left with a single member is disbanded,
+// and a queued party leaves its queue.
+func (s *Service) LeaveParty(userID string) error {
+	s.partyMu.Lock()
+	party := s.userParties[userID]
+	if party == nil {
+		s.partyMu.Unlock()
+		return errors.New("user is not in a party")
+	}
+	s.partyMu.Unlock()
+
+	return s.breakUpParty(party, userID, "left")
+}
+
+// DisbandParty breaks up a player's party. Only the leader can disband it.
+func (s *Service) DisbandParty(userID string) error {
+	s.partyMu.Lock()
+	party := s.userParties[userID]
+	if party == nil {
+		s.partyMu.Unlock()
+		return errors.New("user is not in a party")
+	}
+	if party.LeaderID != userID {
+		s.partyMu.Unlock()
+		return errors.New("only the party leader can disband the party")
+	}
+	s.partyMu.Unlock()
+
+	return s.breakUpParty(party, "", "disbanded")
+}
+
+// GetParty gets the party of a player, or nil if they are not in one
+func (s *Service) GetParty(userID string) *Party {
+	s.partyMu.Lock()
+	defer s.partyMu.Unlock()
+
+	party := s.userParties[userID]
+	if party == nil {
+		return nil
+	}
+	return party.snapshot()
+}
+
+// breakUpParty takes a player out of a party, or everyone if leaverID is empty. The party leaves
+// any queue or ready check it is in, and is disbanded once fewer than two members remain.
+func (s *Service) breakUpParty(party *Party, leaverID, reason string) error {
+	ctx := context.Background()
+
+	// A party that loses a member can no longer play the match it was queued or matched for
+	if check := s.readyCheck(party.LeaderID); check != nil {
+		withdrawn := leaverID
+		if withdrawn == "" {
+			withdrawn = party.LeaderID
+		}
+		s.withdrawFromReadyCheck(check, withdrawn, "party_"+reason)
+	} else if entry, err := s.store.Entry(ctx, party.LeaderID); err == nil && entry != nil && entry.PartyID == party.ID {
+		if _, err := s.store.Leave(ctx, party.LeaderID); err != nil {
+			log.Printf("Failed to take party %s out of matchmaking queue: %v", party.ID, err)
+		} else {
+			s.recordEntries(models.MatchmakingSampleLeft, []QueueEntry{*entry}, time.Now())
+		}
+	}
+
+	s.partyMu.Lock()
+	if s.parties[party.ID] != party {
+		s.partyMu.Unlock()
+		return nil
+	}
+
+	former := party.Members
+	remaining := make([]string, 0, len(party.Members))
+	for _, memberID := range party.Members {
+		if leaverID != "" && memberID != leaverID {
+			remaining = append(remaining, memberID)
+		}
+	}
+	party.Members = remaining
+
+	disbanded := len(remaining) < 2
+	if disbanded {
+		s.closePartyLocked(party, former)
+	} else {
+		delete(s.userParties, leaverID)
+		if party.LeaderID == leaverID {
+			party.LeaderID = remaining[0]
+		}
+	}
+	snapshot := party.snapshot()
+	s.partyMu.Unlock()
+
+	if !disbanded {
+		log.Printf("User %s left party %s", leaverID, party.ID)
+		s.broadcastParty(snapshot)
+		return nil
+	}
+
+	log.Printf("Disbanded party %s: %s", party.ID, reason)
+	for _, memberID := range former {
+		s.sendParty(memberID, websocket.MessageTypePartyDisbanded, websocket.PartyPayload{
+			PartyID: party.ID,
+			Reason:  reason,
+		})
+	}
+
+	return nil
+}
+
+// expirePartyInvite withdraws an invite that was not accepted in time. A party whose leader is
+// left on their own with no invites pending is disbanded.
+func (s *Service) expirePartyInvite(partyID, friendID string, expiresAt time.Time) {
+	s.partyMu.Lock()
+	party, exists := s.parties[partyID]
+	if !exists || !party.Invites[friendID].Equal(expiresAt) {
+		s.partyMu.Unlock()
+		return
+	}
+
+	delete(party.Invites, friendID)
+	disbanded := len(party.Members) < 2 && len(party.Invites) == 0
+	if disbanded {
+		s.closePartyLocked(party, party.Members)
+	}
+	snapshot := party.snapshot()
+	s.partyMu.Unlock()
+
+	if disbanded {
+		s.sendParty(snapshot.LeaderID, websocket.MessageTypePartyDisbanded, websocket.PartyPayload{
+			PartyID: snapshot.ID,
+			Reason:  "invite_expired",
+		})
+		return
+	}
+	s.broadcastParty(snapshot)
+}
+
+// closePartyLocked forgets a party and its members. The caller must hold partyMu.
+func (s *Service) closePartyLocked(party *Party, members []string) {
+	delete(s.parties, party.ID)
+	for _, memberID := range members {
+		if s.userParties[memberID] == party {
+			delete(s.userParties, memberID)
+		}
+	}
+}
+
+// queueEntry gets the queue entry a player is waiting in, which is their party's if they are in one
+func (s *Service) queueEntry(ctx context.Context, userID string) (*QueueEntry, error) {
+	party := s.GetParty(userID)
+	if party == nil || party.LeaderID == userID {
+		return s.store.Entry(ctx, userID)
+	}
+
+	entry, err := s.store.Entry(ctx, party.LeaderID)
+	if err != nil || entry == nil || entry.PartyID != party.ID {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// checkIdle checks that a player is neither queued nor waiting to accept a match
+func (s *Service) checkIdle(userID string) error {
+	if s.readyCheck(userID) != nil {
+		return errors.New("user has a match waiting to be accepted")
+	}
+
+	entry, err := s.queueEntry(context.Background(), userID)
+	if err != nil {
+		return fmt.Errorf("failed to check if user is in queue: %w", err)
+	}
+	if entry != nil {
+		return errors.New("user is already in matchmaking queue")
+	}
+	return nil
+}
+
+// broadcastParty pushes the state of a party to its members
+func (s *Service) broadcastParty(party *Party) {
+	if s.websocketHub == nil {
+		return
+	}
+
+	entry, err := s.store.Entry(context.Background(), party.LeaderID)
+	queued := err == nil && entry != nil && entry.PartyID == party.ID
+
+	payload := websocket.PartyPayload{
+		PartyID:  party.ID,
+		LeaderID: party.LeaderID,
+		Members:  s.playerPayloads(party.Members),
+		Queued:   queued,
+	}
+	for friendID := range party.Invites {
+		payload.Invited = append(payload.Invited, friendID)
+	}
+
+	for _, memberID := range party.Members {
+		s.sendParty(memberID, websocket.MessageTypePartyState, payload)
+	}
+}
+
+// sendParty sends a party message to a player, if they are connected
+func (s *Service) sendParty(userID string, messageType websocket.MessageType, payload websocket.PartyPayload) {
+	if s.websocketHub == nil {
+		return
+	}
+	client := s.websocketHub.GetClientByUserID(userID)
+	if client == nil {
+		return
+	}
+
+	if err := s.websocketHub.SendParty(client, messageType, payload); err != nil {
+		log.Printf("Failed to send %s to user %s: %v", messageType, userID, err)
+	}
+}
+
+// playerPayloads describes players for WebSocket messages, skipping any who cannot be found
+func (s *Service) playerPayloads(userIDs []string) []websocket.PlayerPayload {
+	payloads := make([]websocket.PlayerPayload, 0, len(userIDs))
+	if s.userRepo == nil {
+		return payloads
+	}
+
+	for _, userID := range userIDs {
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			log.Printf("Failed to get user %s: %v", userID, err)
+			continue
+		}
+		payloads = append(payloads, websocket.PlayerPayload{
+			UserID:   user.ID,
+			Username: user.Username,
+			IsBot:    user.IsBot,
+		})
+	}
+	return payloads
+}
+
+// playersByID describes players for WebSocket messages by user ID
+func (s *Service) playersByID(userIDs []string) map[string]websocket.PlayerPayload {
+	players := make(map[string]websocket.PlayerPayload, len(userIDs))
+	for _, player := range s.playerPayloads(userIDs) {
+		players[player.UserID] = player
+	}
+	return players
+}
+
+// describeSides lists the teammates and opponents of a player on one side of a match
+func describeSides(sides [2][]string, side int, userID string, players map[string]websocket.PlayerPayload) (teammates, opponents []websocket.PlayerPayload) {
+	for _, id := range sides[side] {
+		if player, ok := players[id]; ok && id != userID {
+			teammates = append(teammates, player)
+		}
+	}
+	for _, id := range sides[1-side] {
+		if player, ok := players[id]; ok {
+			opponents = append(opponents, player)
+		}
+	}
+	return teammates, opponents
+}
+
+// snapshot copies a party, so it can be read without holding partyMu
+func (p *Party) snapshot() *Party {
+	party := *p
+	party.Members = append([]string{}, p.Members...)
+	party.Invites = make(map[string]time.Time, len(p.Invites))
+	for friendID, expiresAt := range p.Invites {
+		party.Invites[friendID] = expiresAt
+	}
+	return &party
+}
@@ -0,0 +1,164 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// Game types players can queue for
+var queueGameTypes = []string{"duel", "blitz", "team"}
+
+// Weight of the latest wait in a queue's average wait
+const waitSmoothing = 0.2
+
+// QueueID identifies a matchmaking queue. Players only meet players of the same queue.
+type QueueID struct {
+	GameType string `json:"game_type"`
+	Ranked   bool   `json:"ranked"`
+	Variant  string `json:"variant"`
+}
+
+// EloWindow is how far apart the ratings of two players of a queue may be. It starts at
+// Initial and widens by Increment for every Interval the players wait, up to Max.
+type EloWindow struct {
+	Initial   int
+	Increment int
+	Interval  time.Duration
+	Max       int
+}
+
+// Ranked queues keep ratings close; casual queues widen faster, since nothing is at stake
+var (
+	rankedWindow = EloWindow{
+		Initial:   initialEloRange,
+		Increment: eloRangeIncrement,
+		Interval:  eloRangeIncrementInterval,
+		Max:       maxEloRange,
+	}
+	casualWindow = EloWindow{
+		Initial:   2 * initialEloRange,
+		Increment: 2 * eloRangeIncrement,
+		Interval:  eloRangeIncrementInterval,
+		Max:       2 * maxEloRange,
+	}
+)
+
+// Queues lists every matchmaking queue
+func Queues() []QueueID {
+	variants := []string{models.GameVariantClassic, models.GameVariantImpossible}
+
+	queues := make([]QueueID, 0, len(queueGameTypes)*2*len(variants))
+	for _, gameType := range queueGameTypes {
+		for _, ranked := range []bool{true, false} {
+			for _, variant := range variants {
+				queues = append(queues, QueueID{GameType: gameType, Ranked: ranked, Variant: variant})
+			}
+		}
+	}
+	return queues
+}
+
+// IsValid checks if a queue exists
+func (q QueueID) IsValid() bool {
+	if !models.IsValidGameVariant(q.Variant) {
+		return false
+	}
+	for _, gameType := range queueGameTypes {
+		if gameType == q.GameType {
+			return true
+		}
+	}
+	return false
+}
+
+// String gets the name of a queue, like "duel:ranked:classic"
+func (q QueueID) String() string {
+	ranked := "casual"
+	if q.Ranked {
+		ranked = "ranked"
+	}
+	return fmt.Sprintf("%s:%s:%s", q.GameType, ranked, q.Variant)
+}
+
+// AllowsParties checks if parties may join a queue: team queues, ranked or not, and every casual queue
+func (q QueueID) AllowsParties() bool {
+	return q.GameType == "team" || !q.Ranked
+}
+
+// Window gets the ELO window policy of a queue
+func (q QueueID) Window() EloWindow {
+	if q.Ranked {
+		return rankedWindow
+	}
+	return casualWindow
+}
+
+// QueueStats are the size of a queue and how long its players usually wait
+type QueueStats struct {
+	Queue         QueueID
+	Size          int
+	EstimatedWait time.Duration // Average wait of recently matched players
+}
+
+// GetQueueStats gets the statistics of every queue
+func (s *Service) GetQueueStats() ([]QueueStats, error) {
+	ctx := context.Background()
+	queues := Queues()
+
+	stats := make([]QueueStats, len(queues))
+	for i, q := range queues {
+		size, err := s.store.Size(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue statistics: %w", err)
+		}
+
+		// Queues that never matched anyone have no estimate
+		avgWait, err := s.store.AverageWait(ctx, q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get queue statistics: %w", err)
+		}
+
+		stats[i] = QueueStats{
+			Queue:         q,
+			Size:          size,
+			EstimatedWait: avgWait,
+		}
+	}
+
+	return stats, nil
+}
+
+// recordWaits adds the waits of matched players to the average wait of their queue.
+// It is only called by the replica holding the processor lock.
+func (s *Service) recordWaits(ctx context.Context, q QueueID, matches []Match, now time.Time) {
+	if len(matches) == 0 {
+		return
+	}
+
+	waits := make([]time.Duration, 0, 2*len(matches))
+	for _, m := range matches {
+		for _, entry := range m.Entries() {
+			waits = append(waits, now.Sub(entry.JoinedAt))
+		}
+	}
+
+	if err := s.store.RecordWaits(ctx, q, waits); err != nil {
+		log.Printf("Failed to record waits of queue %s: %v", q, err)
+	}
+}
+
+// Helper function to convert queue statistics to a WebSocket payload
+func queueStatsPayload(stats QueueStats) websocket.QueueStatsPayload {
+	return websocket.QueueStatsPayload{
+		GameType:      stats.Queue.GameType,
+		Ranked:        stats.Queue.Ranked,
+		Variant:       stats.Queue.Variant,
+		Size:          stats.Size,
+		EstimatedWait: stats.EstimatedWait.Seconds(),
+	}
+}
@@ -0,0 +1,320 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// Default ready check policy
+const (
+	defaultReadyCheckTimeout = 15 * time.Second
+	defaultDeclineCooldown   = 30 * time.Second
+)
+
+// ReadyCheck is a match waiting for every player to accept it. Its game is only created once they have.
+type ReadyCheck struct {
+	ID        string
+	Match     Match
+	Accepted  map[string]bool // Players who have accepted, by user ID
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	timer     *time.Timer
+}
+
+// SetReadyCheckPolicy sets how long matched players have to accept their match, and how long
+// players who decline or do not answer are kept out of the queue
+func (s *Service) SetReadyCheckPolicy(timeout, cooldown time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.readyCheckTimeout = timeout
+	s.declineCooldown = cooldown
+}
+
+// AcceptReadyCheck accepts the match a player was offered. The game is created once every player has accepted.
+func (s *Service) AcceptReadyCheck(userID, readyCheckID string) error {
+	s.readyMu.Lock()
+	check, err := s.readyCheckLocked(userID, readyCheckID)
+	if err != nil {
+		s.readyMu.Unlock()
+		return err
+	}
+
+	check.Accepted[userID] = true
+	ready := len(check.Accepted) == len(check.Match.UserIDs())
+	if ready {
+		check.timer.Stop()
+		s.closeReadyCheckLocked(check)
+	}
+	accepted := check.acceptedIDs()
+	s.readyMu.Unlock()
+
+	if ready {
+		s.recordReadyCheck(check, readyCheckAccepted, time.Now())
+		s.matchProcessor.createMatch(context.Background(), check.Match)
+		return nil
+	}
+
+	// Tell every player who has accepted so far
+	for _, id := range check.Match.UserIDs() {
+		s.sendReadyCheck(id, websocket.MessageTypeReadyCheckUpdate, websocket.ReadyCheckPayload{
+			ReadyCheckID: check.ID,
+			Accepted:     accepted,
+		})
+	}
+
+	return nil
+}
+
+// DeclineReadyCheck declines the match a player was offered. The player is put on cooldown and
+// the other players go back into the queue.
+func (s *Service) DeclineReadyCheck(userID, readyCheckID string) error {
+	s.readyMu.Lock()
+	check, err := s.readyCheckLocked(userID, readyCheckID)
+	s.readyMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.withdrawFromReadyCheck(check, userID, "declined")
+
+	return nil
+}
+
+// startReadyCheck asks every player of a match to accept it
+func (s *Service) startReadyCheck(m Match) {
+	s.mu.Lock()
+	timeout := s.readyCheckTimeout
+	s.mu.Unlock()
+
+	now := time.Now()
+	check := &ReadyCheck{
+		ID:        uuid.NewString(),
+		Match:     m,
+		Accepted:  make(map[string]bool),
+		CreatedAt: now,
+		ExpiresAt: now.Add(timeout),
+	}
+
+	s.readyMu.Lock()
+	for _, id := range m.UserIDs() {
+		s.readyChecks[id] = check
+	}
+
+	// Cancel the match if it is not accepted in time
+	check.timer = time.AfterFunc(timeout, func() {
+		s.expireReadyCheck(check)
+	})
+	s.readyMu.Unlock()
+
+	log.Printf("Started ready check %s for users %s", check.ID, strings.Join(m.UserIDs(), ", "))
+
+	if s.websocketHub == nil {
+		return
+	}
+
+	players := s.playersByID(m.UserIDs())
+
+	// Tell each player who they were matched with, and with whom in a team match
+	sides := m.Sides()
+	for i, side := range sides {
+		for _, userID := range side {
+			payload := websocket.ReadyCheckPayload{
+				ReadyCheckID: check.ID,
+				GameType:     m.Player.GameType,
+				Variant:      m.Player.Variant,
+				IsRanked:     m.Player.Ranked,
+				ExpiresAt:    check.ExpiresAt.UnixNano() / int64(time.Millisecond),
+			}
+			payload.Teammates, payload.Opponents = describeSides(sides, i, userID, players)
+			if !m.IsTeamMatch() && len(payload.Opponents) == 1 {
+				payload.Opponent, payload.Opponents = &payload.Opponents[0], nil
+			}
+
+			s.sendReadyCheck(userID, websocket.MessageTypeReadyCheck, payload)
+		}
+	}
+}
+
+// expireReadyCheck cancels a ready check that was not accepted in time. Players who did not
+// accept are put on cooldown, and players who did go back into the queue.
+func (s *Service) expireReadyCheck(check *ReadyCheck) {
+	s.readyMu.Lock()
+	if s.readyChecks[check.Match.Player.UserID] != check {
+		s.readyMu.Unlock()
+		return
+	}
+	s.closeReadyCheckLocked(check)
+	accepted := make(map[string]bool, len(check.Accepted))
+	for userID := range check.Accepted {
+		accepted[userID] = true
+	}
+	s.readyMu.Unlock()
+
+	s.cancelReadyCheck(check, "timeout", func(id string) bool { return !accepted[id] })
+}
+
+// withdrawFromReadyCheck cancels a ready check on behalf of one of its players, who is put on cooldown
+func (s *Service) withdrawFromReadyCheck(check *ReadyCheck, userID, reason string) {
+	s.readyMu.Lock()
+	if s.readyChecks[userID] != check {
+		s.readyMu.Unlock()
+		return
+	}
+	check.timer.Stop()
+	s.closeReadyCheckLocked(check)
+	s.readyMu.Unlock()
+
+	s.cancelReadyCheck(check, reason, func(id string) bool { return id == userID })
+}
+
+// cancelReadyCheck ends a ready check without a game. Penalized players are put on cooldown; the
+// others go back into their queue, keeping the wait and widened ELO window they had when matched.
+// A party only goes back if none of its members was penalized.
+func (s *Service) cancelReadyCheck(check *ReadyCheck, reason string, penalized func(userID string) bool) {
+	ctx := context.Background()
+
+	s.mu.Lock()
+	cooldown := s.declineCooldown
+	s.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range check.Match.Entries() {
+		requeue := true
+		payloads := make(map[string]websocket.ReadyCheckPayload)
+		for _, id := range entry.UserIDs() {
+			payload := websocket.ReadyCheckPayload{
+				ReadyCheckID: check.ID,
+				Reason:       reason,
+			}
+
+			if penalized(id) {
+				requeue = false
+				until := now.Add(cooldown)
+				if err := s.store.SetCooldown(ctx, id, until); err != nil {
+					log.Printf("Failed to put user %s on matchmaking cooldown: %v", id, err)
+				}
+				payload.CooldownUntil = until.UnixNano() / int64(time.Millisecond)
+			}
+			payloads[id] = payload
+		}
+
+		if requeue {
+			// The time spent in the ready check does not count against the queue timeout
+			entry.Timeout = entry.Timeout.Add(now.Sub(check.CreatedAt))
+			added, err := s.store.Join(ctx, entry)
+			if err != nil {
+				log.Printf("Failed to put user %s back into matchmaking queue: %v", entry.UserID, err)
+			}
+			for id, payload := range payloads {
+				payload.Requeued = added
+				payloads[id] = payload
+			}
+		}
+
+		for _, id := range entry.UserIDs() {
+			s.sendReadyCheck(id, websocket.MessageTypeReadyCheckCancelled, payloads[id])
+		}
+	}
+
+	log.Printf("Cancelled ready check %s for users %s: %s", check.ID, strings.Join(check.Match.UserIDs(), ", "), reason)
+	s.recordReadyCheck(check, reason, now)
+}
+
+// readyCheck gets the open ready check of a player, or nil if there is none
+func (s *Service) readyCheck(userID string) *ReadyCheck {
+	s.readyMu.Lock()
+	defer s.readyMu.Unlock()
+
+	return s.readyChecks[userID]
+}
+
+// readyCheckLocked gets the open ready check of a player that they have not answered yet.
+// The caller must hold readyMu.
+func (s *Service) readyCheckLocked(userID, readyCheckID string) (*ReadyCheck, error) {
+	check, exists := s.readyChecks[userID]
+	if !exists || (readyCheckID != "" && check.ID != readyCheckID) {
+		return nil, errors.New("no ready check found")
+	}
+	if check.Accepted[userID] {
+		return nil, errors.New("match already accepted")
+	}
+	return check, nil
+}
+
+// closeReadyCheckLocked forgets a ready check. The caller must hold readyMu.
+func (s *Service) closeReadyCheckLocked(check *ReadyCheck) {
+	for _, id := range check.Match.UserIDs() {
+		delete(s.readyChecks, id)
+	}
+}
+
+// sendReadyCheck sends a ready check message to a player, if they are connected
+func (s *Service) sendReadyCheck(userID string, messageType websocket.MessageType, payload websocket.ReadyCheckPayload) {
+	if s.websocketHub == nil {
+		return
+	}
+	client := s.websocketHub.GetClientByUserID(userID)
+	if client == nil {
+		return
+	}
+
+	if err := s.websocketHub.SendReadyCheck(client, messageType, payload); err != nil {
+		log.Printf("Failed to send %s to user %s: %v", messageType, userID, err)
+	}
+}
+
+// handleReadyAccept handles a client accepting their match
+func (s *Service) handleReadyAccept(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ReadyCheckPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			s.websocketHub.SendError(c, 400, "Invalid ready check payload")
+			return
+		}
+	}
+
+	if err := s.AcceptReadyCheck(c.UserID, payload.ReadyCheckID); err != nil {
+		s.websocketHub.SendError(c, 400, err.Error())
+	}
+}
+
+// handleReadyDecline handles a client declining their match
+func (s *Service) handleReadyDecline(c *websocket.Client, msg *websocket.Message) {
+	var payload websocket.ReadyCheckPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			s.websocketHub.SendError(c, 400, "Invalid ready check payload")
+			return
+		}
+	}
+
+	if err := s.DeclineReadyCheck(c.UserID, payload.ReadyCheckID); err != nil {
+		s.websocketHub.SendError(c, 400, err.Error())
+	}
+}
+
+// acceptedIDs lists the players who have accepted a ready check
+func (c *ReadyCheck) acceptedIDs() []string {
+	ids := make([]string, 0, len(c.Accepted))
+	for _, id := range c.Match.UserIDs() {
+		if c.Accepted[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Helper function to describe how long a player is kept out of the queue
+func cooldownError(until time.Time) error {
+	return fmt.Errorf("user may not queue for another %d seconds", int(math.Ceil(time.Until(until).Seconds())))
+}
@@ -0,0 +1,167 @@
+package matchmaking
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestReadyCheckCancellation(t *testing.T) {
+	tests := []struct {
+		name         string
+		accept       []string // Players who accept before the check ends
+		decline      string   // Player who declines, if any
+		wantCooldown map[string]bool
+	}{
+		{"player declines", nil, "player", map[string]bool{"player": true}},
+		{"player declines after opponent accepted", []string{"opponent"}, "player", map[string]bool{"player": true}},
+		{"nobody answers", nil, "", map[string]bool{"player": true, "opponent": true}},
+		{"only opponent accepts", []string{"opponent"}, "", map[string]bool{"player": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryStore()
+			s := NewService(store, nil, nil, nil)
+			s.SetReadyCheckPolicy(50*time.Millisecond, time.Minute)
+
+			now := time.Now()
+			m := Match{
+				Player:   testEntry("player", 1200, rankedDuel, now, 20*time.Second),
+				Opponent: testEntry("opponent", 1250, rankedDuel, now, 10*time.Second),
+				EloRange: 150,
+			}
+			s.startReadyCheck(m)
+
+			// Matched players cannot queue again while the check is open
+			if err := s.JoinQueue("player", "duel", true, "classic"); err == nil {
+				t.Error("JoinQueue() during a ready check succeeded, want an error")
+			}
+
+			for _, userID := range tt.accept {
+				if err := s.AcceptReadyCheck(userID, ""); err != nil {
+					t.Fatalf("AcceptReadyCheck(%s) error = %v", userID, err)
+				}
+			}
+
+			if tt.decline != "" {
+				if err := s.DeclineReadyCheck(tt.decline, ""); err != nil {
+					t.Fatalf("DeclineReadyCheck() error = %v", err)
+				}
+			} else {
+				// Wait for the check to time out
+				deadline := time.Now().Add(time.Second)
+				for s.readyCheck("player") != nil && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+				}
+			}
+
+			if s.readyCheck("player") != nil || s.readyCheck("opponent") != nil {
+				t.Fatal("ready check still open, want it closed")
+			}
+
+			for _, original := range []QueueEntry{m.Player, m.Opponent} {
+				until, err := store.Cooldown(ctx, original.UserID)
+				if err != nil {
+					t.Fatalf("Cooldown() error = %v", err)
+				}
+				entry, err := store.Entry(ctx, original.UserID)
+				if err != nil {
+					t.Fatalf("Entry() error = %v", err)
+				}
+
+				if tt.wantCooldown[original.UserID] {
+					if until.IsZero() {
+						t.Errorf("user %s has no cooldown, want one", original.UserID)
+					}
+					if entry != nil {
+						t.Errorf("user %s was requeued, want them left out", original.UserID)
+					}
+					continue
+				}
+
+				if !until.IsZero() {
+					t.Errorf("user %s has a cooldown, want none", original.UserID)
+				}
+				if entry == nil {
+					t.Fatalf("user %s was not requeued", original.UserID)
+				}
+				if !entry.JoinedAt.Equal(original.JoinedAt) {
+					t.Errorf("requeued user %s joined at %v, want their original %v", original.UserID, entry.JoinedAt, original.JoinedAt)
+				}
+				if !entry.Timeout.After(original.Timeout) {
+					t.Errorf("requeued user %s times out at %v, want after %v", original.UserID, entry.Timeout, original.Timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyCheckPartyDecline(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	s := NewService(store, nil, nil, nil)
+	s.SetReadyCheckPolicy(time.Minute, time.Minute)
+
+	now := time.Now()
+	team := QueueID{GameType: "team", Ranked: false, Variant: "classic"}
+	solo := testEntry("solo", 1180, team, now, 5*time.Second)
+	m := Match{
+		Player:   testParty("leader", "member", 1200, team, now, 10*time.Second),
+		Opponent: testEntry("opponent", 1210, team, now, 0),
+		Partner:  &solo,
+		EloRange: 200,
+	}
+	s.startReadyCheck(m)
+
+	// Every player of the match takes part in the check
+	for _, userID := range m.UserIDs() {
+		if s.readyCheck(userID) == nil {
+			t.Fatalf("user %s has no ready check", userID)
+		}
+	}
+
+	// The game waits for every player, not just two
+	for _, userID := range []string{"leader", "opponent", "solo"} {
+		if err := s.AcceptReadyCheck(userID, ""); err != nil {
+			t.Fatalf("AcceptReadyCheck(%s) error = %v", userID, err)
+		}
+	}
+	if s.readyCheck("member") == nil {
+		t.Fatal("ready check closed before every player accepted")
+	}
+
+	if err := s.DeclineReadyCheck("member", ""); err != nil {
+		t.Fatalf("DeclineReadyCheck() error = %v", err)
+	}
+
+	// Only the decliner sits out, but their party cannot be requeued without them
+	tests := []struct {
+		userID       string
+		wantCooldown bool
+		wantQueued   bool
+	}{
+		{"leader", false, false},
+		{"member", true, false},
+		{"opponent", false, true},
+		{"solo", false, true},
+	}
+	for _, tt := range tests {
+		until, err := store.Cooldown(ctx, tt.userID)
+		if err != nil {
+			t.Fatalf("Cooldown() error = %v", err)
+		}
+		if !until.IsZero() != tt.wantCooldown {
+			t.Errorf("user %s on cooldown = %v, want %v", tt.userID, !until.IsZero(), tt.wantCooldown)
+		}
+
+		entry, err := store.Entry(ctx, tt.userID)
+		if err != nil {
+			t.Fatalf("Entry() error = %v", err)
+		}
+		if (entry != nil) != tt.wantQueued {
+			t.Errorf("user %s queued = %v, want %v", tt.userID, entry != nil, tt.wantQueued)
+		}
+	}
+}
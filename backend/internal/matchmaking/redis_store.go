@@ -0,0 +1,404 @@
+package matchmaking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/hectoclash/internal/models"
+)
+
+// Prefix of every matchmaking key
+const redisKeyPrefix = "matchmaking:"
+
+// RedisStore keeps the matchmaking queues in Redis, so they are shared by every replica.
+// Queues are changed through the Lua scripts in scripts.go, which Redis runs atomically.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore creates a queue store backed by Redis
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: redisKeyPrefix,
+	}
+}
+
+// queueKeys are the Redis keys of a matchmaking queue
+type queueKeys struct {
+	queue      string // Queued players scored by rating
+	timeout    string // Queued players scored by when their entry expires
+	stats      string // Average wait and number of matches
+	userPrefix string // Prefix of the keys holding each player's queue entry
+}
+
+// keys gets the Redis keys of a queue
+func (s *RedisStore) keys(queue QueueID) queueKeys {
+	return queueKeys{
+		queue:      s.prefix + "queue:" + queue.String(),
+		timeout:    s.prefix + "queue:" + queue.String() + ":timeout",
+		stats:      s.prefix + "queue:" + queue.String() + ":stats",
+		userPrefix: s.prefix + "user:",
+	}
+}
+
+// Helper functions to get the keys of a player
+func (s *RedisStore) userKey(userID string) string     { return s.prefix + "user:" + userID }
+func (s *RedisStore) gameKey(userID string) string     { return s.userKey(userID) + ":game" }
+func (s *RedisStore) botOfferKey(userID string) string { return s.userKey(userID) + botOfferSuffix }
+func (s *RedisStore) cooldownKey(userID string) string { return s.userKey(userID) + ":cooldown" }
+
+// Join adds a player to the queue of their entry
+func (s *RedisStore) Join(ctx context.Context, entry QueueEntry) (bool, error) {
+	keys := s.keys(entry.Queue())
+
+	entryJSON, err := encodeQueueEntry(entry)
+	if err != nil {
+		return false, err
+	}
+
+	// The entry's key expires with the entry
+	ttl := time.Until(entry.Timeout)
+	if ttl < time.Second {
+		return false, errors.New("queue entry has expired")
+	}
+
+	added, err := joinScript.Run(ctx, s.client,
+		[]string{keys.queue, keys.timeout, s.userKey(entry.UserID)},
+		entry.UserID,
+		entry.Rating,
+		entry.Timeout.Unix(),
+		string(entryJSON),
+		int64(ttl.Seconds()),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return added == 1, nil
+}
+
+// Leave takes a player out of their queue
+func (s *RedisStore) Leave(ctx context.Context, userID string) (bool, error) {
+	entry, err := s.Entry(ctx, userID)
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	keys := s.keys(entry.Queue())
+	removed, err := leaveScript.Run(ctx, s.client,
+		[]string{keys.queue, keys.timeout, s.userKey(userID), s.botOfferKey(userID)},
+		userID,
+	).Int()
+	if err != nil {
+		return false, err
+	}
+
+	return removed == 1, nil
+}
+
+// Entry gets the queue entry of a player
+func (s *RedisStore) Entry(ctx context.Context, userID string) (*QueueEntry, error) {
+	entryJSON, err := s.client.Get(ctx, s.userKey(userID)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return parseQueueEntry(entryJSON)
+}
+
+// Match pairs up the players of a queue and takes the pairs out of it
+func (s *RedisStore) Match(ctx context.Context, queue QueueID, window EloWindow, now time.Time, rules *PairingRules) ([]Match, error) {
+	keys := s.keys(queue)
+
+	// The script reads the rules as the checked user IDs and the excluded pairs
+	encodedRules := ""
+	if rules != nil {
+		payload := struct {
+			Checked  []string    `json:"checked"`
+			Excluded [][2]string `json:"excluded"`
+		}{Checked: []string{}, Excluded: [][2]string{}}
+		for userID := range rules.Checked {
+			payload.Checked = append(payload.Checked, userID)
+		}
+		for pair := range rules.Excluded {
+			payload.Excluded = append(payload.Excluded, pair)
+		}
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		encodedRules = string(data)
+	}
+
+	result, err := matchScript.Run(ctx, s.client,
+		[]string{keys.queue, keys.timeout},
+		keys.userPrefix,
+		botOfferSuffix,
+		now.Unix(),
+		int64(matchmakingTimeout.Seconds()),
+		window.Initial,
+		window.Increment,
+		window.Interval.Milliseconds(),
+		window.Max,
+		encodedRules,
+		now.UnixMilli(),
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	// The ELO window and number of entries of each match, followed by its entries
+	var matches []Match
+	for i := 0; i+2 <= len(result); {
+		eloRange, err := strconv.Atoi(result[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ELO range %q: %w", result[i], err)
+		}
+		count, err := strconv.Atoi(result[i+1])
+		if err != nil || count < 2 || count > 3 || i+2+count > len(result) {
+			return nil, fmt.Errorf("invalid match of %q entries", result[i+1])
+		}
+
+		entries := make([]QueueEntry, count)
+		for n := range entries {
+			entry, err := parseQueueEntry(result[i+2+n])
+			if err != nil {
+				return nil, err
+			}
+			entries[n] = *entry
+		}
+		i += 2 + count
+
+		m := Match{Player: entries[0], Opponent: entries[1], EloRange: eloRange}
+		if count == 3 {
+			m.Partner = &entries[2]
+		}
+		matches = append(matches, m)
+	}
+
+	return matches, nil
+}
+
+// Expire takes every player whose entry has timed out out of a queue
+func (s *RedisStore) Expire(ctx context.Context, queue QueueID, now time.Time) ([]QueueEntry, error) {
+	keys := s.keys(queue)
+	result, err := expireScript.Run(ctx, s.client,
+		[]string{keys.queue, keys.timeout},
+		keys.userPrefix,
+		botOfferSuffix,
+		now.Unix(),
+	).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := make([]QueueEntry, 0, len(result))
+	for _, entryJSON := range result {
+		entry, err := parseQueueEntry(entryJSON)
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, *entry)
+	}
+	return expired, nil
+}
+
+// Waiting gets the players of a queue who joined before a time and have not timed out. Players
+// put back into the queue keep when they joined but expire later, so the join time is read from
+// each entry rather than worked out from its expiry.
+func (s *RedisStore) Waiting(ctx context.Context, queue QueueID, joinedBefore, now time.Time) ([]QueueEntry, error) {
+	keys := s.keys(queue)
+	userIDs, err := s.client.ZRangeByScore(ctx, keys.timeout, &redis.ZRangeBy{
+		Min: fmt.Sprintf("(%d", now.Unix()),
+		Max: "+inf",
+	}).Result()
+	if err != nil || len(userIDs) == 0 {
+		return nil, err
+	}
+
+	entryKeys := make([]string, len(userIDs))
+	for i, userID := range userIDs {
+		entryKeys[i] = s.userKey(userID)
+	}
+	values, err := s.client.MGet(ctx, entryKeys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]QueueEntry, 0, len(values))
+	for _, value := range values {
+		entryJSON, ok := value.(string)
+		if !ok {
+			continue
+		}
+		entry, err := parseQueueEntry(entryJSON)
+		if err != nil {
+			return nil, err
+		}
+		if !entry.JoinedAt.After(joinedBefore) {
+			entries = append(entries, *entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// Size gets the number of players in a queue
+func (s *RedisStore) Size(ctx context.Context, queue QueueID) (int, error) {
+	size, err := s.client.ZCard(ctx, s.keys(queue).queue).Result()
+	return int(size), err
+}
+
+// RecordWaits adds the waits of matched players to the average wait of their queue.
+// Only the replica holding the processor lock records waits, so reading and writing the average is safe.
+func (s *RedisStore) RecordWaits(ctx context.Context, queue QueueID, waits []time.Duration) error {
+	if len(waits) == 0 {
+		return nil
+	}
+
+	statsKey := s.keys(queue).stats
+	seconds, err := s.client.HGet(ctx, statsKey, "avg_wait").Float64()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	average, hasAverage := time.Duration(seconds*float64(time.Second)), err == nil
+
+	for _, wait := range waits {
+		average, hasAverage = smoothWait(average, hasAverage, wait), true
+	}
+
+	return s.client.HSet(ctx, statsKey, "avg_wait", average.Seconds()).Err()
+}
+
+// AverageWait gets the average wait of a queue's recently matched players
+func (s *RedisStore) AverageWait(ctx context.Context, queue QueueID) (time.Duration, error) {
+	seconds, err := s.client.HGet(ctx, s.keys(queue).stats, "avg_wait").Float64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// SetGame records the game a matched player was put in
+func (s *RedisStore) SetGame(ctx context.Context, userID, gameID string) error {
+	return s.client.Set(ctx, s.gameKey(userID), gameID, userGameTTL).Err()
+}
+
+// Game gets the game a player was last matched into
+func (s *RedisStore) Game(ctx context.Context, userID string) (string, error) {
+	gameID, err := s.client.Get(ctx, s.gameKey(userID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return gameID, err
+}
+
+// SetCooldown keeps a player out of the queues until a time
+func (s *RedisStore) SetCooldown(ctx context.Context, userID string, until time.Time) error {
+	return s.client.Set(ctx, s.cooldownKey(userID), until.Unix(), time.Until(until)).Err()
+}
+
+// Cooldown gets when a player may queue again
+func (s *RedisStore) Cooldown(ctx context.Context, userID string) (time.Time, error) {
+	until, err := s.client.Get(ctx, s.cooldownKey(userID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return time.Unix(until, 0), nil
+}
+
+// OfferBot stores a bot offered to a queued player until it expires
+func (s *RedisStore) OfferBot(ctx context.Context, userID string, offer BotOffer, expiresAt time.Time) (bool, error) {
+	offerJSON, err := json.Marshal(offer)
+	if err != nil {
+		return false, err
+	}
+	return s.client.SetNX(ctx, s.botOfferKey(userID), offerJSON, time.Until(expiresAt)).Result()
+}
+
+// TakeBotOffer removes and returns the bot offered to a player
+func (s *RedisStore) TakeBotOffer(ctx context.Context, userID string) (*BotOffer, error) {
+	offerKey := s.botOfferKey(userID)
+	offerJSON, err := s.client.Get(ctx, offerKey).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var offer BotOffer
+	if err := json.Unmarshal([]byte(offerJSON), &offer); err != nil {
+		return nil, fmt.Errorf("failed to parse bot offer: %w", err)
+	}
+
+	// Claim the offer so it can only be taken once
+	deleted, err := s.client.Del(ctx, offerKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if deleted == 0 {
+		return nil, nil
+	}
+	return &offer, nil
+}
+
+// Lock takes a named lock for a while
+func (s *RedisStore) Lock(ctx context.Context, name string, ttl time.Duration) (string, bool, error) {
+	token := uuid.NewString()
+	locked, err := s.client.SetNX(ctx, s.prefix+name+":lock", token, ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	return token, locked, nil
+}
+
+// Unlock releases a lock, unless it has expired and been taken since
+func (s *RedisStore) Unlock(ctx context.Context, name, token string) error {
+	return releaseLockScript.Run(ctx, s.client, []string{s.prefix + name + ":lock"}, token).Err()
+}
+
+// storedEntry is a queue entry as Redis keeps it. Lua cannot parse times, so the scripts read
+// when the player joined from JoinedAtMs.
+type storedEntry struct {
+	QueueEntry
+	JoinedAtMs int64 `json:"joined_at_ms"` // Unix milliseconds
+}
+
+// Helper function to encode a queue entry for Redis
+func encodeQueueEntry(entry QueueEntry) ([]byte, error) {
+	entryJSON, err := json.Marshal(storedEntry{QueueEntry: entry, JoinedAtMs: entry.JoinedAt.UnixMilli()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal queue entry: %w", err)
+	}
+	return entryJSON, nil
+}
+
+// Helper function to parse a stored queue entry
+func parseQueueEntry(entryJSON string) (*QueueEntry, error) {
+	var entry QueueEntry
+	if err := json.Unmarshal([]byte(entryJSON), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse queue entry: %w", err)
+	}
+	if entry.Variant == "" {
+		entry.Variant = models.GameVariantClassic
+	}
+	return &entry, nil
+}
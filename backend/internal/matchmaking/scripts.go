@@ -0,0 +1,216 @@
+package matchmaking
+
+import "github.com/go-redis/redis/v8"
+
+// RedisStore only changes queues through these scripts. Redis runs each script as a single command,
+// so replicas never see half of a change, and two of them can never take the same player out
+// of the queue. The scripts build the keys of each player's queue entry from a prefix, so the
+// queues have to live on a single Redis node.
+
+// joinScript adds a player to the queue unless they are already in it.
+//
+// KEYS: queue by rating, queue by expiry, player's queue entry
+// ARGV: user ID, rating, expiry (Unix seconds), queue entry as JSON, queue entry TTL (seconds)
+// Returns 1 if the player was added, 0 if they were already queued.
+var joinScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[3]) == 1 then
+	return 0
+end
+redis.call('SET', KEYS[3], ARGV[4], 'EX', ARGV[5])
+redis.call('ZADD', KEYS[1], ARGV[2], ARGV[1])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[1])
+return 1
+`)
+
+// leaveScript takes a player out of the queue and withdraws any bot offer made to them.
+//
+// KEYS: queue by rating, queue by expiry, player's queue entry, player's bot offer
+// ARGV: user ID
+// Returns 1 if the player was queued, 0 otherwise.
+var leaveScript = redis.NewScript(`
+local queued = redis.call('DEL', KEYS[3])
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('DEL', KEYS[4])
+return queued
+`)
+
+// expireScript takes every player whose entry has expired out of the queue.
+//
+// KEYS: queue by rating, queue by expiry
+// ARGV: queue entry key prefix, bot offer key suffix, now (Unix seconds)
+// Returns the entries of the players taken out.
+var expireScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[3])
+local entries = {}
+for _, id in ipairs(expired) do
+	local entry = redis.call('GET', ARGV[1] .. id)
+	if entry then
+		table.insert(entries, entry)
+	end
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('ZREM', KEYS[2], id)
+	redis.call('DEL', ARGV[1] .. id, ARGV[1] .. id .. ARGV[2])
+end
+return entries
+`)
+
+// matchScript pairs up queued players and takes the pairs out of the queue. It pairs players the
+// same way as pairEntries, which documents the rules.
+//
+// Players are walked in rating order, and the walk stops looking once ratings are further apart
+// than the widest window, so a tick is close to linear in the size of the queue.
+//
+// KEYS: queue by rating, queue by expiry
+// ARGV: queue entry key prefix, bot offer key suffix, now (Unix seconds), queue timeout (seconds),
+// initial ELO range, ELO range increment, increment interval (milliseconds), max ELO range,
+// pairing rules as JSON, or "" to allow every pair, now (Unix milliseconds)
+// Returns, for each match, the ELO window of the match, the number of entries and the entries:
+// the player, the opponent and, when a party plays two solo players, the opponent's partner.
+var matchScript = redis.NewScript(`
+local now = tonumber(ARGV[3])
+local timeout = tonumber(ARGV[4])
+local initialRange = tonumber(ARGV[5])
+local increment = tonumber(ARGV[6])
+local interval = tonumber(ARGV[7])
+local maxRange = tonumber(ARGV[8])
+local nowMs = tonumber(ARGV[10])
+
+-- Windows widen with the time since the player joined, like eloRange. Entries stored before the
+-- join time was kept fall back to their expiry.
+local function eloRange(entry, expires)
+	local joined = tonumber(entry.joined_at_ms) or (expires - timeout) * 1000
+	local waited = nowMs - joined
+	local steps = 0
+	if interval > 0 and waited > 0 then
+		steps = math.floor(waited / interval)
+	end
+	return math.min(initialRange + steps * increment, maxRange)
+end
+
+-- Only checked entries are paired, and never with an entry they are excluded with
+local checked, excluded = nil, {}
+if ARGV[9] ~= '' then
+	local rules = cjson.decode(ARGV[9])
+	checked = {}
+	for _, id in ipairs(rules.checked) do
+		checked[id] = true
+	end
+	for _, pair in ipairs(rules.excluded) do
+		excluded[pair[1] .. '|' .. pair[2]] = true
+		excluded[pair[2] .. '|' .. pair[1]] = true
+	end
+end
+
+local function allows(a, b)
+	return not excluded[a.id .. '|' .. b.id]
+end
+
+-- Collect the players whose entries are still valid
+local players = {}
+local entries = redis.call('ZRANGE', KEYS[1], 0, -1, 'WITHSCORES')
+for i = 1, #entries, 2 do
+	local id = entries[i]
+	local expires = tonumber(redis.call('ZSCORE', KEYS[2], id))
+	local data = redis.call('GET', ARGV[1] .. id)
+	if expires and data and expires > now and (checked == nil or checked[id]) then
+		local ok, entry = pcall(cjson.decode, data)
+		if ok and type(entry) == 'table' then
+			local size = 1
+			if type(entry.members) == 'table' and #entry.members > 0 then
+				size = #entry.members
+			end
+			players[#players + 1] = {
+				id = id,
+				data = data,
+				rating = tonumber(entries[i + 1]),
+				size = size,
+				range = eloRange(entry, expires),
+			}
+		end
+	end
+end
+
+local function dequeue(p)
+	redis.call('ZREM', KEYS[1], p.id)
+	redis.call('ZREM', KEYS[2], p.id)
+	redis.call('DEL', ARGV[1] .. p.id, ARGV[1] .. p.id .. ARGV[2])
+end
+
+local result = {}
+local matched = {}
+
+local function append(range, ...)
+	local match = {...}
+	result[#result + 1] = tostring(range)
+	result[#result + 1] = tostring(#match)
+	for _, p in ipairs(match) do
+		matched[p.index] = true
+		dequeue(p)
+		result[#result + 1] = p.data
+	end
+end
+
+-- Find a solo player to team up against the party of a pair
+local function findPartner(i, j, reach)
+	for k = i + 1, #players do
+		local gap = players[k].rating - players[i].rating
+		if gap > maxRange then
+			return nil
+		end
+		if k ~= j and not matched[k] and players[k].size == 1 and gap <= math.max(reach, players[k].range)
+			and allows(players[k], players[i]) and allows(players[k], players[j]) then
+			return k
+		end
+	end
+	return nil
+end
+
+-- Pair them up
+for i = 1, #players do
+	players[i].index = i
+end
+for i = 1, #players do
+	local p = players[i]
+	if not matched[i] then
+		for j = i + 1, #players do
+			local o = players[j]
+			local gap = o.rating - p.rating
+			if gap > maxRange then
+				break
+			end
+			local reach = math.max(p.range, o.range)
+			if not matched[j] and gap <= reach and allows(p, o) then
+				if p.size == o.size then
+					append(reach, p, o)
+					break
+				end
+				if p.size + o.size == 3 then
+					local k = findPartner(i, j, reach)
+					if k then
+						local party, solo = p, o
+						if o.size > 1 then
+							party, solo = o, p
+						end
+						append(math.max(reach, players[k].range), party, solo, players[k])
+						break
+					end
+				end
+			end
+		end
+	end
+end
+return result
+`)
+
+// releaseLockScript releases a lock only if it is still held with the given token, so a lock
+// that expired and was taken by someone else is left alone.
+//
+// KEYS: lock
+// ARGV: token
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
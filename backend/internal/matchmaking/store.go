@@ -0,0 +1,248 @@
+package matchmaking
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// QueueStore holds the matchmaking queues, along with the few things kept about queued players.
+// Every change to a queue must be atomic: replicas sharing a store must never match a player twice.
+type QueueStore interface {
+	// Join adds a player to the queue of their entry, returning false if they are already queued
+	Join(ctx context.Context, entry QueueEntry) (bool, error)
+
+	// Leave takes a player out of their queue and withdraws any bot offer made to them,
+	// returning false if they were not queued
+	Leave(ctx context.Context, userID string) (bool, error)
+
+	// Entry gets the queue entry of a player, or nil if they are not queued
+	Entry(ctx context.Context, userID string) (*QueueEntry, error)
+
+	// Match pairs up the players of a queue within its ELO window, following the pairing rules if
+	// there are any, and takes the pairs out of the queue
+	Match(ctx context.Context, queue QueueID, window EloWindow, now time.Time, rules *PairingRules) ([]Match, error)
+
+	// Expire takes every player whose entry has timed out out of a queue, returning their entries
+	Expire(ctx context.Context, queue QueueID, now time.Time) ([]QueueEntry, error)
+
+	// Waiting gets the players of a queue who joined before a time and have not timed out
+	Waiting(ctx context.Context, queue QueueID, joinedBefore, now time.Time) ([]QueueEntry, error)
+
+	// Size gets the number of players in a queue
+	Size(ctx context.Context, queue QueueID) (int, error)
+
+	// RecordWaits adds the waits of matched players to the average wait of their queue
+	RecordWaits(ctx context.Context, queue QueueID, waits []time.Duration) error
+
+	// AverageWait gets the average wait of a queue's recently matched players, or 0 if there were none
+	AverageWait(ctx context.Context, queue QueueID) (time.Duration, error)
+
+	// SetGame records the game a matched player was put in
+	SetGame(ctx context.Context, userID, gameID string) error
+
+	// Game gets the game a player was last matched into, or "" if there is none
+	Game(ctx context.Context, userID string) (string, error)
+
+	// SetCooldown keeps a player out of the queues until a time
+	SetCooldown(ctx context.Context, userID string, until time.Time) error
+
+	// Cooldown gets when a player may queue again, or the zero time if they may queue now
+	Cooldown(ctx context.Context, userID string) (time.Time, error)
+
+	// OfferBot stores a bot offered to a queued player until it expires, returning false if they already had one
+	OfferBot(ctx context.Context, userID string, offer BotOffer, expiresAt time.Time) (bool, error)
+
+	// TakeBotOffer removes and returns the bot offered to a player, or nil if there is none
+	TakeBotOffer(ctx context.Context, userID string) (*BotOffer, error)
+
+	// Lock takes a named lock for a while, returning the token that releases it
+	Lock(ctx context.Context, name string, ttl time.Duration) (string, bool, error)
+
+	// Unlock releases a lock, unless it has expired and been taken since
+	Unlock(ctx context.Context, name, token string) error
+}
+
+// Match is a pair of players, or of sides of equal size, taken out of the queue to play each other.
+// Entries of the same size are paired up. A party can also be matched against two solo players,
+// in which case the party is the Player, and the solo players are the Opponent and their Partner.
+type Match struct {
+	Player   QueueEntry
+	Opponent QueueEntry
+	Partner  *QueueEntry // Solo player teamed up with the Opponent against a party
+	EloRange int         // ELO window the pair was matched in
+}
+
+// Entries lists the queue entries of a match
+func (m Match) Entries() []QueueEntry {
+	entries := []QueueEntry{m.Player, m.Opponent}
+	if m.Partner != nil {
+		entries = append(entries, *m.Partner)
+	}
+	return entries
+}
+
+// Sides lists the players of each side of a match
+func (m Match) Sides() [2][]string {
+	opponents := append([]string{}, m.Opponent.UserIDs()...)
+	if m.Partner != nil {
+		opponents = append(opponents, m.Partner.UserIDs()...)
+	}
+	return [2][]string{append([]string{}, m.Player.UserIDs()...), opponents}
+}
+
+// UserIDs lists every player of a match
+func (m Match) UserIDs() []string {
+	sides := m.Sides()
+	return append(sides[0], sides[1]...)
+}
+
+// IsTeamMatch checks if a match is played between sides: every match of a team queue, even
+// between two solo players, and every match that puts more than one player on each side
+func (m Match) IsTeamMatch() bool {
+	return m.Player.GameType == "team" || m.Player.Size() > 1
+}
+
+// PairingRules restrict who a queue's entries may be paired with, as worked out by the
+// matchmaking constraints before a tick. Nil rules allow every pair.
+type PairingRules struct {
+	Checked  map[string]bool    // Entries the rules were worked out for, by user ID; others wait for the next tick
+	Excluded map[[2]string]bool // Pairs of entries that may not play together or on the same side, by user ID
+}
+
+// NewPairingRules creates pairing rules that allow every pair of the checked entries
+func NewPairingRules() *PairingRules {
+	return &PairingRules{
+		Checked:  make(map[string]bool),
+		Excluded: make(map[[2]string]bool),
+	}
+}
+
+// Exclude keeps two entries apart
+func (r *PairingRules) Exclude(a, b string) {
+	if b < a {
+		a, b = b, a
+	}
+	r.Excluded[[2]string{a, b}] = true
+}
+
+// Considers checks if an entry may be paired at all
+func (r *PairingRules) Considers(userID string) bool {
+	return r == nil || r.Checked[userID]
+}
+
+// Allows checks if two entries may be paired
+func (r *PairingRules) Allows(a, b string) bool {
+	if r == nil {
+		return true
+	}
+	if b < a {
+		a, b = b, a
+	}
+	return !r.Excluded[[2]string{a, b}]
+}
+
+// How long a matched player's game is remembered
+const userGameTTL = time.Hour
+
+// eloRange gets the ELO window of a player who has waited for a while
+func eloRange(window EloWindow, waited time.Duration) int {
+	steps := 0
+	if window.Interval > 0 && waited > 0 {
+		steps = int(waited / window.Interval)
+	}
+	return min(window.Initial+steps*window.Increment, window.Max)
+}
+
+// pairEntries pairs up the entries of a queue, the same way as the Redis store's match script.
+// Entries are walked in rating order, and each is paired with the next unpaired entry of the same
+// size whose rating is within reach, or with the next two solo players within reach of a party.
+// The reach of a pair is the wider ELO window of the two, and windows widen the longer a player waits.
+// Pairs the rules exclude are skipped, and so are partners excluded with either player.
+func pairEntries(players []QueueEntry, window EloWindow, now time.Time, rules *PairingRules) []Match {
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Rating != players[j].Rating {
+			return players[i].Rating < players[j].Rating
+		}
+		return players[i].UserID < players[j].UserID
+	})
+
+	ranges := make([]int, len(players))
+	for i, p := range players {
+		ranges[i] = eloRange(window, now.Sub(p.JoinedAt))
+	}
+
+	// findPartner finds a solo player to team up against the party of a pair
+	matched := make([]bool, len(players))
+	findPartner := func(i, j, reach int) int {
+		for k := i + 1; k < len(players); k++ {
+			gap := players[k].Rating - players[i].Rating
+			if gap > window.Max {
+				break
+			}
+			if k == j || matched[k] || players[k].Size() != 1 || gap > max(reach, ranges[k]) {
+				continue
+			}
+			if rules.Allows(players[k].UserID, players[i].UserID) && rules.Allows(players[k].UserID, players[j].UserID) {
+				return k
+			}
+		}
+		return -1
+	}
+
+	var matches []Match
+	for i, p := range players {
+		if matched[i] {
+			continue
+		}
+		for j := i + 1; j < len(players); j++ {
+			o := players[j]
+			gap := o.Rating - p.Rating
+			if gap > window.Max {
+				break
+			}
+			reach := max(ranges[i], ranges[j])
+			if matched[j] || gap > reach || !rules.Allows(p.UserID, o.UserID) {
+				continue
+			}
+
+			if p.Size() == o.Size() {
+				matched[i], matched[j] = true, true
+				matches = append(matches, Match{Player: p, Opponent: o, EloRange: reach})
+				break
+			}
+
+			// Otherwise a party of two can only play two solo players
+			if p.Size()+o.Size() != 3 {
+				continue
+			}
+			k := findPartner(i, j, reach)
+			if k < 0 {
+				continue
+			}
+
+			party, solo, partner := p, o, players[k]
+			if o.Size() > 1 {
+				party, solo = o, p
+			}
+			matched[i], matched[j], matched[k] = true, true, true
+			matches = append(matches, Match{
+				Player:   party,
+				Opponent: solo,
+				Partner:  &partner,
+				EloRange: max(reach, ranges[k]),
+			})
+			break
+		}
+	}
+
+	return matches
+}
+
+// smoothWait adds a wait to an average wait, which starts out as the first wait
+func smoothWait(average time.Duration, hasAverage bool, wait time.Duration) time.Duration {
+	if !hasAverage {
+		return wait
+	}
+	return time.Duration((1-waitSmoothing)*float64(average) + waitSmoothing*float64(wait))
+}
@@ -0,0 +1,139 @@
+package matchmaking
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Prefix that keeps the store tests away from the real queues when both share a Redis
+const storeTestPrefix = "matchmaking:test:"
+
+// requeued changes a queue entry into that of a player put back into the queue after a match fell
+// through. They keep when they joined, but expire a full timeout after being put back.
+func requeued(entry QueueEntry, now time.Time) QueueEntry {
+	entry.Timeout = now.Add(matchmakingTimeout)
+	return entry
+}
+
+// TestStoresMatchAlike runs the memory store and the Redis store's match script on the same
+// queues, since the script duplicates pairEntries. The Redis store is skipped without Redis.
+func TestStoresMatchAlike(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	team := QueueID{GameType: "team", Ranked: true, Variant: "classic"}
+
+	tests := []struct {
+		name        string
+		queue       QueueID
+		entries     []QueueEntry
+		wantMatches []string // Sides and ELO window of each match, in order
+		wantWaiting []string // Players who joined at least 10 seconds ago
+	}{
+		{
+			"closest ratings pair first",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 0), testEntry("b", 1210, rankedDuel, now, 0), testEntry("c", 1220, rankedDuel, now, 0)},
+			[]string{"[[a] [b]] 100"},
+			nil,
+		},
+		{
+			"window widens with the wait",
+			rankedDuel,
+			[]QueueEntry{testEntry("a", 1200, rankedDuel, now, 20*time.Second), testEntry("b", 1450, rankedDuel, now, 0)},
+			[]string{"[[a] [b]] 300"},
+			[]string{"a"},
+		},
+		{
+			"requeued player keeps their wait",
+			rankedDuel,
+			[]QueueEntry{requeued(testEntry("a", 1200, rankedDuel, now, 20*time.Second), now), testEntry("b", 1450, rankedDuel, now, 0)},
+			[]string{"[[a] [b]] 300"},
+			[]string{"a"},
+		},
+		{
+			"wait is counted from the join, not from the requeue",
+			rankedDuel,
+			[]QueueEntry{requeued(testEntry("a", 1200, rankedDuel, now, 4*time.Second), now), testEntry("b", 1450, rankedDuel, now, 0)},
+			nil,
+			nil,
+		},
+		{
+			"requeued party against two solo players",
+			team,
+			[]QueueEntry{testEntry("solo1", 1150, team, now, 0), requeued(testParty("a1", "a2", 1200, team, now, 10*time.Second), now), testEntry("solo2", 1350, team, now, 0)},
+			[]string{"[[a1 a2] [solo1 solo2]] 200"},
+			[]string{"a1"},
+		},
+	}
+
+	for _, tt := range tests {
+		stores := map[string]func(t *testing.T) QueueStore{
+			"memory": func(t *testing.T) QueueStore { return NewMemoryStore() },
+			"redis":  testRedisStore,
+		}
+		for _, name := range []string{"memory", "redis"} {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				store := stores[name](t)
+				for _, entry := range tt.entries {
+					if _, err := store.Join(ctx, entry); err != nil {
+						t.Fatalf("Join() error = %v", err)
+					}
+				}
+
+				waiting, err := store.Waiting(ctx, tt.queue, now.Add(-10*time.Second), now)
+				if err != nil {
+					t.Fatalf("Waiting() error = %v", err)
+				}
+				var gotWaiting []string
+				for _, entry := range waiting {
+					gotWaiting = append(gotWaiting, entry.UserID)
+				}
+				sort.Strings(gotWaiting)
+				if fmt.Sprint(gotWaiting) != fmt.Sprint(tt.wantWaiting) {
+					t.Errorf("Waiting() = %v, want %v", gotWaiting, tt.wantWaiting)
+				}
+
+				matches, err := store.Match(ctx, tt.queue, tt.queue.Window(), now, nil)
+				if err != nil {
+					t.Fatalf("Match() error = %v", err)
+				}
+				var got []string
+				for _, m := range matches {
+					got = append(got, fmt.Sprint(m.Sides(), " ", m.EloRange))
+				}
+				if fmt.Sprint(got) != fmt.Sprint(tt.wantMatches) {
+					t.Errorf("Match() = %v, want %v", got, tt.wantMatches)
+				}
+			})
+		}
+	}
+}
+
+// testRedisStore creates a Redis store under the test prefix, which is cleared before and after
+// the test. The test is skipped without Redis.
+func testRedisStore(t *testing.T) QueueStore {
+	client := testRedis(t)
+	ctx := context.Background()
+
+	clear := func() {
+		keys, err := client.Keys(ctx, storeTestPrefix+"*").Result()
+		if err != nil {
+			t.Fatalf("Failed to list test keys: %v", err)
+		}
+		if len(keys) > 0 {
+			if err := client.Del(ctx, keys...).Err(); err != nil {
+				t.Fatalf("Failed to clear test keys: %v", err)
+			}
+		}
+	}
+	clear()
+	t.Cleanup(func() {
+		clear()
+		client.Close()
+	})
+
+	return &RedisStore{client: client, prefix: storeTestPrefix}
+}
@@ -0,0 +1,174 @@
+package matchmaking
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+)
+
+// Limits of the matchmaking stats that can be asked for
+const (
+	defaultStatsBandWidth = 100
+	maxStatsPeriod        = 31 * 24 * time.Hour
+)
+
+// SetTelemetry records how long players wait and how fair their matches are, so that the ELO
+// window parameters can be tuned with data
+func (s *Service) SetTelemetry(statsRepo *repository.MatchmakingStatsRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statsRepo = statsRepo
+}
+
+// GetStats summarizes the quality of matchmaking over a period, by rating band and by hour.
+// A band width of 0 uses the default.
+func (s *Service) GetStats(filter repository.MatchmakingStatsFilter, bandWidth int) (*models.MatchmakingStats, error) {
+	s.mu.Lock()
+	statsRepo := s.statsRepo
+	s.mu.Unlock()
+
+	if statsRepo == nil {
+		return nil, errors.New("matchmaking telemetry is disabled")
+	}
+	if !filter.Since.Before(filter.Until) {
+		return nil, errors.New("period must end after it starts")
+	}
+	if filter.Until.Sub(filter.Since) > maxStatsPeriod {
+		return nil, fmt.Errorf("period cannot be longer than %d days", int(maxStatsPeriod.Hours()/24))
+	}
+	if bandWidth == 0 {
+		bandWidth = defaultStatsBandWidth
+	}
+	if bandWidth < 0 {
+		return nil, errors.New("band width must be positive")
+	}
+
+	stats := &models.MatchmakingStats{
+		Since:     filter.Since,
+		Until:     filter.Until,
+		BandWidth: bandWidth,
+	}
+
+	var err error
+	if stats.ByBand, err = statsRepo.StatsByBand(filter, bandWidth); err != nil {
+		return nil, fmt.Errorf("failed to get stats by rating band: %w", err)
+	}
+	if stats.ByHour, err = statsRepo.StatsByHour(filter); err != nil {
+		return nil, fmt.Errorf("failed to get stats by hour: %w", err)
+	}
+	if stats.ReadyChecks, err = statsRepo.CountReadyChecks(filter); err != nil {
+		return nil, fmt.Errorf("failed to count ready checks: %w", err)
+	}
+	if stats.Outcomes, err = statsRepo.CountOutcomes(filter); err != nil {
+		return nil, fmt.Errorf("failed to count queue outcomes: %w", err)
+	}
+
+	stats.AcceptRate = share(stats.ReadyChecks, readyCheckAccepted)
+	stats.AbandonRate = share(stats.Outcomes, models.MatchmakingSampleLeft)
+	stats.TimeoutRate = share(stats.Outcomes, models.MatchmakingSampleTimeout)
+
+	return stats, nil
+}
+
+// Outcome of a ready check every player accepted
+const readyCheckAccepted = "accepted"
+
+// recordSamples saves matchmaking samples, if telemetry is enabled. Failing to save them never
+// gets in the way of matchmaking.
+func (s *Service) recordSamples(samples []models.MatchmakingSample) {
+	s.mu.Lock()
+	statsRepo := s.statsRepo
+	s.mu.Unlock()
+
+	if statsRepo == nil || len(samples) == 0 {
+		return
+	}
+	if err := statsRepo.CreateSamples(samples); err != nil {
+		log.Printf("Failed to record %d matchmaking samples: %v", len(samples), err)
+	}
+}
+
+// recordEntries records how queue entries left their queue
+func (s *Service) recordEntries(kind string, entries []QueueEntry, now time.Time) {
+	samples := make([]models.MatchmakingSample, len(entries))
+	for i, entry := range entries {
+		samples[i] = entrySample(kind, entry, now)
+	}
+	s.recordSamples(samples)
+}
+
+// recordReadyCheck records how a ready check ended
+func (s *Service) recordReadyCheck(check *ReadyCheck, outcome string, now time.Time) {
+	sample := entrySample(models.MatchmakingSampleReadyCheck, check.Match.Player, now)
+	sample.WaitMs = now.Sub(check.CreatedAt).Milliseconds()
+	sample.RatingGap = ratingGap(check.Match)
+	sample.EloRange = check.Match.EloRange
+	sample.Outcome = outcome
+	s.recordSamples([]models.MatchmakingSample{sample})
+}
+
+// matchSamples describes the entries of the matches made in a queue of a given size
+func matchSamples(matches []Match, queueSize int, now time.Time) []models.MatchmakingSample {
+	var samples []models.MatchmakingSample
+	for _, m := range matches {
+		gap := ratingGap(m)
+		for _, entry := range m.Entries() {
+			sample := entrySample(models.MatchmakingSampleMatched, entry, now)
+			sample.RatingGap = gap
+			sample.EloRange = m.EloRange
+			sample.QueueSize = queueSize
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Helper function to describe a queue entry as it leaves its queue
+func entrySample(kind string, entry QueueEntry, now time.Time) models.MatchmakingSample {
+	return models.MatchmakingSample{
+		Kind:      kind,
+		GameType:  entry.GameType,
+		Ranked:    entry.Ranked,
+		Variant:   entry.Variant,
+		UserID:    entry.UserID,
+		PartySize: entry.Size(),
+		Rating:    entry.Rating,
+		WaitMs:    now.Sub(entry.JoinedAt).Milliseconds(),
+		CreatedAt: now,
+	}
+}
+
+// Helper function to get the difference between the average ratings of the sides of a match
+func ratingGap(m Match) int {
+	opponents := []QueueEntry{m.Opponent}
+	if m.Partner != nil {
+		opponents = append(opponents, *m.Partner)
+	}
+
+	total := 0
+	for _, entry := range opponents {
+		total += entry.Rating
+	}
+	gap := m.Player.Rating - total/len(opponents)
+	if gap < 0 {
+		return -gap
+	}
+	return gap
+}
+
+// Helper function to get the share of a count among all counts
+func share(counts map[string]int64, key string) float64 {
+	var total int64
+	for _, count := range counts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(counts[key]) / float64(total)
+}
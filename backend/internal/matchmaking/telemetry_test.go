@@ -0,0 +1,70 @@
+package matchmaking
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestMatchSamples(t *testing.T) {
+	now := time.Now()
+	solo := Match{
+		Player:   testEntry("alice", 1200, rankedDuel, now, 10*time.Second),
+		Opponent: testEntry("bob", 1260, rankedDuel, now, 3*time.Second),
+		EloRange: 150,
+	}
+	partner := testEntry("dave", 1400, casualDuel, now, 20*time.Second)
+	team := Match{
+		Player:   testParty("carol", "carl", 1350, casualDuel, now, 5*time.Second),
+		Opponent: testEntry("erin", 1200, casualDuel, now, 8*time.Second),
+		Partner:  &partner,
+		EloRange: 200,
+	}
+
+	samples := matchSamples([]Match{solo, team}, 7, now)
+	if len(samples) != 5 {
+		t.Fatalf("matchSamples() = %d samples, want one for each of the 5 entries", len(samples))
+	}
+
+	tests := []struct {
+		userID    string
+		partySize int
+		wait      time.Duration
+		gap       int
+		eloRange  int
+	}{
+		{"alice", 1, 10 * time.Second, 60, 150},
+		{"bob", 1, 3 * time.Second, 60, 150},
+		{"carol", 2, 5 * time.Second, 50, 200}, // The party plays the average of its opponents, 1300
+		{"erin", 1, 8 * time.Second, 50, 200},
+		{"dave", 1, 20 * time.Second, 50, 200},
+	}
+	for i, tt := range tests {
+		got := samples[i]
+		if got.Kind != models.MatchmakingSampleMatched || got.UserID != tt.userID || got.PartySize != tt.partySize {
+			t.Errorf("sample %d = %s of %s with %d players, want matched of %s with %d players",
+				i, got.Kind, got.UserID, got.PartySize, tt.userID, tt.partySize)
+		}
+		if got.WaitMs != tt.wait.Milliseconds() {
+			t.Errorf("sample of %s waited %dms, want %dms", tt.userID, got.WaitMs, tt.wait.Milliseconds())
+		}
+		if got.RatingGap != tt.gap || got.EloRange != tt.eloRange || got.QueueSize != 7 {
+			t.Errorf("sample of %s = gap %d, range %d, queue %d, want gap %d, range %d, queue 7",
+				tt.userID, got.RatingGap, got.EloRange, got.QueueSize, tt.gap, tt.eloRange)
+		}
+	}
+}
+
+func TestShare(t *testing.T) {
+	counts := map[string]int64{"matched": 6, "left": 3, "timeout": 1}
+	if got := share(counts, "left"); got != 0.3 {
+		t.Errorf("share(left) = %v, want 0.3", got)
+	}
+	if got := share(counts, "bot"); got != 0 {
+		t.Errorf("share(bot) = %v, want 0", got)
+	}
+	if got := share(map[string]int64{}, "left"); got != 0 {
+		t.Errorf("share() of no counts = %v, want 0", got)
+	}
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,19 +13,30 @@ import (
 
 // AuthMiddleware is a middleware for authentication
 type AuthMiddleware struct {
-	authService *services.AuthService
+	authService       *services.AuthService
+	botAccountService *services.BotAccountService
+	botLimiter        *RateLimiter
 }
 
-// NewAuthMiddleware creates a new authentication middleware
-func NewAuthMiddleware(authService *services.AuthService) *AuthMiddleware {
+// NewAuthMiddleware creates a new authentication middleware.
+// Requests made with a bot token are authenticated as the bot and rate limited by botLimiter.
+func NewAuthMiddleware(authService *services.AuthService, botAccountService *services.BotAccountService, botLimiter *RateLimiter) *AuthMiddleware {
 	return &AuthMiddleware{
-		authService: authService,
+		authService:       authService,
+		botAccountService: botAccountService,
+		botLimiter:        botLimiter,
 	}
 }
 
 // RequireAuth is a middleware that requires authentication
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Bots authenticate with their token instead of a session
+		if botToken := bearerToken(c); services.IsBotToken(botToken) {
+			m.authenticateBot(c, botToken)
+			return
+		}
+
 		// Get token from cookie
 		tokenString, err := c.Cookie("access_token")
 		if err != nil {
@@ -88,9 +101,59 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	}
 }
 
+// RequireBot is a middleware that requires authentication with a bot token
+func (m *AuthMiddleware) RequireBot() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		botToken := bearerToken(c)
+		if !services.IsBotToken(botToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Bot token required",
+			})
+			c.Abort()
+			return
+		}
+
+		m.authenticateBot(c, botToken)
+	}
+}
+
+// RequireAdmin is a middleware that requires an authenticated administrator. It must follow RequireAuth.
+func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, exists := c.Get("userID")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Authentication required",
+			})
+			c.Abort()
+			return
+		}
+
+		user, err := m.authService.GetUserByID(userID.(string))
+		if err != nil || !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Admin access required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // OptionalAuth is a middleware that optionally authenticates the user
 func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Bots authenticate with their token instead of a session
+		if botToken := bearerToken(c); services.IsBotToken(botToken) {
+			m.authenticateBot(c, botToken)
+			return
+		}
+
 		// Get token from cookie
 		tokenString, err := c.Cookie("access_token")
 		if err != nil {
@@ -138,6 +201,47 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 	}
 }
 
+// Helper function to authenticate a request made with a bot token and apply the bot's rate limit
+func (m *AuthMiddleware) authenticateBot(c *gin.Context, botToken string) {
+	// Validate token
+	bot, err := m.botAccountService.ValidateToken(botToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Invalid bot token",
+		})
+		c.Abort()
+		return
+	}
+
+	// Apply the bot's rate limit
+	if m.botLimiter != nil && !m.botLimiter.Allow(bot.ID) {
+		retryAfter := int(math.Ceil(m.botLimiter.RetryAfter(bot.ID).Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "Rate limit exceeded",
+		})
+		c.Abort()
+		return
+	}
+
+	// Set user ID in context
+	c.Set("userID", bot.ID)
+	c.Set("isBot", true)
+
+	c.Next()
+}
+
+// Helper function to get the bearer token from the Authorization header
+func bearerToken(c *gin.Context) string {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(authHeader, "Bearer ")
+}
+
 // Helper function to set authentication cookies
 func setAuthCookies(c *gin.Context, tokenPair *services.TokenPair, rememberMe bool) {
 	// Calculate expiry times
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimiter limits how often each key may make requests, using a token bucket per key
+type RateLimiter struct {
+	rate    float64 // Tokens added per second
+	burst   float64 // Most tokens a bucket holds
+	buckets map[string]*bucket
+	mu      sync.Mutex
+}
+
+// bucket holds the tokens left for one key
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// NewRateLimiter creates a new rate limiter allowing perMinute requests a minute, and up to burst at once
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow checks if a key may make a request now, and counts the request if so
+func (l *RateLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+
+	// Refill the bucket for the time that passed
+	b.tokens += now.Sub(b.updated).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// RetryAfter returns how long a key has to wait before its next request is allowed
+func (l *RateLimiter) RetryAfter(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok || b.tokens >= 1 || l.rate <= 0 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// BotToken is a long-lived API token that lets an external bot act as its account.
+// Only a hash of the token is stored; the token itself is shown once, when it is created.
+type BotToken struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     string     `json:"user_id" gorm:"type:uuid;not null;index"` // The bot account
+	User       User       `json:"-" gorm:"foreignKey:UserID"`
+	Name       string     `json:"name" gorm:"size:50;not null"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"` // SHA-256 of the token, hex encoded
+	Prefix     string     `json:"prefix" gorm:"size:12;not null"`        // Start of the token, to tell tokens apart
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" gorm:"null"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" gorm:"null"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// BotTokenResponse is a newly created bot token, the only time the token itself is returned
+type BotTokenResponse struct {
+	BotToken
+	Token string `json:"token"`
+}
@@ -40,18 +40,80 @@ type Game struct {
 	ID             string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	PuzzleSequence string     `json:"puzzle_sequence" gorm:"not null"` // The 6-digit sequence
 	Status         GameStatus `json:"status" gorm:"type:varchar(20);not null;default:'waiting'"`
-	GameType       string     `json:"game_type" gorm:"type:varchar(20);not null;default:'duel'"` // duel, practice, tournament
+	GameType       string     `json:"game_type" gorm:"type:varchar(20);not null;default:'duel'"` // duel, practice, tournament, private
 	Difficulty     int        `json:"difficulty" gorm:"default:1"` // 1-5 difficulty rating
-	WinnerID       *string    `json:"winner_id,omitempty" gorm:"type:uuid;null"`
+	Variant        string     `json:"variant" gorm:"type:varchar(20);not null;default:'classic'"`
+	TimeLimit      int        `json:"time_limit" gorm:"default:0"` // in seconds, 0 for no limit
+	IsPrivate      bool       `json:"is_private" gorm:"default:false"` // Private games are hidden from public listings
+	Casual         bool       `json:"casual" gorm:"default:false"` // Casual games are not rated
+	WinnerID       *string    `json:"winner_id,omitempty" gorm:"type:uuid;null"` // In a game with sides, the best player of the winning side
+	WinningTeam    int        `json:"winning_team,omitempty" gorm:"default:0"` // Side that won a game with sides, 1 or 2
 	Winner         *User      `json:"-" gorm:"foreignKey:WinnerID"`
 	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
 	StartedAt      *time.Time `json:"started_at,omitempty" gorm:"null"`
 	CompletedAt    *time.Time `json:"completed_at,omitempty" gorm:"null"`
 	Duration       *float64   `json:"duration,omitempty" gorm:"null"` // in seconds
+	RematchOfID    *string    `json:"rematch_of_id,omitempty" gorm:"type:uuid;null"` // Game this game is a rematch of
+	SeriesID       *string    `json:"series_id,omitempty" gorm:"type:uuid;null;index"` // First game of the rematch chain
+	RatedAt        *time.Time `json:"-" gorm:"null"` // When player ratings were updated for this game
+	PuzzleSelection PuzzleSelection `json:"-" gorm:"embedded;embeddedPrefix:puzzle_selection_"` // Why the puzzle was picked for the players
 	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 	Players        []Player   `json:"players" gorm:"foreignKey:GameID"`
 }
 
+// Ways a game's puzzle is picked for its players
+const (
+	PuzzleSelectionUnseen     = "unseen"     // A stored puzzle none of the players had attempted
+	PuzzleSelectionGenerated  = "generated"  // Every stored candidate had been attempted, so a new puzzle was generated
+	PuzzleSelectionRepeat     = "repeat"     // No unseen puzzle could be found, so one of the right rating was reused
+	PuzzleSelectionUnsolvable = "unsolvable" // The impossible variant drew a puzzle without a solution
+)
+
+// PuzzleSelection records why a game's puzzle was picked for its players
+type PuzzleSelection struct {
+	Reason       string `json:"reason" gorm:"size:20;default:''"`
+	TargetRating int    `json:"target_rating" gorm:"default:0"` // Mean rating of the players
+	RatingSpread int    `json:"rating_spread" gorm:"default:0"` // Highest minus lowest rating of the players
+	Candidates   int    `json:"candidates" gorm:"default:0"`    // Stored puzzles suitable for the target rating
+	Excluded     int    `json:"excluded" gorm:"default:0"`      // Candidates one of the players had already attempted
+	Misfit       int    `json:"misfit" gorm:"default:0"`        // Rating points by which players fall outside the puzzle's ELO range
+}
+
+// Game types that change player ratings
+var ratedGameTypes = map[string]bool{
+	"duel":       true,
+	"blitz":      true,
+	"tournament": true,
+	"team":       true,
+}
+
+// IsRated checks if a game changes the ratings of its players. Private and casual games are not
+// rated. Neither are games against bots, unless an operator allowed the bot ranked play, so bots
+// stay off the ranked leaderboards. The players must be loaded with their users.
+func (g *Game) IsRated() bool {
+	if !ratedGameTypes[g.GameType] || g.IsPrivate || g.Casual {
+		return false
+	}
+
+	for _, p := range g.Players {
+		if !p.User.PlaysRanked() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// HasSides checks if a game is played between two sides of players, which win or lose together
+func (g *Game) HasSides() bool {
+	for _, p := range g.Players {
+		if p.Team != 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // Player represents a player in a game
 type Player struct {
 	ID                string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
@@ -65,6 +127,8 @@ type Player struct {
 	Score             *int       `json:"score,omitempty" gorm:"null"` // Points earned in this game
 	RatingChange      *int       `json:"rating_change,omitempty" gorm:"null"` // Change in rating after game
 	Attempts          int        `json:"attempts" gorm:"default:0"` // Number of solution attempts
+	Forfeited         bool       `json:"forfeited" gorm:"default:false"` // Did not reconnect within the grace period
+	Team              int        `json:"team,omitempty" gorm:"default:0"` // Side of a team match, 1 or 2; 0 when there are no sides
 	JoinedAt          time.Time  `json:"joined_at" gorm:"autoCreateTime"`
 	FinishedAt        *time.Time `json:"finished_at,omitempty" gorm:"null"` // When player finished the puzzle
 	UpdatedAt         time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
@@ -83,25 +147,50 @@ type GameResponse struct {
 	Status         GameStatus       `json:"status"`
 	GameType       string           `json:"game_type"`
 	Difficulty     int              `json:"difficulty"`
+	Variant        string           `json:"variant"`
+	TimeLimit      int              `json:"time_limit"`
+	IsPrivate      bool             `json:"is_private"`
+	Casual         bool             `json:"casual"`
 	WinnerID       *string          `json:"winner_id,omitempty"`
+	WinningTeam    int              `json:"winning_team,omitempty"`
 	CreatedAt      time.Time        `json:"created_at"`
 	StartedAt      *time.Time       `json:"started_at,omitempty"`
 	CompletedAt    *time.Time       `json:"completed_at,omitempty"`
 	Duration       *float64         `json:"duration,omitempty"`
+	RematchOfID    *string          `json:"rematch_of_id,omitempty"`
+	SeriesID       *string          `json:"series_id,omitempty"`
+	PuzzleSelection *PuzzleSelection `json:"puzzle_selection,omitempty"` // Only for games whose puzzle was picked for their players
 	Players        []PlayerResponse `json:"players"`
 }
 
+// GameSeriesResponse is the response structure for a head-to-head series of rematches
+type GameSeriesResponse struct {
+	SeriesID string           `json:"series_id"`
+	Games    []GameResponse   `json:"games"`
+	Wins     map[string]int   `json:"wins"` // Games won per user ID
+	Draws    int              `json:"draws"`
+}
+
+// LiveGameResponse is the response structure for a game that can be spectated
+type LiveGameResponse struct {
+	GameResponse
+	Spectators int `json:"spectators"`
+}
+
 // PlayerResponse is the response structure for player data
 type PlayerResponse struct {
 	ID                string     `json:"id"`
 	UserID            string     `json:"user_id"`
 	Username          string     `json:"username"`
+	IsBot             bool       `json:"is_bot"`
 	SolutionSubmitted *string    `json:"solution_submitted,omitempty"`
 	SolutionTime      *float64   `json:"solution_time,omitempty"`
 	IsCorrect         *bool      `json:"is_correct,omitempty"`
 	Score             *int       `json:"score,omitempty"`
 	RatingChange      *int       `json:"rating_change,omitempty"`
 	Attempts          int        `json:"attempts"`
+	Forfeited         bool       `json:"forfeited"`
+	Team              int        `json:"team,omitempty"`
 	JoinedAt          time.Time  `json:"joined_at"`
 	FinishedAt        *time.Time `json:"finished_at,omitempty"`
 	Progress          *float64   `json:"progress,omitempty"`
@@ -115,14 +204,26 @@ func (g *Game) ToResponse() GameResponse {
 		Status:         g.Status,
 		GameType:       g.GameType,
 		Difficulty:     g.Difficulty,
+		Variant:        g.Variant,
+		TimeLimit:      g.TimeLimit,
+		IsPrivate:      g.IsPrivate,
+		Casual:         g.Casual,
 		WinnerID:       g.WinnerID,
+		WinningTeam:    g.WinningTeam,
 		CreatedAt:      g.CreatedAt,
 		StartedAt:      g.StartedAt,
 		CompletedAt:    g.CompletedAt,
 		Duration:       g.Duration,
+		RematchOfID:    g.RematchOfID,
+		SeriesID:       g.SeriesID,
 		Players:        make([]PlayerResponse, len(g.Players)),
 	}
 
+	if g.PuzzleSelection.Reason != "" {
+		selection := g.PuzzleSelection
+		response.PuzzleSelection = &selection
+	}
+
 	for i, player := range g.Players {
 		response.Players[i] = player.ToResponse()
 	}
@@ -136,12 +237,15 @@ func (p *Player) ToResponse() PlayerResponse {
 		ID:                p.ID,
 		UserID:            p.UserID,
 		Username:          p.User.Username,
+		IsBot:             p.User.IsBot,
 		SolutionSubmitted: p.SolutionSubmitted,
 		SolutionTime:      p.SolutionTime,
 		IsCorrect:         p.IsCorrect,
 		Score:             p.Score,
 		RatingChange:      p.RatingChange,
 		Attempts:          p.Attempts,
+		Forfeited:         p.Forfeited,
+		Team:              p.Team,
 		JoinedAt:          p.JoinedAt,
 		FinishedAt:        p.FinishedAt,
 	}
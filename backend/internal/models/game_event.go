@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// GameEventType represents the type of a recorded game event
+type GameEventType string
+
+const (
+	GameEventPlayerJoined GameEventType = "player_joined"
+	GameEventGameStarted  GameEventType = "game_started"
+	GameEventAttempt      GameEventType = "attempt"
+	GameEventProgress     GameEventType = "progress"
+	GameEventGameEnded    GameEventType = "game_ended"
+)
+
+// GameEvent is an entry of the append-only event log of a game
+type GameEvent struct {
+	ID         string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	GameID     string        `json:"game_id" gorm:"type:uuid;not null;index"`
+	UserID     *string       `json:"user_id,omitempty" gorm:"type:uuid;null"` // nil for game-wide events
+	Type       GameEventType `json:"type" gorm:"type:varchar(20);not null"`
+	OffsetMs   int64         `json:"offset_ms" gorm:"not null"` // Milliseconds since the game was created
+	Solution   *string       `json:"solution,omitempty" gorm:"null"`
+	IsCorrect  *bool         `json:"is_correct,omitempty" gorm:"null"`
+	Score      *int          `json:"score,omitempty" gorm:"null"`
+	Attempt    *int          `json:"attempt,omitempty" gorm:"null"` // Attempt number for attempt events
+	Progress   *float64      `json:"progress,omitempty" gorm:"null"`
+	OccurredAt time.Time     `json:"occurred_at" gorm:"not null"`
+}
+
+// GameReplay is the timeline of a finished game
+type GameReplay struct {
+	GameID         string           `json:"game_id"`
+	GameType       string           `json:"game_type"`
+	PuzzleSequence string           `json:"puzzle_sequence"`
+	Status         GameStatus       `json:"status"`
+	WinnerID       *string          `json:"winner_id,omitempty"`
+	StartOffsetMs  *int64           `json:"start_offset_ms,omitempty"` // Offset of the game start within the timeline
+	DurationMs     int64            `json:"duration_ms"`               // Offset of the last event
+	Players        []PlayerResponse `json:"players"`
+	Events         []GameEvent      `json:"events"`
+}
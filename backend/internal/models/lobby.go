@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+)
+
+// LobbyStatus represents the status of a private lobby
+type LobbyStatus string
+
+const (
+	LobbyStatusOpen    LobbyStatus = "open"
+	LobbyStatusStarted LobbyStatus = "started"
+	LobbyStatusClosed  LobbyStatus = "closed"
+)
+
+// Game variants
+const (
+	GameVariantClassic    = "classic"    // Reach 100 using all six digits in order
+	GameVariantImpossible = "impossible" // Classic, but some sequences have no solution and players may claim so
+)
+
+// IsValidGameVariant checks if a string names a game variant
+func IsValidGameVariant(variant string) bool {
+	return variant == GameVariantClassic || variant == GameVariantImpossible
+}
+
+// Lobby represents a private lobby that players join with an invite code
+type Lobby struct {
+	ID         string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Code       string        `json:"code" gorm:"size:8;not null;uniqueIndex"` // Short invite code
+	HostID     string        `json:"host_id" gorm:"type:uuid;not null"`
+	Host       User          `json:"-" gorm:"foreignKey:HostID"`
+	Password   string        `json:"-" gorm:"null"` // Password hash, empty for lobbies without a password
+	Status     LobbyStatus   `json:"status" gorm:"type:varchar(20);not null;default:'open';index"`
+	MaxPlayers int           `json:"max_players" gorm:"not null;default:2"`
+	TimeLimit  int           `json:"time_limit" gorm:"not null;default:0"` // in seconds, 0 for no limit
+	Difficulty int           `json:"difficulty" gorm:"not null;default:0"` // 1-5, 0 for the members' mean rating
+	Variant    string        `json:"variant" gorm:"type:varchar(20);not null;default:'classic'"`
+	GameID     *string       `json:"game_id,omitempty" gorm:"type:uuid;null"` // Set once the host starts the game
+	CreatedAt  time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+	Members    []LobbyMember `json:"members,omitempty" gorm:"foreignKey:LobbyID"`
+}
+
+// LobbyMember represents a player waiting in a lobby
+type LobbyMember struct {
+	ID       string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	LobbyID  string    `json:"lobby_id" gorm:"type:uuid;not null;uniqueIndex:idx_lobby_member"`
+	UserID   string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_lobby_member"`
+	User     User      `json:"-" gorm:"foreignKey:UserID"`
+	JoinedAt time.Time `json:"joined_at" gorm:"autoCreateTime"`
+}
+
+// LobbyKick records that the host removed a user from a lobby, who may not join it again
+type LobbyKick struct {
+	ID       string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	LobbyID  string    `json:"lobby_id" gorm:"type:uuid;not null;uniqueIndex:idx_lobby_kick"`
+	UserID   string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_lobby_kick"`
+	KickedAt time.Time `json:"kicked_at" gorm:"autoCreateTime"`
+}
+
+// LobbySettings holds the settings a host can change. Settings left out keep their current value.
+type LobbySettings struct {
+	Password   *string `json:"password,omitempty"` // Empty removes the password
+	MaxPlayers int     `json:"max_players"`
+	TimeLimit  *int    `json:"time_limit,omitempty"` // 0 for no limit
+	Difficulty *int    `json:"difficulty,omitempty"` // 0 to suit the members' mean rating
+	Variant    string  `json:"variant"`
+}
+
+// LobbyResponse is the response structure for lobby data
+type LobbyResponse struct {
+	ID          string                `json:"id"`
+	Code        string                `json:"code"`
+	HostID      string                `json:"host_id"`
+	Status      LobbyStatus           `json:"status"`
+	HasPassword bool                  `json:"has_password"`
+	MaxPlayers  int                   `json:"max_players"`
+	TimeLimit   int                   `json:"time_limit"`
+	Difficulty  int                   `json:"difficulty"`
+	Variant     string                `json:"variant"`
+	GameID      *string               `json:"game_id,omitempty"`
+	Members     []LobbyMemberResponse `json:"members"`
+	CreatedAt   time.Time             `json:"created_at"`
+}
+
+// LobbyMemberResponse is the response structure for lobby member data
+type LobbyMemberResponse struct {
+	UserID   string    `json:"user_id"`
+	Username string    `json:"username"`
+	Rating   int       `json:"rating"`
+	IsHost   bool      `json:"is_host"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ToResponse converts a Lobby to a LobbyResponse
+func (l *Lobby) ToResponse() LobbyResponse {
+	response := LobbyResponse{
+		ID:          l.ID,
+		Code:        l.Code,
+		HostID:      l.HostID,
+		Status:      l.Status,
+		HasPassword: l.Password != "",
+		MaxPlayers:  l.MaxPlayers,
+		TimeLimit:   l.TimeLimit,
+		Difficulty:  l.Difficulty,
+		Variant:     l.Variant,
+		GameID:      l.GameID,
+		Members:     make([]LobbyMemberResponse, len(l.Members)),
+		CreatedAt:   l.CreatedAt,
+	}
+
+	for i, member := range l.Members {
+		response.Members[i] = LobbyMemberResponse{
+			UserID:   member.UserID,
+			Username: member.User.Username,
+			Rating:   member.User.Rating,
+			IsHost:   member.UserID == l.HostID,
+			JoinedAt: member.JoinedAt,
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Kinds of matchmaking samples
+const (
+	MatchmakingSampleMatched    = "matched"     // A queue entry was matched
+	MatchmakingSampleTimeout    = "timeout"     // A queue entry timed out without a match
+	MatchmakingSampleLeft       = "left"        // A player left the queue before being matched
+	MatchmakingSampleBot        = "bot"         // A player left the queue to play the bot offered to them
+	MatchmakingSampleReadyCheck = "ready_check" // A ready check ended, as told by its outcome
+)
+
+// MatchmakingSample records how one queue entry or ready check fared, so that the quality of
+// matchmaking can be measured over time
+type MatchmakingSample struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Kind      string    `json:"kind" gorm:"size:20;not null"`
+	GameType  string    `json:"game_type" gorm:"size:20;not null"`
+	Ranked    bool      `json:"ranked"`
+	Variant   string    `json:"variant" gorm:"size:20;not null"`
+	UserID    string    `json:"user_id" gorm:"type:uuid;not null"` // The player, or a party's leader
+	PartySize int       `json:"party_size" gorm:"not null;default:1"`
+	Rating    int       `json:"rating" gorm:"not null"`           // The entry's rating, or a party's average
+	WaitMs    int64     `json:"wait_ms" gorm:"not null"`          // Time in the queue, or how long a ready check took
+	RatingGap int       `json:"rating_gap"`                       // Difference between the average ratings of a match's sides
+	EloRange  int       `json:"elo_range"`                        // ELO window the match was made in
+	QueueSize int       `json:"queue_size"`                       // Entries in the queue when the match was made
+	Outcome   string    `json:"outcome,omitempty" gorm:"size:30"` // How a ready check ended: "accepted" or why it was cancelled
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// MatchmakingBucketStats summarizes the matches and timeouts of a rating band or an hour.
+// Waits are in seconds.
+type MatchmakingBucketStats struct {
+	Band           *int       `json:"band,omitempty"` // Lowest rating of the band
+	Hour           *time.Time `json:"hour,omitempty"`
+	Matched        int64      `json:"matched"`
+	WaitP50        float64    `json:"wait_p50"`
+	WaitP90        float64    `json:"wait_p90"`
+	WaitP99        float64    `json:"wait_p99"`
+	RatingGapP50   float64    `json:"rating_gap_p50"`
+	RatingGapP90   float64    `json:"rating_gap_p90"`
+	EloRangeP50    float64    `json:"elo_range_p50"`
+	EloRangeP90    float64    `json:"elo_range_p90"`
+	QueueSizeAvg   float64    `json:"queue_size_avg"`
+	Timeouts       int64      `json:"timeouts"`
+	TimeoutWaitP50 float64    `json:"timeout_wait_p50"`
+	TimeoutWaitP90 float64    `json:"timeout_wait_p90"`
+}
+
+// MatchmakingStats summarizes the quality of matchmaking over a period
+type MatchmakingStats struct {
+	Since       time.Time                `json:"since"`
+	Until       time.Time                `json:"until"`
+	BandWidth   int                      `json:"band_width"`
+	ByBand      []MatchmakingBucketStats `json:"by_band"`
+	ByHour      []MatchmakingBucketStats `json:"by_hour"`
+	ReadyChecks map[string]int64         `json:"ready_checks"` // Ready checks by outcome
+	Outcomes    map[string]int64         `json:"outcomes"`     // Queue entries by how they left the queue
+	AcceptRate  float64                  `json:"accept_rate"`  // Share of ready checks every player accepted
+	AbandonRate float64                  `json:"abandon_rate"` // Share of queue entries that left before a match
+	TimeoutRate float64                  `json:"timeout_rate"` // Share of queue entries that timed out
+}
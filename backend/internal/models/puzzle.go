@@ -29,6 +29,7 @@ type Puzzle struct {
 	AvgSolveTime    float64        `json:"avg_solve_time" gorm:"default:0"`  // Average time to solve in seconds
 	MinELO          int            `json:"min_elo" gorm:"default:0"`         // Minimum ELO rating recommended for this puzzle
 	MaxELO          int            `json:"max_elo" gorm:"default:3000"`      // Maximum ELO rating recommended for this puzzle
+	Unsolvable      bool           `json:"unsolvable" gorm:"default:false;index"` // No way to reach 100; only served in the impossible variant
 	CreatedAt       time.Time      `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt       time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
 }
@@ -41,6 +42,8 @@ type PuzzleSolution struct {
 	Expression   string    `json:"expression" gorm:"not null"`
 	Complexity   float64   `json:"complexity" gorm:"not null"` // Calculated complexity score
 	IsOptimal    bool      `json:"is_optimal" gorm:"default:false"`
+	DiscoveredBy *string   `json:"discovered_by,omitempty" gorm:"type:uuid;null"` // First player to find it in zen mode
+	DiscoveredAt *time.Time `json:"discovered_at,omitempty" gorm:"null"`
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 }
 
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// PuzzleExposure records that a user has been served a puzzle. The table is partitioned by user,
+// so it is created by repository.CreatePuzzleExposureTable rather than migrated automatically.
+type PuzzleExposure struct {
+	UserID      string    `json:"user_id" gorm:"primaryKey;type:uuid"`
+	Sequence    string    `json:"sequence" gorm:"primaryKey;size:6"`
+	Times       int       `json:"times"` // How many times the user has been served the puzzle
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
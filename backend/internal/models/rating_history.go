@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+)
+
+// RatingSource represents what caused a rating change
+type RatingSource string
+
+const (
+	RatingSourceDuel       RatingSource = "duel"       // Rated head-to-head duel
+	RatingSourceBlitz      RatingSource = "blitz"      // Rated blitz game
+	RatingSourceTournament RatingSource = "tournament" // Rated tournament game
+	RatingSourceTeam       RatingSource = "team"       // Rated team game
+	RatingSourcePractice   RatingSource = "practice"   // Practice session
+	RatingSourcePuzzle     RatingSource = "puzzle"     // Puzzle solved outside a game
+	RatingSourceForfeit    RatingSource = "forfeit"    // Penalty for not coming back to a game
+)
+
+// RatingHistory records a single change of a user's rating
+type RatingHistory struct {
+	ID           string       `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID       string       `json:"user_id" gorm:"type:uuid;not null"`
+	GameID       *string      `json:"game_id,omitempty" gorm:"type:uuid;null"` // Game that caused the change, if any
+	Mode         RatingMode   `json:"mode" gorm:"type:varchar(20);not null;default:'duel'"`
+	Source       RatingSource `json:"source" gorm:"type:varchar(20);not null"`
+	RatingBefore int          `json:"rating_before" gorm:"not null"`
+	RatingAfter  int          `json:"rating_after" gorm:"not null"`
+	Delta        int          `json:"delta" gorm:"not null"`
+	CreatedAt    time.Time    `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// TableName sets the table name of rating history entries
+func (RatingHistory) TableName() string {
+	return "rating_history"
+}
+
+// RatingBucket summarizes a user's rating over one bucket of time
+type RatingBucket struct {
+	Start   time.Time `json:"start"`
+	Open    int       `json:"open"`  // Rating at the start of the bucket
+	Close   int       `json:"close"` // Rating at the end of the bucket
+	High    int       `json:"high"`
+	Low     int       `json:"low"`
+	Changes int       `json:"changes"`
+}
+
+// RatingStats holds a user's current, peak and lowest rating
+type RatingStats struct {
+	UserID   string     `json:"user_id"`
+	Mode     RatingMode `json:"mode"`
+	Current  int        `json:"current"`
+	Peak     int        `json:"peak"`
+	PeakAt   *time.Time `json:"peak_at,omitempty"`
+	Lowest   int        `json:"lowest"`
+	LowestAt *time.Time `json:"lowest_at,omitempty"`
+	Changes  int64      `json:"changes"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Lengths of a Puzzle Rush run, in minutes
+var RushDurations = []int{3, 5}
+
+// RushRun is a finished Puzzle Rush run: as many puzzles as possible before the clock
+// runs out or the player makes three mistakes
+type RushRun struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID        string    `json:"user_id" gorm:"type:uuid;not null;index:idx_rush_runs_user_duration"`
+	User          User      `json:"-" gorm:"foreignKey:UserID"`
+	Duration      int       `json:"duration" gorm:"not null;index:idx_rush_runs_user_duration"` // Length of the run in minutes
+	Score         int       `json:"score" gorm:"not null"`
+	PuzzlesSolved int       `json:"puzzles_solved" gorm:"not null"`
+	Strikes       int       `json:"strikes" gorm:"not null"`
+	MaxDifficulty int       `json:"max_difficulty" gorm:"not null"`     // Hardest puzzle level reached
+	EndReason     string    `json:"end_reason" gorm:"size:20;not null"` // "time_up", "strikes" or "ended"
+	StartedAt     time.Time `json:"started_at" gorm:"not null"`
+	CompletedAt   time.Time `json:"completed_at" gorm:"not null"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// RushLeaderboardEntry is a row of a Puzzle Rush leaderboard, holding a player's best run
+type RushLeaderboardEntry struct {
+	Rank          int       `json:"rank"`
+	UserID        string    `json:"user_id"`
+	Username      string    `json:"username"`
+	RunID         string    `json:"run_id"`
+	Duration      int       `json:"duration"`
+	Score         int       `json:"score"`
+	PuzzlesSolved int       `json:"puzzles_solved"`
+	CompletedAt   time.Time `json:"completed_at"`
+}
+
+// IsValidRushDuration checks if a number of minutes is a Puzzle Rush length
+func IsValidRushDuration(minutes int) bool {
+	for _, d := range RushDurations {
+		if d == minutes {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"time"
+)
+
+// TournamentFormat represents the pairing system used by a tournament
+type TournamentFormat string
+
+const (
+	TournamentFormatSingleElimination TournamentFormat = "single_elimination"
+	TournamentFormatSwiss             TournamentFormat = "swiss"
+)
+
+// TournamentStatus represents the status of a tournament
+type TournamentStatus string
+
+const (
+	TournamentStatusRegistration TournamentStatus = "registration"
+	TournamentStatusActive       TournamentStatus = "active"
+	TournamentStatusCompleted    TournamentStatus = "completed"
+	TournamentStatusCancelled    TournamentStatus = "cancelled"
+)
+
+// PairingResult represents the outcome of a tournament pairing
+type PairingResult string
+
+const (
+	PairingResultPending PairingResult = "pending"
+	PairingResultPlayer1 PairingResult = "player1" // Player 1 won
+	PairingResultPlayer2 PairingResult = "player2" // Player 2 won
+	PairingResultDraw    PairingResult = "draw"
+	PairingResultBye     PairingResult = "bye" // Player 1 received a bye
+)
+
+// Tournament represents a tournament
+type Tournament struct {
+	ID             string                  `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Name           string                  `json:"name" gorm:"not null"`
+	Format         TournamentFormat        `json:"format" gorm:"type:varchar(30);not null"`
+	Status         TournamentStatus        `json:"status" gorm:"type:varchar(20);not null;default:'registration';index"`
+	CreatorID      string                  `json:"creator_id" gorm:"type:uuid;not null"`
+	Creator        User                    `json:"-" gorm:"foreignKey:CreatorID"`
+	MaxPlayers     int                     `json:"max_players" gorm:"not null;default:16"`
+	TotalRounds    int                     `json:"total_rounds" gorm:"not null;default:0"` // 0 = derived from player count
+	CurrentRound   int                     `json:"current_round" gorm:"not null;default:0"`
+	RoundTimeLimit int                     `json:"round_time_limit" gorm:"not null;default:300"` // in seconds
+	StartsAt       *time.Time              `json:"starts_at,omitempty" gorm:"null"`
+	StartedAt      *time.Time              `json:"started_at,omitempty" gorm:"null"`
+	CompletedAt    *time.Time              `json:"completed_at,omitempty" gorm:"null"`
+	WinnerID       *string                 `json:"winner_id,omitempty" gorm:"type:uuid;null"`
+	CreatedAt      time.Time               `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time               `json:"updated_at" gorm:"autoUpdateTime"`
+	Participants   []TournamentParticipant `json:"participants,omitempty" gorm:"foreignKey:TournamentID"`
+}
+
+// TournamentParticipant represents a registered player and their standing in a tournament
+type TournamentParticipant struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TournamentID   string    `json:"tournament_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_participant"`
+	UserID         string    `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_tournament_participant"`
+	User           User      `json:"-" gorm:"foreignKey:UserID"`
+	Seed           int       `json:"seed" gorm:"not null;default:0"`
+	Rating         int       `json:"rating" gorm:"not null"` // Rating at registration time
+	Points         float64   `json:"points" gorm:"not null;default:0"`
+	Wins           int       `json:"wins" gorm:"not null;default:0"`
+	Draws          int       `json:"draws" gorm:"not null;default:0"`
+	Losses         int       `json:"losses" gorm:"not null;default:0"`
+	Buchholz       float64   `json:"buchholz" gorm:"not null;default:0"`
+	TotalSolveTime float64   `json:"total_solve_time" gorm:"not null;default:0"` // in seconds
+	HadBye         bool      `json:"had_bye" gorm:"not null;default:false"`
+	Eliminated     bool      `json:"eliminated" gorm:"not null;default:false"`
+	RegisteredAt   time.Time `json:"registered_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TournamentRound represents a round of a tournament
+type TournamentRound struct {
+	ID           string              `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TournamentID string              `json:"tournament_id" gorm:"type:uuid;not null;index"`
+	Number       int                 `json:"number" gorm:"not null"`
+	StartedAt    time.Time           `json:"started_at" gorm:"not null"`
+	Deadline     time.Time           `json:"deadline" gorm:"not null"`
+	CompletedAt  *time.Time          `json:"completed_at,omitempty" gorm:"null"`
+	Pairings     []TournamentPairing `json:"pairings,omitempty" gorm:"foreignKey:RoundID"`
+}
+
+// TournamentPairing represents a single game between two participants in a round
+type TournamentPairing struct {
+	ID           string        `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	TournamentID string        `json:"tournament_id" gorm:"type:uuid;not null;index"`
+	RoundID      string        `json:"round_id" gorm:"type:uuid;not null;index"`
+	Board        int           `json:"board" gorm:"not null"` // Position of the pairing within the round
+	Player1ID    string        `json:"player1_id" gorm:"type:uuid;not null"`
+	Player2ID    *string       `json:"player2_id,omitempty" gorm:"type:uuid;null"` // nil for a bye
+	GameID       *string       `json:"game_id,omitempty" gorm:"type:uuid;null;index"`
+	Result       PairingResult `json:"result" gorm:"type:varchar(20);not null;default:'pending'"`
+	CreatedAt    time.Time     `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time     `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TournamentStanding is a single row of a tournament standings table
+type TournamentStanding struct {
+	Rank           int     `json:"rank"`
+	UserID         string  `json:"user_id"`
+	Username       string  `json:"username"`
+	Seed           int     `json:"seed"`
+	Points         float64 `json:"points"`
+	Wins           int     `json:"wins"`
+	Draws          int     `json:"draws"`
+	Losses         int     `json:"losses"`
+	Buchholz       float64 `json:"buchholz"`
+	TotalSolveTime float64 `json:"total_solve_time"`
+	Eliminated     bool    `json:"eliminated"`
+}
+
+// IsBye reports whether the pairing is a bye
+func (p *TournamentPairing) IsBye() bool {
+	return p.Player2ID == nil
+}
+
+// HasPlayer reports whether the given user plays in this pairing
+func (p *TournamentPairing) HasPlayer(userID string) bool {
+	return p.Player1ID == userID || (p.Player2ID != nil && *p.Player2ID == userID)
+}
+
+// ToStanding converts a TournamentParticipant to a TournamentStanding
+func (tp *TournamentParticipant) ToStanding(rank int) TournamentStanding {
+	return TournamentStanding{
+		Rank:           rank,
+		UserID:         tp.UserID,
+		Username:       tp.User.Username,
+		Seed:           tp.Seed,
+		Points:         tp.Points,
+		Wins:           tp.Wins,
+		Draws:          tp.Draws,
+		Losses:         tp.Losses,
+		Buchholz:       tp.Buchholz,
+		TotalSolveTime: tp.TotalSolveTime,
+		Eliminated:     tp.Eliminated,
+	}
+}
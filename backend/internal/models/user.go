@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -10,17 +11,26 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID           string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Username     string     `json:"username" gorm:"uniqueIndex;size:50;not null"`
-	Email        string     `json:"email" gorm:"uniqueIndex;size:100;not null"`
-	Password     string     `json:"-" gorm:"not null"` // Password hash, not exposed in JSON
-	Rating       int        `json:"rating" gorm:"default:1000"`
-	Streak       int        `json:"streak" gorm:"default:0"`
-	LastLogin    time.Time  `json:"last_login"`
-	LastActivity time.Time  `json:"last_activity"`
-	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
-	DeletedAt    *time.Time `json:"-" gorm:"index"`
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Username         string     `json:"username" gorm:"uniqueIndex;size:50;not null"`
+	Email            string     `json:"email" gorm:"uniqueIndex;size:100;not null"`
+	Password         string     `json:"-" gorm:"not null"` // Password hash, not exposed in JSON
+	Rating           int        `json:"rating" gorm:"default:1000"`
+	RatingDeviation  float64    `json:"rating_deviation" gorm:"default:350"`   // Glicko-2 rating deviation
+	RatingVolatility float64    `json:"rating_volatility" gorm:"default:0.06"` // Glicko-2 volatility
+	RatedGames       int        `json:"rated_games" gorm:"default:0"`
+	Provisional      bool       `json:"provisional" gorm:"default:true"` // Rating is still settling
+	RatedAt          *time.Time `json:"rated_at,omitempty" gorm:"null"`  // When the last rated game finished
+	Streak           int        `json:"streak" gorm:"default:0"`
+	IsBot            bool       `json:"is_bot" gorm:"default:false;index"`                  // Played by a program, not a person
+	BotOwnerID       *string    `json:"bot_owner_id,omitempty" gorm:"type:uuid;null;index"` // Person running an external bot; server bots have none
+	BotRanked        bool       `json:"bot_ranked" gorm:"default:false"`                    // Bot may play ranked games against people, set by operators
+	IsAdmin          bool       `json:"-" gorm:"default:false"`                             // May use the admin API, set by operators
+	LastLogin        time.Time  `json:"last_login"`
+	LastActivity     time.Time  `json:"last_activity"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt        *time.Time `json:"-" gorm:"index"`
 }
 
 // UserStats represents statistics for a user
@@ -67,29 +77,56 @@ func (u *User) ComparePassword(password string) error {
 	return nil
 }
 
+// BuiltInBotPrefix starts the username of every built-in bot
+const BuiltInBotPrefix = "hectobot_"
+
+// IsReservedUsername checks if a username is kept for built-in bots, in any case
+func IsReservedUsername(username string) bool {
+	return strings.HasPrefix(strings.ToLower(username), BuiltInBotPrefix)
+}
+
+// IsExternalBot checks if the user is a bot run by a person through the bot API
+func (u *User) IsExternalBot() bool {
+	return u.IsBot && u.BotOwnerID != nil
+}
+
+// PlaysRanked checks if the user may play ranked games against people.
+// Bots only do so when an operator has allowed it.
+func (u *User) PlaysRanked() bool {
+	return !u.IsBot || u.BotRanked
+}
+
 // UserResponse is the response structure for user data
 type UserResponse struct {
-	ID           string    `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	Rating       int       `json:"rating"`
-	Streak       int       `json:"streak"`
-	LastLogin    time.Time `json:"last_login"`
-	LastActivity time.Time `json:"last_activity"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID              string    `json:"id"`
+	Username        string    `json:"username"`
+	Email           string    `json:"email"`
+	Rating          int       `json:"rating"`
+	RatingDeviation float64   `json:"rating_deviation"`
+	Provisional     bool      `json:"provisional"`
+	Streak          int       `json:"streak"`
+	IsBot           bool      `json:"is_bot"`
+	BotOwnerID      *string   `json:"bot_owner_id,omitempty"`
+	LastLogin       time.Time `json:"last_login"`
+	LastActivity    time.Time `json:"last_activity"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 // ToResponse converts a User to a UserResponse
 func (u *User) ToResponse() UserResponse {
 	return UserResponse{
-		ID:           u.ID,
-		Username:     u.Username,
-		Email:        u.Email,
-		Rating:       u.Rating,
-		Streak:       u.Streak,
-		LastLogin:    u.LastLogin,
-		LastActivity: u.LastActivity,
-		CreatedAt:    u.CreatedAt,
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		Rating:          u.Rating,
+		RatingDeviation: u.RatingDeviation,
+		Provisional:     u.Provisional,
+		Streak:          u.Streak,
+		IsBot:           u.IsBot,
+		BotOwnerID:      u.BotOwnerID,
+		LastLogin:       u.LastLogin,
+		LastActivity:    u.LastActivity,
+		CreatedAt:       u.CreatedAt,
 	}
 }
 
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// RatingMode represents a game mode with its own rating
+type RatingMode string
+
+const (
+	RatingModeDuel     RatingMode = "duel"     // Duels, tournaments and private games
+	RatingModeBlitz    RatingMode = "blitz"    // Short head-to-head games
+	RatingModePractice RatingMode = "practice" // Practice sessions and solo puzzles
+	RatingModeTeam     RatingMode = "team"     // Team games
+)
+
+// RatingModes lists every rating mode
+var RatingModes = []RatingMode{
+	RatingModeDuel,
+	RatingModeBlitz,
+	RatingModePractice,
+	RatingModeTeam,
+}
+
+// UserRating is a user's rating in one game mode.
+// The duel rating is mirrored to User.Rating, which remains the headline rating.
+type UserRating struct {
+	ID               string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID           string     `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_user_rating_mode"`
+	User             User       `json:"-" gorm:"foreignKey:UserID"`
+	Mode             RatingMode `json:"mode" gorm:"type:varchar(20);not null;uniqueIndex:idx_user_rating_mode"`
+	Rating           int        `json:"rating" gorm:"not null;default:1000"`
+	RatingDeviation  float64    `json:"rating_deviation" gorm:"not null;default:350"`
+	RatingVolatility float64    `json:"rating_volatility" gorm:"not null;default:0.06"`
+	RatedGames       int        `json:"rated_games" gorm:"not null;default:0"`
+	Provisional      bool       `json:"provisional" gorm:"not null;default:true"`
+	RatedAt          *time.Time `json:"rated_at,omitempty" gorm:"null"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RatingLeaderboardEntry is a row of a per-mode rating leaderboard
+type RatingLeaderboardEntry struct {
+	Rank            int        `json:"rank"`
+	UserID          string     `json:"user_id"`
+	Username        string     `json:"username"`
+	Mode            RatingMode `json:"mode"`
+	Rating          int        `json:"rating"`
+	RatingDeviation float64    `json:"rating_deviation"`
+	RatedGames      int        `json:"rated_games"`
+	Provisional     bool       `json:"provisional"`
+}
+
+// IsValidRatingMode checks if a string names a rating mode
+func IsValidRatingMode(mode string) bool {
+	for _, m := range RatingModes {
+		if string(m) == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// RatingModeForGameType returns the rating mode a game type is played in
+func RatingModeForGameType(gameType string) RatingMode {
+	switch gameType {
+	case "duel", "tournament", "private":
+		return RatingModeDuel
+	case "blitz":
+		return RatingModeBlitz
+	case "team":
+		return RatingModeTeam
+	}
+	return RatingModePractice
+}
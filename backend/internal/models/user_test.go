@@ -0,0 +1,22 @@
+package models
+
+import "testing"
+
+func TestIsReservedUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     bool
+	}{
+		{"hectobot_club", true},
+		{"HectoBot_Club", true},
+		{"hectobot", false},
+		{"my_hectobot_club", false},
+		{"alice", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsReservedUsername(tt.username); got != tt.want {
+			t.Errorf("IsReservedUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
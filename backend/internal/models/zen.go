@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// FoundSolution records a solution a player found to a puzzle in zen mode
+type FoundSolution struct {
+	ID         string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID     string         `json:"user_id" gorm:"type:uuid;not null;uniqueIndex:idx_found_solutions_user_solution;index:idx_found_solutions_user_puzzle"`
+	PuzzleID   string         `json:"puzzle_id" gorm:"type:uuid;not null;index:idx_found_solutions_user_puzzle"`
+	SolutionID string         `json:"solution_id" gorm:"type:uuid;not null;uniqueIndex:idx_found_solutions_user_solution"`
+	Solution   PuzzleSolution `json:"-" gorm:"foreignKey:SolutionID"`
+	CreatedAt  time.Time      `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -1,17 +1,33 @@
 package practice
 
 import (
+	"errors"
+	"sync"
 	"time"
 
 	"github.com/hectoclash/internal/models"
 	"github.com/hectoclash/internal/puzzle"
 )
 
+// Session modes
+const (
+	ModeStandard = "standard" // One puzzle after another at the player's level
+	ModeRush     = "rush"     // As many puzzles as possible against a clock, with escalating difficulty
+)
+
+// RushMaxStrikes is how many wrong answers end a rush run
+const RushMaxStrikes = 3
+
+// ErrSessionEnded is returned for a solution submitted after the session ended, such as when a rush run is out of time
+var ErrSessionEnded = errors.New("session has ended")
+
 // SessionConfig represents configuration for a practice session
 type SessionConfig struct {
 	UserID    string `json:"user_id"`
 	TimedMode bool   `json:"timed_mode"` // true for timed (60s per question), false for untimed
 	StartELO  int    `json:"start_elo"`  // Starting ELO for the session (default: user's current ELO)
+	Mode      string `json:"mode"`       // ModeStandard (default) or ModeRush
+	Duration  int    `json:"duration"`   // Length of a rush run in minutes
 }
 
 // Session represents a practice session
@@ -29,6 +45,25 @@ type Session struct {
 	PuzzlesSolved int                    `json:"puzzles_solved"`
 	Status        string                 `json:"status"` // "active", "completed", "failed"
 	Metadata      map[string]any `json:"metadata,omitempty"`
+	Mode          string                 `json:"mode"` // ModeStandard or ModeRush
+
+	// Rush runs only
+	Duration      int             `json:"duration,omitempty"` // in minutes
+	EndsAt        *time.Time      `json:"ends_at,omitempty"`
+	Score         int             `json:"score"`
+	Strikes       int             `json:"strikes"`
+	MaxDifficulty int             `json:"max_difficulty,omitempty"`
+	EndReason     string          `json:"end_reason,omitempty"` // "time_up", "strikes" or "ended"
+	Run           *models.RushRun `json:"run,omitempty"`        // The recorded run, once it is over
+	PersonalBest  bool            `json:"personal_best"`        // The run beat the player's previous best
+	BestScore     int             `json:"best_score,omitempty"`
+
+	mu sync.Mutex // Serializes submissions and the clock of a rush run
+}
+
+// IsRush checks if the session is a rush run
+func (s *Session) IsRush() bool {
+	return s.Mode == ModeRush
 }
 
 // Service defines the interface for practice mode operations
@@ -36,4 +71,5 @@ type Service interface {
 	CreateSession(config SessionConfig) (*Session, error)
 	SubmitSolution(session *Session, solution string) (*puzzle.ValidationResult, error)
 	EndSession(session *Session) error
+	ExpireSession(session *Session) error
 }
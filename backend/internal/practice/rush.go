@@ -0,0 +1,193 @@
+package practice
+
+import (
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/puzzle"
+)
+
+// Puzzle Rush difficulty and scoring
+const (
+	rushPuzzlesPerLevel = 3  // Correct answers before the puzzles get a level harder
+	rushPointsPerLevel  = 10 // Points for a puzzle, for each level of its difficulty
+)
+
+// submitRushSolution checks an answer in a rush run. Every puzzle gets one answer: a correct one
+// scores, a wrong one is a strike, and either way the run moves on to the next puzzle.
+func (s *ServiceImpl) submitRushSolution(session *Session, solution string) (*puzzle.ValidationResult, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.Status != "active" {
+		return nil, ErrSessionEnded
+	}
+
+	// The clock is kept here, so an answer that arrives late does not count
+	now := time.Now()
+	if session.EndsAt != nil && !now.Before(*session.EndsAt) {
+		err := s.finishRush(session, "time_up")
+		if err != nil {
+			log.Printf("Error finishing rush run %s: %v", session.ID, err)
+		}
+		return nil, ErrSessionEnded
+	}
+
+	// Find game by ID
+	game, err := s.gameRepo.FindByID(session.GameID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Find player
+	player, err := s.gameRepo.FindPlayerByGameAndUser(session.GameID, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the solution; rush runs are scored by difficulty alone and do not change ratings
+	difficulty := session.CurrentPuzzle.Difficulty
+	result := s.puzzleService.CheckSolution(session.CurrentPuzzle.Sequence, difficulty, solution, session.StartELO)
+	result.RatingChange = 0
+	result.Score = 0
+	if result.IsCorrect {
+		result.Score = rushPoints(difficulty)
+	}
+
+	// Calculate solution time
+	var solveTime float64
+	if game.StartedAt != nil {
+		solveTime = now.Sub(*game.StartedAt).Seconds()
+	}
+
+	// Update player's solution
+	isCorrect := result.IsCorrect
+	score := result.Score
+	player.SolutionSubmitted = &solution
+	player.SolutionTime = &solveTime
+	player.IsCorrect = &isCorrect
+	player.Score = &score
+	player.FinishedAt = &now
+	player.Attempts++
+
+	// The puzzle is over either way
+	game.Status = models.GameStatusCompleted
+	game.CompletedAt = &now
+	game.Duration = &solveTime
+	if isCorrect {
+		game.WinnerID = &session.UserID
+	}
+
+	err = s.gameRepo.Update(game)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.gameRepo.UpdatePlayer(player)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update session
+	if isCorrect {
+		session.PuzzlesSolved++
+		session.Score += score
+	} else {
+		session.Strikes++
+	}
+	session.LastUpdatedAt = now
+
+	// Three strikes end the run
+	if session.Strikes >= RushMaxStrikes {
+		err = s.finishRush(session, "strikes")
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Generate next puzzle
+	err = s.generateNextPuzzle(session)
+	if err != nil {
+		log.Printf("Error generating next rush puzzle: %v", err)
+		// Don't fail the request if we can't generate the next puzzle
+	}
+
+	return result, nil
+}
+
+// finishRush ends a rush run and records it. The caller holds the session lock.
+// It does nothing if the run is already over.
+func (s *ServiceImpl) finishRush(session *Session, reason string) error {
+	if session.Status != "active" {
+		return nil
+	}
+
+	now := time.Now()
+	session.Status = "completed"
+	session.CompletedAt = &now
+	session.LastUpdatedAt = now
+	session.EndReason = reason
+
+	// Close the puzzle that was open when the run ended
+	err := s.closeGame(session, now)
+	if err != nil {
+		log.Printf("Error closing rush game %s: %v", session.GameID, err)
+	}
+
+	// Get the previous best before recording this run
+	best, err := s.rushRepo.FindBestByUser(session.UserID, session.Duration)
+	if err != nil && err.Error() != "rush run not found" {
+		return err
+	}
+
+	run := &models.RushRun{
+		UserID:        session.UserID,
+		Duration:      session.Duration,
+		Score:         session.Score,
+		PuzzlesSolved: session.PuzzlesSolved,
+		Strikes:       session.Strikes,
+		MaxDifficulty: session.MaxDifficulty,
+		EndReason:     reason,
+		StartedAt:     session.StartedAt,
+		CompletedAt:   now,
+	}
+
+	err = s.rushRepo.Create(run)
+	if err != nil {
+		return err
+	}
+
+	session.Run = run
+	session.PersonalBest = run.Score > 0 && (best == nil || run.Score > best.Score)
+	session.BestScore = run.Score
+	if best != nil && best.Score > run.Score {
+		session.BestScore = best.Score
+	}
+
+	return nil
+}
+
+// nextRushPuzzle gets the next puzzle of a rush run, which gets harder as more are solved
+func (s *ServiceImpl) nextRushPuzzle(session *Session) (*models.Puzzle, error) {
+	exclude := ""
+	if session.CurrentPuzzle != nil {
+		exclude = session.CurrentPuzzle.Sequence
+	}
+	return s.puzzleService.GetSimilarPuzzle(rushDifficulty(session.PuzzlesSolved), exclude, nil)
+}
+
+// Helper function to get the difficulty of rush puzzles after a number of correct answers
+func rushDifficulty(solved int) models.DifficultyLevel {
+	level := models.DifficultyEasy + models.DifficultyLevel(solved/rushPuzzlesPerLevel)
+	if level > models.DifficultyChampion {
+		level = models.DifficultyChampion
+	}
+	return level
+}
+
+// Helper function to get the points for solving a rush puzzle
+func rushPoints(difficulty models.DifficultyLevel) int {
+	return int(difficulty) * rushPointsPerLevel
+}
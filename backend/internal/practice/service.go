@@ -16,6 +16,7 @@ type ServiceImpl struct {
 	userRepo      *repository.UserRepository
 	puzzleService *puzzle.Service
 	eventService  EventNotifier
+	rushRepo      *repository.RushRepository
 }
 
 // EventNotifier defines the interface for notifying events
@@ -32,12 +33,14 @@ func NewService(
 	userRepo *repository.UserRepository,
 	puzzleService *puzzle.Service,
 	eventService EventNotifier,
+	rushRepo *repository.RushRepository,
 ) Service {
 	return &ServiceImpl{
 		gameRepo:      gameRepo,
 		userRepo:      userRepo,
 		puzzleService: puzzleService,
 		eventService:  eventService,
+		rushRepo:      rushRepo,
 	}
 }
 
@@ -45,11 +48,22 @@ func NewService(
 func (s *ServiceImpl) CreateSession(config SessionConfig) (*Session, error) {
 	// Get user's current ELO if not specified
 	if config.StartELO <= 0 {
-		user, err := s.userRepo.FindByID(config.UserID)
+		practiceRating, err := s.userRepo.GetUserRating(config.UserID, models.RatingModePractice)
 		if err != nil {
 			return nil, err
 		}
-		config.StartELO = user.Rating
+		config.StartELO = practiceRating.Rating
+	}
+
+	// Check the mode
+	if config.Mode == "" {
+		config.Mode = ModeStandard
+	}
+	if config.Mode != ModeStandard && config.Mode != ModeRush {
+		return nil, errors.New("invalid practice mode")
+	}
+	if config.Mode == ModeRush && !models.IsValidRushDuration(config.Duration) {
+		return nil, errors.New("invalid rush duration")
 	}
 
 	// Create a new practice session
@@ -64,6 +78,15 @@ func (s *ServiceImpl) CreateSession(config SessionConfig) (*Session, error) {
 		PuzzlesSolved: 0,
 		Status:        "active",
 		Metadata:      make(map[string]any),
+		Mode:          config.Mode,
+	}
+
+	// A rush run is played against one clock instead of a limit per puzzle
+	if session.IsRush() {
+		endsAt := session.StartedAt.Add(time.Duration(config.Duration) * time.Minute)
+		session.TimedMode = false
+		session.Duration = config.Duration
+		session.EndsAt = &endsAt
 	}
 
 	// Generate the first puzzle
@@ -75,19 +98,33 @@ func (s *ServiceImpl) CreateSession(config SessionConfig) (*Session, error) {
 	return session, nil
 }
 
-// generateNextPuzzle generates the next puzzle for a practice session
+// generateNextPuzzle generates the next puzzle for a practice session. Standard sessions get
+// puzzles the user has not seen yet.
 func (s *ServiceImpl) generateNextPuzzle(session *Session) error {
-	// Get a puzzle suitable for the current ELO
-	puzzle, err := s.puzzleService.GetPuzzleForUser(session.CurrentELO)
+	// Get a puzzle suitable for the current ELO, or the next step of a rush run
+	var puzzle *models.Puzzle
+	var err error
+	gameType := "practice"
+	if session.IsRush() {
+		puzzle, err = s.nextRushPuzzle(session)
+		gameType = "rush"
+	} else {
+		puzzle, err = s.puzzleService.GetPuzzleForUser(session.UserID, session.CurrentELO)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Rush puzzles are picked by difficulty rather than for the user, so record them here
+	if session.IsRush() {
+		s.puzzleService.MarkSeen([]string{session.UserID}, puzzle.Sequence)
+	}
+
 	// Create a new game for this puzzle
 	game := &models.Game{
 		PuzzleSequence: puzzle.Sequence,
 		Status:         models.GameStatusActive,
-		GameType:       "practice",
+		GameType:       gameType,
 		Difficulty:     int(puzzle.Difficulty),
 	}
 
@@ -122,12 +159,19 @@ func (s *ServiceImpl) generateNextPuzzle(session *Session) error {
 	session.CurrentPuzzle = puzzle
 	session.GameID = game.ID
 	session.LastUpdatedAt = now
+	if int(puzzle.Difficulty) > session.MaxDifficulty {
+		session.MaxDifficulty = int(puzzle.Difficulty)
+	}
 
 	return nil
 }
 
 // SubmitSolution submits a solution for the current puzzle in a practice session
 func (s *ServiceImpl) SubmitSolution(session *Session, solution string) (*puzzle.ValidationResult, error) {
+	if session.IsRush() {
+		return s.submitRushSolution(session, solution)
+	}
+
 	// Find game by ID
 	game, err := s.gameRepo.FindByID(session.GameID)
 	if err != nil {
@@ -202,18 +246,25 @@ func (s *ServiceImpl) SubmitSolution(session *Session, solution string) (*puzzle
 		session.CurrentELO += ratingChange
 		session.LastUpdatedAt = now
 
-		// Update user's rating if this is a ranked practice session
-		user, err := s.userRepo.FindByID(session.UserID)
+		// Update user's practice rating
+		practiceRating, err := s.userRepo.GetUserRating(session.UserID, models.RatingModePractice)
 		if err != nil {
 			return nil, err
 		}
 
-		user.Rating += ratingChange
-		err = s.userRepo.Update(user)
+		oldRating := practiceRating.Rating
+		practiceRating.Rating += ratingChange
+		err = s.userRepo.SaveUserRating(practiceRating)
 		if err != nil {
 			return nil, err
 		}
 
+		// Record the change
+		err = s.userRepo.AddRatingHistory(session.UserID, &game.ID, models.RatingModePractice, models.RatingSourcePractice, oldRating, practiceRating.Rating)
+		if err != nil {
+			log.Printf("Error recording rating history: %v", err)
+		}
+
 		// Update user stats
 		stats, err := s.userRepo.GetUserStats(session.UserID)
 		if err != nil {
@@ -222,7 +273,6 @@ func (s *ServiceImpl) SubmitSolution(session *Session, solution string) (*puzzle
 
 		stats.GamesPlayed++
 		stats.GamesWon++
-		stats.Rating = user.Rating
 
 		// Update streak
 		stats.UpdateStreak(now)
@@ -270,28 +320,53 @@ func (s *ServiceImpl) SubmitSolution(session *Session, solution string) (*puzzle
 
 // EndSession ends a practice session
 func (s *ServiceImpl) EndSession(session *Session) error {
+	// A rush run that is ended early is still recorded
+	if session.IsRush() {
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		return s.finishRush(session, "ended")
+	}
+
 	now := time.Now()
 	session.Status = "completed"
 	session.CompletedAt = &now
 	session.LastUpdatedAt = now
 
-	// If there's an active game, mark it as completed
-	if session.GameID != "" {
-		game, err := s.gameRepo.FindByID(session.GameID)
-		if err == nil && game.Status == models.GameStatusActive {
-			game.Status = models.GameStatusCompleted
-			game.CompletedAt = &now
+	return s.closeGame(session, now)
+}
+
+// ExpireSession ends a rush run whose clock has run out. It does nothing for other sessions
+// or for a run that is already over.
+func (s *ServiceImpl) ExpireSession(session *Session) error {
+	if !session.IsRush() {
+		return nil
+	}
 
-			if game.StartedAt != nil {
-				duration := now.Sub(*game.StartedAt).Seconds()
-				game.Duration = &duration
-			}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return s.finishRush(session, "time_up")
+}
 
-			// Update game in database
-			err = s.gameRepo.Update(game)
-			if err != nil {
-				return err
-			}
+// Helper function to mark the active game of a session as completed
+func (s *ServiceImpl) closeGame(session *Session, now time.Time) error {
+	if session.GameID == "" {
+		return nil
+	}
+
+	game, err := s.gameRepo.FindByID(session.GameID)
+	if err == nil && game.Status == models.GameStatusActive {
+		game.Status = models.GameStatusCompleted
+		game.CompletedAt = &now
+
+		if game.StartedAt != nil {
+			duration := now.Sub(*game.StartedAt).Seconds()
+			game.Duration = &duration
+		}
+
+		// Update game in database
+		err = s.gameRepo.Update(game)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -0,0 +1,56 @@
+package puzzle
+
+import (
+	"errors"
+	"strings"
+)
+
+// CanonicalForm rewrites a solution the way the solver writes it, with as few parentheses as the
+// evaluator needs. Solutions that only differ in spacing or redundant grouping get the same form,
+// which matches the expression of the stored solution.
+func CanonicalForm(expression string) (string, error) {
+	// Accept the same notation as the solution validator
+	expression = strings.ReplaceAll(expression, " ", "")
+	expression = strings.ReplaceAll(expression, "×", "*")
+	expression = strings.ReplaceAll(expression, "÷", "/")
+
+	evaluator := NewExpressionEvaluator()
+	tokens, err := evaluator.tokenize(expression)
+	if err != nil {
+		return "", err
+	}
+
+	postfix, err := evaluator.infixToPostfix(tokens)
+	if err != nil {
+		return "", err
+	}
+
+	// Rebuild the expression tree from the postfix form, writing each operation as the solver does
+	stack := make([]solverTerm, 0, len(postfix))
+	for _, token := range postfix {
+		if token.Type == "number" {
+			stack = append(stack, solverTerm{text: token.Value, prec: precAtom})
+			continue
+		}
+
+		if len(stack) < 2 {
+			return "", errors.New("invalid expression")
+		}
+		left, right := stack[len(stack)-2], stack[len(stack)-1]
+		stack = stack[:len(stack)-2]
+
+		op := token.Type[0]
+		prec := operatorPrecedence(op)
+		stack = append(stack, solverTerm{
+			text: wrap(left, prec, false, op) + string(op) + wrap(right, prec, true, op),
+			op:   op,
+			prec: prec,
+		})
+	}
+
+	if len(stack) != 1 {
+		return "", errors.New("invalid expression")
+	}
+
+	return stack[0].text, nil
+}
@@ -0,0 +1,86 @@
+package puzzle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCanonicalForm(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+		wantErr    bool
+	}{
+		{name: "spacing", expression: " 1 + 2 *  3 ", want: "1+2*3"},
+		{name: "multiplication and division signs", expression: "2 × 3 ÷ 4", want: "2*3/4"},
+		{name: "redundant left grouping", expression: "(1+2)+3", want: "1+2+3"},
+		{name: "redundant right addition", expression: "1+(2+3)", want: "1+2+3"},
+		{name: "redundant right multiplication", expression: "1*(2*3)", want: "1*2*3"},
+		{name: "redundant product grouping", expression: "1+(2*3)", want: "1+2*3"},
+		{name: "nested parentheses", expression: "((12))+3", want: "12+3"},
+		{name: "needed for lower precedence", expression: "(1+2)*3", want: "(1+2)*3"},
+		{name: "needed after subtraction", expression: "1-(2+3)", want: "1-(2+3)"},
+		{name: "needed after subtraction of a difference", expression: "1-(2-3)", want: "1-(2-3)"},
+		{name: "needed after division", expression: "1/(2*3)", want: "1/(2*3)"},
+		{name: "right power", expression: "2^(3^2)", want: "2^(3^2)"},
+		{name: "left power", expression: "(2^3)^2", want: "(2^3)^2"},
+		{name: "dangling operator", expression: "1+", wantErr: true},
+		{name: "unclosed parenthesis", expression: "(1+2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalForm(tt.expression)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("CanonicalForm(%q) = %q, want an error", tt.expression, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CanonicalForm(%q) error: %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Errorf("CanonicalForm(%q) = %q, want %q", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveReturnsCanonicalSolutions(t *testing.T) {
+	solver := NewSolver()
+	evaluator := NewExpressionEvaluator()
+
+	for _, sequence := range []string{"123456", "987654", "111111", "358712"} {
+		solutions := solver.Solve(sequence)
+		if len(solutions) == 0 {
+			t.Errorf("Solve(%q) found no solutions", sequence)
+			continue
+		}
+
+		seen := make(map[string]bool, len(solutions))
+		for _, solution := range solutions {
+			canonical, err := CanonicalForm(solution)
+			if err != nil {
+				t.Errorf("CanonicalForm(%q) error: %v", solution, err)
+				continue
+			}
+			if canonical != solution {
+				t.Errorf("Solve(%q) returned %q, canonical form is %q", sequence, solution, canonical)
+			}
+			if seen[canonical] {
+				t.Errorf("Solve(%q) returned %q more than once", sequence, solution)
+			}
+			seen[canonical] = true
+
+			if value, err := evaluator.Evaluate(solution); err != nil || math.Abs(value-solverTarget) > 1e-9 {
+				t.Errorf("Solve(%q) returned %q = %v (%v), want %d", sequence, solution, value, err, solverTarget)
+			}
+		}
+	}
+
+	if solutions := solver.Solve("000000"); len(solutions) != 0 {
+		t.Errorf("Solve(%q) = %v, want no solutions", "000000", solutions)
+	}
+}
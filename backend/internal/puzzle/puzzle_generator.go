@@ -35,82 +35,7 @@ func (g *PuzzleGenerator) GenerateSequence() string {
 
 // GenerateSolutions generates all possible solutions for a sequence
 func (g *PuzzleGenerator) GenerateSolutions(sequence string) []string {
-	// Convert sequence to digits
-	digits := make([]int, len(sequence))
-	for i, c := range sequence {
-		digits[i] = int(c - '0')
-	}
-
-	// Generate all possible expressions
-	expressions := []string{}
-	g.backtrack(digits, 0, "", &expressions)
-
-	// Filter valid solutions (result = 100)
-	validSolutions := []string{}
-	for _, expr := range expressions {
-		result, err := g.evaluator.Evaluate(expr)
-		if err == nil && result == 100 {
-			validSolutions = append(validSolutions, expr)
-		}
-	}
-
-	return validSolutions
-}
-
-// Backtrack recursively generates all possible expressions
-func (g *PuzzleGenerator) backtrack(digits []int, index int, current string, results *[]string) {
-	if index == len(digits) {
-		// We've used all digits, evaluate the expression
-		if current != "" {
-			// Only add valid expressions (those that can be evaluated)
-			_, err := g.evaluator.Evaluate(current)
-			if err == nil {
-				*results = append(*results, current)
-			}
-		}
-		return
-	}
-
-	// Try using the current digit
-	digit := digits[index]
-	digitStr := fmt.Sprintf("%d", digit)
-
-	// If this is the first digit, just add it
-	if current == "" {
-		g.backtrack(digits, index+1, digitStr, results)
-		return
-	}
-
-	// Try adding the digit with different operators
-	operators := []string{"+", "-", "*", "/"}
-	for _, op := range operators {
-		// Add operator and digit
-		g.backtrack(digits, index+1, current+op+digitStr, results)
-
-		// Try with parentheses around the current expression
-		g.backtrack(digits, index+1, "("+current+")"+op+digitStr, results)
-
-		// Try with parentheses around the new term
-		g.backtrack(digits, index+1, current+op+"("+digitStr, results)
-
-		// Try with closing parenthesis
-		if strings.Count(current, "(") > strings.Count(current, ")") {
-			g.backtrack(digits, index+1, current+")"+op+digitStr, results)
-			g.backtrack(digits, index+1, current+op+digitStr+")", results)
-		}
-
-		// Try with opening parenthesis
-		g.backtrack(digits, index+1, "("+current+op+digitStr, results)
-		g.backtrack(digits, index+1, current+op+"("+digitStr, results)
-	}
-
-	// Try with just closing a parenthesis if there are unclosed ones
-	if strings.Count(current, "(") > strings.Count(current, ")") {
-		g.backtrack(digits, index, current+")", results)
-	}
-
-	// Try with just opening a new parenthesis
-	g.backtrack(digits, index, current+"(", results)
+	return NewSolver().Solve(sequence)
 }
 
 // FindOptimalSolution finds the most elegant solution among all valid solutions
@@ -2,9 +2,11 @@ package puzzle
 
 import (
 	"fmt"
+	"log"
 	"math"
 	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/hectoclash/internal/models"
@@ -12,6 +14,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// Puzzles without a solution count as expert puzzles: proving that nothing works is hard
+const unsolvableDifficulty = models.DifficultyExpert
+
 // Service provides puzzle functionality
 type Service struct {
 	puzzleRepo           *repository.PuzzleRepository
@@ -19,6 +24,8 @@ type Service struct {
 	cache                *PuzzleCache
 	solutionValidator     *SolutionValidator
 	solutionMetricsRepo  *repository.SolutionMetricsRepository
+	solutionsMu          sync.Mutex // Serializes adding solutions to stored puzzles
+	exposureRepo         *repository.PuzzleExposureRepository
 }
 
 // NewService creates a new puzzle service
@@ -49,6 +56,11 @@ func (s *Service) GeneratePuzzle() (*models.Puzzle, error) {
 	// Check if the puzzle already exists
 	existingPuzzle, err := s.puzzleRepo.FindBySequence(sequence)
 	if err == nil {
+		// Sequences without a solution are only served in the impossible variant
+		if existingPuzzle.Unsolvable {
+			return s.GeneratePuzzle()
+		}
+
 		// Puzzle already exists, return it
 		return existingPuzzle, nil
 	}
@@ -60,7 +72,8 @@ func (s *Service) GeneratePuzzle() (*models.Puzzle, error) {
 	}
 
 	if len(solutions) == 0 {
-		// No solutions found, try again with a new sequence
+		// No solutions found, keep the sequence for the impossible variant and try again with a new sequence
+		_ = s.saveUnsolvablePuzzle(sequence)
 		return s.GeneratePuzzle()
 	}
 
@@ -131,11 +144,72 @@ func (s *Service) GetPuzzle(id string) (*models.Puzzle, error) {
 	return puzzle, nil
 }
 
-// GetPuzzleForUser gets a puzzle suitable for a user's ELO rating
-func (s *Service) GetPuzzleForUser(userELO int) (*models.Puzzle, error) {
+// SetExposureRepository tracks the puzzles each user has been served, so that users get puzzles
+// they have not seen yet
+func (s *Service) SetExposureRepository(exposureRepo *repository.PuzzleExposureRepository) {
+	s.exposureRepo = exposureRepo
+}
+
+// Windows around a user's rating searched for a puzzle they have not seen, in order
+var unseenPuzzleWindows = []int{0, 100, 200, 400}
+
+// Number of new puzzles generated when a user has seen every stored puzzle near their rating
+const unseenPuzzleGenerated = 3
+
+// GetPuzzleForUser gets a puzzle suitable for a user's ELO rating that they have not been served
+// yet, and records that they have been now. Once they have seen every stored puzzle of their
+// rating, puzzles of nearby ratings are tried, then new puzzles are generated, and only then do
+// they get the puzzle of their rating they saw the longest time ago.
+func (s *Service) GetPuzzleForUser(userID string, userELO int) (*models.Puzzle, error) {
+	if s.exposureRepo == nil || userID == "" {
+		return s.GetPuzzleForELO(userELO)
+	}
+
+	puzzle, err := s.unseenPuzzleForUser(userID, userELO)
+	if err != nil {
+		return nil, err
+	}
+
+	s.MarkSeen([]string{userID}, puzzle.Sequence)
+	return puzzle, nil
+}
+
+// unseenPuzzleForUser follows the fallbacks of GetPuzzleForUser
+func (s *Service) unseenPuzzleForUser(userID string, userELO int) (*models.Puzzle, error) {
+	for _, window := range unseenPuzzleWindows {
+		puzzle, err := s.exposureRepo.GetRandomUnseenPuzzle(userID, userELO, window)
+		if err == nil {
+			return puzzle, nil
+		}
+	}
+
+	// Generating may hand back a stored puzzle, so check that it is new to the user as well
+	for i := 0; i < unseenPuzzleGenerated; i++ {
+		puzzle, err := s.GeneratePuzzle()
+		if err != nil {
+			return nil, err
+		}
+		seen, err := s.SeenBy([]string{userID}, []string{puzzle.Sequence})
+		if err != nil {
+			return nil, err
+		}
+		if !seen[puzzle.Sequence] {
+			return puzzle, nil
+		}
+	}
+
+	puzzle, err := s.exposureRepo.GetLeastRecentlySeenPuzzle(userID, userELO)
+	if err == nil {
+		return puzzle, nil
+	}
+	return s.GetPuzzleForELO(userELO)
+}
+
+// GetPuzzleForELO gets a puzzle suitable for an ELO rating, whoever it is for
+func (s *Service) GetPuzzleForELO(userELO int) (*models.Puzzle, error) {
 	// Try to get a puzzle from cache first
 	puzzle := s.cache.GetByELO(userELO)
-	if puzzle != nil {
+	if puzzle != nil && !puzzle.Unsolvable {
 		return puzzle, nil
 	}
 
@@ -158,6 +232,189 @@ func (s *Service) GetPuzzleForUser(userELO int) (*models.Puzzle, error) {
 	return puzzle, nil
 }
 
+// SeenBy finds which of some puzzle sequences any of a group of users has been served. Nothing
+// counts as seen when exposures are not tracked.
+func (s *Service) SeenBy(userIDs, sequences []string) (map[string]bool, error) {
+	if s.exposureRepo == nil {
+		return map[string]bool{}, nil
+	}
+	return s.exposureRepo.FindSeen(userIDs, sequences)
+}
+
+// MarkSeen records that users have been served a puzzle. Failing to record it never gets in the
+// way of serving the puzzle.
+func (s *Service) MarkSeen(userIDs []string, sequence string) {
+	if s.exposureRepo == nil {
+		return
+	}
+	if err := s.exposureRepo.Record(userIDs, sequence, time.Now()); err != nil {
+		log.Printf("Failed to record puzzle %s as seen by %d users: %v", sequence, len(userIDs), err)
+	}
+}
+
+// GetUnsolvablePuzzle gets a stored puzzle that has no solution, for the impossible variant
+func (s *Service) GetUnsolvablePuzzle() (*models.Puzzle, error) {
+	return s.puzzleRepo.GetRandomUnsolvablePuzzle()
+}
+
+// PreGenerateUnsolvablePuzzles searches random sequences until the given number of puzzles without a
+// solution is stored. Only about one sequence in a few hundred has no solution, so this is slow.
+func (s *Service) PreGenerateUnsolvablePuzzles(count int) error {
+	existing, err := s.puzzleRepo.CountUnsolvablePuzzles()
+	if err != nil {
+		return err
+	}
+
+	solver := NewSolver()
+	for needed := count - int(existing); needed > 0; {
+		sequence := s.generateRandomSequence()
+		if solver.HasSolution(sequence) {
+			continue
+		}
+
+		// Skip sequences that are already stored
+		if _, err := s.puzzleRepo.FindBySequence(sequence); err == nil {
+			continue
+		}
+
+		err = s.saveUnsolvablePuzzle(sequence)
+		if err != nil {
+			return err
+		}
+		needed--
+	}
+
+	return nil
+}
+
+// GetSimilarPuzzle gets a puzzle of about the given difficulty that differs from the given sequence.
+// Puzzles that any of the given users has been served are avoided, unless nothing else can be found.
+func (s *Service) GetSimilarPuzzle(difficulty models.DifficultyLevel, excludeSequence string, userIDs []string) (*models.Puzzle, error) {
+	// A stored puzzle of the difficulty that the users have seen, in case there is nothing else
+	var repeat *models.Puzzle
+
+	// Try stored puzzles of the same difficulty first
+	for attempt := 0; attempt < 3; attempt++ {
+		puzzle, err := s.puzzleRepo.GetRandomPuzzleByDifficulty(difficulty)
+		if err != nil {
+			break
+		}
+		if puzzle.Sequence == excludeSequence {
+			continue
+		}
+		seen, err := s.SeenBy(userIDs, []string{puzzle.Sequence})
+		if err != nil {
+			return nil, err
+		}
+		if !seen[puzzle.Sequence] {
+			s.cache.Set(puzzle)
+			return puzzle, nil
+		}
+		repeat = puzzle
+	}
+
+	// Otherwise generate puzzles until one is within a level of the requested difficulty
+	var fallback *models.Puzzle
+	for attempt := 0; attempt < 5; attempt++ {
+		puzzle, err := s.GeneratePuzzle()
+		if err != nil {
+			return nil, err
+		}
+		if puzzle.Sequence == excludeSequence {
+			continue
+		}
+		seen, err := s.SeenBy(userIDs, []string{puzzle.Sequence})
+		if err != nil {
+			return nil, err
+		}
+		if seen[puzzle.Sequence] {
+			continue
+		}
+		if math.Abs(float64(puzzle.Difficulty-difficulty)) <= 1 {
+			s.cache.Set(puzzle)
+			return puzzle, nil
+		}
+		fallback = puzzle
+	}
+
+	if fallback == nil {
+		fallback = repeat
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("no puzzle found for difficulty %d", difficulty)
+	}
+
+	s.cache.Set(fallback)
+	return fallback, nil
+}
+
+// GetSolutions gets the stored solutions of a puzzle. A puzzle stored without them is solved first.
+func (s *Service) GetSolutions(puzzle *models.Puzzle) ([]models.PuzzleSolution, error) {
+	s.solutionsMu.Lock()
+	defer s.solutionsMu.Unlock()
+
+	solutions, err := s.puzzleRepo.GetSolutions(puzzle.ID)
+	if err != nil || len(solutions) > 0 {
+		return solutions, err
+	}
+
+	// Solve the puzzle and store its solutions
+	for _, expression := range NewSolver().Solve(puzzle.Sequence) {
+		solution := models.PuzzleSolution{
+			PuzzleID:   puzzle.ID,
+			Expression: expression,
+			Complexity: s.calculateSolutionComplexity(expression),
+			IsOptimal:  expression == puzzle.OptimalSolution,
+		}
+		err = s.puzzleRepo.CreateSolution(&solution)
+		if err != nil {
+			return nil, err
+		}
+		solutions = append(solutions, solution)
+	}
+
+	return solutions, nil
+}
+
+// AddSolution stores a correct solution the solver did not find, such as one whose steps go beyond
+// the solver's bounds. The expression must be in canonical form. An expression that is already
+// stored is returned as it is.
+func (s *Service) AddSolution(puzzle *models.Puzzle, expression string) (*models.PuzzleSolution, error) {
+	s.solutionsMu.Lock()
+	defer s.solutionsMu.Unlock()
+
+	// Check if another player stored it first
+	solutions, err := s.puzzleRepo.GetSolutions(puzzle.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range solutions {
+		if solutions[i].Expression == expression {
+			return &solutions[i], nil
+		}
+	}
+
+	solution := &models.PuzzleSolution{
+		PuzzleID:   puzzle.ID,
+		Expression: expression,
+		Complexity: s.calculateSolutionComplexity(expression),
+	}
+	err = s.puzzleRepo.CreateSolution(solution)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the puzzle's count in step
+	puzzle.SolutionCount = len(solutions) + 1
+	err = s.puzzleRepo.Update(puzzle)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.Set(puzzle)
+
+	return solution, nil
+}
+
 // ValidateSolution validates a solution for a puzzle
 func (s *Service) ValidateSolution(puzzleID, solution string, userID string) (*ValidationResult, error) {
 	// Get the puzzle (using cache if available)
@@ -166,14 +423,14 @@ func (s *Service) ValidateSolution(puzzleID, solution string, userID string) (*V
 		return nil, err
 	}
 
-	// Get user's rating
-	user, err := s.userRepo.FindByID(userID)
+	// Get user's practice rating
+	practiceRating, err := s.userRepo.GetUserRating(userID, models.RatingModePractice)
 	if err != nil {
 		return nil, err
 	}
 
 	// Validate the solution
-	validationResult := s.solutionValidator.ValidateSolution(puzzle, solution, user.Rating)
+	validationResult := s.solutionValidator.ValidateSolution(puzzle, solution, practiceRating.Rating)
 
 	// If the solution is correct, update stats in the background
 	if validationResult.IsCorrect {
@@ -197,6 +454,33 @@ func (s *Service) ValidateSolution(puzzleID, solution string, userID string) (*V
 	return &validationResult, nil
 }
 
+// CheckImpossibleClaim checks a claim that a puzzle sequence has no solution, without updating any stats
+func (s *Service) CheckImpossibleClaim(sequence string, difficulty models.DifficultyLevel, playerRating int) *ValidationResult {
+	// The sequence identifies the puzzle in the validator's cache
+	puzzle := &models.Puzzle{
+		ID:         "sequence:" + sequence,
+		Sequence:   sequence,
+		Difficulty: difficulty,
+	}
+
+	result := s.solutionValidator.ValidateImpossibleClaim(puzzle, playerRating)
+	return &result
+}
+
+// CheckSolution validates a solution against a puzzle sequence without updating any stats.
+// Games use it because they keep their own player stats and ratings.
+func (s *Service) CheckSolution(sequence string, difficulty models.DifficultyLevel, solution string, playerRating int) *ValidationResult {
+	// The sequence identifies the puzzle in the validator's cache
+	puzzle := &models.Puzzle{
+		ID:         "sequence:" + sequence,
+		Sequence:   sequence,
+		Difficulty: difficulty,
+	}
+
+	result := s.solutionValidator.ValidateSolution(puzzle, solution, playerRating)
+	return &result
+}
+
 // Helper function to update user stats after a successful solution
 func (s *Service) updateUserStats(userID string, result ValidationResult) error {
 	// Get user stats
@@ -209,13 +493,13 @@ func (s *Service) updateUserStats(userID string, result ValidationResult) error
 	stats.GamesPlayed++
 	stats.GamesWon++
 
-	// Update rating
-	user, err := s.userRepo.FindByID(userID)
+	// Update practice rating
+	practiceRating, err := s.userRepo.GetUserRating(userID, models.RatingModePractice)
 	if err != nil {
 		return err
 	}
-	user.Rating += result.RatingChange
-	stats.Rating = user.Rating
+	oldRating := practiceRating.Rating
+	practiceRating.Rating += result.RatingChange
 
 	// Update streak
 	now := time.Now()
@@ -229,12 +513,20 @@ func (s *Service) updateUserStats(userID string, result ValidationResult) error
 		stats.AvgSolveTime = (stats.AvgSolveTime*float64(stats.GamesPlayed-1) + solveTime) / float64(stats.GamesPlayed)
 	}
 
-	// Save user and stats
-	err = s.userRepo.Update(user)
+	// Save rating and stats
+	err = s.userRepo.SaveUserRating(practiceRating)
 	if err != nil {
 		return err
 	}
 
+	// Record the change
+	if result.RatingChange != 0 {
+		err = s.userRepo.AddRatingHistory(userID, nil, models.RatingModePractice, models.RatingSourcePuzzle, oldRating, practiceRating.Rating)
+		if err != nil {
+			return err
+		}
+	}
+
 	return s.userRepo.UpdateUserStats(stats)
 }
 
@@ -356,6 +648,19 @@ func (s *Service) UpdatePuzzleStats(puzzleID string, solveTime float64, isCorrec
 	return s.puzzleRepo.UpdatePuzzleStats(puzzleID, solveTime, isCorrect)
 }
 
+// Helper function to store a sequence that has no solution
+func (s *Service) saveUnsolvablePuzzle(sequence string) error {
+	puzzle := &models.Puzzle{
+		Sequence:    sequence,
+		Difficulty:  unsolvableDifficulty,
+		Explanation: "This sequence has no solution",
+		MinELO:      s.calculateMinELO(unsolvableDifficulty),
+		MaxELO:      s.calculateMaxELO(unsolvableDifficulty),
+		Unsolvable:  true,
+	}
+	return s.puzzleRepo.Create(puzzle)
+}
+
 // Helper function to generate a random 6-digit sequence
 func (s *Service) generateRandomSequence() string {
 	// Create a local random generator with a random source
@@ -688,14 +993,14 @@ func (s *Service) GetPuzzleDifficultyRating(difficulty models.DifficultyLevel) i
 	}
 }
 
-// GetUserELO gets a user's ELO rating from the repository
+// GetUserELO gets a user's practice rating from the repository
 func (s *Service) GetUserELO(userID string) (int, error) {
-	// Get the user from the repository
-	user, err := s.userRepo.FindByID(userID)
+	// Get the user's practice rating from the repository
+	practiceRating, err := s.userRepo.GetUserRating(userID, models.RatingModePractice)
 	if err != nil {
 		return 1000, err // Return default rating if user not found
 	}
 
 	// Return the user's rating
-	return user.Rating, nil
+	return practiceRating.Rating, nil
 }
@@ -38,9 +38,16 @@ type SolutionMetric struct {
 	ExecutionTime    float64 // Time to validate in milliseconds
 }
 
+// Points lost for a wrong impossible claim, per level of the puzzle's difficulty
+const wrongClaimPenaltyPerLevel = 50
+
+// ImpossibleClaim is submitted instead of a solution to claim that a puzzle has no solution
+const ImpossibleClaim = "impossible"
+
 // SolutionValidator validates solutions for Hectoc puzzles
 type SolutionValidator struct {
 	evaluator      *ExpressionEvaluator
+	solver         *Solver
 	resultCache    map[string]ValidationResult
 	cacheMutex     sync.RWMutex
 	cacheMaxSize   int
@@ -52,6 +59,7 @@ type SolutionValidator struct {
 func NewSolutionValidator() *SolutionValidator {
 	return &SolutionValidator{
 		evaluator:      NewExpressionEvaluator(),
+		solver:         NewSolver(),
 		resultCache:    make(map[string]ValidationResult),
 		cacheMutex:     sync.RWMutex{},
 		cacheMaxSize:   1000,
@@ -170,6 +178,64 @@ func (v *SolutionValidator) ValidateSolution(puzzle *models.Puzzle, solution str
 	return result
 }
 
+// ValidateImpossibleClaim checks a claim that a puzzle has no solution by searching every solution with the solver.
+// A correct claim scores like a solution. A wrong claim scores a penalty and costs rating as if the puzzle was failed.
+func (v *SolutionValidator) ValidateImpossibleClaim(puzzle *models.Puzzle, playerRating int) ValidationResult {
+	// Start timing the validation
+	startTime := time.Now()
+
+	// Check cache first, since the search is slow. Claims have their own keys, so they never
+	// meet a cached solution.
+	cacheKey := fmt.Sprintf("impossible-claim:%s", puzzle.ID)
+	result, found := v.getCachedResult(cacheKey)
+	if !found {
+		result = v.checkImpossibleClaim(puzzle)
+		v.cacheResult(cacheKey, result)
+	}
+
+	// The rating change depends on the claimant, so it is never cached
+	if result.IsCorrect {
+		result.RatingChange = v.calculateRatingChange(playerRating, int(puzzle.Difficulty), float64(time.Since(startTime).Milliseconds())/1000.0)
+	} else {
+		result.RatingChange = v.calculateRatingLoss(playerRating, int(puzzle.Difficulty))
+	}
+
+	result.ExecutionTime = float64(time.Since(startTime).Microseconds()) / 1000.0
+
+	return result
+}
+
+// Helper function to search a puzzle for solutions and score a claim that it has none,
+// without the rating change of the claimant
+func (v *SolutionValidator) checkImpossibleClaim(puzzle *models.Puzzle) ValidationResult {
+	result := ValidationResult{
+		IsCorrect: false,
+		Steps:     []ValidationStep{},
+	}
+
+	// Search every way to reach 100
+	solutions := v.solver.Solve(puzzle.Sequence)
+	if len(solutions) > 0 {
+		result.Steps = append(result.Steps, ValidationStep{
+			Description: "Check if the puzzle has no solution",
+			Result:      fmt.Sprintf("The puzzle has %d solutions", len(solutions)),
+			IsSuccess:   false,
+		})
+		result.ErrorMessage = fmt.Sprintf("The puzzle can be solved, for example %s", solutions[0])
+		result.Score = -int(puzzle.Difficulty) * wrongClaimPenaltyPerLevel
+	} else {
+		result.Steps = append(result.Steps, ValidationStep{
+			Description: "Check if the puzzle has no solution",
+			Result:      "The puzzle has no solution",
+			IsSuccess:   true,
+		})
+		result.IsCorrect = true
+		result.Score = int(puzzle.Difficulty) * 100
+	}
+
+	return result
+}
+
 // Helper function to clean a solution
 func (v *SolutionValidator) cleanSolution(solution string) string {
 	// Remove all whitespace
@@ -276,6 +342,18 @@ func (v *SolutionValidator) calculateRatingChange(playerRating, puzzleDifficulty
 	return ratingChange
 }
 
+// Helper function to calculate the rating lost by failing a puzzle
+func (v *SolutionValidator) calculateRatingLoss(playerRating, puzzleDifficulty int) int {
+	// Convert difficulty to ELO rating
+	puzzleRating := 800 + (puzzleDifficulty-1)*400
+
+	// A loss against the puzzle, with the same K-factor as a solve
+	k := 32
+	expectedScore := 1.0 / (1.0 + math.Pow(10, float64(puzzleRating-playerRating)/400.0))
+
+	return -int(math.Round(float64(k) * expectedScore))
+}
+
 // Helper function to get a cached validation result
 func (v *SolutionValidator) getCachedResult(key string) (ValidationResult, bool) {
 	v.cacheMutex.RLock()
@@ -0,0 +1,37 @@
+package puzzle
+
+import (
+	"testing"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestValidateImpossibleClaimRatesEachClaimant(t *testing.T) {
+	v := NewSolutionValidator()
+	puzzleObj := &models.Puzzle{ID: "claim-test", Sequence: "123456", Difficulty: models.DifficultyLevel(3)}
+
+	// A literal "impossible" solution must not answer the claim from the cache
+	if result := v.ValidateSolution(puzzleObj, ImpossibleClaim, 1200); result.IsCorrect {
+		t.Fatal("ValidateSolution() accepted the word impossible as a solution")
+	}
+
+	weak := v.ValidateImpossibleClaim(puzzleObj, 800)
+	strong := v.ValidateImpossibleClaim(puzzleObj, 2000)
+	if weak.IsCorrect || strong.IsCorrect {
+		t.Fatal("ValidateImpossibleClaim() accepted a claim against a solvable puzzle")
+	}
+	if weak.Score != strong.Score {
+		t.Errorf("claim scores = %d and %d, want the same penalty", weak.Score, strong.Score)
+	}
+
+	// The second claim comes from the cache but is still rated for its own claimant
+	if want := v.calculateRatingLoss(800, 3); weak.RatingChange != want {
+		t.Errorf("rating change at 800 = %d, want %d", weak.RatingChange, want)
+	}
+	if want := v.calculateRatingLoss(2000, 3); strong.RatingChange != want {
+		t.Errorf("rating change at 2000 = %d, want %d", strong.RatingChange, want)
+	}
+	if weak.RatingChange == strong.RatingChange {
+		t.Errorf("both claimants lost %d rating, want a loss that depends on their rating", weak.RatingChange)
+	}
+}
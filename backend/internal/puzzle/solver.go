@@ -0,0 +1,235 @@
+package puzzle
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Target every Hectoc solution must reach
+const solverTarget = 100
+
+// Bounds that keep exact arithmetic within int64 and prune values no solution needs
+const (
+	maxSolverMagnitude = 1_000_000
+	maxSolverExponent  = 6
+)
+
+// Operator precedences, matching the expression evaluator
+const (
+	precAdd = iota + 1
+	precMul
+	precPow
+	precAtom
+)
+
+// Solver finds every solution of a Hectoc sequence.
+// Digits may be joined into numbers and combined with +, -, *, / and ^, with any grouping.
+type Solver struct {
+	evaluator *ExpressionEvaluator
+}
+
+// solverTerm is an expression over a run of digits together with its exact value
+type solverTerm struct {
+	num, den int64 // Value as a reduced fraction with a positive denominator
+	text     string
+	op       byte // Outermost operator, or 0 for a number
+	prec     int
+}
+
+// NewSolver creates a new solver
+func NewSolver() *Solver {
+	return &Solver{
+		evaluator: NewExpressionEvaluator(),
+	}
+}
+
+// Solve finds the distinct solutions of a sequence, simplest first.
+// Solutions are written with as few parentheses as the evaluator needs, so expressions
+// that only differ in redundant grouping are reported once.
+func (s *Solver) Solve(sequence string) []string {
+	if sequence == "" {
+		return nil
+	}
+	for _, c := range sequence {
+		if c < '0' || c > '9' {
+			return nil
+		}
+	}
+
+	n := len(sequence)
+	memo := make(map[[2]int][]solverTerm)
+
+	var terms func(i, j int) []solverTerm
+	terms = func(i, j int) []solverTerm {
+		key := [2]int{i, j}
+		if cached, ok := memo[key]; ok {
+			return cached
+		}
+
+		seen := make(map[string]bool)
+		result := make([]solverTerm, 0)
+		add := func(t solverTerm) {
+			if !seen[t.text] {
+				seen[t.text] = true
+				result = append(result, t)
+			}
+		}
+
+		// The digits joined into one number
+		value, err := strconv.ParseInt(sequence[i:j], 10, 64)
+		if err == nil && value <= maxSolverMagnitude {
+			add(solverTerm{num: value, den: 1, text: sequence[i:j], prec: precAtom})
+		}
+
+		// Every split into a left and a right part
+		for k := i + 1; k < j; k++ {
+			for _, left := range terms(i, k) {
+				for _, right := range terms(k, j) {
+					for _, op := range []byte{'+', '-', '*', '/', '^'} {
+						if t, ok := combine(left, right, op); ok {
+							add(t)
+						}
+					}
+				}
+			}
+		}
+
+		memo[key] = result
+		return result
+	}
+
+	solutions := make([]string, 0)
+	for _, t := range terms(0, n) {
+		if t.den == 1 && t.num == solverTarget {
+			// Make sure the evaluator agrees, since it decides whether a submission is correct
+			value, err := s.evaluator.Evaluate(t.text)
+			if err == nil && value == solverTarget {
+				solutions = append(solutions, t.text)
+			}
+		}
+	}
+
+	// Simplest first: shortest, then alphabetical for a stable order
+	sort.Slice(solutions, func(a, b int) bool {
+		if len(solutions[a]) != len(solutions[b]) {
+			return len(solutions[a]) < len(solutions[b])
+		}
+		return solutions[a] < solutions[b]
+	})
+
+	return solutions
+}
+
+// HasSolution checks if a sequence can be solved at all
+func (s *Solver) HasSolution(sequence string) bool {
+	return len(s.Solve(sequence)) > 0
+}
+
+// Helper function to combine two terms with an operator, reporting false if the result is not usable
+func combine(left, right solverTerm, op byte) (solverTerm, bool) {
+	var num, den int64
+
+	switch op {
+	case '+':
+		num, den = left.num*right.den+right.num*left.den, left.den*right.den
+	case '-':
+		num, den = left.num*right.den-right.num*left.den, left.den*right.den
+	case '*':
+		num, den = left.num*right.num, left.den*right.den
+	case '/':
+		if right.num == 0 {
+			return solverTerm{}, false
+		}
+		num, den = left.num*right.den, left.den*right.num
+	case '^':
+		// The evaluator only raises to whole, non-negative powers
+		if right.den != 1 || right.num < 0 || right.num > maxSolverExponent {
+			return solverTerm{}, false
+		}
+		num, den = 1, 1
+		for e := int64(0); e < right.num; e++ {
+			num *= left.num
+			den *= left.den
+			if abs64(num) > maxSolverMagnitude*maxSolverMagnitude || den > maxSolverMagnitude*maxSolverMagnitude {
+				return solverTerm{}, false
+			}
+		}
+	}
+
+	num, den = reduce(num, den)
+	if abs64(num) > maxSolverMagnitude*den || den > maxSolverMagnitude {
+		return solverTerm{}, false
+	}
+
+	prec := operatorPrecedence(op)
+
+	return solverTerm{
+		num:  num,
+		den:  den,
+		text: wrap(left, prec, false, op) + string(op) + wrap(right, prec, true, op),
+		op:   op,
+		prec: prec,
+	}, true
+}
+
+// Helper function to get the precedence of an operator
+func operatorPrecedence(op byte) int {
+	switch op {
+	case '*', '/':
+		return precMul
+	case '^':
+		return precPow
+	}
+	return precAdd
+}
+
+// Helper function to parenthesize an operand only where the evaluator needs it.
+// The evaluator is left-associative for every operator, including ^.
+func wrap(t solverTerm, parentPrec int, isRight bool, parentOp byte) string {
+	needsParens := t.prec < parentPrec
+	if isRight && t.prec == parentPrec {
+		// a-(b-c), a/(b*c) and a^(b^c) change meaning without parentheses; a+(b+c) and a*(b*c) do not
+		needsParens = parentOp == '-' || parentOp == '/' || parentOp == '^' ||
+			(parentOp == '+' && t.op == '-') || (parentOp == '*' && t.op == '/')
+	}
+	if parentOp == '^' && t.prec != precAtom {
+		// Keep powers unambiguous for readers as well
+		needsParens = true
+	}
+
+	if needsParens {
+		return "(" + t.text + ")"
+	}
+	return t.text
+}
+
+// Helper function to reduce a fraction and give it a positive denominator
+func reduce(num, den int64) (int64, int64) {
+	if den < 0 {
+		num, den = -num, -den
+	}
+	g := gcd(abs64(num), den)
+	if g > 1 {
+		num, den = num/g, den/g
+	}
+	return num, den
+}
+
+// Helper function to get the greatest common divisor of two non-negative numbers
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+// Helper function to get the absolute value of a number
+func abs64(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
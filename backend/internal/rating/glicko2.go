@@ -0,0 +1,149 @@
+package rating
+
+import (
+	"math"
+)
+
+// Glicko-2 constants
+const (
+	// Ratings are stored on the Glicko scale and converted to the Glicko-2 scale around this value
+	glickoCenter = 1500.0
+	glickoScale  = 173.7178
+
+	// System constant constraining the change in volatility over time
+	tau = 0.5
+
+	// Convergence tolerance of the volatility iteration
+	epsilon = 0.000001
+
+	// Rating deviation and volatility of players without rated games
+	DefaultDeviation  = 350.0
+	DefaultVolatility = 0.06
+
+	// Rating deviation never grows past that of a new player, nor shrinks below this floor
+	MinDeviation = 30.0
+)
+
+// Rating is a player's Glicko-2 rating on the Glicko scale
+type Rating struct {
+	Rating     float64
+	Deviation  float64
+	Volatility float64
+}
+
+// Result is the outcome of a game against one opponent
+type Result struct {
+	Opponent Rating
+	Score    float64 // 1 for a win, 0.5 for a draw, 0 for a loss
+}
+
+// Update applies the results of one rating period to a rating
+func Update(player Rating, results []Result) Rating {
+	mu, phi := toGlicko2(player)
+	sigma := player.Volatility
+
+	// A player without games only becomes less certain
+	if len(results) == 0 {
+		return fromGlicko2(mu, math.Sqrt(phi*phi+sigma*sigma), sigma)
+	}
+
+	// Estimated variance and improvement from the game outcomes
+	var varianceInv, improvement float64
+	for _, result := range results {
+		muJ, phiJ := toGlicko2(result.Opponent)
+		g := gFactor(phiJ)
+		e := expectedScore(mu, muJ, phiJ)
+		varianceInv += g * g * e * (1 - e)
+		improvement += g * (result.Score - e)
+	}
+	v := 1 / varianceInv
+	delta := v * improvement
+
+	// New volatility
+	sigma = newVolatility(phi, sigma, v, delta)
+
+	// New rating deviation and rating
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu = mu + phi*phi*improvement
+
+	return fromGlicko2(mu, phi, sigma)
+}
+
+// Decay increases the rating deviation of a player who has not played for a number of rating periods
+func Decay(player Rating, periods float64) Rating {
+	if periods <= 0 {
+		return player
+	}
+
+	mu, phi := toGlicko2(player)
+	sigma := player.Volatility
+
+	return fromGlicko2(mu, math.Sqrt(phi*phi+periods*sigma*sigma), sigma)
+}
+
+// newVolatility finds the new volatility with the Illinois algorithm (step 5 of the Glicko-2 paper)
+func newVolatility(phi, sigma, v, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		num := ex * (delta*delta - phi*phi - v - ex)
+		den := 2 * math.Pow(phi*phi+v+ex, 2)
+		return num/den - (x-a)/(tau*tau)
+	}
+
+	// Initial bracket
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	// Narrow the bracket until it converges
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
+
+// Helper function to convert a rating to the Glicko-2 scale
+func toGlicko2(r Rating) (float64, float64) {
+	return (r.Rating - glickoCenter) / glickoScale, r.Deviation / glickoScale
+}
+
+// Helper function to convert a rating back from the Glicko-2 scale
+func fromGlicko2(mu, phi, sigma float64) Rating {
+	deviation := phi * glickoScale
+	deviation = math.Max(MinDeviation, math.Min(DefaultDeviation, deviation))
+
+	return Rating{
+		Rating:     mu*glickoScale + glickoCenter,
+		Deviation:  deviation,
+		Volatility: sigma,
+	}
+}
+
+// Helper function to weigh an opponent by their rating deviation
+func gFactor(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// Helper function to calculate the expected score on the Glicko-2 scale
+func expectedScore(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-gFactor(phiJ)*(mu-muJ)))
+}
@@ -0,0 +1,459 @@
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+)
+
+const (
+	// Length of a rating period; a player's deviation grows for every period they sit out
+	ratingPeriod = 7 * 24 * time.Hour
+
+	// Ratings stay provisional until a player has this many rated games and a settled deviation
+	provisionalGames     = 10
+	provisionalDeviation = 110.0
+)
+
+// Time buckets of the rating history
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+)
+
+// Service applies head-to-head ratings when games complete
+type Service struct {
+	userRepo *repository.UserRepository
+	gameRepo *repository.GameRepository
+}
+
+// NewService creates a new rating service
+func NewService(userRepo *repository.UserRepository, gameRepo *repository.GameRepository, gameService *game.Service) *Service {
+	service := &Service{
+		userRepo: userRepo,
+		gameRepo: gameRepo,
+	}
+
+	// Rate games as they complete
+	gameService.OnGameCompleted(service.handleGameCompleted)
+
+	return service
+}
+
+// RateGame updates the ratings of every player of a completed game. A game is only ever rated once.
+func (s *Service) RateGame(gameID string) error {
+	// Find game by ID
+	g, err := s.gameRepo.FindByID(gameID)
+	if err != nil {
+		return err
+	}
+
+	if !g.IsRated() {
+		return nil
+	}
+	if g.Status != models.GameStatusCompleted {
+		return errors.New("game has not completed")
+	}
+	if len(g.Players) < 2 {
+		return nil
+	}
+
+	now := time.Now()
+	if g.CompletedAt != nil {
+		now = *g.CompletedAt
+	}
+
+	// The claim and every new rating are saved together, so a failure leaves the game unrated
+	// rather than rated for some of its players
+	return s.gameRepo.Transaction(func(games *repository.GameRepository, users *repository.UserRepository) error {
+		// Claim the game so concurrent completion notifications cannot rate it twice
+		claimed, err := games.MarkRated(gameID)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return nil
+		}
+
+		// Lock every player's rating in the game's mode, in the same order in every game so that
+		// games sharing players cannot deadlock
+		order := make([]int, len(g.Players))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return g.Players[order[a]].UserID < g.Players[order[b]].UserID })
+
+		mode := models.RatingModeForGameType(g.GameType)
+		ratings := make([]*models.UserRating, len(g.Players))
+		before := make([]Rating, len(g.Players))
+		for _, i := range order {
+			userRating, err := users.LockUserRating(g.Players[i].UserID, mode)
+			if err != nil {
+				return err
+			}
+			ratings[i] = userRating
+			before[i] = currentRating(userRating, now)
+		}
+
+		for i := range g.Players {
+			results, rated := playerResults(g, before, i)
+			if !rated {
+				continue
+			}
+
+			after := Update(before[i], results)
+			if err := saveRating(games, users, ratings[i], &g.Players[i], g.GameType, after, now); err != nil {
+				return fmt.Errorf("failed to save rating for user %s: %w", ratings[i].UserID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetHistory gets a user's rating series in a game mode grouped into time buckets
+func (s *Service) GetHistory(userID string, mode models.RatingMode, bucket string, from, to *time.Time) ([]models.RatingBucket, error) {
+	if bucket == "" {
+		bucket = BucketDay
+	}
+	if bucket != BucketDay && bucket != BucketWeek && bucket != BucketMonth {
+		return nil, errors.New("bucket must be day, week or month")
+	}
+
+	// Check if user exists
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.userRepo.GetRatingHistory(userID, mode, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := make([]models.RatingBucket, 0)
+	for _, entry := range history {
+		start := bucketStart(entry.CreatedAt, bucket)
+
+		// Start a new bucket
+		if len(buckets) == 0 || !buckets[len(buckets)-1].Start.Equal(start) {
+			buckets = append(buckets, models.RatingBucket{
+				Start: start,
+				Open:  entry.RatingBefore,
+				High:  entry.RatingBefore,
+				Low:   entry.RatingBefore,
+			})
+		}
+
+		current := &buckets[len(buckets)-1]
+		current.Close = entry.RatingAfter
+		current.Changes++
+		if entry.RatingAfter > current.High {
+			current.High = entry.RatingAfter
+		}
+		if entry.RatingAfter < current.Low {
+			current.Low = entry.RatingAfter
+		}
+	}
+
+	return buckets, nil
+}
+
+// GetStats gets a user's current, peak and lowest rating in a game mode
+func (s *Service) GetStats(userID string, mode models.RatingMode) (*models.RatingStats, error) {
+	// Check if user exists
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	userRating, err := s.userRepo.GetUserRating(userID, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &models.RatingStats{
+		UserID:  userID,
+		Mode:    mode,
+		Current: userRating.Rating,
+		Peak:    userRating.Rating,
+		Lowest:  userRating.Rating,
+	}
+
+	changes, err := s.userRepo.CountRatingChanges(userID, mode)
+	if err != nil {
+		return nil, err
+	}
+	stats.Changes = changes
+
+	// Without any changes the current rating is all there is
+	if changes == 0 {
+		return stats, nil
+	}
+
+	peak, err := s.userRepo.FindRatingExtreme(userID, mode, true)
+	if err != nil {
+		return nil, err
+	}
+	lowest, err := s.userRepo.FindRatingExtreme(userID, mode, false)
+	if err != nil {
+		return nil, err
+	}
+	stats.Peak, stats.PeakAt = peak.RatingAfter, &peak.CreatedAt
+	stats.Lowest, stats.LowestAt = lowest.RatingAfter, &lowest.CreatedAt
+
+	// The starting rating counts too, but has no time of its own
+	first, err := s.userRepo.FindFirstRatingChange(userID, mode)
+	if err != nil {
+		return nil, err
+	}
+	if first.RatingBefore > stats.Peak {
+		stats.Peak, stats.PeakAt = first.RatingBefore, nil
+	}
+	if first.RatingBefore < stats.Lowest {
+		stats.Lowest, stats.LowestAt = first.RatingBefore, nil
+	}
+
+	return stats, nil
+}
+
+// GetRatings gets a user's rating in every game mode
+func (s *Service) GetRatings(userID string) ([]models.UserRating, error) {
+	// Check if user exists
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	ratings := make([]models.UserRating, 0, len(models.RatingModes))
+	for _, mode := range models.RatingModes {
+		userRating, err := s.userRepo.GetUserRating(userID, mode)
+		if err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, *userRating)
+	}
+
+	return ratings, nil
+}
+
+// GetLeaderboard gets the top rated players of a game mode
+func (s *Service) GetLeaderboard(mode models.RatingMode, limit, offset int) ([]models.RatingLeaderboardEntry, error) {
+	ratings, err := s.userRepo.GetTopPlayersByMode(mode, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.RatingLeaderboardEntry, len(ratings))
+	for i, r := range ratings {
+		entries[i] = models.RatingLeaderboardEntry{
+			Rank:            offset + i + 1,
+			UserID:          r.UserID,
+			Username:        r.User.Username,
+			Mode:            r.Mode,
+			Rating:          r.Rating,
+			RatingDeviation: r.RatingDeviation,
+			RatedGames:      r.RatedGames,
+			Provisional:     r.Provisional,
+		}
+	}
+
+	return entries, nil
+}
+
+// handleGameCompleted rates a game once it has completed
+func (s *Service) handleGameCompleted(g *models.Game) {
+	if !g.IsRated() || g.Status != models.GameStatusCompleted {
+		return
+	}
+
+	if err := s.RateGame(g.ID); err != nil {
+		log.Printf("Error rating game %s: %v", g.ID, err)
+	}
+}
+
+// saveRating stores a player's new rating, its history and the change on their game record
+func saveRating(games *repository.GameRepository, users *repository.UserRepository, userRating *models.UserRating, player *models.Player, gameType string, after Rating, ratedAt time.Time) error {
+	newRating := int(math.Round(after.Rating))
+	oldRating := userRating.Rating
+	delta := newRating - oldRating
+
+	// Update the rating of the game's mode
+	userRating.Rating = newRating
+	userRating.RatingDeviation = after.Deviation
+	userRating.RatingVolatility = after.Volatility
+	userRating.RatedGames++
+	userRating.Provisional = userRating.RatedGames < provisionalGames || after.Deviation > provisionalDeviation
+	userRating.RatedAt = &ratedAt
+
+	err := users.SaveUserRating(userRating)
+	if err != nil {
+		return err
+	}
+
+	// Record the change
+	err = users.AddRatingHistory(userRating.UserID, &player.GameID, userRating.Mode, ratingSource(gameType), oldRating, newRating)
+	if err != nil {
+		return err
+	}
+
+	player.RatingChange = &delta
+
+	return games.UpdatePlayer(player)
+}
+
+// Helper function to get the rating history source of a game type
+func ratingSource(gameType string) models.RatingSource {
+	switch gameType {
+	case "tournament":
+		return models.RatingSourceTournament
+	case "blitz":
+		return models.RatingSourceBlitz
+	case "team":
+		return models.RatingSourceTeam
+	}
+	return models.RatingSourceDuel
+}
+
+// Helper function to get the start of the time bucket a moment falls in
+func bucketStart(t time.Time, bucket string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch bucket {
+	case BucketWeek:
+		// Weeks start on Monday
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case BucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return day
+}
+
+// Helper function to get a user's rating, grown more uncertain by the time since their last rated game
+func currentRating(userRating *models.UserRating, now time.Time) Rating {
+	r := Rating{
+		Rating:     float64(userRating.Rating),
+		Deviation:  userRating.RatingDeviation,
+		Volatility: userRating.RatingVolatility,
+	}
+
+	// Users created before ratings had deviations
+	if r.Deviation <= 0 {
+		r.Deviation = DefaultDeviation
+	}
+	if r.Volatility <= 0 {
+		r.Volatility = DefaultVolatility
+	}
+
+	if userRating.RatedAt != nil {
+		r = Decay(r, now.Sub(*userRating.RatedAt).Hours()/ratingPeriod.Hours())
+	}
+
+	return r
+}
+
+// Helper function to get the results a player of a completed game is rated on: one against every
+// opponent, or in a game with sides, one against the other side. Players who forfeited are not
+// rated, since they already lost the forfeit penalty, but they still count as opponents who lost.
+func playerResults(g *models.Game, before []Rating, i int) ([]Result, bool) {
+	if g.Players[i].Forfeited {
+		return nil, false
+	}
+	if g.HasSides() {
+		return []Result{sideResult(g, before, i)}, true
+	}
+
+	results := make([]Result, 0, len(g.Players)-1)
+	for j := range g.Players {
+		if i == j {
+			continue
+		}
+		results = append(results, Result{
+			Opponent: before[j],
+			Score:    outcome(&g.Players[i], &g.Players[j], g.WinnerID),
+		})
+	}
+	return results, true
+}
+
+// Helper function to rate a player of a game with sides as if they had played one opponent with the
+// average rating of the other side. The side's result counts for every player on it.
+func sideResult(g *models.Game, before []Rating, i int) Result {
+	player := &g.Players[i]
+
+	var rating, variance float64
+	opponents := 0
+	for j, p := range g.Players {
+		if p.Team == player.Team {
+			continue
+		}
+		rating += before[j].Rating
+		variance += before[j].Deviation * before[j].Deviation
+		opponents++
+	}
+
+	result := Result{Opponent: before[i], Score: 0.5}
+	if opponents > 0 {
+		result.Opponent = Rating{
+			Rating:     rating / float64(opponents),
+			Deviation:  math.Sqrt(variance / float64(opponents)),
+			Volatility: DefaultVolatility,
+		}
+	}
+
+	switch {
+	case g.WinningTeam == player.Team:
+		result.Score = 1
+	case g.WinningTeam != 0:
+		result.Score = 0
+	}
+	return result
+}
+
+// Helper function to score a player against an opponent in the same game
+func outcome(player, opponent *models.Player, winnerID *string) float64 {
+	// The winner beats everyone
+	if winnerID != nil {
+		switch *winnerID {
+		case player.UserID:
+			return 1
+		case opponent.UserID:
+			return 0
+		}
+	}
+
+	// Forfeiting loses to anyone who stayed
+	if player.Forfeited != opponent.Forfeited {
+		if player.Forfeited {
+			return 0
+		}
+		return 1
+	}
+
+	// Solving beats not solving, and a higher score beats a lower one
+	playerScore, opponentScore := solvedScore(player), solvedScore(opponent)
+	switch {
+	case playerScore > opponentScore:
+		return 1
+	case playerScore < opponentScore:
+		return 0
+	}
+
+	return 0.5
+}
+
+// Helper function to get a player's score, or -1 if they did not solve the puzzle
+func solvedScore(player *models.Player) int {
+	if player.IsCorrect == nil || !*player.IsCorrect || player.Score == nil {
+		return -1
+	}
+	return *player.Score
+}
@@ -0,0 +1,81 @@
+package rating
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestSideResult(t *testing.T) {
+	g := &models.Game{
+		WinningTeam: 1,
+		Players: []models.Player{
+			{UserID: "a", Team: 1},
+			{UserID: "b", Team: 1},
+			{UserID: "c", Team: 2},
+			{UserID: "d", Team: 2},
+		},
+	}
+	before := []Rating{
+		{Rating: 1500, Deviation: 100},
+		{Rating: 1600, Deviation: 100},
+		{Rating: 1400, Deviation: 60},
+		{Rating: 1800, Deviation: 80},
+	}
+
+	// Each side plays the average of the other, and teammates are never rated against each other
+	tests := []struct {
+		player     int
+		wantRating float64
+		wantScore  float64
+	}{
+		{player: 0, wantRating: 1600, wantScore: 1},
+		{player: 2, wantRating: 1550, wantScore: 0},
+		{player: 3, wantRating: 1550, wantScore: 0},
+	}
+	for _, tt := range tests {
+		got := sideResult(g, before, tt.player)
+		if got.Opponent.Rating != tt.wantRating || got.Score != tt.wantScore {
+			t.Errorf("sideResult(%s) = opponent %.0f, score %.1f, want %.0f, %.1f",
+				g.Players[tt.player].UserID, got.Opponent.Rating, got.Score, tt.wantRating, tt.wantScore)
+		}
+	}
+
+	if got := sideResult(g, before, 0).Opponent.Deviation; math.Abs(got-math.Sqrt(5000)) > 1e-9 {
+		t.Errorf("opponent deviation = %.4f, want %.4f", got, math.Sqrt(5000))
+	}
+
+	// Neither side wins a draw
+	g.WinningTeam = 0
+	if got := sideResult(g, before, 2).Score; got != 0.5 {
+		t.Errorf("draw score = %.1f, want 0.5", got)
+	}
+}
+
+func TestPlayerResultsSkipForfeits(t *testing.T) {
+	winnerID := "a"
+	g := &models.Game{
+		WinnerID: &winnerID,
+		Players: []models.Player{
+			{UserID: "a"},
+			{UserID: "b"},
+			{UserID: "c", Forfeited: true},
+		},
+	}
+	before := []Rating{{Rating: 1500}, {Rating: 1500}, {Rating: 1500}}
+
+	// The forfeit penalty is the only rating change of a player who forfeited
+	if results, rated := playerResults(g, before, 2); rated || results != nil {
+		t.Errorf("playerResults(c) = %v, %v, want the forfeiting player left unrated", results, rated)
+	}
+
+	// The others still beat the player who forfeited
+	results, rated := playerResults(g, before, 1)
+	if !rated || len(results) != 2 {
+		t.Fatalf("playerResults(b) = %v, %v, want two results", results, rated)
+	}
+	if results[0].Score != 0 || results[1].Score != 1 {
+		t.Errorf("playerResults(b) scores = %.1f and %.1f, want a loss to a and a win over c", results[0].Score, results[1].Score)
+	}
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+)
+
+// BotTokenRepository handles database operations for bot API tokens
+type BotTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewBotTokenRepository creates a new bot token repository
+func NewBotTokenRepository(db *gorm.DB) *BotTokenRepository {
+	return &BotTokenRepository{db: db}
+}
+
+// Create creates a new bot token
+func (r *BotTokenRepository) Create(token *models.BotToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByID finds a bot token by ID
+func (r *BotTokenRepository) FindByID(id string) (*models.BotToken, error) {
+	var token models.BotToken
+	err := r.db.First(&token, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bot token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindActiveByHash finds a token that has not been revoked by its hash, together with its bot
+func (r *BotTokenRepository) FindActiveByHash(hash string) (*models.BotToken, error) {
+	var token models.BotToken
+	err := r.db.Preload("User").First(&token, "token_hash = ? AND revoked_at IS NULL", hash).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("bot token not found")
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// FindByUser gets all tokens of a bot, newest first
+func (r *BotTokenRepository) FindByUser(userID string) ([]models.BotToken, error) {
+	var tokens []models.BotToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// Revoke revokes a token so it can no longer be used
+func (r *BotTokenRepository) Revoke(id string) error {
+	return r.db.Model(&models.BotToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// TouchLastUsed records that a token was just used
+func (r *BotTokenRepository) TouchLastUsed(id string) error {
+	return r.db.Model(&models.BotToken{}).Where("id = ?", id).Update("last_used_at", time.Now()).Error
+}
@@ -32,8 +32,11 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 	err = db.AutoMigrate(
 		&models.User{},
 		&models.UserStats{},
+		&models.UserRating{},
+		&models.RatingHistory{},
 		&models.Game{},
 		&models.Player{},
+		&models.GameEvent{},
 		&models.LeaderboardEntry{},
 		&models.Achievement{},
 		&models.UserAchievement{},
@@ -41,6 +44,17 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		&models.Puzzle{},
 		&models.PuzzleSolution{},
 		&SolutionMetrics{},
+		&models.Tournament{},
+		&models.TournamentParticipant{},
+		&models.TournamentRound{},
+		&models.TournamentPairing{},
+		&models.Lobby{},
+		&models.LobbyMember{},
+		&models.LobbyKick{},
+		&models.BotToken{},
+		&models.RushRun{},
+		&models.FoundSolution{},
+		&models.MatchmakingSample{},
 	)
 	if err != nil {
 		return nil, err
@@ -52,6 +66,18 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		return nil, err
 	}
 
+	// Create the partitioned table of puzzles users have been served
+	err = CreatePuzzleExposureTable(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// Give every user a rating in each game mode
+	err = MigrateUserRatings(db)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Println("Database connected and migrated successfully")
 
 	return &Database{DB: db}, nil
@@ -216,3 +216,15 @@ func (r *FriendRepository) IsBlocked(userID, blockedByID string) (bool, error) {
 		Count(&count).Error
 	return count > 0, err
 }
+
+// FindBlocksAmong finds the blocks between users of a group, in either direction
+func (r *FriendRepository) FindBlocksAmong(userIDs []string) ([]models.Friendship, error) {
+	var friendships []models.Friendship
+	if len(userIDs) == 0 {
+		return friendships, nil
+	}
+	err := r.db.Where("status = ? AND user_id IN ? AND friend_id IN ?",
+		models.FriendshipStatusBlocked, userIDs, userIDs).
+		Find(&friendships).Error
+	return friendships, err
+}
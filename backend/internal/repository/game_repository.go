@@ -6,6 +6,7 @@ import (
 
 	"github.com/hectoclash/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GameRepository handles database operations for games
@@ -36,6 +37,50 @@ func (r *GameRepository) FindByID(id string) (*models.Game, error) {
 	return &game, nil
 }
 
+// FindByIDForUpdate finds a game by ID and locks its row until the surrounding transaction ends
+func (r *GameRepository) FindByIDForUpdate(id string) (*models.Game, error) {
+	var game models.Game
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&game, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("game not found")
+		}
+		return nil, err
+	}
+
+	// Load players separately so the lock only covers the game row
+	err = r.db.Preload("User").Where("game_id = ?", id).Order("joined_at ASC").Find(&game.Players).Error
+	if err != nil {
+		return nil, err
+	}
+	return &game, nil
+}
+
+// Transaction runs fn in a database transaction with game and user repositories bound to it
+func (r *GameRepository) Transaction(fn func(games *GameRepository, users *UserRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&GameRepository{db: tx}, &UserRepository{db: tx})
+	})
+}
+
+// CompleteGame ends an active game, reporting false if it had already ended.
+// Only the first call decides the status and the winner, so callers can race to complete a game safely.
+func (r *GameRepository) CompleteGame(game *models.Game) (bool, error) {
+	result := r.db.Model(&models.Game{}).
+		Where("id = ? AND status = ?", game.ID, models.GameStatusActive).
+		Updates(map[string]any{
+			"status":       game.Status,
+			"completed_at": game.CompletedAt,
+			"duration":     game.Duration,
+			"winner_id":    game.WinnerID,
+			"winning_team": game.WinningTeam,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // Update updates a game
 func (r *GameRepository) Update(game *models.Game) error {
 	return r.db.Save(game).Error
@@ -46,10 +91,10 @@ func (r *GameRepository) Delete(id string) error {
 	return r.db.Delete(&models.Game{}, "id = ?", id).Error
 }
 
-// FindActiveGames finds all active games
+// FindActiveGames finds all active public games
 func (r *GameRepository) FindActiveGames() ([]models.Game, error) {
 	var games []models.Game
-	err := r.db.Preload("Players.User").Where("status = ?", models.GameStatusActive).Find(&games).Error
+	err := r.db.Preload("Players.User").Where("status = ? AND is_private = ?", models.GameStatusActive, false).Find(&games).Error
 	return games, err
 }
 
@@ -132,6 +177,17 @@ func (r *GameRepository) CountGamesByDateRange(startDate, endDate time.Time) (in
 	return count, err
 }
 
+// MarkRated marks a game as rated, reporting false if it already was
+func (r *GameRepository) MarkRated(gameID string) (bool, error) {
+	result := r.db.Model(&models.Game{}).
+		Where("id = ? AND rated_at IS NULL", gameID).
+		Update("rated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // AddPlayerToGame adds a player to a game
 func (r *GameRepository) AddPlayerToGame(player *models.Player) error {
 	return r.db.Create(player).Error
@@ -169,3 +225,127 @@ func (r *GameRepository) FindPlayerByGameAndUser(gameID, userID string) (*models
 	}
 	return &player, nil
 }
+
+// AppendEvent appends an event to a game's event log
+func (r *GameRepository) AppendEvent(event *models.GameEvent) error {
+	return r.db.Create(event).Error
+}
+
+// GetEvents gets the event log of a game in chronological order
+func (r *GameRepository) GetEvents(gameID string) ([]models.GameEvent, error) {
+	var events []models.GameEvent
+	err := r.db.Where("game_id = ?", gameID).
+		Order("offset_ms ASC, occurred_at ASC").
+		Find(&events).Error
+	return events, err
+}
+
+// FindRematchOf finds the rematch created from a game
+func (r *GameRepository) FindRematchOf(gameID string) (*models.Game, error) {
+	var game models.Game
+	err := r.db.Preload("Players.User").First(&game, "rematch_of_id = ?", gameID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("game not found")
+		}
+		return nil, err
+	}
+	return &game, nil
+}
+
+// FindGamesBySeriesID finds all games of a rematch series in the order they were played
+func (r *GameRepository) FindGamesBySeriesID(seriesID string) ([]models.Game, error) {
+	var games []models.Game
+	err := r.db.Preload("Players.User").
+		Where("id = ? OR series_id = ?", seriesID, seriesID).
+		Order("created_at ASC").
+		Find(&games).Error
+	return games, err
+}
+
+// FindRecentOpponents finds who each of a group of users played in their last few games, by user ID
+func (r *GameRepository) FindRecentOpponents(userIDs []string, games int) (map[string][]string, error) {
+	opponents := make(map[string][]string)
+	if len(userIDs) == 0 || games <= 0 {
+		return opponents, nil
+	}
+
+	var rows []struct {
+		UserID     string
+		OpponentID string
+	}
+	err := r.db.Raw(`
+		SELECT recent.user_id, opponent.user_id AS opponent_id
+		FROM (
+			SELECT user_id, game_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY joined_at DESC) AS n
+			FROM players
+			WHERE user_id IN ?
+		) AS recent
+		JOIN players AS opponent ON opponent.game_id = recent.game_id AND opponent.user_id <> recent.user_id
+		WHERE recent.n <= ?`, userIDs, games).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		opponents[row.UserID] = append(opponents[row.UserID], row.OpponentID)
+	}
+	return opponents, nil
+}
+
+// FindPlayedSequences finds the puzzle sequences each of a group of users played in their last
+// few games, by user ID
+func (r *GameRepository) FindPlayedSequences(userIDs []string, games int) (map[string][]string, error) {
+	sequences := make(map[string][]string)
+	if len(userIDs) == 0 || games <= 0 {
+		return sequences, nil
+	}
+
+	var rows []struct {
+		UserID         string
+		PuzzleSequence string
+	}
+	err := r.db.Raw(`
+		SELECT recent.user_id, games.puzzle_sequence
+		FROM (
+			SELECT user_id, game_id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY joined_at DESC) AS n
+			FROM players
+			WHERE user_id IN ?
+		) AS recent
+		JOIN games ON games.id = recent.game_id
+		WHERE recent.n <= ?`, userIDs, games).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		sequences[row.UserID] = append(sequences[row.UserID], row.PuzzleSequence)
+	}
+	return sequences, nil
+}
+
+// FindAttemptedSequences finds which of some puzzle sequences any of a group of users has played
+func (r *GameRepository) FindAttemptedSequences(userIDs, sequences []string) (map[string]bool, error) {
+	attempted := make(map[string]bool)
+	if len(userIDs) == 0 || len(sequences) == 0 {
+		return attempted, nil
+	}
+
+	var rows []string
+	err := r.db.Raw(`
+		SELECT DISTINCT games.puzzle_sequence
+		FROM players
+		JOIN games ON games.id = players.game_id
+		WHERE players.user_id IN ? AND games.puzzle_sequence IN ?`, userIDs, sequences).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sequence := range rows {
+		attempted[sequence] = true
+	}
+	return attempted, nil
+}
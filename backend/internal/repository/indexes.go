@@ -163,6 +163,29 @@ func CreateIndexes(db *gorm.DB) error {
 		return err
 	}
 
+	// Tournament indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tournament_rounds_tournament_number ON tournament_rounds (tournament_id, number)").Error; err != nil {
+		return err
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_tournament_pairings_round_board ON tournament_pairings (round_id, board)").Error; err != nil {
+		return err
+	}
+
+	// User rating indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_user_ratings_mode_rating ON user_ratings (mode, rating DESC)").Error; err != nil {
+		return err
+	}
+
+	// Rating history indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_rating_history_user_mode_created ON rating_history (user_id, mode, created_at)").Error; err != nil {
+		return err
+	}
+
+	// Game event indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_game_events_game_offset ON game_events (game_id, offset_ms)").Error; err != nil {
+		return err
+	}
+
 	// Composite indexes for common queries
 	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_friendships_user_friend_status ON friendships (user_id, friend_id, status)").Error; err != nil {
 		return err
@@ -183,6 +206,11 @@ func CreateIndexes(db *gorm.DB) error {
 		return err
 	}
 
+	// Matchmaking sample indexes
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_matchmaking_samples_created_kind ON matchmaking_samples (created_at, kind)").Error; err != nil {
+		return err
+	}
+
 	log.Println("Database indexes created successfully")
 	return nil
 }
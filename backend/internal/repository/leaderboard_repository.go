@@ -209,6 +209,12 @@ func (r *LeaderboardRepository) generateLeaderboard(leaderboardType models.Leade
 				users ON players.user_id = users.id
 			WHERE 
 				games.created_at BETWEEN ? AND ?
+				AND users.is_bot = false
+				AND NOT EXISTS (
+					SELECT 1 FROM players bot_players
+					JOIN users bot_users ON bot_players.user_id = bot_users.id
+					WHERE bot_players.game_id = games.id AND bot_users.is_bot = true
+				)
 			GROUP BY 
 				players.user_id
 			ORDER BY 
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LobbyRepository handles database operations for private lobbies
+type LobbyRepository struct {
+	db *gorm.DB
+}
+
+// NewLobbyRepository creates a new lobby repository
+func NewLobbyRepository(db *gorm.DB) *LobbyRepository {
+	return &LobbyRepository{db: db}
+}
+
+// Create creates a new lobby
+func (r *LobbyRepository) Create(lobby *models.Lobby) error {
+	return r.db.Create(lobby).Error
+}
+
+// Update updates a lobby
+func (r *LobbyRepository) Update(lobby *models.Lobby) error {
+	return r.db.Omit("Members").Save(lobby).Error
+}
+
+// FindByCode finds a lobby by its invite code
+func (r *LobbyRepository) FindByCode(code string) (*models.Lobby, error) {
+	var lobby models.Lobby
+	err := r.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("joined_at ASC")
+	}).
+		Preload("Members.User").
+		First(&lobby, "code = ?", code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("lobby not found")
+		}
+		return nil, err
+	}
+	return &lobby, nil
+}
+
+// FindByCodeForUpdate finds a lobby by its invite code and locks its row until the surrounding
+// transaction ends, so that members are added and removed one at a time. The members are read
+// after the lock is taken.
+func (r *LobbyRepository) FindByCodeForUpdate(code string) (*models.Lobby, error) {
+	var locked models.Lobby
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "code = ?", code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("lobby not found")
+		}
+		return nil, err
+	}
+	return r.FindByCode(code)
+}
+
+// Transaction runs fn in a database transaction, with a repository bound to it
+func (r *LobbyRepository) Transaction(fn func(lobbies *LobbyRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&LobbyRepository{db: tx})
+	})
+}
+
+// CodeExists checks if an invite code is already in use
+func (r *LobbyRepository) CodeExists(code string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Lobby{}).Where("code = ?", code).Count(&count).Error
+	return count > 0, err
+}
+
+// AddMember adds a member to a lobby
+func (r *LobbyRepository) AddMember(member *models.LobbyMember) error {
+	return r.db.Create(member).Error
+}
+
+// RemoveMember removes a member from a lobby
+func (r *LobbyRepository) RemoveMember(lobbyID, userID string) error {
+	result := r.db.Where("lobby_id = ? AND user_id = ?", lobbyID, userID).
+		Delete(&models.LobbyMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("member not found")
+	}
+	return nil
+}
+
+// AddKick records that a user was removed from a lobby by its host. Kicking a user twice is harmless.
+func (r *LobbyRepository) AddKick(lobbyID, userID string) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.LobbyKick{LobbyID: lobbyID, UserID: userID}).Error
+}
+
+// IsKicked checks if a user was removed from a lobby by its host
+func (r *LobbyRepository) IsKicked(lobbyID, userID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.LobbyKick{}).
+		Where("lobby_id = ? AND user_id = ?", lobbyID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
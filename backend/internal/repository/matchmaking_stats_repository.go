@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+)
+
+// MatchmakingStatsFilter narrows the matchmaking samples that are summarized
+type MatchmakingStatsFilter struct {
+	Since    time.Time
+	Until    time.Time
+	GameType string // Every game type if empty
+	Ranked   *bool  // Ranked and casual queues if nil
+	Variant  string // Every variant if empty
+}
+
+// MatchmakingStatsRepository handles database operations for matchmaking telemetry
+type MatchmakingStatsRepository struct {
+	db *gorm.DB
+}
+
+// NewMatchmakingStatsRepository creates a new matchmaking stats repository
+func NewMatchmakingStatsRepository(db *gorm.DB) *MatchmakingStatsRepository {
+	return &MatchmakingStatsRepository{db: db}
+}
+
+// CreateSamples saves matchmaking samples
+func (r *MatchmakingStatsRepository) CreateSamples(samples []models.MatchmakingSample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	return r.db.Create(&samples).Error
+}
+
+// Aggregates of the matches and timeouts of a bucket; waits are stored in milliseconds
+const bucketAggregates = `
+	COUNT(*) FILTER (WHERE kind = 'matched') AS matched,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY wait_ms) FILTER (WHERE kind = 'matched'), 0) / 1000 AS wait_p50,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY wait_ms) FILTER (WHERE kind = 'matched'), 0) / 1000 AS wait_p90,
+	COALESCE(percentile_cont(0.99) WITHIN GROUP (ORDER BY wait_ms) FILTER (WHERE kind = 'matched'), 0) / 1000 AS wait_p99,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY rating_gap) FILTER (WHERE kind = 'matched'), 0) AS rating_gap_p50,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY rating_gap) FILTER (WHERE kind = 'matched'), 0) AS rating_gap_p90,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY elo_range) FILTER (WHERE kind = 'matched'), 0) AS elo_range_p50,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY elo_range) FILTER (WHERE kind = 'matched'), 0) AS elo_range_p90,
+	COALESCE(AVG(queue_size) FILTER (WHERE kind = 'matched'), 0) AS queue_size_avg,
+	COUNT(*) FILTER (WHERE kind = 'timeout') AS timeouts,
+	COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY wait_ms) FILTER (WHERE kind = 'timeout'), 0) / 1000 AS timeout_wait_p50,
+	COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY wait_ms) FILTER (WHERE kind = 'timeout'), 0) / 1000 AS timeout_wait_p90`
+
+// bucketRow is a row of bucket aggregates, grouped by rating band or by hour
+type bucketRow struct {
+	Band           int
+	Hour           time.Time
+	Matched        int64
+	WaitP50        float64
+	WaitP90        float64
+	WaitP99        float64
+	RatingGapP50   float64
+	RatingGapP90   float64
+	EloRangeP50    float64
+	EloRangeP90    float64
+	QueueSizeAvg   float64
+	Timeouts       int64
+	TimeoutWaitP50 float64
+	TimeoutWaitP90 float64
+}
+
+// StatsByBand summarizes matches and timeouts by rating band
+func (r *MatchmakingStatsRepository) StatsByBand(filter MatchmakingStatsFilter, bandWidth int) ([]models.MatchmakingBucketStats, error) {
+	if bandWidth <= 0 {
+		return nil, fmt.Errorf("invalid band width %d", bandWidth)
+	}
+
+	where, args := filter.where(models.MatchmakingSampleMatched, models.MatchmakingSampleTimeout)
+	var rows []bucketRow
+	err := r.db.Raw(`
+		SELECT rating / ? * ? AS band, `+bucketAggregates+`
+		FROM matchmaking_samples
+		WHERE `+where+`
+		GROUP BY band
+		ORDER BY band`, append([]interface{}{bandWidth, bandWidth}, args...)...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.MatchmakingBucketStats, len(rows))
+	for i, row := range rows {
+		band := row.Band
+		stats[i] = row.stats()
+		stats[i].Band = &band
+	}
+	return stats, nil
+}
+
+// StatsByHour summarizes matches and timeouts by hour
+func (r *MatchmakingStatsRepository) StatsByHour(filter MatchmakingStatsFilter) ([]models.MatchmakingBucketStats, error) {
+	where, args := filter.where(models.MatchmakingSampleMatched, models.MatchmakingSampleTimeout)
+	var rows []bucketRow
+	err := r.db.Raw(`
+		SELECT date_trunc('hour', created_at) AS hour, `+bucketAggregates+`
+		FROM matchmaking_samples
+		WHERE `+where+`
+		GROUP BY hour
+		ORDER BY hour`, args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]models.MatchmakingBucketStats, len(rows))
+	for i, row := range rows {
+		hour := row.Hour
+		stats[i] = row.stats()
+		stats[i].Hour = &hour
+	}
+	return stats, nil
+}
+
+// CountReadyChecks counts ready checks by outcome
+func (r *MatchmakingStatsRepository) CountReadyChecks(filter MatchmakingStatsFilter) (map[string]int64, error) {
+	where, args := filter.where(models.MatchmakingSampleReadyCheck)
+	return r.countBy("outcome", where, args)
+}
+
+// CountOutcomes counts queue entries by how they left the queue
+func (r *MatchmakingStatsRepository) CountOutcomes(filter MatchmakingStatsFilter) (map[string]int64, error) {
+	where, args := filter.where(models.MatchmakingSampleMatched, models.MatchmakingSampleTimeout,
+		models.MatchmakingSampleLeft, models.MatchmakingSampleBot)
+	return r.countBy("kind", where, args)
+}
+
+// countBy counts the samples matching a condition by a column
+func (r *MatchmakingStatsRepository) countBy(column, where string, args []interface{}) (map[string]int64, error) {
+	var rows []struct {
+		Label string
+		Count int64
+	}
+	err := r.db.Raw(`
+		SELECT `+column+` AS label, COUNT(*) AS count
+		FROM matchmaking_samples
+		WHERE `+where+`
+		GROUP BY `+column, args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Label] = row.Count
+	}
+	return counts, nil
+}
+
+// where builds the condition selecting the samples of some kinds that pass the filter
+func (f MatchmakingStatsFilter) where(kinds ...string) (string, []interface{}) {
+	conditions := []string{"kind IN ?", "created_at >= ?", "created_at < ?"}
+	args := []interface{}{kinds, f.Since, f.Until}
+	if f.GameType != "" {
+		conditions = append(conditions, "game_type = ?")
+		args = append(args, f.GameType)
+	}
+	if f.Ranked != nil {
+		conditions = append(conditions, "ranked = ?")
+		args = append(args, *f.Ranked)
+	}
+	if f.Variant != "" {
+		conditions = append(conditions, "variant = ?")
+		args = append(args, f.Variant)
+	}
+	return strings.Join(conditions, " AND "), args
+}
+
+// stats converts a row of bucket aggregates to the stats of its bucket
+func (row bucketRow) stats() models.MatchmakingBucketStats {
+	return models.MatchmakingBucketStats{
+		Matched:        row.Matched,
+		WaitP50:        row.WaitP50,
+		WaitP90:        row.WaitP90,
+		WaitP99:        row.WaitP99,
+		RatingGapP50:   row.RatingGapP50,
+		RatingGapP90:   row.RatingGapP90,
+		EloRangeP50:    row.EloRangeP50,
+		EloRangeP90:    row.EloRangeP90,
+		QueueSizeAvg:   row.QueueSizeAvg,
+		Timeouts:       row.Timeouts,
+		TimeoutWaitP50: row.TimeoutWaitP50,
+		TimeoutWaitP90: row.TimeoutWaitP90,
+	}
+}
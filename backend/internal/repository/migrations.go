@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"log"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+)
+
+// MigrateUserRatings seeds the per-mode ratings of users from their single rating.
+// The duel rating takes over the full Glicko-2 state; the other modes start from the rating
+// with a fresh deviation, since it mixed results from every mode. Users that already have a
+// rating in a mode are left alone, so this is safe to run on every start.
+// Rating history recorded before modes existed is moved to the mode it belongs to.
+func MigrateUserRatings(db *gorm.DB) error {
+	log.Println("Migrating user ratings...")
+
+	for _, mode := range models.RatingModes {
+		query := `
+			INSERT INTO user_ratings (user_id, mode, rating, rating_deviation, rating_volatility, rated_games, provisional, rated_at, created_at, updated_at)
+			SELECT id, ?, rating, 350, 0.06, 0, true, NULL, NOW(), NOW()
+			FROM users
+			WHERE deleted_at IS NULL
+			ON CONFLICT (user_id, mode) DO NOTHING
+		`
+		if mode == models.RatingModeDuel {
+			query = `
+				INSERT INTO user_ratings (user_id, mode, rating, rating_deviation, rating_volatility, rated_games, provisional, rated_at, created_at, updated_at)
+				SELECT id, ?, rating, rating_deviation, rating_volatility, rated_games, provisional, rated_at, NOW(), NOW()
+				FROM users
+				WHERE deleted_at IS NULL
+				ON CONFLICT (user_id, mode) DO NOTHING
+			`
+		}
+
+		if err := db.Exec(query, mode).Error; err != nil {
+			return err
+		}
+	}
+
+	// History recorded before modes existed defaults to duel; practice and puzzle changes belong to practice
+	return db.Exec(
+		"UPDATE rating_history SET mode = ? WHERE source IN (?, ?) AND mode <> ?",
+		models.RatingModePractice, models.RatingSourcePractice, models.RatingSourcePuzzle, models.RatingModePractice,
+	).Error
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Number of hash partitions of the puzzle exposure table. A user's exposures all live in one
+// partition, so looking them up only touches that partition's primary key.
+const puzzleExposurePartitions = 16
+
+// CreatePuzzleExposureTable creates the puzzle exposure table, hash partitioned by user, and its
+// partitions
+func CreatePuzzleExposureTable(db *gorm.DB) error {
+	log.Println("Creating puzzle exposure table...")
+
+	err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS puzzle_exposures (
+			user_id UUID NOT NULL,
+			sequence VARCHAR(6) NOT NULL,
+			times INTEGER NOT NULL DEFAULT 1,
+			first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (user_id, sequence)
+		) PARTITION BY HASH (user_id)`).Error
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < puzzleExposurePartitions; i++ {
+		err := db.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS puzzle_exposures_p%d PARTITION OF puzzle_exposures FOR VALUES WITH (MODULUS %d, REMAINDER %d)",
+			i, puzzleExposurePartitions, i)).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PuzzleExposureRepository handles database operations for the puzzles users have been served
+type PuzzleExposureRepository struct {
+	db *gorm.DB
+}
+
+// NewPuzzleExposureRepository creates a new puzzle exposure repository
+func NewPuzzleExposureRepository(db *gorm.DB) *PuzzleExposureRepository {
+	return &PuzzleExposureRepository{db: db}
+}
+
+// Record records that users have been served a puzzle
+func (r *PuzzleExposureRepository) Record(userIDs []string, sequence string, seenAt time.Time) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	exposures := make([]models.PuzzleExposure, len(userIDs))
+	for i, userID := range userIDs {
+		exposures[i] = models.PuzzleExposure{
+			UserID:      userID,
+			Sequence:    sequence,
+			Times:       1,
+			FirstSeenAt: seenAt,
+			LastSeenAt:  seenAt,
+		}
+	}
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "sequence"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "times"}, Value: gorm.Expr("puzzle_exposures.times + 1")},
+			{Column: clause.Column{Name: "last_seen_at"}, Value: seenAt},
+		},
+	}).Create(&exposures).Error
+}
+
+// FindSeen finds which of some puzzle sequences any of a group of users has been served
+func (r *PuzzleExposureRepository) FindSeen(userIDs, sequences []string) (map[string]bool, error) {
+	seen := make(map[string]bool)
+	if len(userIDs) == 0 || len(sequences) == 0 {
+		return seen, nil
+	}
+
+	var rows []string
+	err := r.db.Raw(`
+		SELECT DISTINCT sequence
+		FROM puzzle_exposures
+		WHERE user_id IN ? AND sequence IN ?`, userIDs, sequences).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sequence := range rows {
+		seen[sequence] = true
+	}
+	return seen, nil
+}
+
+// GetRandomUnseenPuzzle gets a random puzzle suitable for some rating within a window around the
+// given one, that a user has not been served yet
+func (r *PuzzleExposureRepository) GetRandomUnseenPuzzle(userID string, elo, window int) (*models.Puzzle, error) {
+	var puzzle models.Puzzle
+	err := r.db.
+		Where("min_elo <= ? AND max_elo >= ? AND unsolvable = ?", elo+window, elo-window, false).
+		Where("NOT EXISTS (SELECT 1 FROM puzzle_exposures WHERE puzzle_exposures.user_id = ? AND puzzle_exposures.sequence = puzzles.sequence)", userID).
+		Order("RANDOM()").
+		Take(&puzzle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
+
+// GetLeastRecentlySeenPuzzle gets the puzzle suitable for a rating that a user was served the
+// longest time ago
+func (r *PuzzleExposureRepository) GetLeastRecentlySeenPuzzle(userID string, elo int) (*models.Puzzle, error) {
+	var puzzle models.Puzzle
+	err := r.db.
+		Joins("JOIN puzzle_exposures ON puzzle_exposures.sequence = puzzles.sequence AND puzzle_exposures.user_id = ?", userID).
+		Where("puzzles.min_elo <= ? AND puzzles.max_elo >= ? AND puzzles.unsolvable = ?", elo, elo, false).
+		Order("puzzle_exposures.last_seen_at").
+		Take(&puzzle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &puzzle, nil
+}
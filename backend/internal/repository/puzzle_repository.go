@@ -3,6 +3,7 @@ package repository
 import (
 	"errors"
 	"math/rand"
+	"time"
 
 	"github.com/hectoclash/internal/models"
 	"gorm.io/gorm"
@@ -71,6 +72,20 @@ func (r *PuzzleRepository) GetSolutions(puzzleID string) ([]models.PuzzleSolutio
 	return solutions, err
 }
 
+// ClaimDiscovery records a player as the first to find a solution, reporting false if someone found it before
+func (r *PuzzleRepository) ClaimDiscovery(solutionID, userID string) (bool, error) {
+	result := r.db.Model(&models.PuzzleSolution{}).
+		Where("id = ? AND discovered_by IS NULL", solutionID).
+		Updates(map[string]any{
+			"discovered_by": userID,
+			"discovered_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
 // GetOptimalSolution gets the optimal solution for a puzzle
 func (r *PuzzleRepository) GetOptimalSolution(puzzleID string) (*models.PuzzleSolution, error) {
 	var solution models.PuzzleSolution
@@ -87,7 +102,7 @@ func (r *PuzzleRepository) GetOptimalSolution(puzzleID string) (*models.PuzzleSo
 // GetPuzzlesByDifficulty gets puzzles by difficulty level
 func (r *PuzzleRepository) GetPuzzlesByDifficulty(difficulty models.DifficultyLevel, limit, offset int) ([]models.Puzzle, error) {
 	var puzzles []models.Puzzle
-	err := r.db.Where("difficulty = ?", difficulty).
+	err := r.db.Where("difficulty = ? AND unsolvable = ?", difficulty, false).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -98,7 +113,7 @@ func (r *PuzzleRepository) GetPuzzlesByDifficulty(difficulty models.DifficultyLe
 // GetPuzzlesByELORange gets puzzles suitable for a specific ELO rating
 func (r *PuzzleRepository) GetPuzzlesByELORange(elo int, limit, offset int) ([]models.Puzzle, error) {
 	var puzzles []models.Puzzle
-	err := r.db.Where("min_elo <= ? AND max_elo >= ?", elo, elo).
+	err := r.db.Where("min_elo <= ? AND max_elo >= ? AND unsolvable = ?", elo, elo, false).
 		Order("created_at DESC").
 		Limit(limit).
 		Offset(offset).
@@ -109,7 +124,7 @@ func (r *PuzzleRepository) GetPuzzlesByELORange(elo int, limit, offset int) ([]m
 // GetRandomPuzzleByELORange gets a random puzzle suitable for a specific ELO rating
 func (r *PuzzleRepository) GetRandomPuzzleByELORange(elo int) (*models.Puzzle, error) {
 	var puzzles []models.Puzzle
-	err := r.db.Where("min_elo <= ? AND max_elo >= ?", elo, elo).Find(&puzzles).Error
+	err := r.db.Where("min_elo <= ? AND max_elo >= ? AND unsolvable = ?", elo, elo, false).Find(&puzzles).Error
 	if err != nil {
 		return nil, err
 	}
@@ -126,7 +141,7 @@ func (r *PuzzleRepository) GetRandomPuzzleByELORange(elo int) (*models.Puzzle, e
 // GetRandomPuzzleByDifficulty gets a random puzzle of a specific difficulty
 func (r *PuzzleRepository) GetRandomPuzzleByDifficulty(difficulty models.DifficultyLevel) (*models.Puzzle, error) {
 	var puzzles []models.Puzzle
-	err := r.db.Where("difficulty = ?", difficulty).Find(&puzzles).Error
+	err := r.db.Where("difficulty = ? AND unsolvable = ?", difficulty, false).Find(&puzzles).Error
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +155,30 @@ func (r *PuzzleRepository) GetRandomPuzzleByDifficulty(difficulty models.Difficu
 	return &puzzles[randomIndex], nil
 }
 
+// GetRandomUnsolvablePuzzle gets a random puzzle that has no solution
+func (r *PuzzleRepository) GetRandomUnsolvablePuzzle() (*models.Puzzle, error) {
+	var puzzles []models.Puzzle
+	err := r.db.Where("unsolvable = ?", true).Find(&puzzles).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(puzzles) == 0 {
+		return nil, errors.New("no unsolvable puzzles found")
+	}
+
+	// Select a random puzzle
+	randomIndex := rand.Intn(len(puzzles))
+	return &puzzles[randomIndex], nil
+}
+
+// CountUnsolvablePuzzles counts the puzzles that have no solution
+func (r *PuzzleRepository) CountUnsolvablePuzzles() (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Puzzle{}).Where("unsolvable = ?", true).Count(&count).Error
+	return count, err
+}
+
 // UpdatePuzzleStats updates the statistics for a puzzle after a game
 func (r *PuzzleRepository) UpdatePuzzleStats(puzzleID string, solveTime float64, isCorrect bool) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
@@ -185,13 +224,13 @@ func (r *PuzzleRepository) CountPuzzles() (int64, error) {
 // CountPuzzlesByDifficulty counts puzzles by difficulty
 func (r *PuzzleRepository) CountPuzzlesByDifficulty(difficulty models.DifficultyLevel) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Puzzle{}).Where("difficulty = ?", difficulty).Count(&count).Error
+	err := r.db.Model(&models.Puzzle{}).Where("difficulty = ? AND unsolvable = ?", difficulty, false).Count(&count).Error
 	return count, err
 }
 
 // CountPuzzlesByELORange counts puzzles by ELO range
 func (r *PuzzleRepository) CountPuzzlesByELORange(minELO, maxELO int) (int64, error) {
 	var count int64
-	err := r.db.Model(&models.Puzzle{}).Where("min_elo <= ? AND max_elo >= ?", maxELO, minELO).Count(&count).Error
+	err := r.db.Model(&models.Puzzle{}).Where("min_elo <= ? AND max_elo >= ? AND unsolvable = ?", maxELO, minELO, false).Count(&count).Error
 	return count, err
 }
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+)
+
+// RushRepository handles database operations for Puzzle Rush runs
+type RushRepository struct {
+	db *gorm.DB
+}
+
+// NewRushRepository creates a new rush repository
+func NewRushRepository(db *gorm.DB) *RushRepository {
+	return &RushRepository{db: db}
+}
+
+// Create records a finished run
+func (r *RushRepository) Create(run *models.RushRun) error {
+	return r.db.Create(run).Error
+}
+
+// FindBestByUser finds a user's best run of a length, the earliest one winning ties
+func (r *RushRepository) FindBestByUser(userID string, duration int) (*models.RushRun, error) {
+	var run models.RushRun
+	err := r.db.Where("user_id = ? AND duration = ?", userID, duration).
+		Order("score DESC, completed_at ASC").
+		First(&run).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("rush run not found")
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// FindByUser gets a user's runs, newest first
+func (r *RushRepository) FindByUser(userID string, limit, offset int) ([]models.RushRun, error) {
+	var runs []models.RushRun
+	err := r.db.Where("user_id = ?", userID).
+		Order("completed_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&runs).Error
+	return runs, err
+}
+
+// GetLeaderboard gets the best run of each player for a run length, best first. Bots are left out.
+func (r *RushRepository) GetLeaderboard(duration, limit, offset int) ([]models.RushLeaderboardEntry, error) {
+	// Best run of each player
+	best := r.db.Table("rush_runs").
+		Select("DISTINCT ON (rush_runs.user_id) rush_runs.id, rush_runs.user_id, rush_runs.score, rush_runs.puzzles_solved, rush_runs.completed_at").
+		Joins("JOIN users ON users.id = rush_runs.user_id AND users.is_bot = ?", false).
+		Where("rush_runs.duration = ?", duration).
+		Order("rush_runs.user_id, rush_runs.score DESC, rush_runs.completed_at ASC")
+
+	var entries []models.RushLeaderboardEntry
+	err := r.db.Table("(?) AS best", best).
+		Select("best.id AS run_id, best.user_id, users.username, best.score, best.puzzles_solved, best.completed_at").
+		Joins("JOIN users ON users.id = best.user_id").
+		Order("best.score DESC, best.completed_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Number the entries
+	for i := range entries {
+		entries[i].Rank = offset + i + 1
+		entries[i].Duration = duration
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TournamentRepository handles database operations for tournaments
+type TournamentRepository struct {
+	db *gorm.DB
+}
+
+// NewTournamentRepository creates a new tournament repository
+func NewTournamentRepository(db *gorm.DB) *TournamentRepository {
+	return &TournamentRepository{db: db}
+}
+
+// Create creates a new tournament
+func (r *TournamentRepository) Create(tournament *models.Tournament) error {
+	return r.db.Create(tournament).Error
+}
+
+// Update updates a tournament
+func (r *TournamentRepository) Update(tournament *models.Tournament) error {
+	return r.db.Omit("Participants").Save(tournament).Error
+}
+
+// FindByID finds a tournament by ID
+func (r *TournamentRepository) FindByID(id string) (*models.Tournament, error) {
+	var tournament models.Tournament
+	err := r.db.Preload("Participants.User").First(&tournament, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tournament not found")
+		}
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// FindByIDForUpdate finds a tournament by ID without its participants and locks its row until
+// the surrounding transaction ends
+func (r *TournamentRepository) FindByIDForUpdate(id string) (*models.Tournament, error) {
+	var tournament models.Tournament
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tournament, "id = ?", id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tournament not found")
+		}
+		return nil, err
+	}
+	return &tournament, nil
+}
+
+// Transaction runs fn in a database transaction, with a repository bound to it
+func (r *TournamentRepository) Transaction(fn func(tournaments *TournamentRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&TournamentRepository{db: tx})
+	})
+}
+
+// FindByStatus finds tournaments by status
+func (r *TournamentRepository) FindByStatus(status models.TournamentStatus, limit, offset int) ([]models.Tournament, error) {
+	var tournaments []models.Tournament
+	err := r.db.Preload("Participants.User").
+		Where("status = ?", status).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&tournaments).Error
+	return tournaments, err
+}
+
+// FindRecent finds recent tournaments with pagination
+func (r *TournamentRepository) FindRecent(limit, offset int) ([]models.Tournament, error) {
+	var tournaments []models.Tournament
+	err := r.db.Preload("Participants.User").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&tournaments).Error
+	return tournaments, err
+}
+
+// AddParticipant registers a participant for a tournament
+func (r *TournamentRepository) AddParticipant(participant *models.TournamentParticipant) error {
+	return r.db.Create(participant).Error
+}
+
+// RemoveParticipant removes a participant from a tournament
+func (r *TournamentRepository) RemoveParticipant(tournamentID, userID string) error {
+	result := r.db.Where("tournament_id = ? AND user_id = ?", tournamentID, userID).
+		Delete(&models.TournamentParticipant{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("participant not found")
+	}
+	return nil
+}
+
+// UpdateParticipant updates a participant
+func (r *TournamentRepository) UpdateParticipant(participant *models.TournamentParticipant) error {
+	return r.db.Omit("User").Save(participant).Error
+}
+
+// GetParticipants gets all participants of a tournament
+func (r *TournamentRepository) GetParticipants(tournamentID string) ([]models.TournamentParticipant, error) {
+	var participants []models.TournamentParticipant
+	err := r.db.Preload("User").
+		Where("tournament_id = ?", tournamentID).
+		Order("seed ASC").
+		Find(&participants).Error
+	return participants, err
+}
+
+// CountParticipants counts the participants of a tournament
+func (r *TournamentRepository) CountParticipants(tournamentID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.TournamentParticipant{}).
+		Where("tournament_id = ?", tournamentID).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateRound creates a round together with its pairings
+func (r *TournamentRepository) CreateRound(round *models.TournamentRound) error {
+	return r.db.Create(round).Error
+}
+
+// UpdateRound updates a round
+func (r *TournamentRepository) UpdateRound(round *models.TournamentRound) error {
+	return r.db.Omit("Pairings").Save(round).Error
+}
+
+// GetRounds gets all rounds of a tournament with their pairings
+func (r *TournamentRepository) GetRounds(tournamentID string) ([]models.TournamentRound, error) {
+	var rounds []models.TournamentRound
+	err := r.db.Preload("Pairings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("board ASC")
+	}).
+		Where("tournament_id = ?", tournamentID).
+		Order("number ASC").
+		Find(&rounds).Error
+	return rounds, err
+}
+
+// FindRound finds a round of a tournament by number
+func (r *TournamentRepository) FindRound(tournamentID string, number int) (*models.TournamentRound, error) {
+	var round models.TournamentRound
+	err := r.db.Preload("Pairings", func(db *gorm.DB) *gorm.DB {
+		return db.Order("board ASC")
+	}).
+		Where("tournament_id = ? AND number = ?", tournamentID, number).
+		First(&round).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("round not found")
+		}
+		return nil, err
+	}
+	return &round, nil
+}
+
+// FindOverdueRounds finds uncompleted rounds whose deadline has passed
+func (r *TournamentRepository) FindOverdueRounds() ([]models.TournamentRound, error) {
+	var rounds []models.TournamentRound
+	err := r.db.Where("completed_at IS NULL AND deadline < NOW()").Find(&rounds).Error
+	return rounds, err
+}
+
+// UpdatePairing updates a pairing
+func (r *TournamentRepository) UpdatePairing(pairing *models.TournamentPairing) error {
+	return r.db.Save(pairing).Error
+}
+
+// FindPairingByGameID finds the pairing that is played in a game
+func (r *TournamentRepository) FindPairingByGameID(gameID string) (*models.TournamentPairing, error) {
+	var pairing models.TournamentPairing
+	err := r.db.Where("game_id = ?", gameID).First(&pairing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("pairing not found")
+		}
+		return nil, err
+	}
+	return &pairing, nil
+}
+
+// GetPairings gets all pairings of a tournament
+func (r *TournamentRepository) GetPairings(tournamentID string) ([]models.TournamentPairing, error) {
+	var pairings []models.TournamentPairing
+	err := r.db.Where("tournament_id = ?", tournamentID).Find(&pairings).Error
+	return pairings, err
+}
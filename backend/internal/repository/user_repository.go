@@ -6,6 +6,7 @@ import (
 
 	"github.com/hectoclash/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserRepository handles database operations for users
@@ -62,6 +63,13 @@ func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	return &user, nil
 }
 
+// FindBotsByOwner gets the external bots a user runs
+func (r *UserRepository) FindBotsByOwner(ownerID string) ([]models.User, error) {
+	var users []models.User
+	err := r.db.Where("bot_owner_id = ? AND deleted_at IS NULL", ownerID).Order("created_at ASC").Find(&users).Error
+	return users, err
+}
+
 // Update updates a user
 func (r *UserRepository) Update(user *models.User) error {
 	return r.db.Save(user).Error
@@ -98,6 +106,21 @@ func (r *UserRepository) GetUserStats(userID string) (*models.UserStats, error)
 	return &stats, nil
 }
 
+// LockUserStats gets a user's stats and locks their row until the surrounding transaction ends
+func (r *UserRepository) LockUserStats(userID string) (*models.UserStats, error) {
+	// Make sure the stats exist
+	if _, err := r.GetUserStats(userID); err != nil {
+		return nil, err
+	}
+
+	var stats models.UserStats
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&stats, "user_id = ?", userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
 // UpdateUserStats updates user statistics
 func (r *UserRepository) UpdateUserStats(stats *models.UserStats) error {
 	return r.db.Save(stats).Error
@@ -106,7 +129,7 @@ func (r *UserRepository) UpdateUserStats(stats *models.UserStats) error {
 // GetTopPlayers gets the top players by rating
 func (r *UserRepository) GetTopPlayers(limit int) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Order("rating DESC").Limit(limit).Find(&users).Error
+	err := r.db.Where("is_bot = ?", false).Order("rating DESC").Limit(limit).Find(&users).Error
 	return users, err
 }
 
@@ -140,6 +163,155 @@ func (r *UserRepository) UpdateUserRating(userID string, newRating int) error {
 	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("rating", newRating).Error
 }
 
+// GetUserRating gets a user's rating in a game mode, creating it if it does not exist yet
+func (r *UserRepository) GetUserRating(userID string, mode models.RatingMode) (*models.UserRating, error) {
+	var rating models.UserRating
+	err := r.db.First(&rating, "user_id = ? AND mode = ?", userID, mode).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Create new rating if not found
+			rating = models.UserRating{
+				UserID:           userID,
+				Mode:             mode,
+				Rating:           1000,
+				RatingDeviation:  350,
+				RatingVolatility: 0.06,
+				Provisional:      true,
+			}
+			err = r.db.Create(&rating).Error
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, err
+		}
+	}
+	return &rating, nil
+}
+
+// LockUserRating gets a user's rating in a game mode and locks its row until the surrounding transaction ends
+func (r *UserRepository) LockUserRating(userID string, mode models.RatingMode) (*models.UserRating, error) {
+	// Make sure the rating exists
+	if _, err := r.GetUserRating(userID, mode); err != nil {
+		return nil, err
+	}
+
+	var rating models.UserRating
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rating, "user_id = ? AND mode = ?", userID, mode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// SaveUserRating saves a user's rating in a game mode. The duel rating is mirrored to the user and their stats.
+func (r *UserRepository) SaveUserRating(rating *models.UserRating) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(rating).Error; err != nil {
+			return err
+		}
+
+		if rating.Mode != models.RatingModeDuel {
+			return nil
+		}
+
+		err := tx.Model(&models.User{}).Where("id = ?", rating.UserID).Updates(map[string]any{
+			"rating":            rating.Rating,
+			"rating_deviation":  rating.RatingDeviation,
+			"rating_volatility": rating.RatingVolatility,
+			"rated_games":       rating.RatedGames,
+			"provisional":       rating.Provisional,
+			"rated_at":          rating.RatedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.UserStats{}).Where("user_id = ?", rating.UserID).
+			Update("rating", rating.Rating).Error
+	})
+}
+
+// GetTopPlayersByMode gets the top players of a game mode by rating
+func (r *UserRepository) GetTopPlayersByMode(mode models.RatingMode, limit, offset int) ([]models.UserRating, error) {
+	var ratings []models.UserRating
+	err := r.db.Preload("User").
+		Joins("JOIN users ON users.id = user_ratings.user_id AND users.is_bot = ?", false).
+		Where("user_ratings.mode = ?", mode).
+		Order("user_ratings.rating DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&ratings).Error
+	return ratings, err
+}
+
+// AddRatingHistory records a change of a user's rating in a game mode
+func (r *UserRepository) AddRatingHistory(userID string, gameID *string, mode models.RatingMode, source models.RatingSource, before, after int) error {
+	entry := &models.RatingHistory{
+		UserID:       userID,
+		GameID:       gameID,
+		Mode:         mode,
+		Source:       source,
+		RatingBefore: before,
+		RatingAfter:  after,
+		Delta:        after - before,
+	}
+	return r.db.Create(entry).Error
+}
+
+// GetRatingHistory gets a user's rating changes in a game mode in chronological order, optionally within a time range
+func (r *UserRepository) GetRatingHistory(userID string, mode models.RatingMode, from, to *time.Time) ([]models.RatingHistory, error) {
+	query := r.db.Where("user_id = ? AND mode = ?", userID, mode)
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	var history []models.RatingHistory
+	err := query.Order("created_at ASC").Find(&history).Error
+	return history, err
+}
+
+// FindRatingExtreme finds the rating change that left a user at their highest or lowest rating in a game mode
+func (r *UserRepository) FindRatingExtreme(userID string, mode models.RatingMode, highest bool) (*models.RatingHistory, error) {
+	order := "rating_after ASC, created_at ASC"
+	if highest {
+		order = "rating_after DESC, created_at ASC"
+	}
+
+	var entry models.RatingHistory
+	err := r.db.Where("user_id = ? AND mode = ?", userID, mode).Order(order).First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("rating history not found")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// FindFirstRatingChange finds the oldest rating change of a user in a game mode
+func (r *UserRepository) FindFirstRatingChange(userID string, mode models.RatingMode) (*models.RatingHistory, error) {
+	var entry models.RatingHistory
+	err := r.db.Where("user_id = ? AND mode = ?", userID, mode).Order("created_at ASC").First(&entry).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("rating history not found")
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// CountRatingChanges counts the rating changes of a user in a game mode
+func (r *UserRepository) CountRatingChanges(userID string, mode models.RatingMode) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.RatingHistory{}).Where("user_id = ? AND mode = ?", userID, mode).Count(&count).Error
+	return count, err
+}
+
 // GetUserWithStats gets a user with their stats
 func (r *UserRepository) GetUserWithStats(userID string) (*models.User, *models.UserStats, error) {
 	user, err := r.FindByID(userID)
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/hectoclash/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ZenRepository handles database operations for the solutions players find in zen mode
+type ZenRepository struct {
+	db *gorm.DB
+}
+
+// NewZenRepository creates a new zen repository
+func NewZenRepository(db *gorm.DB) *ZenRepository {
+	return &ZenRepository{db: db}
+}
+
+// AddFound records that a player found a solution, reporting false if they had found it before
+func (r *ZenRepository) AddFound(found *models.FoundSolution) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(found)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// FindFound gets the solutions a player found to a puzzle, in the order they were found
+func (r *ZenRepository) FindFound(userID, puzzleID string) ([]models.FoundSolution, error) {
+	var found []models.FoundSolution
+	err := r.db.Preload("Solution").
+		Where("user_id = ? AND puzzle_id = ?", userID, puzzleID).
+		Order("created_at ASC").
+		Find(&found).Error
+	return found, err
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupAdminRoutes sets up the routes for operators
+func SetupAdminRoutes(router *gin.Engine, matchmakingHandler *handlers.MatchmakingHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for admin routes
+	adminGroup := router.Group("/api/admin")
+	{
+		// All admin routes require an administrator
+		adminGroup.Use(authMiddleware.RequireAuth(), authMiddleware.RequireAdmin())
+
+		// Matchmaking quality dashboard
+		adminGroup.GET("/matchmaking/stats", matchmakingHandler.GetStats)
+	}
+}
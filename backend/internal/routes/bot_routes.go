@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupBotRoutes sets up the routes of the bot API
+func SetupBotRoutes(router *gin.Engine, botHandler *handlers.BotHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Routes for people running bots and challenging them
+	botsGroup := router.Group("/api/bots")
+	{
+		// All bot management routes require authentication
+		botsGroup.Use(authMiddleware.RequireAuth())
+
+		// Create a bot account
+		botsGroup.POST("", botHandler.CreateBot)
+
+		// Get the current user's bots
+		botsGroup.GET("", botHandler.GetBots)
+
+		// Create, list and revoke a bot's API tokens
+		botsGroup.POST("/:id/tokens", botHandler.CreateToken)
+		botsGroup.GET("/:id/tokens", botHandler.GetTokens)
+		botsGroup.DELETE("/:id/tokens/:tokenId", botHandler.RevokeToken)
+
+		// Challenge a bot to a game
+		botsGroup.POST("/:id/challenge", botHandler.ChallengeBot)
+	}
+
+	// Routes for bots, authenticated with a bot token
+	botGroup := router.Group("/api/bot")
+	{
+		botGroup.Use(authMiddleware.RequireBot())
+
+		// Get the bot's account
+		botGroup.GET("/account", botHandler.GetAccount)
+
+		// Get, accept and decline challenges
+		botGroup.GET("/challenges", botHandler.GetChallenges)
+		botGroup.POST("/challenges/:id/accept", botHandler.AcceptChallenge)
+		botGroup.POST("/challenges/:id/decline", botHandler.DeclineChallenge)
+	}
+}
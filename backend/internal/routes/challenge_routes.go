@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupChallengeRoutes sets up the routes for direct challenges between players
+func SetupChallengeRoutes(router *gin.Engine, challengeHandler *handlers.ChallengeHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for challenge routes
+	challengeGroup := router.Group("/api/challenges")
+	{
+		// All challenge routes require authentication
+		challengeGroup.Use(authMiddleware.RequireAuth())
+
+		// List open challenges received and sent
+		challengeGroup.GET("", challengeHandler.GetChallenges)
+
+		// Challenge another player
+		challengeGroup.POST("", challengeHandler.CreateChallenge)
+
+		// Answer a challenge
+		challengeGroup.POST("/:id/accept", challengeHandler.AcceptChallenge)
+		challengeGroup.POST("/:id/decline", challengeHandler.DeclineChallenge)
+		challengeGroup.POST("/:id/counter", challengeHandler.CounterChallenge)
+
+		// Withdraw a challenge
+		challengeGroup.DELETE("/:id", challengeHandler.DeclineChallenge)
+	}
+}
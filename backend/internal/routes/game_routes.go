@@ -17,6 +17,9 @@ func SetupGameRoutes(router *gin.Engine, gameHandler *handlers.GameHandler, auth
 		// Create a new game (requires authentication)
 		gameGroup.POST("", authMiddleware.RequireAuth(), gameHandler.CreateGame)
 
+		// Get live games that can be spectated
+		gameGroup.GET("/live", authMiddleware.OptionalAuth(), gameHandler.GetLiveGames)
+
 		// Get a game by ID
 		gameGroup.GET("/:id", authMiddleware.OptionalAuth(), gameHandler.GetGame)
 
@@ -26,6 +29,12 @@ func SetupGameRoutes(router *gin.Engine, gameHandler *handlers.GameHandler, auth
 		// Submit a solution for a game (requires authentication)
 		gameGroup.POST("/:id/submit", authMiddleware.RequireAuth(), gameHandler.SubmitSolution)
 
+		// Get the replay of a finished game
+		gameGroup.GET("/:id/replay", authMiddleware.OptionalAuth(), gameHandler.GetGameReplay)
+
+		// Get the rematch series of a game
+		gameGroup.GET("/:id/series", authMiddleware.OptionalAuth(), gameHandler.GetGameSeries)
+
 		// Get duel status (requires authentication)
 		gameGroup.GET("/:id/duel", authMiddleware.RequireAuth(), gameHandler.GetDuelStatus)
 	}
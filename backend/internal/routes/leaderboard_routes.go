@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupLeaderboardRoutes sets up the leaderboard routes
+func SetupLeaderboardRoutes(router *gin.Engine, ratingHandler *handlers.RatingHandler, rushHandler *handlers.RushHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for leaderboard routes
+	leaderboardGroup := router.Group("/api/leaderboard")
+	{
+		// Get the top rated players of a game mode
+		leaderboardGroup.GET("/ratings", authMiddleware.OptionalAuth(), ratingHandler.GetLeaderboard)
+
+		// Get the best Puzzle Rush runs of a length
+		leaderboardGroup.GET("/rush", authMiddleware.OptionalAuth(), rushHandler.GetLeaderboard)
+	}
+}
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupLobbyRoutes sets up the private lobby routes
+func SetupLobbyRoutes(router *gin.Engine, lobbyHandler *handlers.LobbyHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for lobby routes
+	lobbyGroup := router.Group("/api/lobbies")
+	{
+		// Create a new lobby (requires authentication)
+		lobbyGroup.POST("", authMiddleware.RequireAuth(), lobbyHandler.CreateLobby)
+
+		// Get a lobby by invite code
+		lobbyGroup.GET("/:code", authMiddleware.OptionalAuth(), lobbyHandler.GetLobby)
+
+		// Join a lobby (requires authentication)
+		lobbyGroup.POST("/:code/join", authMiddleware.RequireAuth(), lobbyHandler.JoinLobby)
+
+		// Leave a lobby (requires authentication)
+		lobbyGroup.POST("/:code/leave", authMiddleware.RequireAuth(), lobbyHandler.LeaveLobby)
+
+		// Change lobby settings (requires authentication)
+		lobbyGroup.PUT("/:code/settings", authMiddleware.RequireAuth(), lobbyHandler.UpdateSettings)
+
+		// Kick a player from a lobby (requires authentication)
+		lobbyGroup.POST("/:code/kick", authMiddleware.RequireAuth(), lobbyHandler.KickPlayer)
+
+		// Start the lobby's game (requires authentication)
+		lobbyGroup.POST("/:code/start", authMiddleware.RequireAuth(), lobbyHandler.StartLobby)
+	}
+}
@@ -23,6 +23,20 @@ func SetupMatchmakingRoutes(router *gin.Engine, matchmakingHandler *handlers.Mat
 		// Get queue status
 		matchmakingGroup.GET("/queue/status", matchmakingHandler.GetQueueStatus)
 
+		// Accept or decline a match before its game is created
+		matchmakingGroup.POST("/ready/accept", matchmakingHandler.AcceptReadyCheck)
+		matchmakingGroup.POST("/ready/decline", matchmakingHandler.DeclineReadyCheck)
+
+		// Queue together with a friend as a party
+		matchmakingGroup.GET("/party", matchmakingHandler.GetParty)
+		matchmakingGroup.POST("/party/invite", matchmakingHandler.InviteToParty)
+		matchmakingGroup.POST("/party/:id/accept", matchmakingHandler.AcceptPartyInvite)
+		matchmakingGroup.POST("/party/leave", matchmakingHandler.LeaveParty)
+		matchmakingGroup.DELETE("/party", matchmakingHandler.DisbandParty)
+
+		// Play the bot offered while waiting in the queue
+		matchmakingGroup.POST("/bot", matchmakingHandler.AcceptBotOffer)
+
 		// Create custom game
 		matchmakingGroup.POST("/custom", matchmakingHandler.CreateCustomGame)
 	}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupTournamentRoutes sets up the tournament routes
+func SetupTournamentRoutes(router *gin.Engine, tournamentHandler *handlers.TournamentHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for tournament routes
+	tournamentGroup := router.Group("/api/tournaments")
+	{
+		// List tournaments
+		tournamentGroup.GET("", authMiddleware.OptionalAuth(), tournamentHandler.GetTournaments)
+
+		// Create a new tournament (requires authentication)
+		tournamentGroup.POST("", authMiddleware.RequireAuth(), tournamentHandler.CreateTournament)
+
+		// Get a tournament by ID
+		tournamentGroup.GET("/:id", authMiddleware.OptionalAuth(), tournamentHandler.GetTournament)
+
+		// Register for a tournament (requires authentication)
+		tournamentGroup.POST("/:id/register", authMiddleware.RequireAuth(), tournamentHandler.Register)
+
+		// Unregister from a tournament (requires authentication)
+		tournamentGroup.DELETE("/:id/register", authMiddleware.RequireAuth(), tournamentHandler.Unregister)
+
+		// Start a tournament (requires authentication)
+		tournamentGroup.POST("/:id/start", authMiddleware.RequireAuth(), tournamentHandler.StartTournament)
+
+		// Get tournament standings
+		tournamentGroup.GET("/:id/standings", authMiddleware.OptionalAuth(), tournamentHandler.GetStandings)
+
+		// Get tournament rounds and pairings
+		tournamentGroup.GET("/:id/rounds", authMiddleware.OptionalAuth(), tournamentHandler.GetRounds)
+	}
+}
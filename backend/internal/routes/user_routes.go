@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hectoclash/internal/handlers"
+	"github.com/hectoclash/internal/middleware"
+)
+
+// SetupUserRoutes sets up the user routes
+func SetupUserRoutes(router *gin.Engine, ratingHandler *handlers.RatingHandler, rushHandler *handlers.RushHandler, authMiddleware *middleware.AuthMiddleware) {
+	// Create a group for user routes
+	userGroup := router.Group("/api/users")
+	{
+		// Get a user's rating in every game mode
+		userGroup.GET("/:id/ratings", authMiddleware.OptionalAuth(), ratingHandler.GetRatings)
+
+		// Get a user's rating history
+		userGroup.GET("/:id/rating-history", authMiddleware.OptionalAuth(), ratingHandler.GetRatingHistory)
+
+		// Get a user's peak and lowest rating
+		userGroup.GET("/:id/rating-stats", authMiddleware.OptionalAuth(), ratingHandler.GetRatingStats)
+
+		// Get a user's best Puzzle Rush run of each length
+		userGroup.GET("/:id/rush-bests", authMiddleware.OptionalAuth(), rushHandler.GetPersonalBests)
+
+		// Get a user's Puzzle Rush runs
+		userGroup.GET("/:id/rush-runs", authMiddleware.OptionalAuth(), rushHandler.GetRuns)
+	}
+}
@@ -20,6 +20,18 @@ func RegisterWebSocketRoutes(router *gin.Engine, wsHandler *websocket.Handler, a
 		// Game WebSocket connection
 		ws.GET("/game/:id", authMiddleware.RequireAuth(), wsHandler.HandleGameConnection)
 
+		// Spectator WebSocket connection (read-only)
+		ws.GET("/spectate/:id", authMiddleware.OptionalAuth(), wsHandler.HandleSpectatorConnection)
+
+		// Private lobby WebSocket connection
+		ws.GET("/lobby/:code", authMiddleware.RequireAuth(), wsHandler.HandleLobbyConnection)
+
+		// Tournament WebSocket connection
+		ws.GET("/tournament/:id", authMiddleware.OptionalAuth(), wsHandler.HandleTournamentConnection)
+
+		// Event stream of an external bot
+		ws.GET("/bot", authMiddleware.RequireBot(), wsHandler.HandleBotConnection)
+
 		// Reconnection endpoint
 		ws.GET("/reconnect", authMiddleware.RequireAuth(), wsHandler.HandleReconnection)
 	}
@@ -60,6 +60,11 @@ func (s *AuthService) Register(input RegisterInput) (*models.User, error) {
 		return nil, errors.New("email already in use")
 	}
 
+	// Built-in bots are named with a prefix nobody else may use
+	if models.IsReservedUsername(input.Username) {
+		return nil, errors.New("username is reserved")
+	}
+
 	// Check if username already exists
 	existingUser, err = s.userRepo.FindByUsername(input.Username)
 	if err == nil && existingUser != nil {
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+)
+
+const (
+	// BotTokenPrefix starts every bot token, so they are told apart from session tokens
+	BotTokenPrefix = "hcb_"
+
+	// Bot accounts get an address that can never receive mail
+	botEmailDomain = "bots.hectoclash"
+
+	// How many bots one person may run
+	maxBotsPerOwner = 5
+)
+
+// BotAccountService manages the accounts and API tokens of external bots
+type BotAccountService struct {
+	userRepo     *repository.UserRepository
+	botTokenRepo *repository.BotTokenRepository
+}
+
+// NewBotAccountService creates a new bot account service
+func NewBotAccountService(userRepo *repository.UserRepository, botTokenRepo *repository.BotTokenRepository) *BotAccountService {
+	return &BotAccountService{
+		userRepo:     userRepo,
+		botTokenRepo: botTokenRepo,
+	}
+}
+
+// CreateBotInput represents the input for creating a bot account
+type CreateBotInput struct {
+	Username string `json:"username" binding:"required,min=3,max=20"`
+}
+
+// CreateBot creates a bot account run by a person
+func (s *BotAccountService) CreateBot(ownerID string, input CreateBotInput) (*models.User, error) {
+	// Only people run bots
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if owner.IsBot {
+		return nil, errors.New("bots cannot create bots")
+	}
+
+	// Check how many bots the owner already runs
+	bots, err := s.userRepo.FindBotsByOwner(ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bots) >= maxBotsPerOwner {
+		return nil, errors.New("bot limit reached")
+	}
+
+	// Built-in bots are named with a prefix nobody else may use
+	if models.IsReservedUsername(input.Username) {
+		return nil, errors.New("username is reserved")
+	}
+
+	// Check if username already exists
+	existingUser, err := s.userRepo.FindByUsername(input.Username)
+	if err == nil && existingUser != nil {
+		return nil, errors.New("username already taken")
+	}
+
+	// Bots authenticate with tokens, so their password is random
+	password, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create bot user
+	bot := &models.User{
+		ID:         uuid.New().String(),
+		Username:   input.Username,
+		Email:      strings.ToLower(input.Username) + "@" + botEmailDomain,
+		Password:   password,
+		Rating:     1000,
+		IsBot:      true,
+		BotOwnerID: &ownerID,
+	}
+
+	err = s.userRepo.Create(bot)
+	if err != nil {
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+// GetBots gets the bots a person runs
+func (s *BotAccountService) GetBots(ownerID string) ([]models.User, error) {
+	return s.userRepo.FindBotsByOwner(ownerID)
+}
+
+// GetBot gets the account of an external bot
+func (s *BotAccountService) GetBot(botID string) (*models.User, error) {
+	bot, err := s.userRepo.FindByID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if !bot.IsExternalBot() {
+		return nil, errors.New("bot not found")
+	}
+	return bot, nil
+}
+
+// CreateToken creates a new API token for a bot. The token is only returned here.
+func (s *BotAccountService) CreateToken(ownerID, botID, name string) (*models.BotTokenResponse, error) {
+	// Check if the bot belongs to the owner
+	if _, err := s.getOwnedBot(ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	// Generate the token
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+	tokenString := BotTokenPrefix + secret
+
+	token := &models.BotToken{
+		UserID:    botID,
+		Name:      name,
+		TokenHash: hashBotToken(tokenString),
+		Prefix:    tokenString[:len(BotTokenPrefix)+6],
+	}
+
+	err = s.botTokenRepo.Create(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BotTokenResponse{BotToken: *token, Token: tokenString}, nil
+}
+
+// GetTokens gets the tokens of a bot, without the tokens themselves
+func (s *BotAccountService) GetTokens(ownerID, botID string) ([]models.BotToken, error) {
+	// Check if the bot belongs to the owner
+	if _, err := s.getOwnedBot(ownerID, botID); err != nil {
+		return nil, err
+	}
+
+	return s.botTokenRepo.FindByUser(botID)
+}
+
+// RevokeToken revokes a token of a bot
+func (s *BotAccountService) RevokeToken(ownerID, botID, tokenID string) error {
+	// Check if the bot belongs to the owner
+	if _, err := s.getOwnedBot(ownerID, botID); err != nil {
+		return err
+	}
+
+	// Find token by ID
+	token, err := s.botTokenRepo.FindByID(tokenID)
+	if err != nil {
+		return err
+	}
+	if token.UserID != botID {
+		return errors.New("bot token not found")
+	}
+
+	return s.botTokenRepo.Revoke(tokenID)
+}
+
+// ValidateToken finds the bot a token belongs to
+func (s *BotAccountService) ValidateToken(tokenString string) (*models.User, error) {
+	if !IsBotToken(tokenString) {
+		return nil, errors.New("invalid bot token")
+	}
+
+	token, err := s.botTokenRepo.FindActiveByHash(hashBotToken(tokenString))
+	if err != nil {
+		return nil, errors.New("invalid bot token")
+	}
+
+	// Record the use in background
+	go func() {
+		_ = s.botTokenRepo.TouchLastUsed(token.ID)
+	}()
+
+	return &token.User, nil
+}
+
+// IsBotToken checks if a bearer token is a bot token rather than a session token
+func IsBotToken(tokenString string) bool {
+	return strings.HasPrefix(tokenString, BotTokenPrefix)
+}
+
+// Helper function to find a bot run by a person
+func (s *BotAccountService) getOwnedBot(ownerID, botID string) (*models.User, error) {
+	bot, err := s.userRepo.FindByID(botID)
+	if err != nil {
+		return nil, err
+	}
+	if bot.BotOwnerID == nil || *bot.BotOwnerID != ownerID {
+		return nil, errors.New("bot not found")
+	}
+	return bot, nil
+}
+
+// Helper function to hash a bot token for storage
+func hashBotToken(tokenString string) string {
+	sum := sha256.Sum256([]byte(tokenString))
+	return hex.EncodeToString(sum[:])
+}
+
+// Helper function to generate a random hex string from n random bytes
+func randomHex(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
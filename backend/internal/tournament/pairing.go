@@ -0,0 +1,204 @@
+package tournament
+
+import (
+	"math"
+	"sort"
+
+	"github.com/hectoclash/internal/models"
+)
+
+// pairingSpec describes a pairing before it is persisted
+type pairingSpec struct {
+	Player1ID string
+	Player2ID *string // nil for a bye
+}
+
+// IsBye reports whether the pairing is a bye
+func (p pairingSpec) IsBye() bool {
+	return p.Player2ID == nil
+}
+
+// defaultRounds returns the number of rounds needed to separate a field of the given size.
+// This is the length of a knockout bracket and the usual length of a Swiss event.
+func defaultRounds(playerCount int) int {
+	if playerCount < 2 {
+		return 0
+	}
+	return int(math.Ceil(math.Log2(float64(playerCount))))
+}
+
+// bracketOrder returns the seeds of a bracket of the given size in board order,
+// so that seed 1 meets the lowest seed and the top two seeds can only meet in the final.
+// For a size of 8 this is 1, 8, 4, 5, 2, 7, 3, 6.
+func bracketOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order)*2 + 1
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, n-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// pairSingleEliminationFirstRound pairs seeded participants for the first knockout round.
+// When the field is not a power of two the top seeds receive byes.
+func pairSingleEliminationFirstRound(participants []models.TournamentParticipant) []pairingSpec {
+	bySeed := make(map[int]string, len(participants))
+	for _, p := range participants {
+		bySeed[p.Seed] = p.UserID
+	}
+
+	size := 1
+	for size < len(participants) {
+		size *= 2
+	}
+
+	order := bracketOrder(size)
+	pairings := make([]pairingSpec, 0, size/2)
+	for i := 0; i < len(order); i += 2 {
+		player1, ok1 := bySeed[order[i]]
+		player2, ok2 := bySeed[order[i+1]]
+
+		switch {
+		case ok1 && ok2:
+			pairings = append(pairings, pairingSpec{Player1ID: player1, Player2ID: &player2})
+		case ok1:
+			pairings = append(pairings, pairingSpec{Player1ID: player1})
+		case ok2:
+			pairings = append(pairings, pairingSpec{Player1ID: player2})
+		}
+	}
+
+	return pairings
+}
+
+// pairSingleEliminationNextRound pairs the winners of the previous round in bracket order
+func pairSingleEliminationNextRound(previous []models.TournamentPairing) []pairingSpec {
+	sorted := make([]models.TournamentPairing, len(previous))
+	copy(sorted, previous)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Board < sorted[j].Board })
+
+	winners := make([]string, 0, len(sorted))
+	for _, p := range sorted {
+		if winner := pairingWinner(&p); winner != "" {
+			winners = append(winners, winner)
+		}
+	}
+
+	pairings := make([]pairingSpec, 0, (len(winners)+1)/2)
+	for i := 0; i < len(winners); i += 2 {
+		if i+1 < len(winners) {
+			player2 := winners[i+1]
+			pairings = append(pairings, pairingSpec{Player1ID: winners[i], Player2ID: &player2})
+		} else {
+			pairings = append(pairings, pairingSpec{Player1ID: winners[i]})
+		}
+	}
+
+	return pairings
+}
+
+// pairSwissRound pairs participants with similar scores while avoiding rematches.
+// The bye goes to the lowest-ranked participant that has not had one yet.
+func pairSwissRound(participants []models.TournamentParticipant, history []models.TournamentPairing) []pairingSpec {
+	ranked := make([]models.TournamentParticipant, len(participants))
+	copy(ranked, participants)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Points != ranked[j].Points {
+			return ranked[i].Points > ranked[j].Points
+		}
+		if ranked[i].Rating != ranked[j].Rating {
+			return ranked[i].Rating > ranked[j].Rating
+		}
+		return ranked[i].Seed < ranked[j].Seed
+	})
+
+	played := playedOpponents(history)
+	pairings := make([]pairingSpec, 0, (len(ranked)+1)/2)
+
+	// Hand out the bye first so the remaining field is even
+	if len(ranked)%2 == 1 {
+		byeIndex := len(ranked) - 1
+		for i := len(ranked) - 1; i >= 0; i-- {
+			if !ranked[i].HadBye {
+				byeIndex = i
+				break
+			}
+		}
+		pairings = append(pairings, pairingSpec{Player1ID: ranked[byeIndex].UserID})
+		ranked = append(ranked[:byeIndex], ranked[byeIndex+1:]...)
+	}
+
+	paired := make([]bool, len(ranked))
+	games := make([]pairingSpec, 0, len(ranked)/2)
+	for i := range ranked {
+		if paired[i] {
+			continue
+		}
+
+		// Prefer the closest-ranked opponent that has not been played yet
+		opponent := -1
+		fallback := -1
+		for j := i + 1; j < len(ranked); j++ {
+			if paired[j] {
+				continue
+			}
+			if fallback == -1 {
+				fallback = j
+			}
+			if !played[ranked[i].UserID][ranked[j].UserID] {
+				opponent = j
+				break
+			}
+		}
+		if opponent == -1 {
+			opponent = fallback
+		}
+		if opponent == -1 {
+			continue
+		}
+
+		paired[i] = true
+		paired[opponent] = true
+		player2 := ranked[opponent].UserID
+		games = append(games, pairingSpec{Player1ID: ranked[i].UserID, Player2ID: &player2})
+	}
+
+	// Games come first so board 1 is the top pairing and the bye is listed last
+	return append(games, pairings...)
+}
+
+// Helper function to build the set of opponents each player has already met
+func playedOpponents(history []models.TournamentPairing) map[string]map[string]bool {
+	played := make(map[string]map[string]bool)
+	for _, p := range history {
+		if p.Player2ID == nil {
+			continue
+		}
+		if played[p.Player1ID] == nil {
+			played[p.Player1ID] = make(map[string]bool)
+		}
+		if played[*p.Player2ID] == nil {
+			played[*p.Player2ID] = make(map[string]bool)
+		}
+		played[p.Player1ID][*p.Player2ID] = true
+		played[*p.Player2ID][p.Player1ID] = true
+	}
+	return played
+}
+
+// Helper function to get the user ID of the winner of a pairing
+func pairingWinner(p *models.TournamentPairing) string {
+	switch p.Result {
+	case models.PairingResultPlayer1, models.PairingResultBye:
+		return p.Player1ID
+	case models.PairingResultPlayer2:
+		if p.Player2ID != nil {
+			return *p.Player2ID
+		}
+	}
+	return ""
+}
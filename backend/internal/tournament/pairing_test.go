@@ -0,0 +1,181 @@
+package tournament
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hectoclash/internal/models"
+)
+
+func TestBracketOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{4, []int{1, 4, 2, 3}},
+		{8, []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+
+	for _, tt := range tests {
+		if got := bracketOrder(tt.size); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("bracketOrder(%d) = %v, want %v", tt.size, got, tt.want)
+		}
+	}
+
+	// The top two seeds sit in different halves of every bracket, so they can only meet in the final
+	for _, size := range []int{4, 8, 16, 32} {
+		order := bracketOrder(size)
+		top, second := -1, -1
+		for i, seed := range order {
+			switch seed {
+			case 1:
+				top = i
+			case 2:
+				second = i
+			}
+		}
+		if top < size/2 == (second < size/2) {
+			t.Errorf("bracketOrder(%d) puts seeds 1 and 2 in the same half", size)
+		}
+	}
+}
+
+func TestPairSingleEliminationFirstRoundGivesTopSeedsByes(t *testing.T) {
+	participants := seededParticipants(6)
+
+	got := describeSpecs(pairSingleEliminationFirstRound(participants))
+	want := []string{"p1 bye", "p4-p5", "p2 bye", "p3-p6"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("pairSingleEliminationFirstRound() = %v, want %v", got, want)
+	}
+}
+
+func TestPairSwissRound(t *testing.T) {
+	tests := []struct {
+		name         string
+		participants []models.TournamentParticipant
+		history      []models.TournamentPairing
+		want         []string
+	}{
+		{
+			name:         "first round pairs neighbours by rating",
+			participants: seededParticipants(4),
+			want:         []string{"p1-p2", "p3-p4"},
+		},
+		{
+			name: "players are paired within their score group",
+			participants: withPoints(seededParticipants(4), map[string]float64{
+				"p1": 1, "p2": 0, "p3": 1, "p4": 0,
+			}),
+			history: []models.TournamentPairing{testPairing("p1", "p2", models.PairingResultPlayer1), testPairing("p3", "p4", models.PairingResultPlayer1)},
+			want:    []string{"p1-p3", "p2-p4"},
+		},
+		{
+			name: "rematches are avoided",
+			participants: withPoints(seededParticipants(4), map[string]float64{
+				"p1": 1, "p2": 1, "p3": 0, "p4": 0,
+			}),
+			history: []models.TournamentPairing{testPairing("p1", "p2", models.PairingResultDraw), testPairing("p3", "p4", models.PairingResultDraw)},
+			want:    []string{"p1-p3", "p2-p4"},
+		},
+		{
+			name:         "bye goes to the lowest ranked player",
+			participants: seededParticipants(5),
+			want:         []string{"p1-p2", "p3-p4", "p5 bye"},
+		},
+		{
+			name: "nobody gets a second bye",
+			participants: withBye(withPoints(seededParticipants(5), map[string]float64{
+				"p1": 1, "p2": 0, "p3": 1, "p4": 0, "p5": 1,
+			}), "p5"),
+			want: []string{"p1-p3", "p5-p2", "p4 bye"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeSpecs(pairSwissRound(tt.participants, tt.history))
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("pairSwissRound() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComputeBuchholz(t *testing.T) {
+	participants := withPoints(seededParticipants(4), map[string]float64{
+		"p1": 2, "p2": 0, "p3": 1.5, "p4": 0.5,
+	})
+	pairings := []models.TournamentPairing{
+		testPairing("p1", "p2", models.PairingResultPlayer1),
+		testPairing("p3", "p4", models.PairingResultDraw),
+		testPairing("p1", "p3", models.PairingResultPlayer1),
+		{Player1ID: "p2", Result: models.PairingResultBye},   // Byes add nothing
+		testPairing("p2", "p4", models.PairingResultPending), // Neither do unplayed games
+	}
+
+	computeBuchholz(participants, pairings)
+
+	want := map[string]float64{
+		"p1": 0 + 1.5, // p2 and p3
+		"p2": 2,       // p1
+		"p3": 0.5 + 2, // p4 and p1
+		"p4": 1.5,     // p3
+	}
+	for _, p := range participants {
+		if p.Buchholz != want[p.UserID] {
+			t.Errorf("Buchholz of %s = %v, want %v", p.UserID, p.Buchholz, want[p.UserID])
+		}
+	}
+}
+
+// seededParticipants creates participants p1 to pN, seeded and rated in that order
+func seededParticipants(n int) []models.TournamentParticipant {
+	participants := make([]models.TournamentParticipant, n)
+	for i := range participants {
+		participants[i] = models.TournamentParticipant{
+			UserID: fmt.Sprintf("p%d", i+1),
+			Seed:   i + 1,
+			Rating: 2000 - i*100,
+		}
+	}
+	return participants
+}
+
+// withPoints sets the points of participants
+func withPoints(participants []models.TournamentParticipant, points map[string]float64) []models.TournamentParticipant {
+	for i := range participants {
+		participants[i].Points = points[participants[i].UserID]
+	}
+	return participants
+}
+
+// withBye marks that a participant has had a bye
+func withBye(participants []models.TournamentParticipant, userID string) []models.TournamentParticipant {
+	for i := range participants {
+		if participants[i].UserID == userID {
+			participants[i].HadBye = true
+		}
+	}
+	return participants
+}
+
+// testPairing creates a played pairing between two participants
+func testPairing(player1, player2 string, result models.PairingResult) models.TournamentPairing {
+	return models.TournamentPairing{Player1ID: player1, Player2ID: &player2, Result: result}
+}
+
+// describeSpecs writes pairings as "p1-p2", or "p1 bye" for a bye
+func describeSpecs(specs []pairingSpec) []string {
+	described := make([]string, len(specs))
+	for i, spec := range specs {
+		if spec.IsBye() {
+			described[i] = spec.Player1ID + " bye"
+		} else {
+			described[i] = spec.Player1ID + "-" + *spec.Player2ID
+		}
+	}
+	return described
+}
@@ -0,0 +1,774 @@
+package tournament
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hectoclash/internal/game"
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/repository"
+	"github.com/hectoclash/internal/websocket"
+)
+
+const (
+	// Points awarded per result
+	winPoints  = 1.0
+	drawPoints = 0.5
+	byePoints  = 1.0
+
+	// Game type used for tournament games
+	tournamentGameType = "tournament"
+
+	// How often overdue rounds are checked
+	deadlineCheckInterval = 15 * time.Second
+
+	minPlayers = 2
+)
+
+// Service provides tournament functionality
+type Service struct {
+	tournamentRepo *repository.TournamentRepository
+	userRepo       *repository.UserRepository
+	gameService    *game.Service
+	hub            *websocket.Hub
+	mu             sync.Mutex // Serialises result recording and round advancement
+	isRunning      bool
+	stopCh         chan struct{}
+}
+
+// NewService creates a new tournament service
+func NewService(tournamentRepo *repository.TournamentRepository, userRepo *repository.UserRepository, gameService *game.Service, hub *websocket.Hub) *Service {
+	service := &Service{
+		tournamentRepo: tournamentRepo,
+		userRepo:       userRepo,
+		gameService:    gameService,
+		hub:            hub,
+		stopCh:         make(chan struct{}),
+	}
+
+	// Advance rounds as tournament games complete
+	gameService.OnGameCompleted(service.handleGameCompleted)
+
+	return service
+}
+
+// Start starts resolving rounds whose deadline has passed
+func (s *Service) Start() {
+	s.mu.Lock()
+	if s.isRunning {
+		s.mu.Unlock()
+		return
+	}
+	s.isRunning = true
+	s.mu.Unlock()
+
+	log.Println("Tournament service started")
+
+	ticker := time.NewTicker(deadlineCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.resolveOverdueRounds()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Stop stops the tournament service
+func (s *Service) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.isRunning {
+		return
+	}
+
+	close(s.stopCh)
+	s.isRunning = false
+
+	log.Println("Tournament service stopped")
+}
+
+// CreateTournament creates a new tournament open for registration
+func (s *Service) CreateTournament(creatorID, name string, format models.TournamentFormat, maxPlayers, totalRounds, roundTimeLimit int, startsAt *time.Time) (*models.Tournament, error) {
+	// Validate format
+	if format != models.TournamentFormatSingleElimination && format != models.TournamentFormatSwiss {
+		return nil, errors.New("invalid tournament format")
+	}
+
+	// Apply defaults
+	if maxPlayers == 0 {
+		maxPlayers = 16
+	}
+	if maxPlayers < minPlayers {
+		return nil, errors.New("tournament must allow at least two players")
+	}
+	if roundTimeLimit <= 0 {
+		roundTimeLimit = 300
+	}
+	if totalRounds < 0 {
+		return nil, errors.New("invalid number of rounds")
+	}
+
+	// Knockout brackets always run until a single player is left
+	if format == models.TournamentFormatSingleElimination {
+		totalRounds = 0
+	}
+
+	tournament := &models.Tournament{
+		Name:           name,
+		Format:         format,
+		Status:         models.TournamentStatusRegistration,
+		CreatorID:      creatorID,
+		MaxPlayers:     maxPlayers,
+		TotalRounds:    totalRounds,
+		RoundTimeLimit: roundTimeLimit,
+		StartsAt:       startsAt,
+	}
+
+	if err := s.tournamentRepo.Create(tournament); err != nil {
+		return nil, err
+	}
+
+	return tournament, nil
+}
+
+// GetTournament gets a tournament by ID
+func (s *Service) GetTournament(tournamentID string) (*models.Tournament, error) {
+	return s.tournamentRepo.FindByID(tournamentID)
+}
+
+// ListTournaments lists tournaments, optionally filtered by status
+func (s *Service) ListTournaments(status models.TournamentStatus, limit, offset int) ([]models.Tournament, error) {
+	if status == "" {
+		return s.tournamentRepo.FindRecent(limit, offset)
+	}
+	return s.tournamentRepo.FindByStatus(status, limit, offset)
+}
+
+// Register registers a user for a tournament
+func (s *Service) Register(tournamentID, userID string) (*models.TournamentParticipant, error) {
+	// Check if user exists
+	if _, err := s.userRepo.FindByID(userID); err != nil {
+		return nil, err
+	}
+
+	// Seed by the rating of the mode tournament games are rated in
+	userRating, err := s.userRepo.GetUserRating(userID, models.RatingModeForGameType(tournamentGameType))
+	if err != nil {
+		return nil, err
+	}
+
+	participant := &models.TournamentParticipant{
+		TournamentID: tournamentID,
+		UserID:       userID,
+		Rating:       userRating.Rating,
+	}
+
+	// The tournament row stays locked from the checks to the insert, so concurrent registrations
+	// cannot overfill it or slip in after it has started
+	err = s.tournamentRepo.Transaction(func(tournaments *repository.TournamentRepository) error {
+		tournament, err := tournaments.FindByIDForUpdate(tournamentID)
+		if err != nil {
+			return err
+		}
+
+		// Check if registration is open
+		if tournament.Status != models.TournamentStatusRegistration {
+			return errors.New("tournament registration is closed")
+		}
+
+		participants, err := tournaments.GetParticipants(tournamentID)
+		if err != nil {
+			return err
+		}
+
+		// Check if user is already registered
+		for _, p := range participants {
+			if p.UserID == userID {
+				return errors.New("user is already registered")
+			}
+		}
+
+		// Check if tournament is full
+		if len(participants) >= tournament.MaxPlayers {
+			return errors.New("tournament is full")
+		}
+
+		return tournaments.AddParticipant(participant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return participant, nil
+}
+
+// Unregister removes a user from a tournament that has not started yet
+func (s *Service) Unregister(tournamentID, userID string) error {
+	tournament, err := s.tournamentRepo.FindByID(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	if tournament.Status != models.TournamentStatusRegistration {
+		return errors.New("tournament has already started")
+	}
+
+	return s.tournamentRepo.RemoveParticipant(tournamentID, userID)
+}
+
+// StartTournament seeds the participants and starts the first round
+func (s *Service) StartTournament(tournamentID, userID string) (*models.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Close registration under the tournament's row lock, so that nobody registers after the
+	// participants are seeded
+	var tournament *models.Tournament
+	var participants []models.TournamentParticipant
+	err := s.tournamentRepo.Transaction(func(tournaments *repository.TournamentRepository) error {
+		var err error
+		tournament, err = tournaments.FindByIDForUpdate(tournamentID)
+		if err != nil {
+			return err
+		}
+
+		// Only the creator can start a tournament
+		if tournament.CreatorID != userID {
+			return errors.New("only the tournament creator can start it")
+		}
+
+		if tournament.Status != models.TournamentStatusRegistration {
+			return errors.New("tournament has already started")
+		}
+
+		participants, err = tournaments.GetParticipants(tournamentID)
+		if err != nil {
+			return err
+		}
+		if len(participants) < minPlayers {
+			return errors.New("not enough participants")
+		}
+
+		// Seed participants by rating, earliest registration first on ties
+		sort.SliceStable(participants, func(i, j int) bool {
+			if participants[i].Rating != participants[j].Rating {
+				return participants[i].Rating > participants[j].Rating
+			}
+			return participants[i].RegisteredAt.Before(participants[j].RegisteredAt)
+		})
+		for i := range participants {
+			participants[i].Seed = i + 1
+			if err := tournaments.UpdateParticipant(&participants[i]); err != nil {
+				return err
+			}
+		}
+
+		// Derive the number of rounds from the field size
+		if tournament.Format == models.TournamentFormatSingleElimination || tournament.TotalRounds == 0 {
+			tournament.TotalRounds = defaultRounds(len(participants))
+		}
+
+		now := time.Now()
+		tournament.Status = models.TournamentStatusActive
+		tournament.StartedAt = &now
+
+		return tournaments.Update(tournament)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate first round
+	var specs []pairingSpec
+	if tournament.Format == models.TournamentFormatSingleElimination {
+		specs = pairSingleEliminationFirstRound(participants)
+	} else {
+		specs = pairSwissRound(participants, nil)
+	}
+
+	if err := s.startRound(tournament, 1, specs); err != nil {
+		return nil, err
+	}
+
+	return s.tournamentRepo.FindByID(tournamentID)
+}
+
+// GetStandings gets the current standings of a tournament
+func (s *Service) GetStandings(tournamentID string) ([]models.TournamentStanding, error) {
+	participants, err := s.tournamentRepo.GetParticipants(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildStandings(participants), nil
+}
+
+// GetRounds gets all rounds of a tournament with their pairings
+func (s *Service) GetRounds(tournamentID string) ([]models.TournamentRound, error) {
+	return s.tournamentRepo.GetRounds(tournamentID)
+}
+
+// startRound persists a round, scores its byes and creates a game for every other pairing
+func (s *Service) startRound(tournament *models.Tournament, number int, specs []pairingSpec) error {
+	now := time.Now()
+	round := &models.TournamentRound{
+		TournamentID: tournament.ID,
+		Number:       number,
+		StartedAt:    now,
+		Deadline:     now.Add(time.Duration(tournament.RoundTimeLimit) * time.Second),
+	}
+
+	for i, spec := range specs {
+		result := models.PairingResultPending
+		if spec.Player2ID == nil {
+			result = models.PairingResultBye
+		}
+
+		round.Pairings = append(round.Pairings, models.TournamentPairing{
+			TournamentID: tournament.ID,
+			Board:        i + 1,
+			Player1ID:    spec.Player1ID,
+			Player2ID:    spec.Player2ID,
+			Result:       result,
+		})
+	}
+
+	if err := s.tournamentRepo.CreateRound(round); err != nil {
+		return err
+	}
+
+	tournament.CurrentRound = number
+	if err := s.tournamentRepo.Update(tournament); err != nil {
+		return err
+	}
+
+	participants, err := s.participantsByUser(tournament.ID)
+	if err != nil {
+		return err
+	}
+
+	// Score byes and create games for the remaining pairings
+	for i := range round.Pairings {
+		pairing := &round.Pairings[i]
+
+		if pairing.IsBye() {
+			if p, ok := participants[pairing.Player1ID]; ok {
+				p.Points += byePoints
+				p.Wins++
+				p.HadBye = true
+				if err := s.tournamentRepo.UpdateParticipant(p); err != nil {
+					log.Printf("Error scoring bye: %v", err)
+				}
+			}
+			s.notifyPairing(tournament, round, pairing, participants)
+			continue
+		}
+
+		// Create and start the game, with a puzzle fair to both players
+		game, err := s.gameService.CreateMatchedDuel(pairing.Player1ID, *pairing.Player2ID, tournamentGameType, models.GameVariantClassic, false)
+		if err != nil {
+			log.Printf("Error creating tournament game: %v", err)
+			continue
+		}
+
+		pairing.GameID = &game.ID
+		if err := s.tournamentRepo.UpdatePairing(pairing); err != nil {
+			log.Printf("Error updating pairing: %v", err)
+		}
+
+		s.notifyPairing(tournament, round, pairing, participants)
+	}
+
+	s.broadcastStandings(tournament, websocket.MessageTypeTournamentRoundStart)
+
+	// A round made up only of byes is already complete
+	return s.completeRoundIfFinished(tournament.ID, number)
+}
+
+// handleGameCompleted records the result of a finished tournament game
+func (s *Service) handleGameCompleted(completed *models.Game) {
+	if completed.GameType != tournamentGameType {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pairing, err := s.tournamentRepo.FindPairingByGameID(completed.ID)
+	if err != nil {
+		log.Printf("Error finding pairing for game %s: %v", completed.ID, err)
+		return
+	}
+
+	// Games can report completion more than once
+	if pairing.Result != models.PairingResultPending {
+		return
+	}
+
+	// Reload the game so every player's solution time is present
+	g, err := s.gameService.GetGame(completed.ID)
+	if err != nil {
+		log.Printf("Error loading tournament game: %v", err)
+		return
+	}
+
+	if err := s.recordResult(pairing, g); err != nil {
+		log.Printf("Error recording tournament result: %v", err)
+		return
+	}
+
+	s.advanceRound(pairing.TournamentID, pairing.RoundID)
+}
+
+// resolveOverdueRounds settles the pending pairings of rounds whose deadline has passed
+func (s *Service) resolveOverdueRounds() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rounds, err := s.tournamentRepo.FindOverdueRounds()
+	if err != nil {
+		log.Printf("Error finding overdue rounds: %v", err)
+		return
+	}
+
+	for _, round := range rounds {
+		pairings, err := s.tournamentRepo.GetPairings(round.TournamentID)
+		if err != nil {
+			log.Printf("Error getting pairings: %v", err)
+			continue
+		}
+
+		for i := range pairings {
+			pairing := &pairings[i]
+			if pairing.RoundID != round.ID || pairing.Result != models.PairingResultPending {
+				continue
+			}
+
+			// Whoever solved in time wins; unplayed games are scored from an empty game. A game
+			// still going is ended first, so nobody plays on in a pairing that is already scored.
+			var g *models.Game
+			if pairing.GameID != nil {
+				if err := s.gameService.ExpireGame(*pairing.GameID); err != nil {
+					log.Printf("Error ending overdue tournament game %s: %v", *pairing.GameID, err)
+				}
+				g, err = s.gameService.GetGame(*pairing.GameID)
+				if err != nil {
+					log.Printf("Error loading tournament game: %v", err)
+				}
+			}
+			if g == nil {
+				g = &models.Game{}
+			}
+
+			if err := s.recordResult(pairing, g); err != nil {
+				log.Printf("Error recording tournament result: %v", err)
+			}
+		}
+
+		s.advanceRound(round.TournamentID, round.ID)
+	}
+}
+
+// recordResult scores a pairing from the state of its game
+func (s *Service) recordResult(pairing *models.TournamentPairing, g *models.Game) error {
+	tournament, err := s.tournamentRepo.FindByID(pairing.TournamentID)
+	if err != nil {
+		return err
+	}
+
+	participants, err := s.participantsByUser(pairing.TournamentID)
+	if err != nil {
+		return err
+	}
+
+	player1, ok1 := participants[pairing.Player1ID]
+	player2, ok2 := participants[*pairing.Player2ID]
+	if !ok1 || !ok2 {
+		return errors.New("participant not found")
+	}
+
+	// Players that did not solve are charged the full round time
+	timeLimit := float64(tournament.RoundTimeLimit)
+	time1, solved1 := solveTime(g, player1.UserID, timeLimit)
+	time2, solved2 := solveTime(g, player2.UserID, timeLimit)
+
+	// Decide the result
+	result := models.PairingResultDraw
+	switch {
+	case g.WinnerID != nil && *g.WinnerID == player1.UserID:
+		result = models.PairingResultPlayer1
+	case g.WinnerID != nil && *g.WinnerID == player2.UserID:
+		result = models.PairingResultPlayer2
+	case solved1 && !solved2:
+		result = models.PairingResultPlayer1
+	case solved2 && !solved1:
+		result = models.PairingResultPlayer2
+	}
+
+	// Knockout games cannot be drawn: the faster solve wins, then the higher seed
+	if result == models.PairingResultDraw && tournament.Format == models.TournamentFormatSingleElimination {
+		if time1 < time2 || (time1 == time2 && player1.Seed < player2.Seed) {
+			result = models.PairingResultPlayer1
+		} else {
+			result = models.PairingResultPlayer2
+		}
+	}
+
+	pairing.Result = result
+	if err := s.tournamentRepo.UpdatePairing(pairing); err != nil {
+		return err
+	}
+
+	// Update participant scores
+	player1.TotalSolveTime += time1
+	player2.TotalSolveTime += time2
+
+	switch result {
+	case models.PairingResultPlayer1:
+		player1.Points += winPoints
+		player1.Wins++
+		player2.Losses++
+	case models.PairingResultPlayer2:
+		player2.Points += winPoints
+		player2.Wins++
+		player1.Losses++
+	default:
+		player1.Points += drawPoints
+		player2.Points += drawPoints
+		player1.Draws++
+		player2.Draws++
+	}
+
+	if tournament.Format == models.TournamentFormatSingleElimination {
+		if result == models.PairingResultPlayer1 {
+			player2.Eliminated = true
+		} else {
+			player1.Eliminated = true
+		}
+	}
+
+	if err := s.tournamentRepo.UpdateParticipant(player1); err != nil {
+		return err
+	}
+	return s.tournamentRepo.UpdateParticipant(player2)
+}
+
+// advanceRound completes the round once all its pairings are settled and starts the next one
+func (s *Service) advanceRound(tournamentID, roundID string) {
+	rounds, err := s.tournamentRepo.GetRounds(tournamentID)
+	if err != nil {
+		log.Printf("Error getting rounds: %v", err)
+		return
+	}
+
+	for _, round := range rounds {
+		if round.ID == roundID {
+			if err := s.completeRoundIfFinished(tournamentID, round.Number); err != nil {
+				log.Printf("Error advancing tournament round: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// completeRoundIfFinished closes a round with no pending pairings, then starts the next round
+// or completes the tournament
+func (s *Service) completeRoundIfFinished(tournamentID string, number int) error {
+	round, err := s.tournamentRepo.FindRound(tournamentID, number)
+	if err != nil {
+		return err
+	}
+
+	if round.CompletedAt != nil {
+		return nil
+	}
+
+	for _, p := range round.Pairings {
+		if p.Result == models.PairingResultPending {
+			// Keep the standings live while the round is being played
+			if tournament, err := s.tournamentRepo.FindByID(tournamentID); err == nil {
+				s.broadcastStandings(tournament, websocket.MessageTypeTournamentStandings)
+			}
+			return nil
+		}
+	}
+
+	now := time.Now()
+	round.CompletedAt = &now
+	if err := s.tournamentRepo.UpdateRound(round); err != nil {
+		return err
+	}
+
+	// Recompute tie-breaks now that the round is settled
+	participants, err := s.tournamentRepo.GetParticipants(tournamentID)
+	if err != nil {
+		return err
+	}
+	history, err := s.tournamentRepo.GetPairings(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	computeBuchholz(participants, history)
+	for i := range participants {
+		if err := s.tournamentRepo.UpdateParticipant(&participants[i]); err != nil {
+			return err
+		}
+	}
+
+	tournament, err := s.tournamentRepo.FindByID(tournamentID)
+	if err != nil {
+		return err
+	}
+
+	// Decide whether the tournament is over
+	var next []pairingSpec
+	if tournament.Format == models.TournamentFormatSingleElimination {
+		next = pairSingleEliminationNextRound(round.Pairings)
+		if len(next) == 0 || (len(next) == 1 && next[0].IsBye()) {
+			return s.completeTournament(tournament)
+		}
+	} else {
+		if number >= tournament.TotalRounds {
+			return s.completeTournament(tournament)
+		}
+		next = pairSwissRound(participants, history)
+	}
+
+	tournament.Participants = nil
+	return s.startRound(tournament, number+1, next)
+}
+
+// completeTournament marks a tournament as completed and announces the final standings
+func (s *Service) completeTournament(tournament *models.Tournament) error {
+	participants, err := s.tournamentRepo.GetParticipants(tournament.ID)
+	if err != nil {
+		return err
+	}
+
+	standings := buildStandings(participants)
+
+	now := time.Now()
+	tournament.Status = models.TournamentStatusCompleted
+	tournament.CompletedAt = &now
+	if len(standings) > 0 {
+		winnerID := standings[0].UserID
+		tournament.WinnerID = &winnerID
+	}
+	tournament.Participants = nil
+
+	if err := s.tournamentRepo.Update(tournament); err != nil {
+		return err
+	}
+
+	s.broadcastStandings(tournament, websocket.MessageTypeTournamentEnd)
+
+	return nil
+}
+
+// broadcastStandings pushes the current standings to everyone following the tournament
+func (s *Service) broadcastStandings(tournament *models.Tournament, messageType websocket.MessageType) {
+	if s.hub == nil {
+		return
+	}
+
+	standings, err := s.GetStandings(tournament.ID)
+	if err != nil {
+		log.Printf("Error getting standings: %v", err)
+		return
+	}
+
+	payload := websocket.TournamentStandingsPayload{
+		TournamentID: tournament.ID,
+		Round:        tournament.CurrentRound,
+		Status:       string(tournament.Status),
+		Standings:    standingsToPayload(standings),
+	}
+
+	// An empty room just means nobody is watching
+	if err := s.hub.BroadcastTournamentStandings(messageType, payload); err != nil {
+		log.Printf("Tournament standings not delivered: %v", err)
+	}
+}
+
+// notifyPairing tells both players of a pairing who they play in the new round
+func (s *Service) notifyPairing(tournament *models.Tournament, round *models.TournamentRound, pairing *models.TournamentPairing, participants map[string]*models.TournamentParticipant) {
+	if s.hub == nil {
+		return
+	}
+
+	payload := websocket.TournamentPairingPayload{
+		TournamentID: tournament.ID,
+		Round:        round.Number,
+		Board:        pairing.Board,
+		Deadline:     round.Deadline.UnixNano() / int64(time.Millisecond),
+	}
+	if pairing.GameID != nil {
+		payload.GameID = *pairing.GameID
+	}
+
+	players := []string{pairing.Player1ID}
+	if pairing.Player2ID != nil {
+		players = append(players, *pairing.Player2ID)
+	}
+
+	for _, userID := range players {
+		client := s.hub.GetClientByUserID(userID)
+		if client == nil {
+			continue
+		}
+
+		// Fill in the opponent from this player's point of view
+		payload.Opponent = nil
+		for _, opponentID := range players {
+			if opponentID == userID {
+				continue
+			}
+			opponent := &websocket.PlayerPayload{UserID: opponentID}
+			if p, ok := participants[opponentID]; ok {
+				opponent.Username = p.User.Username
+				opponent.IsBot = p.User.IsBot
+			}
+			payload.Opponent = opponent
+		}
+
+		if err := s.hub.SendTournamentPairing(client, payload); err != nil {
+			log.Printf("Error sending tournament pairing: %v", err)
+		}
+	}
+}
+
+// Helper function to load the participants of a tournament keyed by user ID
+func (s *Service) participantsByUser(tournamentID string) (map[string]*models.TournamentParticipant, error) {
+	participants, err := s.tournamentRepo.GetParticipants(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[string]*models.TournamentParticipant, len(participants))
+	for i := range participants {
+		byUser[participants[i].UserID] = &participants[i]
+	}
+	return byUser, nil
+}
+
+// Helper function to get a player's solve time in a game and whether they solved it
+func solveTime(g *models.Game, userID string, timeLimit float64) (float64, bool) {
+	for _, p := range g.Players {
+		if p.UserID != userID {
+			continue
+		}
+		if p.IsCorrect != nil && *p.IsCorrect && p.SolutionTime != nil {
+			return *p.SolutionTime, true
+		}
+	}
+	return timeLimit, false
+}
@@ -0,0 +1,80 @@
+package tournament
+
+import (
+	"sort"
+
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/websocket"
+)
+
+// sortStandings orders participants by points, then Buchholz, then total solve time
+func sortStandings(participants []models.TournamentParticipant) {
+	sort.SliceStable(participants, func(i, j int) bool {
+		a, b := participants[i], participants[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		if a.TotalSolveTime != b.TotalSolveTime {
+			return a.TotalSolveTime < b.TotalSolveTime
+		}
+		return a.Seed < b.Seed
+	})
+}
+
+// buildStandings returns the ranked standings table for a set of participants
+func buildStandings(participants []models.TournamentParticipant) []models.TournamentStanding {
+	sorted := make([]models.TournamentParticipant, len(participants))
+	copy(sorted, participants)
+	sortStandings(sorted)
+
+	standings := make([]models.TournamentStanding, len(sorted))
+	for i := range sorted {
+		standings[i] = sorted[i].ToStanding(i + 1)
+	}
+	return standings
+}
+
+// computeBuchholz sets each participant's Buchholz score to the sum of their opponents' points.
+// Byes do not contribute to the score.
+func computeBuchholz(participants []models.TournamentParticipant, pairings []models.TournamentPairing) {
+	points := make(map[string]float64, len(participants))
+	for _, p := range participants {
+		points[p.UserID] = p.Points
+	}
+
+	buchholz := make(map[string]float64, len(participants))
+	for _, p := range pairings {
+		if p.Player2ID == nil || p.Result == models.PairingResultPending {
+			continue
+		}
+		buchholz[p.Player1ID] += points[*p.Player2ID]
+		buchholz[*p.Player2ID] += points[p.Player1ID]
+	}
+
+	for i := range participants {
+		participants[i].Buchholz = buchholz[participants[i].UserID]
+	}
+}
+
+// Helper function to convert standings to their WebSocket payload
+func standingsToPayload(standings []models.TournamentStanding) []websocket.TournamentStandingPayload {
+	payload := make([]websocket.TournamentStandingPayload, len(standings))
+	for i, s := range standings {
+		payload[i] = websocket.TournamentStandingPayload{
+			Rank:           s.Rank,
+			UserID:         s.UserID,
+			Username:       s.Username,
+			Points:         s.Points,
+			Wins:           s.Wins,
+			Draws:          s.Draws,
+			Losses:         s.Losses,
+			Buchholz:       s.Buchholz,
+			TotalSolveTime: s.TotalSolveTime,
+			Eliminated:     s.Eliminated,
+		}
+	}
+	return payload
+}
@@ -18,6 +18,9 @@ type Client struct {
 	Send   chan []byte
 	rooms  map[string]bool // Rooms the client is in
 	mu     sync.Mutex      // Mutex for thread-safe operations
+
+	// Spectator clients receive a redacted view of their rooms and cannot send game messages
+	spectator bool
 }
 
 // NewClient creates a new WebSocket client
@@ -113,12 +116,34 @@ func (c *Client) JoinRoom(roomID string) {
 	c.hub.JoinGameRoom(roomID, c)
 }
 
+// Spectate adds the client to a room as a read-only spectator
+func (c *Client) Spectate(roomID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.spectator = true
+	c.rooms[roomID] = true
+	c.hub.JoinAsSpectator(roomID, c)
+}
+
+// IsSpectator checks if the client is a read-only spectator
+func (c *Client) IsSpectator() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.spectator
+}
+
 // LeaveRoom removes the client from a room
 func (c *Client) LeaveRoom(roomID string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	delete(c.rooms, roomID)
+	if c.spectator {
+		c.hub.LeaveAsSpectator(roomID, c)
+		return
+	}
 	c.hub.LeaveGameRoom(roomID, c)
 }
 
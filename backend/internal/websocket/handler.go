@@ -3,14 +3,28 @@ package websocket
 import (
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// GameAccess decides who may join a game room, as a player or as a spectator
+type GameAccess interface {
+	IsPlayer(gameID, userID string) (bool, error)
+	CanSpectate(gameID, userID string) (bool, error)
+}
+
+// LobbyAccess decides who may follow a private lobby
+type LobbyAccess interface {
+	IsLobbyMember(code, userID string) (bool, error)
+}
+
 // Handler handles WebSocket connections
 type Handler struct {
-	hub *Hub
+	hub     *Hub
+	games   GameAccess
+	lobbies LobbyAccess
 }
 
 // NewHandler creates a new WebSocket handler
@@ -20,6 +34,18 @@ func NewHandler(hub *Hub) *Handler {
 	}
 }
 
+// SetGameAccess makes game connections check who is playing or may watch a game. Without it,
+// anyone can join any game room.
+func (h *Handler) SetGameAccess(games GameAccess) {
+	h.games = games
+}
+
+// SetLobbyAccess makes lobby connections check who is a member of a lobby. Without it, anyone
+// with the invite code can follow a lobby.
+func (h *Handler) SetLobbyAccess(lobbies LobbyAccess) {
+	h.lobbies = lobbies
+}
+
 // HandleConnection handles WebSocket connection requests
 func (h *Handler) HandleConnection(c *gin.Context) {
 	// Get user ID from context
@@ -72,6 +98,25 @@ func (h *Handler) HandleGameConnection(c *gin.Context) {
 		return
 	}
 
+	// Only players join the game room; everyone else spectates
+	if h.games != nil {
+		isPlayer, err := h.games.IsPlayer(gameID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Game not found",
+			})
+			return
+		}
+		if !isPlayer {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Only players can join a game; use /ws/spectate to watch it",
+			})
+			return
+		}
+	}
+
 	// Generate a client ID
 	clientID := uuid.New().String()
 
@@ -91,6 +136,179 @@ func (h *Handler) HandleGameConnection(c *gin.Context) {
 	// Join game room
 	client.JoinRoom(gameID)
 
+	// Pick up where the player left off if they dropped out of this game
+	h.hub.ResumeGameRoom(gameID, client)
+
+	// Start client goroutines
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// HandleSpectatorConnection handles WebSocket connection requests for spectating a game
+func (h *Handler) HandleSpectatorConnection(c *gin.Context) {
+	// Get game ID from URL
+	gameID := c.Param("id")
+	if gameID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Game ID is required",
+		})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		// Spectating does not require an account
+		userID = "guest-" + uuid.New().String()
+	}
+
+	// Private games can only be watched by their players
+	if h.games != nil {
+		canSpectate, err := h.games.CanSpectate(gameID, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Game not found",
+			})
+			return
+		}
+		if !canSpectate {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Game is private",
+			})
+			return
+		}
+	}
+
+	// Generate a client ID
+	clientID := uuid.New().String()
+
+	// Upgrade HTTP connection to WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading connection: %v", err)
+		return
+	}
+
+	// Create a new client
+	client := NewClient(clientID, userID.(string), h.hub, conn)
+
+	// Register client with hub
+	h.hub.register <- client
+
+	// Join game room as a spectator
+	client.Spectate(gameID)
+
+	// Start client goroutines
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// HandleLobbyConnection handles WebSocket connection requests for a private lobby
+func (h *Handler) HandleLobbyConnection(c *gin.Context) {
+	// Get invite code from URL
+	code := c.Param("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Lobby code is required",
+		})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	// Only members follow a lobby; the invite code alone is not enough
+	if h.lobbies != nil {
+		isMember, err := h.lobbies.IsLobbyMember(code, userID.(string))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Lobby not found",
+			})
+			return
+		}
+		if !isMember {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Only lobby members can follow a lobby; join it first",
+			})
+			return
+		}
+	}
+
+	// Generate a client ID
+	clientID := uuid.New().String()
+
+	// Upgrade HTTP connection to WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading connection: %v", err)
+		return
+	}
+
+	// Create a new client
+	client := NewClient(clientID, userID.(string), h.hub, conn)
+
+	// Register client with hub
+	h.hub.register <- client
+
+	// Join lobby room
+	client.JoinRoom(LobbyRoomID(strings.ToUpper(code)))
+
+	// Start client goroutines
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// HandleTournamentConnection handles WebSocket connection requests for following a tournament
+func (h *Handler) HandleTournamentConnection(c *gin.Context) {
+	// Get tournament ID from URL
+	tournamentID := c.Param("id")
+	if tournamentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Tournament ID is required",
+		})
+		return
+	}
+
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		// Standings are public, so allow guests to follow along
+		userID = "guest-" + uuid.New().String()
+	}
+
+	// Generate a client ID
+	clientID := uuid.New().String()
+
+	// Upgrade HTTP connection to WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading connection: %v", err)
+		return
+	}
+
+	// Create a new client
+	client := NewClient(clientID, userID.(string), h.hub, conn)
+
+	// Register client with hub
+	h.hub.register <- client
+
+	// Join tournament room
+	client.JoinRoom(TournamentRoomID(tournamentID))
+
 	// Start client goroutines
 	go client.WritePump()
 	go client.ReadPump()
@@ -124,20 +342,56 @@ func (h *Handler) HandleReconnection(c *gin.Context) {
 	// Register client with hub
 	h.hub.register <- client
 
-	// Check if user was in any game rooms
-	h.hub.mu.Lock()
-	for gameID, clients := range h.hub.gameRooms {
-		for c := range clients {
-			if c.UserID == userID.(string) {
-				// User was in this game room, join it again
-				h.hub.mu.Unlock()
-				client.JoinRoom(gameID)
-				h.hub.mu.Lock()
-				break
-			}
-		}
+	// Rejoin the game rooms the user dropped out of
+	for _, gameID := range h.hub.DisconnectedRooms(userID.(string)) {
+		client.JoinRoom(gameID)
+		h.hub.ResumeGameRoom(gameID, client)
+	}
+
+	// Start client goroutines
+	go client.WritePump()
+	go client.ReadPump()
+}
+
+// HandleBotConnection handles the event stream of an external bot
+func (h *Handler) HandleBotConnection(c *gin.Context) {
+	// Get user ID from context
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Authentication required",
+		})
+		return
+	}
+
+	// Only bots have an event stream
+	if isBot, _ := c.Get("isBot"); isBot != true {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Bot token required",
+		})
+		return
 	}
-	h.hub.mu.Unlock()
+
+	// Generate a client ID
+	clientID := uuid.New().String()
+
+	// Upgrade HTTP connection to WebSocket
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Error upgrading connection: %v", err)
+		return
+	}
+
+	// Create a new client
+	client := NewClient(clientID, userID.(string), h.hub, conn)
+
+	// Register client with hub
+	h.hub.register <- client
+
+	// Join the bot's stream room
+	client.JoinRoom(BotRoomID(userID.(string)))
 
 	// Start client goroutines
 	go client.WritePump()
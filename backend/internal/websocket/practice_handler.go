@@ -2,10 +2,12 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"sync"
 	"time"
 
+	"github.com/hectoclash/internal/models"
 	"github.com/hectoclash/internal/practice"
 	"github.com/hectoclash/internal/puzzle"
 )
@@ -69,7 +71,13 @@ func (h *PracticeHandler) handlePracticeStart(client *Client, msg *Message) {
 		return
 	}
 
-	log.Printf("Practice start payload: timedMode=%v, startELO=%d", payload.TimedMode, payload.StartELO)
+	log.Printf("Practice start payload: timedMode=%v, startELO=%d, mode=%s", payload.TimedMode, payload.StartELO, payload.Mode)
+
+	// Check the rush settings before ending any running session
+	if payload.Mode == practice.ModeRush && !models.IsValidRushDuration(payload.Duration) {
+		h.sendErrorToClient(client, "Rush duration must be 3 or 5 minutes")
+		return
+	}
 
 	// Check if user already has an active session
 	h.mu.RLock()
@@ -88,10 +96,9 @@ func (h *PracticeHandler) handlePracticeStart(client *Client, msg *Message) {
 				log.Printf("Error ending practice session: %v", endErr)
 			}
 
-			h.mu.Lock()
-			delete(h.sessions, sessionID)
-			delete(h.sessionsByUser, client.UserID)
-			h.mu.Unlock()
+			if h.removeSession(session) && session.IsRush() {
+				h.sendRushEnd(client, session)
+			}
 		}
 	}
 
@@ -100,6 +107,8 @@ func (h *PracticeHandler) handlePracticeStart(client *Client, msg *Message) {
 		UserID:    client.UserID,
 		TimedMode: payload.TimedMode,
 		StartELO:  payload.StartELO,
+		Mode:      payload.Mode,
+		Duration:  payload.Duration,
 	}
 
 	session, err := h.practiceService.CreateSession(config)
@@ -115,6 +124,13 @@ func (h *PracticeHandler) handlePracticeStart(client *Client, msg *Message) {
 	h.sessionsByUser[client.UserID] = session.ID
 	h.mu.Unlock()
 
+	// The server ends a rush run when its clock runs out
+	if session.IsRush() {
+		time.AfterFunc(time.Until(*session.EndsAt), func() {
+			h.expireRush(session)
+		})
+	}
+
 	// Send the first puzzle to the client
 	h.sendNextPuzzle(client, session)
 }
@@ -155,13 +171,13 @@ func (h *PracticeHandler) handlePracticeEnd(client *Client, msg *Message) {
 	}
 
 	// Remove the session
-	h.mu.Lock()
-	delete(h.sessions, payload.SessionID)
-	delete(h.sessionsByUser, client.UserID)
-	h.mu.Unlock()
+	removed := h.removeSession(session)
 
 	// Send confirmation to the client
 	h.sendPracticeEnd(client, payload.SessionID, payload.Reason)
+	if removed && session.IsRush() {
+		h.sendRushEnd(client, session)
+	}
 }
 
 // handlePracticeSubmitSolution handles a practice submit solution message
@@ -193,6 +209,13 @@ func (h *PracticeHandler) handlePracticeSubmitSolution(client *Client, msg *Mess
 
 	// Submit the solution
 	validationResult, err := h.practiceService.SubmitSolution(session, payload.Solution)
+	if errors.Is(err, practice.ErrSessionEnded) {
+		// The answer came after the rush run ended
+		if h.removeSession(session) && session.IsRush() {
+			h.sendRushEnd(client, session)
+		}
+		return
+	}
 	if err != nil {
 		log.Printf("Error submitting solution: %v", err)
 		h.sendErrorToClient(client, "Failed to submit solution")
@@ -204,13 +227,47 @@ func (h *PracticeHandler) handlePracticeSubmitSolution(client *Client, msg *Mess
 
 	// If the session is completed or failed, remove it
 	if session.Status == "completed" || session.Status == "failed" {
-		h.mu.Lock()
-		delete(h.sessions, payload.SessionID)
-		delete(h.sessionsByUser, client.UserID)
-		h.mu.Unlock()
+		if h.removeSession(session) && session.IsRush() {
+			h.sendRushEnd(client, session)
+		}
 	}
 }
 
+// expireRush ends a rush run when its clock runs out and sends the summary to the player
+func (h *PracticeHandler) expireRush(session *practice.Session) {
+	err := h.practiceService.ExpireSession(session)
+	if err != nil {
+		log.Printf("Error expiring rush run %s: %v", session.ID, err)
+	}
+
+	// The run may already have ended some other way
+	if !h.removeSession(session) {
+		return
+	}
+
+	client := h.hub.GetClientByUserID(session.UserID)
+	if client == nil {
+		return
+	}
+	h.sendRushEnd(client, session)
+}
+
+// removeSession removes a session if it is still tracked, and reports whether it was
+func (h *PracticeHandler) removeSession(session *practice.Session) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.sessions[session.ID] != session {
+		return false
+	}
+
+	delete(h.sessions, session.ID)
+	if h.sessionsByUser[session.UserID] == session.ID {
+		delete(h.sessionsByUser, session.UserID)
+	}
+	return true
+}
+
 // sendNextPuzzle sends the next puzzle to the client
 func (h *PracticeHandler) sendNextPuzzle(client *Client, session *practice.Session) {
 	// Create the payload
@@ -227,6 +284,15 @@ func (h *PracticeHandler) sendNextPuzzle(client *Client, session *practice.Sessi
 		payload.TimeLimit = 60 // 60 seconds per puzzle
 	}
 
+	// Add the clock and score of a rush run
+	if session.IsRush() {
+		payload.Mode = session.Mode
+		payload.RunScore = session.Score
+		payload.Strikes = session.Strikes
+		payload.EndsAt = session.EndsAt.UnixNano() / int64(time.Millisecond)
+		payload.TimeLimit = int(time.Until(*session.EndsAt).Seconds())
+	}
+
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -270,6 +336,17 @@ func (h *PracticeHandler) sendPracticeResult(client *Client, session *practice.S
 		}
 	}
 
+	// Add the clock and score of a rush run
+	if session.IsRush() {
+		payload.Mode = session.Mode
+		payload.RunScore = session.Score
+		payload.Strikes = session.Strikes
+		payload.EndsAt = session.EndsAt.UnixNano() / int64(time.Millisecond)
+		if session.Status == "active" {
+			payload.TimeLimit = int(time.Until(*session.EndsAt).Seconds())
+		}
+	}
+
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -316,6 +393,43 @@ func (h *PracticeHandler) sendPracticeEnd(client *Client, sessionID, reason stri
 	h.hub.sendMessageToClient(client, msg)
 }
 
+// sendRushEnd sends the summary of a finished rush run to the client
+func (h *PracticeHandler) sendRushEnd(client *Client, session *practice.Session) {
+	// Create the payload
+	payload := RushEndPayload{
+		SessionID:     session.ID,
+		Reason:        session.EndReason,
+		Duration:      session.Duration,
+		Score:         session.Score,
+		PuzzlesSolved: session.PuzzlesSolved,
+		Strikes:       session.Strikes,
+		MaxDifficulty: session.MaxDifficulty,
+		PersonalBest:  session.PersonalBest,
+		BestScore:     session.BestScore,
+	}
+	if session.Run != nil {
+		payload.RunID = session.Run.ID
+	}
+
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling rush end payload: %v", err)
+		return
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeRushEnd,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Send message to client
+	h.hub.sendMessageToClient(client, msg)
+}
+
 // sendErrorToClient sends an error message to the client
 func (h *PracticeHandler) sendErrorToClient(client *Client, message string) {
 	// Create the payload
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,17 +37,52 @@ const (
 	MessageTypeGameState     MessageType = "game_state"
 	MessageTypePlayerJoined  MessageType = "player_joined"
 	MessageTypePlayerLeft    MessageType = "player_left"
+	MessageTypePlayerReconnected MessageType = "player_reconnected"
+	MessageTypePlayerForfeited MessageType = "player_forfeited"
 	MessageTypeGameStart     MessageType = "game_start"
 	MessageTypeGameEnd       MessageType = "game_end"
 	MessageTypePlayerProgress MessageType = "player_progress"
 	MessageTypeSolutionSubmitted MessageType = "solution_submitted"
 	MessageTypeMatchmakingStatus MessageType = "matchmaking_status"
 	MessageTypeMatchFound    MessageType = "match_found"
+	MessageTypeBotOffer      MessageType = "bot_offer"
 	MessageTypeError         MessageType = "error"
 	MessageTypePing          MessageType = "ping"
 	MessageTypePong          MessageType = "pong"
 	MessageTypeJoinQueue     MessageType = "join_queue"
 	MessageTypeLeaveQueue    MessageType = "leave_queue"
+	MessageTypeSpectatorCount MessageType = "spectator_count"
+
+	// Ready check message types
+	MessageTypeReadyCheck          MessageType = "ready_check"
+	MessageTypeReadyAccept         MessageType = "ready_accept"
+	MessageTypeReadyDecline        MessageType = "ready_decline"
+	MessageTypeReadyCheckUpdate    MessageType = "ready_check_update"
+	MessageTypeReadyCheckCancelled MessageType = "ready_check_cancelled"
+
+	// Party message types
+	MessageTypePartyInvite    MessageType = "party_invite"
+	MessageTypePartyState     MessageType = "party_state"
+	MessageTypePartyDisbanded MessageType = "party_disbanded"
+
+	// Rematch message types
+	MessageTypeRematchOffer     MessageType = "rematch_offer"
+	MessageTypeRematchAccept    MessageType = "rematch_accept"
+	MessageTypeRematchDecline   MessageType = "rematch_decline"
+	MessageTypeRematchCancelled MessageType = "rematch_cancelled"
+	MessageTypeRematchStart     MessageType = "rematch_start"
+
+	// Challenge message types
+	MessageTypeChallengeOffer     MessageType = "challenge_offer"
+	MessageTypeChallengeAccept    MessageType = "challenge_accept"
+	MessageTypeChallengeDecline   MessageType = "challenge_decline"
+	MessageTypeChallengeCounter   MessageType = "challenge_counter"
+	MessageTypeChallengeCancelled MessageType = "challenge_cancelled"
+	MessageTypeChallengeStart     MessageType = "challenge_start"
+
+	// Lobby message types
+	MessageTypeLobbyState  MessageType = "lobby_state"
+	MessageTypeLobbyKicked MessageType = "lobby_kicked"
 
 	// Practice mode message types
 	MessageTypePracticeStart   MessageType = "practice_start"
@@ -54,6 +90,24 @@ const (
 	MessageTypePracticeNextPuzzle MessageType = "practice_next_puzzle"
 	MessageTypePracticeSubmitSolution MessageType = "practice_submit_solution"
 	MessageTypePracticeResult  MessageType = "practice_result"
+	MessageTypeRushEnd         MessageType = "rush_end"
+
+	// Zen mode message types
+	MessageTypeZenStart  MessageType = "zen_start"
+	MessageTypeZenPuzzle MessageType = "zen_puzzle"
+	MessageTypeZenSubmit MessageType = "zen_submit"
+	MessageTypeZenResult MessageType = "zen_result"
+
+	// Tournament message types
+	MessageTypeTournamentStandings  MessageType = "tournament_standings"
+	MessageTypeTournamentRoundStart MessageType = "tournament_round_start"
+	MessageTypeTournamentPairing    MessageType = "tournament_pairing"
+	MessageTypeTournamentEnd        MessageType = "tournament_end"
+
+	// Bot API message types
+	MessageTypeBotChallenge         MessageType = "bot_challenge"
+	MessageTypeBotChallengeCanceled MessageType = "bot_challenge_canceled"
+	MessageTypeBotGameStart         MessageType = "bot_game_start"
 )
 
 // Message represents a WebSocket message
@@ -71,12 +125,14 @@ type GameStatePayload struct {
 	Players   []PlayerPayload  `json:"players"`
 	StartedAt *int64           `json:"started_at,omitempty"`
 	Puzzle    string           `json:"puzzle,omitempty"`
+	Spectators int             `json:"spectators"`
 }
 
 // PlayerPayload represents a player in the game state
 type PlayerPayload struct {
 	UserID    string  `json:"user_id"`
 	Username  string  `json:"username"`
+	IsBot     bool    `json:"is_bot"`
 	Progress  float64 `json:"progress"`
 	IsCorrect *bool   `json:"is_correct,omitempty"`
 	Score     *int    `json:"score,omitempty"`
@@ -92,21 +148,121 @@ type SolutionSubmittedPayload struct {
 	IsCorrect bool   `json:"is_correct"`
 	Score     int    `json:"score,omitempty"`
 	Solution  string `json:"solution"`
+	Redacted  bool   `json:"redacted,omitempty"` // Solution is hidden from spectators until the game ends
+}
+
+// RematchPayload represents the payload for rematch messages
+type RematchPayload struct {
+	GameID     string `json:"game_id"`                // The finished game
+	FromUserID string `json:"from_user_id,omitempty"` // The player that offered the rematch
+	ExpiresAt  int64  `json:"expires_at,omitempty"`   // When the offer expires, in milliseconds
+	NewGameID  string `json:"new_game_id,omitempty"`  // The rematch game, once accepted
+	Reason     string `json:"reason,omitempty"`       // Why the offer was cancelled
+}
+
+// LobbyStatePayload represents the payload for a lobby state message
+type LobbyStatePayload struct {
+	Code        string          `json:"code"`
+	HostID      string          `json:"host_id"`
+	Status      string          `json:"status"`
+	HasPassword bool            `json:"has_password"`
+	MaxPlayers  int             `json:"max_players"`
+	TimeLimit   int             `json:"time_limit"`
+	Difficulty  int             `json:"difficulty"`
+	Variant     string          `json:"variant"`
+	Members     []PlayerPayload `json:"members"`
+	GameID      string          `json:"game_id,omitempty"` // Set once the host starts the game
+}
+
+// SpectatorCountPayload represents the payload for a spectator count message
+type SpectatorCountPayload struct {
+	Spectators int `json:"spectators"`
 }
 
 // MatchmakingStatusPayload represents the payload for a matchmaking status message
 type MatchmakingStatusPayload struct {
-	Status    string  `json:"status"`
-	WaitTime  float64 `json:"wait_time"`
-	QueueSize int     `json:"queue_size"`
+	Status        string              `json:"status"`                   // "idle", "queued", "ready_check", "matched" or "left_queue"
+	WaitTime      float64             `json:"wait_time"`                // Seconds until the queue entry times out
+	TimeInQueue   float64             `json:"time_in_queue"`            // Seconds waited so far
+	EstimatedWait float64             `json:"estimated_wait"`           // Seconds left until a match, going by recent matches
+	QueueSize     int                 `json:"queue_size"`               // Players in the player's queue
+	Queue         *QueueStatsPayload  `json:"queue,omitempty"`          // The player's queue
+	Queues        []QueueStatsPayload `json:"queues,omitempty"`         // Every queue
+	GameID        string              `json:"game_id,omitempty"`        // Set once the player is matched
+	ReadyCheckID  string              `json:"ready_check_id,omitempty"` // Set while the player's match waits to be accepted
+	Cooldown      float64             `json:"cooldown,omitempty"`       // Seconds until the player may queue again
+}
+
+// QueueStatsPayload represents the size and estimated wait of a matchmaking queue
+type QueueStatsPayload struct {
+	GameType      string  `json:"game_type"`
+	Ranked        bool    `json:"ranked"`
+	Variant       string  `json:"variant"`
+	Size          int     `json:"size"`
+	EstimatedWait float64 `json:"estimated_wait"` // Average wait of recently matched players, in seconds
 }
 
 // MatchFoundPayload represents the payload for a match found message
 type MatchFoundPayload struct {
-	GameID    string `json:"game_id"`
-	GameType  string `json:"game_type"`
-	Opponent  PlayerPayload `json:"opponent"`
-	IsRanked  bool   `json:"is_ranked"`
+	GameID    string          `json:"game_id"`
+	GameType  string          `json:"game_type"`
+	Opponent  PlayerPayload   `json:"opponent"`
+	IsRanked  bool            `json:"is_ranked"`
+	Team      int             `json:"team,omitempty"`      // The player's side of a team match, 1 or 2
+	Teammates []PlayerPayload `json:"teammates,omitempty"` // The player's side of a team match, besides themselves
+	Opponents []PlayerPayload `json:"opponents,omitempty"` // The other side of a team match
+}
+
+// ReadyCheckPayload represents the payload for ready check messages
+type ReadyCheckPayload struct {
+	ReadyCheckID  string          `json:"ready_check_id"`
+	GameType      string          `json:"game_type,omitempty"`
+	Variant       string          `json:"variant,omitempty"`
+	IsRanked      bool            `json:"is_ranked,omitempty"`
+	Opponent      *PlayerPayload  `json:"opponent,omitempty"`
+	Teammates     []PlayerPayload `json:"teammates,omitempty"`      // The player's side of a team match, besides themselves
+	Opponents     []PlayerPayload `json:"opponents,omitempty"`      // The other side of a team match
+	ExpiresAt     int64           `json:"expires_at,omitempty"`     // When the ready check ends, in milliseconds
+	Accepted      []string        `json:"accepted,omitempty"`       // Players who have accepted so far
+	Reason        string          `json:"reason,omitempty"`         // Why the ready check was cancelled: "declined" or "timeout"
+	Requeued      bool            `json:"requeued,omitempty"`       // The player was put back into the queue
+	CooldownUntil int64           `json:"cooldown_until,omitempty"` // When the player may queue again, in milliseconds
+}
+
+// PartyPayload represents the payload for party messages
+type PartyPayload struct {
+	PartyID   string          `json:"party_id"`
+	LeaderID  string          `json:"leader_id,omitempty"`
+	Members   []PlayerPayload `json:"members,omitempty"`
+	Invited   []string        `json:"invited,omitempty"`    // Friends with a pending invite
+	InvitedBy *PlayerPayload  `json:"invited_by,omitempty"` // Who sent an invite
+	ExpiresAt int64           `json:"expires_at,omitempty"` // When an invite lapses, in milliseconds
+	Queued    bool            `json:"queued,omitempty"`     // The party is in a matchmaking queue
+	Reason    string          `json:"reason,omitempty"`     // Why the party was disbanded
+}
+
+// ChallengePayload represents the payload for challenge messages between players. Clients name the
+// player to challenge by UserID, or the challenge they answer by ChallengeID, along with any settings.
+type ChallengePayload struct {
+	ChallengeID string         `json:"challenge_id,omitempty"`
+	UserID      string         `json:"user_id,omitempty"` // Who to challenge, when sending a challenge
+	Challenger  *PlayerPayload `json:"challenger,omitempty"`
+	Challenged  *PlayerPayload `json:"challenged,omitempty"`
+	GameType    string         `json:"game_type,omitempty"`
+	Variant     string         `json:"variant,omitempty"`
+	TimeLimit   int            `json:"time_limit,omitempty"` // in seconds, 0 for no limit
+	Ranked      bool           `json:"ranked,omitempty"`
+	CounterOf   string         `json:"counter_of,omitempty"` // The challenge a counter-challenge answers
+	ExpiresAt   int64          `json:"expires_at,omitempty"` // When the challenge lapses, in milliseconds
+	GameID      string         `json:"game_id,omitempty"`    // The game, once accepted
+	Reason      string         `json:"reason,omitempty"`     // Why the challenge was cancelled
+}
+
+// BotOfferPayload represents the payload for a bot opponent offered to a waiting player
+type BotOfferPayload struct {
+	Bot       PlayerPayload `json:"bot"`
+	GameType  string        `json:"game_type"`
+	ExpiresAt int64         `json:"expires_at"`
 }
 
 // ErrorPayload represents the payload for an error message
@@ -119,6 +275,7 @@ type ErrorPayload struct {
 type JoinQueuePayload struct {
 	GameType string `json:"game_type"`
 	Ranked   bool   `json:"ranked"`
+	Variant  string `json:"variant,omitempty"` // Defaults to classic
 }
 
 // LeaveQueuePayload represents the payload for a leave queue message
@@ -128,8 +285,10 @@ type LeaveQueuePayload struct {
 
 // PracticeStartPayload represents the payload for starting a practice session
 type PracticeStartPayload struct {
-	TimedMode bool `json:"timed_mode"`
-	StartELO  int  `json:"start_elo,omitempty"`
+	TimedMode bool   `json:"timed_mode"`
+	StartELO  int    `json:"start_elo,omitempty"`
+	Mode      string `json:"mode,omitempty"`     // "standard" (default) or "rush"
+	Duration  int    `json:"duration,omitempty"` // Length of a rush run in minutes, 3 or 5
 }
 
 // PracticeEndPayload represents the payload for ending a practice session
@@ -146,6 +305,10 @@ type PracticeNextPuzzlePayload struct {
 	CurrentELO    int    `json:"current_elo"`
 	PuzzlesSolved int    `json:"puzzles_solved"`
 	TimeLimit     int    `json:"time_limit,omitempty"` // in seconds, only for timed mode
+	Mode          string `json:"mode,omitempty"`
+	RunScore      int    `json:"run_score,omitempty"` // Score of the rush run so far
+	Strikes       int    `json:"strikes,omitempty"`
+	EndsAt        int64  `json:"ends_at,omitempty"` // When the rush run ends, in Unix milliseconds
 }
 
 // PracticeSubmitSolutionPayload represents the payload for submitting a solution in practice mode
@@ -166,13 +329,139 @@ type PracticeResultPayload struct {
 	NextDifficulty int   `json:"next_difficulty,omitempty"`
 	TimeLimit     int    `json:"time_limit,omitempty"` // in seconds, only for timed mode
 	Status        string `json:"status"` // "active", "completed", "failed"
+	Mode          string `json:"mode,omitempty"`
+	RunScore      int    `json:"run_score,omitempty"` // Score of the rush run so far
+	Strikes       int    `json:"strikes,omitempty"`
+	EndsAt        int64  `json:"ends_at,omitempty"` // When the rush run ends, in Unix milliseconds
+}
+
+// RushEndPayload represents the payload for the summary of a finished rush run
+type RushEndPayload struct {
+	SessionID     string `json:"session_id"`
+	RunID         string `json:"run_id,omitempty"`
+	Reason        string `json:"reason"` // "time_up", "strikes" or "ended"
+	Duration      int    `json:"duration"`
+	Score         int    `json:"score"`
+	PuzzlesSolved int    `json:"puzzles_solved"`
+	Strikes       int    `json:"strikes"`
+	MaxDifficulty int    `json:"max_difficulty"`
+	PersonalBest  bool   `json:"personal_best"` // The run beat the player's previous best
+	BestScore     int    `json:"best_score"`
+}
+
+// ZenStartPayload represents the payload for starting zen mode on a puzzle
+type ZenStartPayload struct {
+	PuzzleID string `json:"puzzle_id,omitempty"` // A puzzle is picked for the player if left out
+}
+
+// ZenFoundPayload represents a solution the player found in zen mode
+type ZenFoundPayload struct {
+	Solution       string `json:"solution"`
+	FirstDiscovery bool   `json:"first_discovery"`
+}
+
+// ZenPuzzlePayload represents the payload for a zen mode puzzle and the player's progress on it
+type ZenPuzzlePayload struct {
+	PuzzleID   string            `json:"puzzle_id"`
+	Puzzle     string            `json:"puzzle"`
+	Difficulty int               `json:"difficulty"`
+	Found      []ZenFoundPayload `json:"found"`
+	FoundCount int               `json:"found_count"`
+	Total      int               `json:"total"`
+}
+
+// ZenSubmitPayload represents the payload for submitting a solution in zen mode
+type ZenSubmitPayload struct {
+	PuzzleID string `json:"puzzle_id"`
+	Solution string `json:"solution"`
+}
+
+// ZenResultPayload represents the payload for the result of a zen mode submission
+type ZenResultPayload struct {
+	PuzzleID       string `json:"puzzle_id"`
+	IsCorrect      bool   `json:"is_correct"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+	Solution       string `json:"solution,omitempty"` // Canonical form of a correct solution
+	Duplicate      bool   `json:"duplicate"`
+	FirstDiscovery bool   `json:"first_discovery"`
+	FoundCount     int    `json:"found_count"`
+	Total          int    `json:"total"`
+}
+
+// TournamentStandingPayload represents a row of the tournament standings
+type TournamentStandingPayload struct {
+	Rank           int     `json:"rank"`
+	UserID         string  `json:"user_id"`
+	Username       string  `json:"username"`
+	Points         float64 `json:"points"`
+	Wins           int     `json:"wins"`
+	Draws          int     `json:"draws"`
+	Losses         int     `json:"losses"`
+	Buchholz       float64 `json:"buchholz"`
+	TotalSolveTime float64 `json:"total_solve_time"`
+	Eliminated     bool    `json:"eliminated"`
+}
+
+// TournamentStandingsPayload represents the payload for a tournament standings message
+type TournamentStandingsPayload struct {
+	TournamentID string                      `json:"tournament_id"`
+	Round        int                         `json:"round"`
+	Status       string                      `json:"status"`
+	Standings    []TournamentStandingPayload `json:"standings"`
+}
+
+// TournamentPairingPayload represents the payload for a tournament pairing message
+type TournamentPairingPayload struct {
+	TournamentID string         `json:"tournament_id"`
+	Round        int            `json:"round"`
+	Board        int            `json:"board"`
+	GameID       string         `json:"game_id,omitempty"`
+	Opponent     *PlayerPayload `json:"opponent,omitempty"` // nil for a bye
+	Deadline     int64          `json:"deadline"`
+}
+
+// BotChallengePayload represents the payload for messages about a challenge to a bot
+type BotChallengePayload struct {
+	ChallengeID string        `json:"challenge_id"`
+	Challenger  PlayerPayload `json:"challenger"`
+	Bot         PlayerPayload `json:"bot"`
+	GameType    string        `json:"game_type"`
+	Variant     string        `json:"variant"`
+	TimeLimit   int           `json:"time_limit"` // in seconds, 0 for no limit
+	Ranked      bool          `json:"ranked"`
+	ExpiresAt   int64         `json:"expires_at"`
+	Reason      string        `json:"reason,omitempty"` // Why a challenge was declined or canceled
+}
+
+// BotGameStartPayload represents the payload for a bot game start message, which delivers the puzzle
+type BotGameStartPayload struct {
+	GameID     string          `json:"game_id"`
+	GameType   string          `json:"game_type"`
+	Variant    string          `json:"variant"`
+	Puzzle     string          `json:"puzzle"`
+	Difficulty int             `json:"difficulty"`
+	TimeLimit  int             `json:"time_limit"`
+	StartedAt  int64           `json:"started_at"`
+	Opponents  []PlayerPayload `json:"opponents"`
+}
+
+// ForfeitPayload represents the payload for a player forfeited message
+type ForfeitPayload struct {
+	Reason       string `json:"reason"`
+	RatingChange int    `json:"rating_change"`
 }
 
 // MatchmakingService defines the interface for matchmaking operations
 type MatchmakingService interface {
-	JoinQueue(userID, gameType string, ranked bool) error
+	JoinQueue(userID, gameType string, ranked bool, variant string) error
 	LeaveQueue(userID string) error
-	GetQueueStatus(userID string) (bool, time.Duration, string, error)
+	GetQueueStatus(userID string) (*MatchmakingStatusPayload, error)
+}
+
+// PresenceListener is told when a player drops out of a game room and when they come back
+type PresenceListener interface {
+	PlayerDisconnected(gameID, userID string)
+	PlayerReconnected(gameID, userID string)
 }
 
 // Hub maintains the set of active clients and broadcasts messages
@@ -189,14 +478,26 @@ type Hub struct {
 	// Game rooms
 	gameRooms map[string]map[*Client]bool
 
+	// Spectators of game rooms, kept apart from the participants
+	spectators map[string]map[*Client]bool
+
+	// Messages withheld from spectators until the game ends
+	withheld map[string][][]byte
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 
 	// Message handlers
 	messageHandlers map[MessageType]func(*Client, *Message)
 
+	// Players who dropped out of a game room, by game ID
+	disconnected map[string]map[string]bool
+
 	// Matchmaking service
 	matchmakingService MatchmakingService
+
+	// Presence listener
+	presenceListener PresenceListener
 }
 
 // NewHub creates a new WebSocket hub
@@ -206,6 +507,9 @@ func NewHub(matchmakingService MatchmakingService) *Hub {
 		register:           make(chan *Client),
 		unregister:         make(chan *Client),
 		gameRooms:          make(map[string]map[*Client]bool),
+		spectators:         make(map[string]map[*Client]bool),
+		withheld:           make(map[string][][]byte),
+		disconnected:       make(map[string]map[string]bool),
 		messageHandlers:    make(map[MessageType]func(*Client, *Message)),
 		matchmakingService: matchmakingService,
 	}
@@ -244,13 +548,7 @@ func (h *Hub) registerDefaultHandlers() {
 		}
 	})
 
-	// Register solution submitted handler
-	h.RegisterMessageHandler(MessageTypeSolutionSubmitted, func(c *Client, msg *Message) {
-		// Broadcast solution to all clients in the game room
-		if msg.GameID != "" {
-			h.BroadcastToGame(msg.GameID, messageToBytes(msg))
-		}
-	})
+	// Solutions are not relayed from clients. The server announces them once they are validated.
 
 	// Register join queue handler
 	h.RegisterMessageHandler(MessageTypeJoinQueue, func(c *Client, msg *Message) {
@@ -272,7 +570,7 @@ func (h *Hub) registerDefaultHandlers() {
 		}
 
 		// Join the queue
-		err := matchmakingService.JoinQueue(c.UserID, payload.GameType, payload.Ranked)
+		err := matchmakingService.JoinQueue(c.UserID, payload.GameType, payload.Ranked, payload.Variant)
 		if err != nil {
 			log.Printf("Error joining queue: %v", err)
 
@@ -298,13 +596,14 @@ func (h *Hub) registerDefaultHandlers() {
 		}
 
 		// Send a matchmaking status update
-		statusMsg := &Message{
-			Type:      MessageTypeMatchmakingStatus,
-			UserID:    c.UserID,
-			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
-			Payload:   []byte(`{"status":"queued","time_in_queue":0}`),
+		status, err := matchmakingService.GetQueueStatus(c.UserID)
+		if err != nil {
+			log.Printf("Error getting queue status: %v", err)
+			status = &MatchmakingStatusPayload{Status: "queued"}
+		}
+		if err := h.SendMatchmakingStatus(c, *status); err != nil {
+			log.Printf("Error sending matchmaking status: %v", err)
 		}
-		h.sendMessageToClient(c, statusMsg)
 	})
 
 	// Register leave queue handler
@@ -411,6 +710,22 @@ func (h *Hub) processMessage(client *Client, data []byte) {
 		msg.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
 	}
 
+	// Spectators are read-only and may only keep their connection alive
+	if client.IsSpectator() && msg.Type != MessageTypePing {
+		payloadBytes, _ := json.Marshal(ErrorPayload{
+			Code:    403,
+			Message: "Spectators cannot send game messages",
+		})
+		h.sendMessageToClient(client, &Message{
+			Type:      MessageTypeError,
+			GameID:    msg.GameID,
+			UserID:    client.UserID,
+			Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+			Payload:   payloadBytes,
+		})
+		return
+	}
+
 	// Log the message
 	log.Printf("Processing message: type=%s, userID=%s", msg.Type, msg.UserID)
 
@@ -458,6 +773,13 @@ func (h *Hub) SetMatchmakingService(service MatchmakingService) {
 	h.matchmakingService = service
 }
 
+// SetPresenceListener sets the listener for players disconnecting from and reconnecting to game rooms
+func (h *Hub) SetPresenceListener(listener PresenceListener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.presenceListener = listener
+}
+
 // Run starts the WebSocket hub
 func (h *Hub) Run() {
 	for {
@@ -476,21 +798,25 @@ func (h *Hub) Run() {
 			h.sendMessageToClient(client, welcomeMsg)
 
 		case client := <-h.unregister:
+			// Stop spectating before the client is removed
+			spectated := h.removeSpectator(client)
+
 			h.mu.Lock()
+			var left []string
 			if _, ok := h.clients[client.ID]; ok {
 				// Remove client from all game rooms
 				for gameID, clients := range h.gameRooms {
 					if _, ok := clients[client]; ok {
 						delete(h.gameRooms[gameID], client)
-
-						// Notify other clients in the room that this client has left
-						leftMsg := &Message{
-							Type:      MessageTypePlayerLeft,
-							GameID:    gameID,
-							UserID:    client.UserID,
-							Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+						left = append(left, gameID)
+
+						// Remember the player unless they are still connected from another client
+						if isGameRoom(gameID) && !h.userInRoom(gameID, client.UserID) {
+							if _, ok := h.disconnected[gameID]; !ok {
+								h.disconnected[gameID] = make(map[string]bool)
+							}
+							h.disconnected[gameID][client.UserID] = true
 						}
-						h.BroadcastToGame(gameID, messageToBytes(leftMsg))
 
 						// Remove empty game rooms
 						if len(h.gameRooms[gameID]) == 0 {
@@ -503,7 +829,29 @@ func (h *Hub) Run() {
 				close(client.Send)
 				delete(h.clients, client.ID)
 			}
+			listener := h.presenceListener
 			h.mu.Unlock()
+
+			// Notify other clients in the rooms that this client has left.
+			// This happens outside the lock because BroadcastToGame takes it too.
+			for _, gameID := range left {
+				leftMsg := &Message{
+					Type:      MessageTypePlayerLeft,
+					GameID:    gameID,
+					UserID:    client.UserID,
+					Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+				}
+				h.BroadcastToGame(gameID, messageToBytes(leftMsg))
+
+				if listener != nil && h.IsDisconnected(gameID, client.UserID) {
+					listener.PlayerDisconnected(gameID, client.UserID)
+				}
+			}
+
+			// Let the remaining clients know the audience changed
+			for _, gameID := range spectated {
+				h.BroadcastSpectatorCount(gameID)
+			}
 		}
 	}
 }
@@ -607,7 +955,9 @@ func (h *Hub) BroadcastToGame(gameID string, message []byte) error {
 	defer h.mu.Unlock()
 
 	// Check if game room exists
-	if _, ok := h.gameRooms[gameID]; !ok {
+	_, hasPlayers := h.gameRooms[gameID]
+	_, hasSpectators := h.spectators[gameID]
+	if !hasPlayers && !hasSpectators {
 		return errors.New("game room not found")
 	}
 
@@ -624,6 +974,11 @@ func (h *Hub) BroadcastToGame(gameID string, message []byte) error {
 		}
 	}
 
+	// Send the spectators' view of the message
+	if hasSpectators {
+		h.sendToSpectators(gameID, message)
+	}
+
 	return nil
 }
 
@@ -635,6 +990,7 @@ func (h *Hub) BroadcastGameState(gameID string, status string, players []PlayerP
 		Players:   players,
 		StartedAt: startedAt,
 		Puzzle:    puzzle,
+		Spectators: h.SpectatorCount(gameID),
 	}
 
 	// Convert payload to JSON
@@ -662,6 +1018,7 @@ func (h *Hub) BroadcastGameStart(gameID string, startTime int64, puzzle string)
 		Status:    "active",
 		StartedAt: &startTime,
 		Puzzle:    puzzle,
+		Spectators: h.SpectatorCount(gameID),
 	}
 
 	// Convert payload to JSON
@@ -712,14 +1069,7 @@ func (h *Hub) BroadcastGameEnd(gameID string, winnerID string, players []PlayerP
 }
 
 // SendMatchmakingStatus sends a matchmaking status message to a specific client
-func (h *Hub) SendMatchmakingStatus(client *Client, status string, waitTime float64, queueSize int) error {
-	// Create matchmaking status message
-	payload := MatchmakingStatusPayload{
-		Status:    status,
-		WaitTime:  waitTime,
-		QueueSize: queueSize,
-	}
-
+func (h *Hub) SendMatchmakingStatus(client *Client, payload MatchmakingStatusPayload) error {
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -748,6 +1098,67 @@ func (h *Hub) SendMatchmakingStatus(client *Client, status string, waitTime floa
 	}
 }
 
+// SendReadyCheck sends a ready check message to a specific client
+func (h *Hub) SendReadyCheck(client *Client, messageType MessageType, payload ReadyCheckPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	h.sendMessageToClient(client, msg)
+	return nil
+}
+
+// SendParty sends a party message to a client
+func (h *Hub) SendParty(client *Client, messageType MessageType, payload PartyPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	h.sendMessageToClient(client, msg)
+	return nil
+}
+
+// SendChallenge sends a challenge message to a client
+func (h *Hub) SendChallenge(client *Client, messageType MessageType, payload ChallengePayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		GameID:    payload.GameID,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	h.sendMessageToClient(client, msg)
+	return nil
+}
+
 // GetClientByUserID gets a client by user ID
 func (h *Hub) GetClientByUserID(userID string) *Client {
 	h.mu.RLock()
@@ -772,6 +1183,11 @@ func (h *Hub) SendMatchFound(client *Client, gameID string, gameType string, opp
 		IsRanked: isRanked,
 	}
 
+	return h.SendTeamMatchFound(client, payload)
+}
+
+// SendTeamMatchFound sends a match found message to a specific client, describing both sides of a team match
+func (h *Hub) SendTeamMatchFound(client *Client, payload MatchFoundPayload) error {
 	// Convert payload to JSON
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
@@ -782,7 +1198,7 @@ func (h *Hub) SendMatchFound(client *Client, gameID string, gameType string, opp
 	msg := &Message{
 		Type:      MessageTypeMatchFound,
 		UserID:    client.UserID,
-		GameID:    gameID,
+		GameID:    payload.GameID,
 		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
 		Payload:   payloadBytes,
 	}
@@ -800,3 +1216,472 @@ func (h *Hub) SendMatchFound(client *Client, gameID string, gameType string, opp
 		return errors.New("client send buffer full")
 	}
 }
+
+// SendBotOffer offers a bot opponent to a player who has waited too long in the queue
+func (h *Hub) SendBotOffer(client *Client, payload BotOfferPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeBotOffer,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Send message to client
+	msgBytes := messageToBytes(msg)
+	if msgBytes == nil {
+		return errors.New("failed to convert message to bytes")
+	}
+
+	select {
+	case client.Send <- msgBytes:
+		return nil
+	default:
+		return errors.New("client send buffer full")
+	}
+}
+
+// TournamentRoomID returns the hub room ID used for a tournament
+func TournamentRoomID(tournamentID string) string {
+	return "tournament:" + tournamentID
+}
+
+// BroadcastTournamentStandings sends the current standings to all clients following a tournament
+func (h *Hub) BroadcastTournamentStandings(messageType MessageType, payload TournamentStandingsPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(TournamentRoomID(payload.TournamentID), messageToBytes(msg))
+}
+
+// SendTournamentPairing sends a player their pairing for a tournament round
+func (h *Hub) SendTournamentPairing(client *Client, payload TournamentPairingPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeTournamentPairing,
+		UserID:    client.UserID,
+		GameID:    payload.GameID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Send message to client
+	msgBytes := messageToBytes(msg)
+	if msgBytes == nil {
+		return errors.New("failed to convert message to bytes")
+	}
+
+	select {
+	case client.Send <- msgBytes:
+		return nil
+	default:
+		return errors.New("client send buffer full")
+	}
+}
+
+// JoinAsSpectator adds a read-only client to a game room
+func (h *Hub) JoinAsSpectator(gameID string, client *Client) {
+	h.mu.Lock()
+	if _, ok := h.spectators[gameID]; !ok {
+		h.spectators[gameID] = make(map[*Client]bool)
+	}
+	h.spectators[gameID][client] = true
+	h.mu.Unlock()
+
+	// Let everyone know the audience changed
+	h.BroadcastSpectatorCount(gameID)
+}
+
+// LeaveAsSpectator removes a read-only client from a game room
+func (h *Hub) LeaveAsSpectator(gameID string, client *Client) {
+	h.mu.Lock()
+	if _, ok := h.spectators[gameID][client]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.spectators[gameID], client)
+	if len(h.spectators[gameID]) == 0 {
+		delete(h.spectators, gameID)
+		delete(h.withheld, gameID)
+	}
+	h.mu.Unlock()
+
+	// Let everyone know the audience changed
+	h.BroadcastSpectatorCount(gameID)
+}
+
+// SpectatorCount returns the number of spectators watching a game
+func (h *Hub) SpectatorCount(gameID string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.spectators[gameID])
+}
+
+// BroadcastSpectatorCount sends the current number of spectators to everyone in a game room
+func (h *Hub) BroadcastSpectatorCount(gameID string) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(SpectatorCountPayload{
+		Spectators: h.SpectatorCount(gameID),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeSpectatorCount,
+		GameID:    gameID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(gameID, messageToBytes(msg))
+}
+
+// sendToSpectators sends the redacted view of a game message to its spectators.
+// Solutions are withheld until the game ends and then delivered in full.
+// The caller must hold the hub lock.
+func (h *Hub) sendToSpectators(gameID string, message []byte) {
+	msg, err := parseMessage(message)
+	if err != nil {
+		return
+	}
+
+	outgoing := [][]byte{message}
+
+	switch msg.Type {
+	case MessageTypeSolutionSubmitted:
+		var payload SolutionSubmittedPayload
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			// Never forward a solution that could not be redacted
+			return
+		}
+
+		// Keep the full message for when the game ends
+		h.withheld[gameID] = append(h.withheld[gameID], message)
+
+		payload.Solution = ""
+		payload.Redacted = true
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		msg.Payload = payloadBytes
+		outgoing = [][]byte{messageToBytes(msg)}
+
+	case MessageTypeGameEnd:
+		// Reveal the withheld solutions after the result
+		outgoing = append(outgoing, h.withheld[gameID]...)
+		delete(h.withheld, gameID)
+	}
+
+	for client := range h.spectators[gameID] {
+		for _, data := range outgoing {
+			select {
+			case client.Send <- data:
+				// Message sent successfully
+			default:
+				// Spectators that cannot keep up simply miss updates
+			}
+		}
+	}
+}
+
+// removeSpectator removes a client from every game it spectates and returns those games
+func (h *Hub) removeSpectator(client *Client) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var gameIDs []string
+	for gameID, clients := range h.spectators {
+		if _, ok := clients[client]; ok {
+			delete(clients, client)
+			if len(clients) == 0 {
+				delete(h.spectators, gameID)
+				delete(h.withheld, gameID)
+			}
+			gameIDs = append(gameIDs, gameID)
+		}
+	}
+
+	return gameIDs
+}
+
+// SendError sends an error message to a specific client
+func (h *Hub) SendError(client *Client, code int, message string) {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(ErrorPayload{
+		Code:    code,
+		Message: message,
+	})
+	if err != nil {
+		log.Printf("Error marshaling error payload: %v", err)
+		return
+	}
+
+	// Send message to client
+	h.sendMessageToClient(client, &Message{
+		Type:      MessageTypeError,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	})
+}
+
+// LobbyRoomID returns the hub room ID used for a private lobby
+func LobbyRoomID(code string) string {
+	return "lobby:" + code
+}
+
+// BroadcastLobbyState sends the current lobby state to everyone in the lobby
+func (h *Hub) BroadcastLobbyState(payload LobbyStatePayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeLobbyState,
+		GameID:    payload.GameID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(LobbyRoomID(payload.Code), messageToBytes(msg))
+}
+
+// BroadcastLobbyKicked tells everyone in a lobby that a player was removed by the host
+func (h *Hub) BroadcastLobbyKicked(code, userID string) error {
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeLobbyKicked,
+		UserID:    userID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(LobbyRoomID(code), messageToBytes(msg))
+}
+
+// RemoveFromLobby takes every connection of a user out of a lobby's room, so that they stop
+// receiving its updates
+func (h *Hub) RemoveFromLobby(code, userID string) {
+	roomID := LobbyRoomID(code)
+
+	h.mu.RLock()
+	var clients []*Client
+	for c := range h.gameRooms[roomID] {
+		if c.UserID == userID {
+			clients = append(clients, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	// Leaving takes the client's lock before the hub's, so the hub's lock must not be held here
+	for _, c := range clients {
+		c.LeaveRoom(roomID)
+	}
+}
+
+// BotRoomID returns the hub room ID used for the event stream of a bot
+func BotRoomID(botID string) string {
+	return "bot:" + botID
+}
+
+// IsBotOnline checks if a bot is connected to its event stream
+func (h *Hub) IsBotOnline(botID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.gameRooms[BotRoomID(botID)]) > 0
+}
+
+// SendBotChallenge sends a message about a challenge to the event stream of the challenged bot
+func (h *Hub) SendBotChallenge(messageType MessageType, payload BotChallengePayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		UserID:    payload.Bot.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(BotRoomID(payload.Bot.UserID), messageToBytes(msg))
+}
+
+// StartBotGame joins a bot's event stream to one of its games and delivers the game's puzzle.
+// From then on the stream also receives every event of the game.
+func (h *Hub) StartBotGame(botID string, payload BotGameStartPayload) error {
+	// Find the bot's stream clients
+	h.mu.RLock()
+	streams := make([]*Client, 0, len(h.gameRooms[BotRoomID(botID)]))
+	for c := range h.gameRooms[BotRoomID(botID)] {
+		streams = append(streams, c)
+	}
+	h.mu.RUnlock()
+
+	if len(streams) == 0 {
+		return errors.New("bot is not connected")
+	}
+
+	// Join the game room
+	for _, c := range streams {
+		c.JoinRoom(payload.GameID)
+	}
+
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypeBotGameStart,
+		GameID:    payload.GameID,
+		UserID:    botID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(BotRoomID(botID), messageToBytes(msg))
+}
+
+// IsDisconnected checks if a player dropped out of a game room and has not come back yet
+func (h *Hub) IsDisconnected(gameID, userID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.disconnected[gameID][userID]
+}
+
+// DisconnectedRooms returns the game rooms a player dropped out of
+func (h *Hub) DisconnectedRooms(userID string) []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var rooms []string
+	for gameID, users := range h.disconnected {
+		if users[userID] {
+			rooms = append(rooms, gameID)
+		}
+	}
+
+	return rooms
+}
+
+// ForgetDisconnected stops tracking a player that dropped out of a game room
+func (h *Hub) ForgetDisconnected(gameID, userID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.disconnected[gameID], userID)
+	if len(h.disconnected[gameID]) == 0 {
+		delete(h.disconnected, gameID)
+	}
+}
+
+// ResumeGameRoom tells a game room that a dropped player is back.
+// It does nothing if the player was not disconnected from the room.
+func (h *Hub) ResumeGameRoom(gameID string, client *Client) bool {
+	h.mu.Lock()
+	if !h.disconnected[gameID][client.UserID] {
+		h.mu.Unlock()
+		return false
+	}
+	delete(h.disconnected[gameID], client.UserID)
+	if len(h.disconnected[gameID]) == 0 {
+		delete(h.disconnected, gameID)
+	}
+	listener := h.presenceListener
+	h.mu.Unlock()
+
+	// Notify the room
+	reconnectedMsg := &Message{
+		Type:      MessageTypePlayerReconnected,
+		GameID:    gameID,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	if err := h.BroadcastToGame(gameID, messageToBytes(reconnectedMsg)); err != nil {
+		log.Printf("Error broadcasting player reconnected: %v", err)
+	}
+
+	if listener != nil {
+		listener.PlayerReconnected(gameID, client.UserID)
+	}
+
+	return true
+}
+
+// BroadcastPlayerForfeited tells a game room that a player forfeited
+func (h *Hub) BroadcastPlayerForfeited(gameID, userID string, payload ForfeitPayload) error {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      MessageTypePlayerForfeited,
+		GameID:    gameID,
+		UserID:    userID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Broadcast message
+	return h.BroadcastToGame(gameID, messageToBytes(msg))
+}
+
+// Helper function to check if a user has a client in a game room. Callers must hold h.mu.
+func (h *Hub) userInRoom(gameID, userID string) bool {
+	for c := range h.gameRooms[gameID] {
+		if c.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper function to check if a room is a game room rather than a lobby or tournament room
+func isGameRoom(roomID string) bool {
+	return !strings.Contains(roomID, ":")
+}
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/hectoclash/internal/zen"
+)
+
+// ZenHandler handles WebSocket messages for zen mode
+type ZenHandler struct {
+	hub        *Hub
+	zenService *zen.Service
+}
+
+// NewZenHandler creates a new zen handler
+func NewZenHandler(hub *Hub, zenService *zen.Service) *ZenHandler {
+	handler := &ZenHandler{
+		hub:        hub,
+		zenService: zenService,
+	}
+
+	// Register message handlers
+	hub.RegisterMessageHandler(MessageTypeZenStart, handler.handleZenStart)
+	hub.RegisterMessageHandler(MessageTypeZenSubmit, handler.handleZenSubmit)
+
+	return handler
+}
+
+// handleZenStart handles a zen start message by sending the puzzle and the player's progress
+func (h *ZenHandler) handleZenStart(client *Client, msg *Message) {
+	// Parse the payload
+	var payload ZenStartPayload
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			log.Printf("Error parsing zen start payload: %v", err)
+			h.sendError(client, "Invalid payload")
+			return
+		}
+	}
+
+	progress, err := h.zenService.Start(client.UserID, payload.PuzzleID)
+	if err != nil {
+		log.Printf("Error starting zen mode: %v", err)
+		h.sendError(client, "Failed to start zen mode")
+		return
+	}
+
+	found := make([]ZenFoundPayload, len(progress.Found))
+	for i, f := range progress.Found {
+		found[i] = ZenFoundPayload{
+			Solution:       f.Solution,
+			FirstDiscovery: f.FirstDiscovery,
+		}
+	}
+
+	h.send(client, MessageTypeZenPuzzle, ZenPuzzlePayload{
+		PuzzleID:   progress.PuzzleID,
+		Puzzle:     progress.Sequence,
+		Difficulty: progress.Difficulty,
+		Found:      found,
+		FoundCount: len(found),
+		Total:      progress.Total,
+	})
+}
+
+// handleZenSubmit handles a zen submit message
+func (h *ZenHandler) handleZenSubmit(client *Client, msg *Message) {
+	// Parse the payload
+	var payload ZenSubmitPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil || payload.PuzzleID == "" {
+		h.sendError(client, "Invalid payload")
+		return
+	}
+
+	result, err := h.zenService.Submit(client.UserID, payload.PuzzleID, payload.Solution)
+	if err != nil {
+		log.Printf("Error submitting zen solution: %v", err)
+		h.sendError(client, "Failed to submit solution")
+		return
+	}
+
+	h.send(client, MessageTypeZenResult, ZenResultPayload{
+		PuzzleID:       payload.PuzzleID,
+		IsCorrect:      result.IsCorrect,
+		ErrorMessage:   result.ErrorMessage,
+		Solution:       result.Solution,
+		Duplicate:      result.Duplicate,
+		FirstDiscovery: result.FirstDiscovery,
+		FoundCount:     result.FoundCount,
+		Total:          result.Total,
+	})
+}
+
+// send sends a zen mode message to the client
+func (h *ZenHandler) send(client *Client, messageType MessageType, payload any) {
+	// Convert payload to JSON
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Error marshaling %s payload: %v", messageType, err)
+		return
+	}
+
+	// Create message
+	msg := &Message{
+		Type:      messageType,
+		UserID:    client.UserID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Payload:   payloadBytes,
+	}
+
+	// Send message to client
+	h.hub.sendMessageToClient(client, msg)
+}
+
+// sendError sends an error message to the client
+func (h *ZenHandler) sendError(client *Client, message string) {
+	h.send(client, MessageTypeError, ErrorPayload{
+		Code:    400,
+		Message: message,
+	})
+}
@@ -0,0 +1,180 @@
+package zen
+
+import (
+	"github.com/hectoclash/internal/models"
+	"github.com/hectoclash/internal/puzzle"
+	"github.com/hectoclash/internal/repository"
+)
+
+// Found is a solution a player found
+type Found struct {
+	Solution       string `json:"solution"`
+	FirstDiscovery bool   `json:"first_discovery"` // The player was the first to find it
+}
+
+// Progress is a player's progress on finding every solution of a puzzle
+type Progress struct {
+	PuzzleID   string  `json:"puzzle_id"`
+	Sequence   string  `json:"sequence"`
+	Difficulty int     `json:"difficulty"`
+	Found      []Found `json:"found"`
+	Total      int     `json:"total"`
+}
+
+// Result is the outcome of a solution submitted in zen mode
+type Result struct {
+	IsCorrect      bool   `json:"is_correct"`
+	ErrorMessage   string `json:"error_message,omitempty"`
+	Solution       string `json:"solution,omitempty"` // Canonical form of a correct solution
+	Duplicate      bool   `json:"duplicate"`          // The player had already found it
+	FirstDiscovery bool   `json:"first_discovery"`    // No one had found it before
+	FoundCount     int    `json:"found_count"`
+	Total          int    `json:"total"`
+}
+
+// Service provides zen mode, in which a player tries to find every distinct solution of one puzzle.
+// Solutions are compared in canonical form against the stored solutions of the puzzle, and the
+// solutions each player found are kept, so a puzzle can be picked up again later.
+type Service struct {
+	userRepo      *repository.UserRepository
+	puzzleRepo    *repository.PuzzleRepository
+	zenRepo       *repository.ZenRepository
+	puzzleService *puzzle.Service
+}
+
+// NewService creates a new zen service
+func NewService(userRepo *repository.UserRepository, puzzleRepo *repository.PuzzleRepository, zenRepo *repository.ZenRepository, puzzleService *puzzle.Service) *Service {
+	return &Service{
+		userRepo:      userRepo,
+		puzzleRepo:    puzzleRepo,
+		zenRepo:       zenRepo,
+		puzzleService: puzzleService,
+	}
+}
+
+// Start gets a puzzle and the player's progress on it. Without a puzzle ID, a puzzle the player
+// has not seen is picked for their practice rating.
+func (s *Service) Start(userID, puzzleID string) (*Progress, error) {
+	var p *models.Puzzle
+	var err error
+	if puzzleID != "" {
+		p, err = s.puzzleService.GetPuzzle(puzzleID)
+	} else {
+		var practiceRating *models.UserRating
+		practiceRating, err = s.userRepo.GetUserRating(userID, models.RatingModePractice)
+		if err != nil {
+			return nil, err
+		}
+		p, err = s.puzzleService.GetPuzzleForUser(userID, practiceRating.Rating)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetProgress(userID, p)
+}
+
+// GetProgress gets the solutions a player found to a puzzle out of all its solutions
+func (s *Service) GetProgress(userID string, p *models.Puzzle) (*Progress, error) {
+	solutions, err := s.puzzleService.GetSolutions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.zenRepo.FindFound(userID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &Progress{
+		PuzzleID:   p.ID,
+		Sequence:   p.Sequence,
+		Difficulty: int(p.Difficulty),
+		Found:      make([]Found, len(found)),
+		Total:      len(solutions),
+	}
+	for i, f := range found {
+		progress.Found[i] = Found{
+			Solution:       f.Solution.Expression,
+			FirstDiscovery: f.Solution.DiscoveredBy != nil && *f.Solution.DiscoveredBy == userID,
+		}
+	}
+
+	return progress, nil
+}
+
+// Submit checks a solution to a puzzle and adds it to the player's found solutions
+func (s *Service) Submit(userID, puzzleID, solution string) (*Result, error) {
+	p, err := s.puzzleService.GetPuzzle(puzzleID)
+	if err != nil {
+		return nil, err
+	}
+
+	solutions, err := s.puzzleService.GetSolutions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.zenRepo.FindFound(userID, p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		FoundCount: len(found),
+		Total:      len(solutions),
+	}
+
+	// Check the solution; zen mode has no scores or ratings
+	validation := s.puzzleService.CheckSolution(p.Sequence, p.Difficulty, solution, 0)
+	if !validation.IsCorrect {
+		result.ErrorMessage = validation.ErrorMessage
+		return result, nil
+	}
+
+	canonical, err := puzzle.CanonicalForm(solution)
+	if err != nil {
+		return nil, err
+	}
+	result.IsCorrect = true
+	result.Solution = canonical
+
+	// Find the stored solution, storing it if the solver missed it
+	var stored *models.PuzzleSolution
+	for i := range solutions {
+		if solutions[i].Expression == canonical {
+			stored = &solutions[i]
+			break
+		}
+	}
+	if stored == nil {
+		stored, err = s.puzzleService.AddSolution(p, canonical)
+		if err != nil {
+			return nil, err
+		}
+		result.Total++
+	}
+
+	// Record the find
+	added, err := s.zenRepo.AddFound(&models.FoundSolution{
+		UserID:     userID,
+		PuzzleID:   p.ID,
+		SolutionID: stored.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		result.Duplicate = true
+		return result, nil
+	}
+	result.FoundCount++
+
+	// The first player to find a solution is credited with it
+	result.FirstDiscovery, err = s.puzzleRepo.ClaimDiscovery(stored.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}